@@ -22,7 +22,9 @@
 package main
 
 import (
+	"context"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -34,10 +36,13 @@ import (
 	"github.com/yoockh/go-game-rental-api/app/echo-server/router"
 	_ "github.com/yoockh/go-game-rental-api/docs"
 	"github.com/yoockh/go-game-rental-api/internal/handler"
+	"github.com/yoockh/go-game-rental-api/internal/model"
 	"github.com/yoockh/go-game-rental-api/internal/repository"
 	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+	"github.com/yoockh/go-game-rental-api/internal/repository/storage"
 	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
 	"github.com/yoockh/go-game-rental-api/internal/service"
+	"github.com/yoockh/go-game-rental-api/internal/worker"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -55,6 +60,22 @@ func main() {
 		logrus.Warn("Using default JWT secret for development")
 	}
 
+	paymentWindow := durationFromEnv("BOOKING_PAYMENT_WINDOW", 24*time.Hour)
+	expiryInterval := durationFromEnv("BOOKING_EXPIRY_INTERVAL", 5*time.Minute)
+	waitlistHoldWindow := durationFromEnv("WAITLIST_HOLD_WINDOW", 24*time.Hour)
+	reconcileAfter := durationFromEnv("PAYMENT_RECONCILE_AFTER", 30*time.Minute)
+	reconcileInterval := durationFromEnv("PAYMENT_RECONCILE_INTERVAL", 15*time.Minute)
+	offlineAccount := model.BankTransfer{
+		Bank:          os.Getenv("OFFLINE_BANK_NAME"),
+		AccountNumber: os.Getenv("OFFLINE_BANK_ACCOUNT_NUMBER"),
+		AccountName:   os.Getenv("OFFLINE_BANK_ACCOUNT_NAME"),
+	}
+	taxRule := model.TaxRule{
+		Rate:           intFromEnv("TAX_RATE", 0),
+		DepositTaxable: boolFromEnv("TAX_DEPOSIT_TAXABLE", false),
+		Inclusive:      boolFromEnv("TAX_INCLUSIVE", false),
+	}
+
 	// Database connection WITHOUT prepared statements
 	dbURL := cfg.DatabaseURL
 
@@ -102,9 +123,22 @@ func main() {
 			&model.User{},
 			&model.Category{},
 			&model.Game{},
+			&model.PromoCode{},
+			&model.Order{},
 			&model.Booking{},
+			&model.BookingStatusHistory{},
+			&model.BookingDateChange{},
+			&model.BookingSettlement{},
 			&model.Payment{},
+			&model.PaymentRefund{},
+			&model.PaymentEvent{},
+			&model.PaymentReconciliation{},
+			&model.PaymentDispute{},
+			&model.Invoice{},
 			&model.Review{},
+			&model.WaitlistEntry{},
+			&model.Wallet{},
+			&model.WalletEntry{},
 		)
 		if err != nil {
 			logrus.Warn("Migration warning:", err)
@@ -117,12 +151,23 @@ func main() {
 	categoryRepo := repository.NewCategoryRepository(db)
 	gameRepo := repository.NewGameRepository(db)
 	bookingRepo := repository.NewBookingRepository(db)
+	bookingHistoryRepo := repository.NewBookingStatusHistoryRepository(db)
+	dateChangeRepo := repository.NewBookingDateChangeRepository(db)
+	settlementRepo := repository.NewBookingSettlementRepository(db)
+	orderRepo := repository.NewOrderRepository(db)
+	waitlistRepo := repository.NewWaitlistRepository(db)
 	paymentRepo := repository.NewPaymentRepository(db)
+	paymentEventRepo := repository.NewPaymentEventRepository(db)
+	reconciliationRepo := repository.NewPaymentReconciliationRepository(db)
 	reviewRepo := repository.NewReviewRepository(db)
+	invoiceRepo := repository.NewInvoiceRepository(db)
+	promoCodeRepo := repository.NewPromoCodeRepository(db)
+	walletRepo := repository.NewWalletRepository(db)
+	disputeRepo := repository.NewPaymentDisputeRepository(db)
+	txManager := repository.NewTxManager(db)
 
 	// Initialize 3rd party repositories with fallback to mock
 	var emailRepo email.EmailRepository
-	var transactionRepo transaction.TransactionRepository
 
 	if repo, err := email.NewSendGridRepository(); err != nil {
 		logrus.Warn("SendGrid failed, using mock:", err)
@@ -131,29 +176,80 @@ func main() {
 		emailRepo = repo
 	}
 
-	if repo, err := transaction.NewMidtransRepository(); err != nil {
-		logrus.Warn("Midtrans failed, using mock:", err)
-		transactionRepo = &transaction.MockTransactionRepository{}
+	// Transfer receipts hold customers' bank details, so they go to a private bucket
+	var receiptStorage storage.StorageRepository
+	if repo, err := storage.NewSupabasePrivateRepository(); err != nil {
+		logrus.Warn("Supabase receipt storage failed, using mock:", err)
+		receiptStorage = &storage.MockStorageRepository{}
+	} else {
+		receiptStorage = repo
+	}
+
+	gateways := transaction.NewRegistry()
+	if repo, err := transaction.NewMidtransRepository(); err == nil {
+		gateways.Register(string(model.ProviderMidtrans), repo)
+	} else if boolFromEnv("PAYMENT_GATEWAY_MOCK", false) {
+		// Local development only: charges never settle and webhooks are rejected
+		logrus.Warn("Midtrans failed, using mock (PAYMENT_GATEWAY_MOCK):", err)
+		gateways.Register(string(model.ProviderMidtrans), &transaction.MockTransactionRepository{})
+	} else {
+		logrus.Warn("Midtrans disabled:", err)
+	}
+	if repo, err := transaction.NewStripeRepository(); err != nil {
+		logrus.Warn("Stripe disabled:", err)
 	} else {
-		transactionRepo = repo
+		gateways.Register(string(model.ProviderStripe), repo)
 	}
 
 	// Initialize services
 	userService := service.NewUserService(userRepo)
 	categoryService := service.NewCategoryService(categoryRepo)
 	gameService := service.NewGameService(gameRepo)
-	bookingService := service.NewBookingService(bookingRepo, gameRepo, userRepo, emailRepo)
-	paymentService := service.NewPaymentService(paymentRepo, bookingRepo, userRepo, gameRepo, bookingService, transactionRepo, emailRepo)
+	waitlistService := service.NewWaitlistService(txManager, waitlistRepo, gameRepo, emailRepo, waitlistHoldWindow)
+	bookingService := service.NewBookingService(txManager, bookingRepo, bookingHistoryRepo, gameRepo, userRepo, waitlistService, gateways, emailRepo, paymentWindow, taxRule)
+	orderService := service.NewOrderService(txManager, orderRepo, gameRepo, waitlistService, gateways, emailRepo, paymentWindow, taxRule)
+	refundService := service.NewRefundService(txManager, paymentRepo, bookingService, orderService, gateways, emailRepo)
+	bookingChangeService := service.NewBookingChangeService(txManager, bookingRepo, dateChangeRepo, paymentRepo, gateways, refundService, emailRepo, paymentWindow)
+	bookingSettlementService := service.NewBookingSettlementService(txManager, bookingRepo, settlementRepo, waitlistService, refundService, emailRepo)
+	disputeService := service.NewDisputeService(txManager, disputeRepo, userRepo, bookingService, orderService, gateways, emailRepo)
+	paymentService := service.NewPaymentService(txManager, paymentRepo, paymentEventRepo, reconciliationRepo, bookingRepo, orderRepo, userRepo, gameRepo, walletRepo, bookingService, bookingChangeService, orderService, disputeService, gateways, emailRepo, receiptStorage, offlineAccount, reconcileAfter)
 	reviewService := service.NewReviewService(reviewRepo, bookingRepo)
+	invoiceService := service.NewInvoiceService(txManager, bookingRepo, invoiceRepo)
+	promoCodeService := service.NewPromoCodeService(promoCodeRepo)
+	walletService := service.NewWalletService(walletRepo, userRepo)
+
+	// Start background jobs
+	go worker.RunPeriodic(context.Background(), "booking-expiry", expiryInterval, func() error {
+		if _, err := bookingService.ExpireUnpaidBookings(); err != nil {
+			return err
+		}
+		if _, err := bookingChangeService.ExpireUnpaidChanges(); err != nil {
+			return err
+		}
+		_, err := orderService.ExpireUnpaidOrders()
+		return err
+	})
+	go worker.RunPeriodic(context.Background(), "waitlist-holds", expiryInterval, func() error {
+		_, err := waitlistService.ProcessHolds()
+		return err
+	})
+	go worker.RunPeriodic(context.Background(), "payment-reconciliation", reconcileInterval, func() error {
+		_, err := paymentService.ReconcilePending()
+		return err
+	})
 
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(userService, JwtSecret, emailRepo)
 	userHandler := handler.NewUserHandler(userService)
 	categoryHandler := handler.NewCategoryHandler(categoryService)
 	gameHandler := handler.NewGameHandler(gameService)
-	bookingHandler := handler.NewBookingHandler(bookingService)
-	paymentHandler := handler.NewPaymentHandler(paymentService)
+	bookingHandler := handler.NewBookingHandler(bookingService, bookingChangeService, bookingSettlementService, invoiceService)
+	orderHandler := handler.NewOrderHandler(orderService)
+	waitlistHandler := handler.NewWaitlistHandler(waitlistService)
+	paymentHandler := handler.NewPaymentHandler(paymentService, refundService, disputeService)
 	reviewHandler := handler.NewReviewHandler(reviewService)
+	promoCodeHandler := handler.NewPromoCodeHandler(promoCodeService)
+	walletHandler := handler.NewWalletHandler(walletService)
 
 	// Setup Echo
 	e := echo.New()
@@ -171,8 +267,12 @@ func main() {
 		categoryHandler,
 		gameHandler,
 		bookingHandler,
+		orderHandler,
+		waitlistHandler,
 		paymentHandler,
 		reviewHandler,
+		promoCodeHandler,
+		walletHandler,
 		JwtSecret,
 	)
 
@@ -184,3 +284,48 @@ func main() {
 	logrus.Infof("Server starting on :%s", port)
 	logrus.Fatal(e.Start(":" + port))
 }
+
+// durationFromEnv reads a Go duration (e.g. "24h", "5m") from the environment,
+// falling back to def when unset or invalid
+func durationFromEnv(key string, def time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		logrus.Warnf("Invalid %s=%q, using default %s", key, raw, def)
+		return def
+	}
+	return d
+}
+
+// intFromEnv reads a non-negative integer from the environment, falling back to
+// def when unset or invalid
+func intFromEnv(key string, def int64) int64 {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n < 0 {
+		logrus.Warnf("Invalid %s=%q, using default %d", key, raw, def)
+		return def
+	}
+	return n
+}
+
+// boolFromEnv reads a boolean (e.g. "true", "1") from the environment, falling
+// back to def when unset or invalid
+func boolFromEnv(key string, def bool) bool {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		logrus.Warnf("Invalid %s=%q, using default %t", key, raw, def)
+		return def
+	}
+	return b
+}
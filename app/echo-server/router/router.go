@@ -13,8 +13,12 @@ func RegisterRoutes(
 	categoryH *handler.CategoryHandler,
 	gameH *handler.GameHandler,
 	bookingH *handler.BookingHandler,
+	orderH *handler.OrderHandler,
+	waitlistH *handler.WaitlistHandler,
 	paymentH *handler.PaymentHandler,
 	reviewH *handler.ReviewHandler,
+	promoH *handler.PromoCodeHandler,
+	walletH *handler.WalletHandler,
 	jwtSecret string,
 ) {
 	// Public endpoints
@@ -22,11 +26,13 @@ func RegisterRoutes(
 	e.POST("/auth/login", authH.Login)
 	e.GET("/games", gameH.GetAllGames)
 	e.GET("/games/:id", gameH.GetGameDetail)
+	e.GET("/games/:id/availability", gameH.GetGameAvailability)
 	e.GET("/games/search", gameH.SearchGames)
 	e.GET("/categories", categoryH.GetAllCategories)
 	e.GET("/categories/:id", categoryH.GetCategoryDetail)
 	e.GET("/games/:game_id/reviews", reviewH.GetGameReviews)
 	e.POST("/webhooks/payments", paymentH.PaymentWebhook)
+	e.POST("/webhooks/payments/:provider", paymentH.PaymentWebhook)
 
 	// Protected routes
 	jwtConfig := myMiddleware.JWTConfig{
@@ -39,17 +45,33 @@ func RegisterRoutes(
 
 	protected.GET("/users/me", userH.GetMyProfile)
 	protected.PUT("/users/me", userH.UpdateMyProfile)
+	protected.GET("/wallet", walletH.GetMyWallet)
 
 	protected.POST("/bookings", bookingH.CreateBooking)
 	protected.GET("/bookings/my", bookingH.GetMyBookings)
 	protected.GET("/bookings/:booking_id", bookingH.GetBookingDetail)
 	protected.PATCH("/bookings/:booking_id/cancel", bookingH.CancelBooking)
+	protected.GET("/bookings/:booking_id/history", bookingH.GetBookingHistory)
+	protected.GET("/bookings/:booking_id/invoice", bookingH.GetBookingInvoice)
+	protected.POST("/bookings/:booking_id/extend", bookingH.ExtendBooking)
+	protected.PATCH("/bookings/:booking_id/dates", bookingH.RescheduleBooking)
 
 	protected.POST("/bookings/:booking_id/payments", paymentH.CreatePayment)
 	protected.GET("/bookings/:booking_id/payments", paymentH.GetPaymentByBooking)
 
 	protected.POST("/bookings/:booking_id/reviews", reviewH.CreateReview)
 
+	protected.POST("/orders", orderH.CreateOrder)
+	protected.GET("/orders/my", orderH.GetMyOrders)
+	protected.GET("/orders/:order_id", orderH.GetOrderDetail)
+	protected.PATCH("/orders/:order_id/cancel", orderH.CancelOrder)
+	protected.POST("/orders/:order_id/payments", paymentH.CreateOrderPayment)
+	protected.POST("/payments/:id/proof", paymentH.UploadPaymentProof)
+
+	protected.POST("/games/:id/waitlist", waitlistH.JoinWaitlist)
+	protected.GET("/waitlist/my", waitlistH.GetMyWaitlist)
+	protected.DELETE("/waitlist/:id", waitlistH.LeaveWaitlist)
+
 	// Admin routes
 	admin := protected.Group("/admin")
 	admin.Use(myMiddleware.RequireRoles("admin", "super_admin")) // BALIK PAKAI INI
@@ -62,16 +84,39 @@ func RegisterRoutes(
 	admin.PUT("/categories/:id", categoryH.UpdateCategory)
 	admin.DELETE("/categories/:id", categoryH.DeleteCategory)
 
+	admin.GET("/promo-codes", promoH.GetAllPromoCodes)
+	admin.GET("/promo-codes/:id", promoH.GetPromoCodeDetail)
+	admin.POST("/promo-codes", promoH.CreatePromoCode)
+	admin.PUT("/promo-codes/:id", promoH.UpdatePromoCode)
+	admin.DELETE("/promo-codes/:id", promoH.DeletePromoCode)
+
 	admin.GET("/bookings", bookingH.GetAllBookings)
+	admin.GET("/bookings/tax-report", bookingH.GetTaxReport)
 	admin.PATCH("/bookings/:id/status", bookingH.UpdateBookingStatus)
+	admin.POST("/bookings/:id/return", bookingH.ReturnBooking)
+	admin.POST("/bookings/:id/return/refund", bookingH.RetryDepositRefund)
 
 	admin.GET("/payments", paymentH.GetAllPayments)
 	admin.GET("/payments/:id", paymentH.GetPaymentDetail)
 	admin.GET("/payments/status", paymentH.GetPaymentsByStatus)
+	admin.GET("/payments/events", paymentH.GetPaymentEvents)
+	admin.POST("/payments/events/:id/replay", paymentH.ReplayPaymentEvent)
+	admin.GET("/payments/reconciliations", paymentH.GetPaymentReconciliations)
+	admin.GET("/payments/reviews", paymentH.GetPaymentReviewQueue)
+	admin.GET("/payments/disputes", paymentH.GetPaymentDisputes)
+	admin.GET("/payments/disputes/:id", paymentH.GetPaymentDispute)
+	admin.POST("/payments/disputes/:id/evidence", paymentH.SubmitDisputeEvidence)
+	admin.POST("/payments/:id/approve", paymentH.ApprovePayment)
+	admin.POST("/payments/:id/deny", paymentH.DenyPayment)
+	admin.POST("/payments/:id/verify", paymentH.VerifyPayment)
+	admin.GET("/payments/:id/proof", paymentH.GetPaymentProof)
+	admin.POST("/payments/:id/refund", paymentH.RefundPayment)
 
 	admin.GET("/users", userH.GetAllUsers)
 	admin.GET("/users/:id", userH.GetUserDetail)
 	admin.PATCH("/users/:id/role", userH.UpdateUserRole)
 	admin.PATCH("/users/:id/status", userH.ToggleUserStatus)
 	admin.DELETE("/users/:id", userH.DeleteUser)
+	admin.GET("/users/:id/wallet", walletH.GetUserWallet)
+	admin.POST("/users/:id/wallet/adjustments", walletH.AdjustUserWallet)
 }
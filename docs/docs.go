@@ -31,7 +31,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get list of all bookings (Admin only)",
+                "description": "Get list of all bookings with filters, customer search and sorting (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -56,6 +56,98 @@ const docTemplate = `{
                         "description": "Items per page",
                         "name": "limit",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "confirmed",
+                            "active",
+                            "completed",
+                            "cancelled"
+                        ],
+                        "type": "string",
+                        "description": "Booking status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Game ID",
+                        "name": "game_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "paid",
+                            "failed",
+                            "refunded"
+                        ],
+                        "type": "string",
+                        "description": "Payment status",
+                        "name": "payment_status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date on or after (YYYY-MM-DD)",
+                        "name": "start_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date on or before (YYYY-MM-DD)",
+                        "name": "start_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date on or after (YYYY-MM-DD)",
+                        "name": "end_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date on or before (YYYY-MM-DD)",
+                        "name": "end_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search customer email or name",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "updated_at",
+                            "start_date",
+                            "end_date",
+                            "payment_due_at"
+                        ],
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "Sort column",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Sort direction",
+                        "name": "order",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -66,6 +158,213 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/bookings/tax-report": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Total the VAT of the bookings invoiced in a period, per tax rate, for tax filing (Admin only). Defaults to the current month up to today",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin - Bookings"
+                ],
+                "summary": "Get tax report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Invoiced on or after (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Invoiced on or before (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tax report retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/dto.TaxReportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid period",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/bookings/{id}/return": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Complete an active booking and settle its security deposit. Late fees and damage charges are deducted and the rest is refunded, or credited to the customer's wallet with to_wallet (Admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin - Bookings"
+                ],
+                "summary": "Record game return",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Booking ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Return details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ReturnBookingRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Booking returned successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.BookingSettlement"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Booking not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/bookings/{id}/return/refund": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Send a security deposit refund that failed when the game was returned again, or credit it to the customer's wallet with to_wallet (Admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin - Bookings"
+                ],
+                "summary": "Retry deposit refund",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Booking ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Refund destination",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/dto.RetryDepositRefundRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Deposit refund retried",
+                        "schema": {
+                            "$ref": "#/definitions/model.BookingSettlement"
+                        }
+                    },
+                    "400": {
+                        "description": "Refund has not failed",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
@@ -79,6 +378,13 @@ const docTemplate = `{
                             "type": "object",
                             "additionalProperties": true
                         }
+                    },
+                    "404": {
+                        "description": "Booking or settlement not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
                     }
                 }
             }
@@ -90,7 +396,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update booking status (Admin only)",
+                "description": "Update booking status (Admin only). Completing a booking records the return as of today without damage, use the return endpoint for anything else",
                 "consumes": [
                     "application/json"
                 ],
@@ -115,12 +421,7 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "object",
-                            "properties": {
-                                "status": {
-                                    "type": "string"
-                                }
-                            }
+                            "$ref": "#/definitions/dto.UpdateBookingStatusRequest"
                         }
                     }
                 ],
@@ -587,14 +888,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/payments/status": {
+        "/admin/payments/disputes": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get list of payments filtered by status (Admin only)",
+                "description": "Get the chargebacks reported by payment providers, newest first, optionally filtered by status (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -604,20 +905,19 @@ const docTemplate = `{
                 "tags": [
                     "Admin - Payments"
                 ],
-                "summary": "Get payments by status",
+                "summary": "Get payment disputes",
                 "parameters": [
                     {
                         "enum": [
-                            "pending",
-                            "completed",
-                            "failed",
-                            "refunded"
+                            "open",
+                            "under_review",
+                            "won",
+                            "lost"
                         ],
                         "type": "string",
-                        "description": "Payment status",
+                        "description": "Dispute status",
                         "name": "status",
-                        "in": "query",
-                        "required": true
+                        "in": "query"
                     },
                     {
                         "type": "integer",
@@ -636,14 +936,14 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Payments retrieved successfully",
+                        "description": "Payment disputes retrieved successfully",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Invalid dispute status",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -659,14 +959,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/payments/{id}": {
+        "/admin/payments/disputes/{id}": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get detailed payment information (Admin only)",
+                "description": "Get a chargeback with the payment it was opened against (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -676,11 +976,11 @@ const docTemplate = `{
                 "tags": [
                     "Admin - Payments"
                 ],
-                "summary": "Get payment detail",
+                "summary": "Get payment dispute",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Payment ID",
+                        "description": "Dispute ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -688,13 +988,13 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Payment retrieved successfully",
+                        "description": "Payment dispute retrieved successfully",
                         "schema": {
-                            "$ref": "#/definitions/model.Payment"
+                            "$ref": "#/definitions/model.PaymentDispute"
                         }
                     },
                     "400": {
-                        "description": "Invalid payment ID",
+                        "description": "Invalid dispute ID",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -708,7 +1008,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Payment not found",
+                        "description": "Dispute not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -717,14 +1017,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/users": {
-            "get": {
+        "/admin/payments/disputes/{id}/evidence": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get list of all users (Admin only)",
+                "description": "Answer an open chargeback. Stripe disputes get the evidence submitted to the card issuer; for other providers send it through their dashboard, it is recorded here. The dispute moves to under review (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -732,35 +1032,36 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Admin - Users"
+                    "Admin - Payments"
                 ],
-                "summary": "Get all users",
+                "summary": "Submit dispute evidence",
                 "parameters": [
                     {
                         "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
+                        "description": "Dispute ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
+                        "description": "Evidence",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.DisputeEvidenceRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Users retrieved successfully",
+                        "description": "Dispute evidence submitted successfully",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/model.PaymentDispute"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Invalid input, dispute not open or rejected by the provider",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -772,18 +1073,32 @@ const docTemplate = `{
                             "type": "object",
                             "additionalProperties": true
                         }
+                    },
+                    "404": {
+                        "description": "Dispute not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Dispute changed by another request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
                     }
                 }
             }
         },
-        "/admin/users/{id}": {
+        "/admin/payments/events": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get detailed information about a specific user (Admin only)",
+                "description": "Get the stored payment provider notifications, optionally filtered by status (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -791,27 +1106,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Admin - Users"
+                    "Admin - Payments"
                 ],
-                "summary": "Get user detail",
+                "summary": "Get payment events",
                 "parameters": [
+                    {
+                        "enum": [
+                            "received",
+                            "processed",
+                            "ignored",
+                            "failed",
+                            "rejected"
+                        ],
+                        "type": "string",
+                        "description": "Event status",
+                        "name": "status",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "User retrieved successfully",
-                        "schema": {
-                            "$ref": "#/definitions/model.User"
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid user ID",
+                        "description": "Payment events retrieved successfully",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -830,23 +1159,18 @@ const docTemplate = `{
                             "type": "object",
                             "additionalProperties": true
                         }
-                    },
-                    "404": {
-                        "description": "User not found",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/payments/events/{id}/replay": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Soft delete a user (Admin only)",
+                "description": "Apply a failed payment notification, or one stuck received by a crashed delivery, again (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -854,13 +1178,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Admin - Users"
+                    "Admin - Payments"
                 ],
-                "summary": "Delete user",
+                "summary": "Replay payment event",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "User ID",
+                        "description": "Payment event ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -868,28 +1192,27 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "User deleted successfully",
+                        "description": "Payment event replayed successfully",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/model.PaymentEvent"
                         }
                     },
                     "400": {
-                        "description": "Invalid user ID",
+                        "description": "Event is not replayable or failed again",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
-                    "403": {
-                        "description": "Forbidden",
+                    "404": {
+                        "description": "Payment event not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -898,14 +1221,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/users/{id}/role": {
-            "patch": {
+        "/admin/payments/reconciliations": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update user role (Admin only)",
+                "description": "Get the pending payments whose gateway status disagreed with ours and how they were resolved (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -913,37 +1236,40 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Admin - Users"
+                    "Admin - Payments"
                 ],
-                "summary": "Update user role",
+                "summary": "Get payment reconciliation report",
                 "parameters": [
+                    {
+                        "enum": [
+                            "applied",
+                            "skipped",
+                            "failed",
+                            "unresolved"
+                        ],
+                        "type": "string",
+                        "description": "Resolution",
+                        "name": "resolution",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
                     },
                     {
-                        "description": "Role update details",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.UpdateUserRoleRequest"
-                        }
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "User role updated successfully",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid input",
+                        "description": "Reconciliation report retrieved successfully",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -966,14 +1292,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/users/{id}/status": {
-            "patch": {
+        "/admin/payments/reviews": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Ban or unban a user (Admin only)",
+                "description": "Get, oldest first, the card payments the provider's fraud detection challenged (queue=fraud, approve or deny them) or the offline payments whose transfer receipt waits for verification (queue=offline, verify them). They are not confirmed until an admin decides (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -981,28 +1307,46 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Admin - Users"
+                    "Admin - Payments"
                 ],
-                "summary": "Toggle user status",
+                "summary": "Get payments awaiting review",
                 "parameters": [
+                    {
+                        "enum": [
+                            "fraud",
+                            "offline"
+                        ],
+                        "type": "string",
+                        "default": "fraud",
+                        "description": "Review queue",
+                        "name": "queue",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "User status updated successfully",
+                        "description": "Review queue retrieved successfully",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "Invalid user ID",
+                        "description": "Invalid review queue",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -1025,9 +1369,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/login": {
-            "post": {
-                "description": "Authenticate user and return JWT token",
+        "/admin/payments/status": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get list of payments filtered by status (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1035,36 +1384,57 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Authentication"
+                    "Admin - Payments"
                 ],
-                "summary": "Login user",
+                "summary": "Get payments by status",
                 "parameters": [
                     {
-                        "description": "Login credentials",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.LoginRequest"
-                        }
+                        "enum": [
+                            "pending",
+                            "paid",
+                            "failed",
+                            "refunded",
+                            "partially_refunded",
+                            "review"
+                        ],
+                        "type": "string",
+                        "description": "Payment status",
+                        "name": "status",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Login successful",
+                        "description": "Payments retrieved successfully",
                         "schema": {
-                            "$ref": "#/definitions/dto.LoginResponse"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
-                    "400": {
-                        "description": "Invalid input",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
-                    "401": {
-                        "description": "Invalid credentials",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -1073,56 +1443,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/register": {
-            "post": {
-                "description": "Register a new user account",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Authentication"
-                ],
-                "summary": "Register user",
-                "parameters": [
-                    {
-                        "description": "Registration details",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.RegisterRequest"
-                        }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "User registered successfully",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    },
-                    "400": {
-                        "description": "Invalid input or validation error",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    }
-                }
-            }
-        },
-        "/bookings": {
-            "post": {
+        "/admin/payments/{id}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new game rental booking",
+                "description": "Get detailed payment information (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1130,37 +1458,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Bookings"
+                    "Admin - Payments"
                 ],
-                "summary": "Create booking",
+                "summary": "Get payment detail",
                 "parameters": [
                     {
-                        "description": "Booking details",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.CreateBookingRequest"
-                        }
+                        "type": "integer",
+                        "description": "Payment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Booking created successfully",
+                    "200": {
+                        "description": "Payment retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.Payment"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid payment ID",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
-                    "400": {
-                        "description": "Invalid input",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "404": {
+                        "description": "Payment not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -1169,14 +1501,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/bookings/my": {
-            "get": {
+        "/admin/payments/{id}/approve": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get list of current user's bookings",
+                "description": "Accept a payment held for fraud review with the provider and confirm what it pays for (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1184,73 +1516,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Bookings"
+                    "Admin - Payments"
                 ],
-                "summary": "Get my bookings",
+                "summary": "Approve challenged payment",
                 "parameters": [
                     {
                         "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
+                        "description": "Payment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Bookings retrieved successfully",
+                        "description": "Payment approved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.Payment"
+                        }
+                    },
+                    "400": {
+                        "description": "Payment is not awaiting review or the provider refused",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
-                    }
-                }
-            }
-        },
-        "/bookings/{booking_id}": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Get detailed information about a specific booking",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Bookings"
-                ],
-                "summary": "Get booking detail",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Booking ID",
-                        "name": "booking_id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Booking retrieved successfully",
+                    },
+                    "404": {
+                        "description": "Payment not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -1259,14 +1559,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/bookings/{booking_id}/cancel": {
-            "patch": {
+        "/admin/payments/{id}/deny": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Cancel a pending booking",
+                "description": "Reject a payment held for fraud review with the provider; it fails like any other failed attempt (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1274,21 +1574,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Bookings"
+                    "Admin - Payments"
                 ],
-                "summary": "Cancel booking",
+                "summary": "Deny challenged payment",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Booking ID",
-                        "name": "booking_id",
+                        "description": "Payment ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Booking cancelled successfully",
+                        "description": "Payment denied successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.Payment"
+                        }
+                    },
+                    "400": {
+                        "description": "Payment is not awaiting review or the provider refused",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Payment not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -1297,14 +1617,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/bookings/{booking_id}/payments": {
+        "/admin/payments/{id}/proof": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get payment information for a specific booking",
+                "description": "Get a short-lived link to the transfer receipt of an offline payment. Receipts are kept in a private bucket, so the link expires after a few minutes (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1312,55 +1632,57 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Payments"
+                    "Admin - Payments"
                 ],
-                "summary": "Get payment by booking",
+                "summary": "Get transfer receipt",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Booking ID",
-                        "name": "booking_id",
+                        "description": "Payment ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Payment retrieved successfully",
+                        "description": "Transfer receipt link created successfully",
                         "schema": {
-                            "$ref": "#/definitions/model.Payment"
+                            "$ref": "#/definitions/dto.PaymentProofResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid booking ID",
+                        "description": "Invalid payment ID",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
                     "404": {
-                        "description": "Payment not found",
+                        "description": "Payment or transfer receipt not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/payments/{id}/refund": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create payment for a booking",
+                "description": "Refund a paid payment through its provider, in full or partially (Admin only). A full refund cancels the bookings it paid for that were not handed over yet.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1368,43 +1690,50 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Payments"
+                    "Admin - Payments"
                 ],
-                "summary": "Create payment",
+                "summary": "Refund payment",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Booking ID",
-                        "name": "booking_id",
+                        "description": "Payment ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Payment details",
+                        "description": "Refund amount (omit for a full refund) and reason",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.CreatePaymentRequest"
+                            "$ref": "#/definitions/dto.RefundPaymentRequest"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Payment created successfully",
+                        "description": "Payment refunded successfully",
                         "schema": {
-                            "$ref": "#/definitions/model.Payment"
+                            "$ref": "#/definitions/model.PaymentRefund"
                         }
                     },
                     "400": {
-                        "description": "Invalid input",
+                        "description": "Invalid input or refund rejected",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Payment not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -1413,14 +1742,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/bookings/{booking_id}/reviews": {
+        "/admin/payments/{id}/verify": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a review for a completed booking",
+                "description": "Approve or reject an offline payment after checking its transfer receipt, or the cash received at the counter. Approval confirms what it pays for; rejection fails it like any other failed attempt and emails the reason to the customer (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1428,44 +1757,50 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Reviews"
+                    "Admin - Payments"
                 ],
-                "summary": "Create review",
+                "summary": "Verify offline payment",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Booking ID",
-                        "name": "booking_id",
+                        "description": "Payment ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Review details",
+                        "description": "Decision",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.CreateReviewRequest"
+                            "$ref": "#/definitions/dto.VerifyPaymentRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Review created successfully",
+                    "200": {
+                        "description": "Payment verified successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.Payment"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input or payment not awaiting verification",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
-                    "400": {
-                        "description": "Invalid input",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "404": {
+                        "description": "Payment not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -1474,9 +1809,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/categories": {
+        "/admin/promo-codes": {
             "get": {
-                "description": "Get list of active game categories",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all promo codes with how many active bookings used each (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1484,12 +1824,100 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Categories"
+                    "Admin - Promo Codes"
                 ],
-                "summary": "Get active categories",
-                "responses": {
+                "summary": "Get promo codes",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
                     "200": {
-                        "description": "Categories retrieved successfully",
+                        "description": "Promo codes retrieved successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a percentage or fixed discount code with an optional validity window, usage limits and game/category restrictions (Admin only). Codes are case-insensitive.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin - Promo Codes"
+                ],
+                "summary": "Create promo code",
+                "parameters": [
+                    {
+                        "description": "Promo code details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.PromoCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Promo code created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.PromoCode"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input or code already exists",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -1498,9 +1926,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/categories/{id}": {
+        "/admin/promo-codes/{id}": {
             "get": {
-                "description": "Get detailed information about a specific category",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a promo code with its usage count (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1508,13 +1941,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Categories"
+                    "Admin - Promo Codes"
                 ],
-                "summary": "Get category detail",
+                "summary": "Get promo code detail",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Category ID",
+                        "description": "Promo code ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1522,31 +1955,41 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Category retrieved successfully",
+                        "description": "Promo code retrieved successfully",
                         "schema": {
-                            "$ref": "#/definitions/model.Category"
+                            "$ref": "#/definitions/model.PromoCode"
                         }
                     },
                     "400": {
-                        "description": "Invalid category ID",
+                        "description": "Invalid promo code ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
                     "404": {
-                        "description": "Category not found",
+                        "description": "Promo code not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     }
                 }
-            }
-        },
-        "/games": {
-            "get": {
-                "description": "Get list of all active games",
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the settings of a promo code (Admin only). Bookings that already used it keep their discount.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1554,39 +1997,64 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Games"
+                    "Admin - Promo Codes"
                 ],
-                "summary": "Get all games",
+                "summary": "Update promo code",
                 "parameters": [
                     {
                         "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
+                        "description": "Promo code ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
+                        "description": "Promo code details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.PromoCodeRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Games retrieved successfully",
+                        "description": "Promo code updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.PromoCode"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Promo code not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     }
                 }
-            }
-        },
-        "/games/search": {
-            "get": {
-                "description": "Search games by name, description, or platform",
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a promo code no booking has used; deactivate used codes instead (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1594,42 +2062,42 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Games"
+                    "Admin - Promo Codes"
                 ],
-                "summary": "Search games",
+                "summary": "Delete promo code",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Search query",
-                        "name": "q",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
-                    },
                     {
                         "type": "integer",
-                        "default": 10,
-                        "description": "Items per page",
-                        "name": "limit",
-                        "in": "query"
+                        "description": "Promo code ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Games search results",
+                        "description": "Promo code deleted successfully",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "Search query required",
+                        "description": "Promo code has been used",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Promo code not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -1638,9 +2106,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/games/{game_id}/reviews": {
+        "/admin/users": {
             "get": {
-                "description": "Get list of reviews for a specific game",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get list of all users (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1648,17 +2121,10 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Reviews"
+                    "Admin - Users"
                 ],
-                "summary": "Get game reviews",
+                "summary": "Get all users",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Game ID",
-                        "name": "game_id",
-                        "in": "path",
-                        "required": true
-                    },
                     {
                         "type": "integer",
                         "default": 1,
@@ -1676,21 +2142,21 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Reviews retrieved successfully",
+                        "description": "Users retrieved successfully",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
-                    "400": {
-                        "description": "Invalid game ID",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -1699,9 +2165,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/games/{id}": {
+        "/admin/users/{id}": {
             "get": {
-                "description": "Get detailed information about a specific game",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get detailed information about a specific user (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1709,13 +2180,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Games"
+                    "Admin - Users"
                 ],
-                "summary": "Get game detail",
+                "summary": "Get user detail",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Game ID",
+                        "description": "User ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1723,36 +2194,48 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "Game retrieved successfully",
+                        "description": "User retrieved successfully",
                         "schema": {
-                            "$ref": "#/definitions/model.Game"
+                            "$ref": "#/definitions/model.User"
                         }
                     },
                     "400": {
-                        "description": "Invalid game ID",
+                        "description": "Invalid user ID",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
-                    "404": {
-                        "description": "Game not found",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     }
                 }
-            }
-        },
-        "/users/me": {
-            "get": {
+            },
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get current user's profile information",
+                "description": "Soft delete a user (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1760,14 +2243,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "Admin - Users"
+                ],
+                "summary": "Delete user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get current user profile",
                 "responses": {
                     "200": {
-                        "description": "Profile retrieved successfully",
+                        "description": "User deleted successfully",
                         "schema": {
-                            "$ref": "#/definitions/model.User"
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "401": {
@@ -1776,16 +2276,25 @@ const docTemplate = `{
                             "type": "object",
                             "additionalProperties": true
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/admin/users/{id}/role": {
+            "patch": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update current user's profile information",
+                "description": "Update user role (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1793,25 +2302,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "Admin - Users"
                 ],
-                "summary": "Update current user profile",
+                "summary": "Update user role",
                 "parameters": [
                     {
-                        "description": "Profile update details",
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role update details",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.UpdateProfileRequest"
+                            "$ref": "#/definitions/dto.UpdateUserRoleRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Profile updated successfully",
+                        "description": "User role updated successfully",
                         "schema": {
-                            "$ref": "#/definitions/model.User"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
@@ -1827,13 +2344,25 @@ const docTemplate = `{
                             "type": "object",
                             "additionalProperties": true
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
                     }
                 }
             }
         },
-        "/webhooks/payments": {
-            "post": {
-                "description": "Receive payment status updates from payment provider",
+        "/admin/users/{id}/status": {
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Ban or unban a user (Admin only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1841,30 +2370,42 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Webhooks"
+                    "Admin - Users"
                 ],
-                "summary": "Payment webhook",
+                "summary": "Toggle user status",
                 "parameters": [
                     {
-                        "description": "Webhook payload",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.PaymentWebhookRequest"
-                        }
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Webhook processed successfully",
+                        "description": "User status updated successfully",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
                     },
                     "400": {
-                        "description": "Invalid webhook payload",
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -1872,539 +2413,3637 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "dto.CreateBookingRequest": {
-            "type": "object",
-            "required": [
-                "end_date",
-                "game_id",
-                "start_date"
-            ],
-            "properties": {
-                "end_date": {
-                    "description": "String format YYYY-MM-DD",
-                    "type": "string"
-                },
-                "game_id": {
-                    "type": "integer"
-                },
-                "notes": {
-                    "type": "string"
-                },
-                "start_date": {
-                    "description": "String format YYYY-MM-DD",
-                    "type": "string"
-                }
-            }
         },
-        "dto.CreateCategoryRequest": {
-            "type": "object",
-            "required": [
-                "name"
-            ],
-            "properties": {
-                "description": {
-                    "type": "string"
-                },
-                "name": {
-                    "type": "string",
-                    "maxLength": 100,
-                    "minLength": 2
+        "/admin/users/{id}/wallet": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a customer's store credit balance with the ledger, newest entries first (Admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin - Wallets"
+                ],
+                "summary": "Get user wallet",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Wallet retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.Wallet"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
                 }
             }
         },
-        "dto.CreateGameRequest": {
-            "type": "object",
-            "required": [
-                "category_id",
-                "condition",
-                "name",
-                "rental_price_per_day",
-                "security_deposit",
-                "stock"
-            ],
-            "properties": {
-                "category_id": {
-                    "type": "integer"
-                },
-                "condition": {
-                    "type": "string",
-                    "enum": [
-                        "excellent",
-                        "good",
-                        "fair"
-                    ]
+        "/admin/users/{id}/wallet/adjustments": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Credit (positive amount) or debit (negative amount) a customer's wallet, e.g. goodwill credit or a correction. A debit cannot take the balance below zero (Admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin - Wallets"
+                ],
+                "summary": "Adjust user wallet",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Adjustment",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.WalletAdjustmentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Wallet adjusted successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.WalletEntry"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input or insufficient balance",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login": {
+            "post": {
+                "description": "Authenticate user and return JWT token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authentication"
+                ],
+                "summary": "Login user",
+                "parameters": [
+                    {
+                        "description": "Login credentials",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Login successful",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid credentials",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "description": "Register a new user account",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authentication"
+                ],
+                "summary": "Register user",
+                "parameters": [
+                    {
+                        "description": "Registration details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "User registered successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input or validation error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/bookings": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new game rental booking. An optional promo_code takes its discount off the rental price (never the deposit) and is stored on the booking.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bookings"
+                ],
+                "summary": "Create booking",
+                "parameters": [
+                    {
+                        "description": "Booking details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateBookingRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Booking created successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/bookings/my": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get list of current user's bookings",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bookings"
+                ],
+                "summary": "Get my bookings",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Bookings retrieved successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/bookings/{booking_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get detailed information about a specific booking",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bookings"
+                ],
+                "summary": "Get booking detail",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Booking ID",
+                        "name": "booking_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Booking retrieved successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/bookings/{booking_id}/cancel": {
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Cancel a pending booking",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bookings"
+                ],
+                "summary": "Cancel booking",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Booking ID",
+                        "name": "booking_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Booking cancelled successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/bookings/{booking_id}/dates": {
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Move a pending or confirmed booking to new dates and reprice it. For a paid booking a higher price creates a supplemental payment (dates move once it is paid) and a lower price is refunded.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bookings"
+                ],
+                "summary": "Reschedule booking",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Booking ID",
+                        "name": "booking_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New dates",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.RescheduleBookingRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Booking rescheduled successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.BookingDateChange"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Booking not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/bookings/{booking_id}/extend": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Request a later end date for a confirmed or active booking. The extra days are held and a supplemental payment is created; the end date moves once it is paid.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bookings"
+                ],
+                "summary": "Extend booking",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Booking ID",
+                        "name": "booking_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New end date",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ExtendBookingRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Extension requested successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.BookingDateChange"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input or dates not available",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Booking not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/bookings/{booking_id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the status changes of a booking with actor, reason and timestamp (owner or admin)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Bookings"
+                ],
+                "summary": "Get booking status history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Booking ID",
+                        "name": "booking_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Booking history retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.BookingStatusHistory"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid booking ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Booking not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/bookings/{booking_id}/invoice": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Download the PDF invoice of a paid booking: sequential invoice number, line items, payment method and paid date (owner or admin). The first request issues the invoice if the payment confirmation did not.",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "Bookings"
+                ],
+                "summary": "Download booking invoice",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Booking ID",
+                        "name": "booking_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Invoice PDF",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid booking ID or booking not paid",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Booking not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/bookings/{booking_id}/payments": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the current payment attempt of a booking: the attempt that paid, or else the latest. Every attempt is listed in the booking detail.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payments"
+                ],
+                "summary": "Get payment by booking",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Booking ID",
+                        "name": "booking_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Payment retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.Payment"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid booking ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Payment not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a payment attempt for a booking. A failed attempt can be followed by a new one while the booking is held. The payment carries the instructions to complete it (VA numbers, QR string, deeplinks, bank account for offline payments, expiry).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payments"
+                ],
+                "summary": "Create payment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Booking ID",
+                        "name": "booking_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Payment details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreatePaymentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Payment created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.Payment"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/bookings/{booking_id}/reviews": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a review for a completed booking",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reviews"
+                ],
+                "summary": "Create review",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Booking ID",
+                        "name": "booking_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Review details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateReviewRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Review created successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/categories": {
+            "get": {
+                "description": "Get list of active game categories",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Categories"
+                ],
+                "summary": "Get active categories",
+                "responses": {
+                    "200": {
+                        "description": "Categories retrieved successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/categories/{id}": {
+            "get": {
+                "description": "Get detailed information about a specific category",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Categories"
+                ],
+                "summary": "Get category detail",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Category ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Category retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.Category"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid category ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Category not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/games": {
+            "get": {
+                "description": "Get list of all active games",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Games"
+                ],
+                "summary": "Get all games",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Games retrieved successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/games/search": {
+            "get": {
+                "description": "Search games by name, description, or platform",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Games"
+                ],
+                "summary": "Search games",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Games search results",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Search query required",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/games/{game_id}/reviews": {
+            "get": {
+                "description": "Get list of reviews for a specific game",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reviews"
+                ],
+                "summary": "Get game reviews",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Game ID",
+                        "name": "game_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Reviews retrieved successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid game ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/games/{id}": {
+            "get": {
+                "description": "Get detailed information about a specific game",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Games"
+                ],
+                "summary": "Get game detail",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Game ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Game retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.Game"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid game ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Game not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/games/{id}/availability": {
+            "get": {
+                "description": "Get the number of free copies per day for a date range (defaults to the next 30 days)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Games"
+                ],
+                "summary": "Get game availability",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Game ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start date (YYYY-MM-DD)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End date (YYYY-MM-DD)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Availability retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GameAvailabilityResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid date range",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Game not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/games/{id}/waitlist": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Queue for a game that has no copy free for the requested dates. When a copy frees up the first customer in line gets a time-limited hold.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Waitlist"
+                ],
+                "summary": "Join game waitlist",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Game ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Requested dates",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.JoinWaitlistRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Joined waitlist successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.WaitlistEntry"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Game not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Book several games at once. Every item is held for its dates and the order is paid with a single payment.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Create order",
+                "parameters": [
+                    {
+                        "description": "Order items",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateOrderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Order created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/my": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get list of current user's orders with their items",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Get my orders",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Orders retrieved successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{order_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get an order with its items and payment",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Get order detail",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid order ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Order not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{order_id}/cancel": {
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Cancel every item of an order that has not been handed over yet",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Cancel order",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order cancelled successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Order cannot be cancelled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Order not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/orders/{order_id}/payments": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a single payment covering every item of an order, with the instructions to complete it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payments"
+                ],
+                "summary": "Create order payment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Order ID",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Payment details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreatePaymentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Payment created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.Payment"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/payments/{id}/proof": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Upload the transfer receipt of an offline payment (JPEG, PNG or PDF, at most 5MB). The payment then waits for an admin to verify it and the booking no longer expires; uploading again replaces the receipt until it is verified.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payments"
+                ],
+                "summary": "Upload transfer receipt",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Payment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Transfer receipt",
+                        "name": "receipt",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Transfer receipt uploaded successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.Payment"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid file or payment not waiting for a receipt",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Payment not owned by user",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Payment not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/users/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get current user's profile information",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Get current user profile",
+                "responses": {
+                    "200": {
+                        "description": "Profile retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update current user's profile information",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Update current user profile",
+                "parameters": [
+                    {
+                        "description": "Profile update details",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Profile updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid input",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/waitlist/my": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the current user's waitlist entries and holds",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Waitlist"
+                ],
+                "summary": "Get my waitlist entries",
+                "responses": {
+                    "200": {
+                        "description": "Waitlist retrieved successfully",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.WaitlistEntry"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/waitlist/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Leave the waitlist or give up an offered hold",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Waitlist"
+                ],
+                "summary": "Leave waitlist",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Waitlist entry ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Left waitlist successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid waitlist entry ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Waitlist entry not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/wallet": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the store credit balance of the current user with the ledger, newest entries first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Wallet"
+                ],
+                "summary": "Get my wallet",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Wallet retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/model.Wallet"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/payments": {
+            "post": {
+                "description": "Receive payment status updates from a payment provider. /webhooks/payments is the Midtrans endpoint; other providers post to /webhooks/payments/{provider}.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Payment webhook",
+                "parameters": [
+                    {
+                        "description": "Webhook payload (Midtrans shape shown)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.PaymentWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhook processed successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid webhook payload or amount mismatch",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid signature",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/payments/{provider}": {
+            "post": {
+                "description": "Receive payment status updates from a payment provider. /webhooks/payments is the Midtrans endpoint; other providers post to /webhooks/payments/{provider}.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Payment webhook",
+                "parameters": [
+                    {
+                        "enum": [
+                            "midtrans",
+                            "stripe"
+                        ],
+                        "type": "string",
+                        "description": "Payment provider",
+                        "name": "provider",
+                        "in": "path"
+                    },
+                    {
+                        "description": "Webhook payload (Midtrans shape shown)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.PaymentWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhook processed successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid webhook payload or amount mismatch",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid signature",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "dto.CreateBookingRequest": {
+            "type": "object",
+            "required": [
+                "end_date",
+                "game_id",
+                "start_date"
+            ],
+            "properties": {
+                "end_date": {
+                    "description": "String format YYYY-MM-DD",
+                    "type": "string"
+                },
+                "game_id": {
+                    "type": "integer"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "promo_code": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "description": "String format YYYY-MM-DD",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.CreateCategoryRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 2
+                }
+            }
+        },
+        "dto.CreateGameRequest": {
+            "type": "object",
+            "required": [
+                "category_id",
+                "condition",
+                "name",
+                "rental_price_per_day",
+                "security_deposit",
+                "stock"
+            ],
+            "properties": {
+                "category_id": {
+                    "type": "integer"
+                },
+                "condition": {
+                    "type": "string",
+                    "enum": [
+                        "excellent",
+                        "good",
+                        "fair"
+                    ]
+                },
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "minLength": 3
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "rental_price_per_day": {
+                    "type": "string",
+                    "minLength": 0
+                },
+                "security_deposit": {
+                    "type": "string",
+                    "minLength": 0
+                },
+                "stock": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "dto.CreateOrderRequest": {
+            "type": "object",
+            "required": [
+                "items"
+            ],
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "maxItems": 10,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/dto.OrderItemRequest"
+                    }
+                },
+                "notes": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.CreatePaymentRequest": {
+            "type": "object",
+            "required": [
+                "provider"
+            ],
+            "properties": {
+                "payment_type": {
+                    "type": "string"
+                },
+                "provider": {
+                    "enum": [
+                        "stripe",
+                        "midtrans",
+                        "wallet",
+                        "offline"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.PaymentProvider"
+                        }
+                    ]
+                },
+                "use_wallet": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "dto.CreateReviewRequest": {
+            "type": "object",
+            "required": [
+                "rating"
+            ],
+            "properties": {
+                "comment": {
+                    "type": "string"
+                },
+                "rating": {
+                    "type": "integer",
+                    "maximum": 5,
+                    "minimum": 1
+                }
+            }
+        },
+        "dto.DayAvailabilityDTO": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "integer"
+                },
+                "date": {
+                    "description": "YYYY-MM-DD",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.DisputeEvidenceRequest": {
+            "type": "object",
+            "required": [
+                "evidence"
+            ],
+            "properties": {
+                "evidence": {
+                    "description": "the case for keeping the payment, e.g. rental and return records",
+                    "type": "string",
+                    "maxLength": 20000
+                }
+            }
+        },
+        "dto.ExtendBookingRequest": {
+            "type": "object",
+            "required": [
+                "end_date"
+            ],
+            "properties": {
+                "end_date": {
+                    "description": "String format YYYY-MM-DD",
+                    "type": "string"
+                },
+                "payment_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.GameAvailabilityResponse": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.DayAvailabilityDTO"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "game_id": {
+                    "type": "integer"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.JoinWaitlistRequest": {
+            "type": "object",
+            "required": [
+                "end_date",
+                "start_date"
+            ],
+            "properties": {
+                "end_date": {
+                    "description": "String format YYYY-MM-DD",
+                    "type": "string"
+                },
+                "start_date": {
+                    "description": "String format YYYY-MM-DD",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.LoginRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.LoginResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/model.User"
+                }
+            }
+        },
+        "dto.OrderItemRequest": {
+            "type": "object",
+            "required": [
+                "end_date",
+                "game_id",
+                "start_date"
+            ],
+            "properties": {
+                "end_date": {
+                    "description": "String format YYYY-MM-DD",
+                    "type": "string"
+                },
+                "game_id": {
+                    "type": "integer"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "description": "String format YYYY-MM-DD",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PaymentProofResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PaymentWebhookRequest": {
+            "type": "object",
+            "required": [
+                "gross_amount",
+                "order_id",
+                "signature_key",
+                "status_code",
+                "transaction_status"
+            ],
+            "properties": {
+                "fraud_status": {
+                    "type": "string"
+                },
+                "gross_amount": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "payment_type": {
+                    "type": "string"
+                },
+                "signature_key": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "string"
+                },
+                "transaction_id": {
+                    "type": "string"
+                },
+                "transaction_status": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.PromoCodeRequest": {
+            "type": "object",
+            "required": [
+                "code",
+                "discount_type"
+            ],
+            "properties": {
+                "amount_off": {
+                    "type": "string",
+                    "minLength": 0
+                },
+                "category_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "code": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "minLength": 3
+                },
+                "description": {
+                    "type": "string"
+                },
+                "discount_type": {
+                    "type": "string",
+                    "enum": [
+                        "percentage",
+                        "fixed"
+                    ]
+                },
+                "game_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "is_active": {
+                    "description": "defaults to true",
+                    "type": "boolean"
+                },
+                "max_discount": {
+                    "type": "string"
+                },
+                "max_uses": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "max_uses_per_user": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "percent_off": {
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0
+                },
+                "valid_from": {
+                    "type": "string"
+                },
+                "valid_until": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.RefundPaymentRequest": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500
+                },
+                "to_wallet": {
+                    "description": "credit the customer's wallet instead of the payment method",
+                    "type": "boolean"
+                }
+            }
+        },
+        "dto.RegisterRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "full_name",
+                "password"
+            ],
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "full_name": {
+                    "type": "string",
+                    "minLength": 2
+                },
+                "password": {
+                    "type": "string",
+                    "minLength": 8
+                },
+                "phone": {
+                    "type": "string",
+                    "minLength": 10
+                }
+            }
+        },
+        "dto.RescheduleBookingRequest": {
+            "type": "object",
+            "required": [
+                "end_date",
+                "start_date"
+            ],
+            "properties": {
+                "end_date": {
+                    "description": "String format YYYY-MM-DD",
+                    "type": "string"
+                },
+                "payment_type": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "description": "String format YYYY-MM-DD",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.RetryDepositRefundRequest": {
+            "type": "object",
+            "properties": {
+                "to_wallet": {
+                    "description": "return the deposit as store credit instead of a refund",
+                    "type": "boolean"
+                }
+            }
+        },
+        "dto.ReturnBookingRequest": {
+            "type": "object",
+            "properties": {
+                "damage_charge": {
+                    "type": "string",
+                    "minLength": 0
+                },
+                "damage_notes": {
+                    "type": "string"
+                },
+                "returned_at": {
+                    "description": "String format YYYY-MM-DD, defaults to today",
+                    "type": "string"
+                },
+                "to_wallet": {
+                    "description": "return the deposit as store credit instead of a refund",
+                    "type": "boolean"
+                }
+            }
+        },
+        "dto.TaxReportResponse": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "description": "YYYY-MM-DD, inclusive",
+                    "type": "string"
+                },
+                "invoices": {
+                    "type": "integer"
+                },
+                "rates": {
+                    "description": "per tax rate, bookings priced before a rate change keep theirs",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.TaxTotal"
+                    }
+                },
+                "tax_amount": {
+                    "type": "string"
+                },
+                "taxable_amount": {
+                    "type": "string"
+                },
+                "to": {
+                    "description": "YYYY-MM-DD, inclusive",
+                    "type": "string"
+                },
+                "total_amount": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.UpdateBookingStatusRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
+                    "enum": [
+                        "pending",
+                        "confirmed",
+                        "active",
+                        "completed",
+                        "cancelled"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.BookingStatus"
+                        }
+                    ]
+                }
+            }
+        },
+        "dto.UpdateCategoryRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 2
+                }
+            }
+        },
+        "dto.UpdateGameRequest": {
+            "type": "object",
+            "properties": {
+                "category_id": {
+                    "type": "integer"
+                },
+                "condition": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "rental_price_per_day": {
+                    "type": "string"
+                },
+                "security_deposit": {
+                    "type": "string"
+                },
+                "stock": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.UpdateProfileRequest": {
+            "type": "object",
+            "required": [
+                "full_name"
+            ],
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "full_name": {
+                    "type": "string",
+                    "minLength": 2
+                },
+                "phone": {
+                    "type": "string",
+                    "minLength": 10
+                }
+            }
+        },
+        "dto.UpdateUserRoleRequest": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "enum": [
+                        "customer",
+                        "partner",
+                        "admin"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.UserRole"
+                        }
+                    ]
+                }
+            }
+        },
+        "dto.VerifyPaymentRequest": {
+            "type": "object",
+            "required": [
+                "approve"
+            ],
+            "properties": {
+                "approve": {
+                    "type": "boolean"
+                },
+                "reason": {
+                    "description": "shown to the customer when rejected",
+                    "type": "string",
+                    "maxLength": 500
+                }
+            }
+        },
+        "dto.WalletAdjustmentRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "description"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string",
+                    "maxLength": 500
+                }
+            }
+        },
+        "model.BankTransfer": {
+            "type": "object",
+            "properties": {
+                "account_name": {
+                    "type": "string"
+                },
+                "account_number": {
+                    "type": "string"
+                },
+                "bank": {
+                    "type": "string"
+                },
+                "reference": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Booking": {
+            "type": "object",
+            "required": [
+                "end_date",
+                "start_date"
+            ],
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "daily_price": {
+                    "type": "string"
+                },
+                "date_changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.BookingDateChange"
+                    }
+                },
+                "discount_amount": {
+                    "description": "taken off the rental price",
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "game": {
+                    "$ref": "#/definitions/model.Game"
+                },
+                "game_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "payment": {
+                    "description": "the attempt that paid, or else the latest",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Payment"
+                        }
+                    ]
+                },
+                "payment_attempts": {
+                    "description": "oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Payment"
+                    }
+                },
+                "payment_due_at": {
+                    "type": "string"
+                },
+                "promo_code_id": {
+                    "type": "integer"
+                },
+                "rental_days": {
+                    "type": "integer"
+                },
+                "review": {
+                    "$ref": "#/definitions/model.Review"
+                },
+                "security_deposit": {
+                    "type": "string"
+                },
+                "settlement": {
+                    "$ref": "#/definitions/model.BookingSettlement"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/model.BookingStatus"
+                },
+                "tax_amount": {
+                    "description": "added to the total unless inclusive",
+                    "type": "string"
+                },
+                "tax_inclusive": {
+                    "type": "boolean"
+                },
+                "tax_rate": {
+                    "description": "VAT percent applied when the booking was made",
+                    "type": "integer"
+                },
+                "taxable_amount": {
+                    "type": "string"
+                },
+                "total_amount": {
+                    "type": "string"
+                },
+                "total_rental_price": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user": {
+                    "description": "Relationships",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    ]
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.BookingDateChange": {
+            "type": "object",
+            "properties": {
+                "amount_due": {
+                    "description": "includes the tax difference unless inclusive",
+                    "type": "string"
+                },
+                "applied_at": {
+                    "type": "string"
+                },
+                "booking_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "discount_amount": {
+                    "description": "the promo discount at the new price",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "new_end_date": {
+                    "type": "string"
+                },
+                "new_start_date": {
+                    "type": "string"
+                },
+                "old_end_date": {
+                    "type": "string"
+                },
+                "old_start_date": {
+                    "type": "string"
+                },
+                "payment": {
+                    "description": "Relationships",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Payment"
+                        }
+                    ]
+                },
+                "payment_id": {
+                    "type": "integer"
+                },
+                "provider_refund_id": {
+                    "type": "string"
+                },
+                "refund_error": {
+                    "type": "string"
+                },
+                "refund_status": {
+                    "description": "outcome of refunding a negative AmountDue",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.RefundStatus"
+                        }
+                    ]
                 },
-                "description": {
-                    "type": "string"
+                "rental_days": {
+                    "type": "integer"
                 },
-                "name": {
-                    "type": "string",
-                    "minLength": 3
+                "requested_by": {
+                    "type": "integer"
                 },
-                "platform": {
-                    "type": "string"
+                "status": {
+                    "$ref": "#/definitions/model.DateChangeStatus"
                 },
-                "rental_price_per_day": {
-                    "type": "number",
-                    "minimum": 0
+                "tax_amount": {
+                    "description": "the booking's tax at the new price",
+                    "type": "string"
                 },
-                "security_deposit": {
-                    "type": "number",
-                    "minimum": 0
+                "total_rental_price": {
+                    "type": "string"
                 },
-                "stock": {
-                    "type": "integer",
-                    "minimum": 0
+                "type": {
+                    "$ref": "#/definitions/model.DateChangeType"
                 }
             }
         },
-        "dto.CreatePaymentRequest": {
+        "model.BookingSettlement": {
             "type": "object",
-            "required": [
-                "provider"
-            ],
             "properties": {
-                "payment_type": {
+                "booking_id": {
+                    "type": "integer"
+                },
+                "created_at": {
                     "type": "string"
                 },
-                "provider": {
-                    "enum": [
-                        "stripe",
-                        "midtrans"
-                    ],
+                "damage_charge": {
+                    "type": "string"
+                },
+                "damage_notes": {
+                    "type": "string"
+                },
+                "deposit_amount": {
+                    "type": "string"
+                },
+                "deposit_refund": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "late_days": {
+                    "type": "integer"
+                },
+                "late_fee": {
+                    "type": "string"
+                },
+                "outstanding_amount": {
+                    "description": "charges the deposit could not cover",
+                    "type": "string"
+                },
+                "provider_refund_id": {
+                    "type": "string"
+                },
+                "refund_error": {
+                    "type": "string"
+                },
+                "refund_status": {
+                    "$ref": "#/definitions/model.DepositRefundStatus"
+                },
+                "refund_to_wallet": {
+                    "description": "deposit returned as store credit",
+                    "type": "boolean"
+                },
+                "returned_at": {
+                    "type": "string"
+                },
+                "settled_by": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.BookingStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "confirmed",
+                "active",
+                "completed",
+                "cancelled"
+            ],
+            "x-enum-varnames": [
+                "BookingPending",
+                "BookingConfirmed",
+                "BookingActive",
+                "BookingCompleted",
+                "BookingCancelled"
+            ]
+        },
+        "model.BookingStatusHistory": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "Relationships",
                     "allOf": [
                         {
-                            "$ref": "#/definitions/model.PaymentProvider"
+                            "$ref": "#/definitions/model.User"
                         }
                     ]
+                },
+                "booking_id": {
+                    "type": "integer"
+                },
+                "changed_by": {
+                    "description": "nil for system changes",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "from_status": {
+                    "$ref": "#/definitions/model.BookingStatus"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "to_status": {
+                    "$ref": "#/definitions/model.BookingStatus"
                 }
             }
         },
-        "dto.CreateReviewRequest": {
+        "model.Category": {
             "type": "object",
             "required": [
-                "rating"
+                "name"
             ],
             "properties": {
-                "comment": {
+                "created_at": {
                     "type": "string"
                 },
-                "rating": {
-                    "type": "integer",
-                    "maximum": 5,
-                    "minimum": 1
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
                 }
             }
         },
-        "dto.LoginRequest": {
-            "type": "object",
-            "required": [
-                "email",
-                "password"
+        "model.DateChangeStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "applied",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "DateChangePending",
+                "DateChangeApplied",
+                "DateChangeFailed"
+            ]
+        },
+        "model.DateChangeType": {
+            "type": "string",
+            "enum": [
+                "extension",
+                "reschedule"
+            ],
+            "x-enum-varnames": [
+                "DateChangeExtension",
+                "DateChangeReschedule"
+            ]
+        },
+        "model.DepositRefundStatus": {
+            "type": "string",
+            "enum": [
+                "none",
+                "pending",
+                "refunded",
+                "failed",
+                "charged_back"
+            ],
+            "x-enum-comments": {
+                "DepositChargedBack": "kept because the customer took the payment back through a dispute"
+            },
+            "x-enum-descriptions": [
+                "",
+                "",
+                "",
+                "",
+                "kept because the customer took the payment back through a dispute"
+            ],
+            "x-enum-varnames": [
+                "DepositRefundNone",
+                "DepositRefundPending",
+                "DepositRefunded",
+                "DepositRefundFailed",
+                "DepositChargedBack"
+            ]
+        },
+        "model.DiscountType": {
+            "type": "string",
+            "enum": [
+                "percentage",
+                "fixed"
+            ],
+            "x-enum-varnames": [
+                "DiscountPercentage",
+                "DiscountFixed"
+            ]
+        },
+        "model.DisputeStatus": {
+            "type": "string",
+            "enum": [
+                "open",
+                "under_review",
+                "won",
+                "lost"
+            ],
+            "x-enum-comments": {
+                "DisputeLost": "the customer got the money back",
+                "DisputeOpen": "waiting for our evidence",
+                "DisputeUnderReview": "evidence submitted, the card issuer decides"
+            },
+            "x-enum-descriptions": [
+                "waiting for our evidence",
+                "evidence submitted, the card issuer decides",
+                "",
+                "the customer got the money back"
             ],
+            "x-enum-varnames": [
+                "DisputeOpen",
+                "DisputeUnderReview",
+                "DisputeWon",
+                "DisputeLost"
+            ]
+        },
+        "model.Game": {
+            "type": "object",
             "properties": {
-                "email": {
+                "admin": {
+                    "$ref": "#/definitions/model.User"
+                },
+                "admin_id": {
+                    "type": "integer"
+                },
+                "available_stock": {
+                    "description": "copies on the shelf: stock minus copies handed out",
+                    "type": "integer"
+                },
+                "category": {
+                    "$ref": "#/definitions/model.Category"
+                },
+                "category_id": {
+                    "type": "integer"
+                },
+                "condition": {
+                    "$ref": "#/definitions/model.GameCondition"
+                },
+                "created_at": {
                     "type": "string"
                 },
-                "password": {
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "rental_price_per_day": {
+                    "type": "string"
+                },
+                "security_deposit": {
+                    "type": "string"
+                },
+                "stock": {
+                    "type": "integer"
+                },
+                "updated_at": {
                     "type": "string"
                 }
             }
         },
-        "dto.LoginResponse": {
+        "model.GameCondition": {
+            "type": "string",
+            "enum": [
+                "excellent",
+                "good",
+                "fair"
+            ],
+            "x-enum-varnames": [
+                "ConditionExcellent",
+                "ConditionGood",
+                "ConditionFair"
+            ]
+        },
+        "model.Order": {
             "type": "object",
             "properties": {
-                "access_token": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Booking"
+                    }
+                },
+                "notes": {
+                    "type": "string"
+                },
+                "payment": {
+                    "description": "the attempt that paid, or else the latest",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Payment"
+                        }
+                    ]
+                },
+                "payment_attempts": {
+                    "description": "oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Payment"
+                    }
+                },
+                "payment_due_at": {
+                    "type": "string"
+                },
+                "total_amount": {
                     "type": "string"
                 },
-                "expires_at": {
+                "updated_at": {
                     "type": "string"
                 },
                 "user": {
-                    "$ref": "#/definitions/model.User"
+                    "description": "Relationships",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    ]
+                },
+                "user_id": {
+                    "type": "integer"
                 }
             }
         },
-        "dto.PaymentWebhookRequest": {
+        "model.Payment": {
             "type": "object",
-            "required": [
-                "provider_payment_id",
-                "status"
-            ],
             "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "booking": {
+                    "description": "Relationships",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.Booking"
+                        }
+                    ]
+                },
+                "booking_id": {
+                    "description": "nil for order payments",
+                    "type": "integer"
+                },
+                "charged_back_amount": {
+                    "description": "taken back through lost disputes",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "failed_at": {
+                    "type": "string"
+                },
                 "failure_reason": {
                     "type": "string"
                 },
+                "id": {
+                    "type": "integer"
+                },
+                "instructions": {
+                    "$ref": "#/definitions/model.PaymentInstructions"
+                },
+                "order": {
+                    "$ref": "#/definitions/model.Order"
+                },
+                "order_id": {
+                    "type": "integer"
+                },
+                "paid_at": {
+                    "type": "string"
+                },
                 "payment_method": {
                     "type": "string"
                 },
-                "provider_payment_id": {
+                "proof_uploaded_at": {
                     "type": "string"
                 },
-                "status": {
+                "provider": {
+                    "$ref": "#/definitions/model.PaymentProvider"
+                },
+                "provider_payment_id": {
                     "type": "string"
-                }
-            }
-        },
-        "dto.RegisterRequest": {
-            "type": "object",
-            "required": [
-                "email",
-                "full_name",
-                "password"
-            ],
-            "properties": {
-                "address": {
+                },
+                "purpose": {
+                    "$ref": "#/definitions/model.PaymentPurpose"
+                },
+                "refunded_amount": {
                     "type": "string"
                 },
-                "email": {
+                "refunds": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.PaymentRefund"
+                    }
+                },
+                "reviewed_at": {
                     "type": "string"
                 },
-                "full_name": {
-                    "type": "string",
-                    "minLength": 2
+                "reviewed_by": {
+                    "description": "admin who decided a fraud review or verified an offline payment",
+                    "type": "integer"
                 },
-                "password": {
-                    "type": "string",
-                    "minLength": 8
+                "status": {
+                    "$ref": "#/definitions/model.PaymentStatus"
                 },
-                "phone": {
-                    "type": "string",
-                    "minLength": 10
+                "wallet_amount": {
+                    "description": "paid from the wallet, the rest through the provider",
+                    "type": "string"
+                },
+                "wallet_refunded_amount": {
+                    "description": "part of RefundedAmount credited to the wallet",
+                    "type": "string"
                 }
             }
         },
-        "dto.UpdateCategoryRequest": {
+        "model.PaymentAction": {
             "type": "object",
-            "required": [
-                "name"
-            ],
             "properties": {
-                "description": {
+                "method": {
                     "type": "string"
                 },
                 "name": {
-                    "type": "string",
-                    "maxLength": 100,
-                    "minLength": 2
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
                 }
             }
         },
-        "dto.UpdateGameRequest": {
+        "model.PaymentDispute": {
             "type": "object",
             "properties": {
-                "category_id": {
-                    "type": "integer"
-                },
-                "condition": {
+                "amount": {
                     "type": "string"
                 },
-                "description": {
+                "created_at": {
                     "type": "string"
                 },
-                "name": {
+                "evidence": {
                     "type": "string"
                 },
-                "platform": {
+                "evidence_due_by": {
                     "type": "string"
                 },
-                "rental_price_per_day": {
-                    "type": "number"
-                },
-                "security_deposit": {
-                    "type": "number"
+                "evidence_submitted_at": {
+                    "type": "string"
                 },
-                "stock": {
+                "evidence_submitted_by": {
                     "type": "integer"
-                }
-            }
-        },
-        "dto.UpdateProfileRequest": {
-            "type": "object",
-            "required": [
-                "full_name"
-            ],
-            "properties": {
-                "address": {
-                    "type": "string"
                 },
-                "full_name": {
-                    "type": "string",
-                    "minLength": 2
+                "id": {
+                    "type": "integer"
                 },
-                "phone": {
-                    "type": "string",
-                    "minLength": 10
-                }
-            }
-        },
-        "dto.UpdateUserRoleRequest": {
-            "type": "object",
-            "required": [
-                "role"
-            ],
-            "properties": {
-                "role": {
-                    "enum": [
-                        "customer",
-                        "partner",
-                        "admin"
-                    ],
+                "payment": {
+                    "description": "Relationships",
                     "allOf": [
                         {
-                            "$ref": "#/definitions/model.UserRole"
+                            "$ref": "#/definitions/model.Payment"
                         }
                     ]
+                },
+                "payment_id": {
+                    "type": "integer"
+                },
+                "provider": {
+                    "$ref": "#/definitions/model.PaymentProvider"
+                },
+                "provider_dispute_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "as given by the provider, e.g. fraudulent",
+                    "type": "string"
+                },
+                "resolved_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/model.DisputeStatus"
+                },
+                "updated_at": {
+                    "type": "string"
                 }
             }
         },
-        "model.Booking": {
+        "model.PaymentEvent": {
             "type": "object",
-            "required": [
-                "end_date",
-                "start_date"
-            ],
             "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
                 "created_at": {
                     "type": "string"
                 },
-                "daily_price": {
-                    "type": "number"
-                },
-                "end_date": {
+                "error": {
                     "type": "string"
                 },
-                "game": {
-                    "$ref": "#/definitions/model.Game"
+                "gateway_order_id": {
+                    "type": "string"
                 },
-                "game_id": {
-                    "type": "integer"
+                "gross_amount": {
+                    "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "notes": {
+                "payload": {
                     "type": "string"
                 },
-                "payment": {
-                    "$ref": "#/definitions/model.Payment"
-                },
-                "rental_days": {
+                "payment_id": {
                     "type": "integer"
                 },
-                "review": {
-                    "$ref": "#/definitions/model.Review"
-                },
-                "security_deposit": {
-                    "type": "number"
-                },
-                "start_date": {
+                "processed_at": {
                     "type": "string"
                 },
+                "provider": {
+                    "$ref": "#/definitions/model.PaymentProvider"
+                },
                 "status": {
-                    "$ref": "#/definitions/model.BookingStatus"
+                    "$ref": "#/definitions/model.PaymentEventStatus"
                 },
-                "total_amount": {
-                    "type": "number"
+                "transaction_id": {
+                    "description": "the gateway order ID when the provider sent none",
+                    "type": "string"
                 },
-                "total_rental_price": {
-                    "type": "number"
+                "transaction_status": {
+                    "type": "string"
                 },
                 "updated_at": {
                     "type": "string"
-                },
-                "user": {
-                    "description": "Relationships",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/model.User"
-                        }
-                    ]
-                },
-                "user_id": {
-                    "type": "integer"
                 }
             }
         },
-        "model.BookingStatus": {
+        "model.PaymentEventStatus": {
             "type": "string",
             "enum": [
-                "pending",
-                "confirmed",
-                "active",
-                "completed",
-                "cancelled"
+                "received",
+                "processed",
+                "ignored",
+                "failed",
+                "rejected"
+            ],
+            "x-enum-comments": {
+                "PaymentEventFailed": "can be replayed",
+                "PaymentEventIgnored": "valid, but the payment had already moved on",
+                "PaymentEventReceived": "being applied; can be replayed once stale",
+                "PaymentEventRejected": "failed signature or amount verification"
+            },
+            "x-enum-descriptions": [
+                "being applied; can be replayed once stale",
+                "",
+                "valid, but the payment had already moved on",
+                "can be replayed",
+                "failed signature or amount verification"
             ],
             "x-enum-varnames": [
-                "BookingPending",
-                "BookingConfirmed",
-                "BookingActive",
-                "BookingCompleted",
-                "BookingCancelled"
+                "PaymentEventReceived",
+                "PaymentEventProcessed",
+                "PaymentEventIgnored",
+                "PaymentEventFailed",
+                "PaymentEventRejected"
             ]
         },
-        "model.Category": {
+        "model.PaymentInstructions": {
             "type": "object",
-            "required": [
-                "name"
-            ],
             "properties": {
-                "created_at": {
+                "actions": {
+                    "description": "deeplinks and QR code images",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.PaymentAction"
+                    }
+                },
+                "bank_transfer": {
+                    "description": "offline payments",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.BankTransfer"
+                        }
+                    ]
+                },
+                "bill_key": {
+                    "description": "Mandiri bill payment",
                     "type": "string"
                 },
-                "description": {
+                "biller_code": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "integer"
+                "client_secret": {
+                    "description": "for confirming a Stripe payment on the client",
+                    "type": "string"
                 },
-                "is_active": {
-                    "type": "boolean"
+                "expires_at": {
+                    "type": "string"
                 },
-                "name": {
+                "payment_code": {
+                    "description": "convenience store payment code",
+                    "type": "string"
+                },
+                "qr_string": {
+                    "type": "string"
+                },
+                "redirect_url": {
                     "type": "string"
+                },
+                "va_numbers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.VirtualAccount"
+                    }
                 }
             }
         },
-        "model.Game": {
+        "model.PaymentProvider": {
+            "type": "string",
+            "enum": [
+                "stripe",
+                "midtrans",
+                "wallet",
+                "offline"
+            ],
+            "x-enum-comments": {
+                "ProviderOffline": "cash or direct bank transfer, verified by an admin",
+                "ProviderWallet": "paid in full from the customer's wallet"
+            },
+            "x-enum-descriptions": [
+                "",
+                "",
+                "paid in full from the customer's wallet",
+                "cash or direct bank transfer, verified by an admin"
+            ],
+            "x-enum-varnames": [
+                "ProviderStripe",
+                "ProviderMidtrans",
+                "ProviderWallet",
+                "ProviderOffline"
+            ]
+        },
+        "model.PaymentPurpose": {
+            "type": "string",
+            "enum": [
+                "booking",
+                "date_change",
+                "order"
+            ],
+            "x-enum-varnames": [
+                "PaymentPurposeBooking",
+                "PaymentPurposeDateChange",
+                "PaymentPurposeOrder"
+            ]
+        },
+        "model.PaymentRefund": {
             "type": "object",
             "properties": {
-                "admin": {
-                    "$ref": "#/definitions/model.User"
-                },
-                "admin_id": {
-                    "type": "integer"
-                },
-                "available_stock": {
-                    "type": "integer"
-                },
-                "category": {
-                    "$ref": "#/definitions/model.Category"
-                },
-                "category_id": {
-                    "type": "integer"
+                "actor": {
+                    "description": "Relationships",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    ]
                 },
-                "condition": {
-                    "$ref": "#/definitions/model.GameCondition"
+                "amount": {
+                    "type": "string"
                 },
                 "created_at": {
                     "type": "string"
                 },
-                "description": {
+                "failure_reason": {
                     "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "is_active": {
-                    "type": "boolean"
+                "payment_id": {
+                    "type": "integer"
                 },
-                "name": {
+                "provider_refund_id": {
                     "type": "string"
                 },
-                "platform": {
+                "reason": {
                     "type": "string"
                 },
-                "rental_price_per_day": {
-                    "type": "number"
-                },
-                "security_deposit": {
-                    "type": "number"
-                },
-                "stock": {
+                "refunded_by": {
+                    "description": "nil for system refunds",
                     "type": "integer"
                 },
+                "status": {
+                    "$ref": "#/definitions/model.RefundStatus"
+                },
                 "updated_at": {
                     "type": "string"
+                },
+                "wallet_amount": {
+                    "description": "part credited to the wallet",
+                    "type": "string"
                 }
             }
         },
-        "model.GameCondition": {
+        "model.PaymentStatus": {
             "type": "string",
             "enum": [
-                "excellent",
-                "good",
-                "fair"
+                "pending",
+                "paid",
+                "failed",
+                "refunded",
+                "review",
+                "partially_refunded"
+            ],
+            "x-enum-comments": {
+                "PaymentReview": "held by fraud detection, or an offline payment awaiting verification, until an admin decides"
+            },
+            "x-enum-descriptions": [
+                "",
+                "",
+                "",
+                "",
+                "held by fraud detection, or an offline payment awaiting verification, until an admin decides",
+                ""
             ],
             "x-enum-varnames": [
-                "ConditionExcellent",
-                "ConditionGood",
-                "ConditionFair"
+                "PaymentPending",
+                "PaymentPaid",
+                "PaymentFailed",
+                "PaymentRefunded",
+                "PaymentReview",
+                "PaymentPartiallyRefunded"
             ]
         },
-        "model.Payment": {
+        "model.PromoCode": {
             "type": "object",
             "properties": {
-                "amount": {
-                    "type": "number"
+                "amount_off": {
+                    "description": "fixed codes",
+                    "type": "string"
                 },
-                "booking": {
-                    "description": "Relationships",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/model.Booking"
-                        }
-                    ]
+                "category_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
                 },
-                "booking_id": {
-                    "type": "integer"
+                "code": {
+                    "description": "stored upper case",
+                    "type": "string"
                 },
                 "created_at": {
                     "type": "string"
                 },
-                "failed_at": {
-                    "type": "string"
+                "created_by": {
+                    "type": "integer"
                 },
-                "failure_reason": {
+                "description": {
                     "type": "string"
                 },
+                "discount_type": {
+                    "$ref": "#/definitions/model.DiscountType"
+                },
+                "game_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
                 "id": {
                     "type": "integer"
                 },
-                "paid_at": {
+                "is_active": {
+                    "type": "boolean"
+                },
+                "max_discount": {
+                    "description": "caps a percentage discount",
                     "type": "string"
                 },
-                "payment_method": {
+                "max_uses": {
+                    "type": "integer"
+                },
+                "max_uses_per_user": {
+                    "type": "integer"
+                },
+                "percent_off": {
+                    "description": "percentage codes, 1-100",
+                    "type": "integer"
+                },
+                "updated_at": {
                     "type": "string"
                 },
-                "provider": {
-                    "$ref": "#/definitions/model.PaymentProvider"
+                "used_count": {
+                    "description": "filled by the admin list",
+                    "type": "integer"
                 },
-                "provider_payment_id": {
+                "valid_from": {
                     "type": "string"
                 },
-                "status": {
-                    "$ref": "#/definitions/model.PaymentStatus"
+                "valid_until": {
+                    "type": "string"
                 }
             }
         },
-        "model.PaymentProvider": {
-            "type": "string",
-            "enum": [
-                "stripe",
-                "midtrans"
-            ],
-            "x-enum-varnames": [
-                "ProviderStripe",
-                "ProviderMidtrans"
-            ]
-        },
-        "model.PaymentStatus": {
+        "model.RefundStatus": {
             "type": "string",
             "enum": [
                 "pending",
-                "paid",
-                "failed",
-                "refunded"
+                "succeeded",
+                "failed"
             ],
             "x-enum-varnames": [
-                "PaymentPending",
-                "PaymentPaid",
-                "PaymentFailed",
-                "PaymentRefunded"
+                "RefundPending",
+                "RefundSucceeded",
+                "RefundFailed"
             ]
         },
         "model.Review": {
@@ -2442,6 +6081,29 @@ const docTemplate = `{
                 }
             }
         },
+        "model.TaxTotal": {
+            "type": "object",
+            "properties": {
+                "invoices": {
+                    "type": "integer"
+                },
+                "tax_amount": {
+                    "type": "string"
+                },
+                "tax_inclusive": {
+                    "type": "boolean"
+                },
+                "tax_rate": {
+                    "type": "integer"
+                },
+                "taxable_amount": {
+                    "type": "string"
+                },
+                "total_amount": {
+                    "type": "string"
+                }
+            }
+        },
         "model.User": {
             "type": "object",
             "required": [
@@ -2490,6 +6152,178 @@ const docTemplate = `{
                 "RoleAdmin",
                 "RoleSuperAdmin"
             ]
+        },
+        "model.VirtualAccount": {
+            "type": "object",
+            "properties": {
+                "bank": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.WaitlistEntry": {
+            "type": "object",
+            "properties": {
+                "booking_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "game": {
+                    "$ref": "#/definitions/model.Game"
+                },
+                "game_id": {
+                    "type": "integer"
+                },
+                "hold_expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "offered_at": {
+                    "type": "string"
+                },
+                "start_date": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/model.WaitlistStatus"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user": {
+                    "description": "Relationships",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    ]
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.WaitlistStatus": {
+            "type": "string",
+            "enum": [
+                "waiting",
+                "offered",
+                "converted",
+                "expired",
+                "cancelled"
+            ],
+            "x-enum-comments": {
+                "WaitlistConverted": "the hold became a booking",
+                "WaitlistOffered": "holding a copy until HoldExpiresAt"
+            },
+            "x-enum-descriptions": [
+                "",
+                "holding a copy until HoldExpiresAt",
+                "the hold became a booking",
+                "",
+                ""
+            ],
+            "x-enum-varnames": [
+                "WaitlistWaiting",
+                "WaitlistOffered",
+                "WaitlistConverted",
+                "WaitlistExpired",
+                "WaitlistCancelled"
+            ]
+        },
+        "model.Wallet": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "string"
+                },
+                "entries": {
+                    "description": "newest first, one page of the ledger",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.WalletEntry"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.WalletEntry": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "string"
+                },
+                "balance_after": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "description": "admin for adjustments, nil otherwise",
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "payment_id": {
+                    "type": "integer"
+                },
+                "refund_id": {
+                    "type": "integer"
+                },
+                "type": {
+                    "$ref": "#/definitions/model.WalletEntryType"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.WalletEntryType": {
+            "type": "string",
+            "enum": [
+                "payment",
+                "payment_release",
+                "refund",
+                "adjustment"
+            ],
+            "x-enum-comments": {
+                "WalletAdjustment": "credit or correction by an admin",
+                "WalletPayment": "paid toward a booking or order",
+                "WalletPaymentRelease": "given back when that payment failed",
+                "WalletRefund": "refund or deposit return credited as store credit"
+            },
+            "x-enum-descriptions": [
+                "paid toward a booking or order",
+                "given back when that payment failed",
+                "refund or deposit return credited as store credit",
+                "credit or correction by an admin"
+            ],
+            "x-enum-varnames": [
+                "WalletPayment",
+                "WalletPaymentRelease",
+                "WalletRefund",
+                "WalletAdjustment"
+            ]
         }
     },
     "securityDefinitions": {
@@ -1,8 +1,77 @@
 package dto
 
+import "github.com/yoockh/go-game-rental-api/internal/model"
+
 type CreateBookingRequest struct {
 	GameID    uint   `json:"game_id" validate:"required"`
 	StartDate string `json:"start_date" validate:"required"` // String format YYYY-MM-DD
 	EndDate   string `json:"end_date" validate:"required"`   // String format YYYY-MM-DD
 	Notes     string `json:"notes,omitempty"`
+	PromoCode string `json:"promo_code,omitempty"`
+}
+
+type ExtendBookingRequest struct {
+	EndDate     string `json:"end_date" validate:"required"` // String format YYYY-MM-DD
+	PaymentType string `json:"payment_type,omitempty"`
+}
+
+type RescheduleBookingRequest struct {
+	StartDate   string `json:"start_date" validate:"required"` // String format YYYY-MM-DD
+	EndDate     string `json:"end_date" validate:"required"`   // String format YYYY-MM-DD
+	PaymentType string `json:"payment_type,omitempty"`
+}
+
+type ReturnBookingRequest struct {
+	ReturnedAt   string      `json:"returned_at,omitempty"` // String format YYYY-MM-DD, defaults to today
+	DamageCharge model.Money `json:"damage_charge" validate:"gte=0" swaggertype:"string"`
+	DamageNotes  string      `json:"damage_notes,omitempty"`
+	ToWallet     bool        `json:"to_wallet,omitempty"` // return the deposit as store credit instead of a refund
+}
+
+type RetryDepositRefundRequest struct {
+	ToWallet bool `json:"to_wallet,omitempty"` // return the deposit as store credit instead of a refund
+}
+
+// AdminBookingListQuery holds the filters of the admin booking list. Dates use YYYY-MM-DD.
+type AdminBookingListQuery struct {
+	Status        string `query:"status" validate:"omitempty,oneof=pending confirmed active completed cancelled"`
+	UserID        uint   `query:"user_id"`
+	GameID        uint   `query:"game_id"`
+	PaymentStatus string `query:"payment_status" validate:"omitempty,oneof=pending paid failed refunded"`
+	StartFrom     string `query:"start_from"`
+	StartTo       string `query:"start_to"`
+	EndFrom       string `query:"end_from"`
+	EndTo         string `query:"end_to"`
+	Search        string `query:"q"` // customer email or name
+	Sort          string `query:"sort" validate:"omitempty,oneof=created_at updated_at start_date end_date payment_due_at"`
+	Order         string `query:"order" validate:"omitempty,oneof=asc desc"`
+}
+
+type UpdateBookingStatusRequest struct {
+	Status model.BookingStatus `json:"status" validate:"required,oneof=pending confirmed active completed cancelled"`
+	Reason string              `json:"reason,omitempty"`
+}
+
+type TaxReportResponse struct {
+	From          string           `json:"from"` // YYYY-MM-DD, inclusive
+	To            string           `json:"to"`   // YYYY-MM-DD, inclusive
+	Invoices      int64            `json:"invoices"`
+	TaxableAmount model.Money      `json:"taxable_amount" swaggertype:"string"`
+	TaxAmount     model.Money      `json:"tax_amount" swaggertype:"string"`
+	TotalAmount   model.Money      `json:"total_amount" swaggertype:"string"`
+	Rates         []model.TaxTotal `json:"rates"` // per tax rate, bookings priced before a rate change keep theirs
+}
+
+func ToTaxReportResponse(from, to string, rates []model.TaxTotal) *TaxReportResponse {
+	resp := &TaxReportResponse{From: from, To: to, Rates: rates}
+	if resp.Rates == nil {
+		resp.Rates = []model.TaxTotal{}
+	}
+	for _, rate := range rates {
+		resp.Invoices += rate.Invoices
+		resp.TaxableAmount = resp.TaxableAmount.Add(rate.TaxableAmount)
+		resp.TaxAmount = resp.TaxAmount.Add(rate.TaxAmount)
+		resp.TotalAmount = resp.TotalAmount.Add(rate.TotalAmount)
+	}
+	return resp
 }
@@ -1,23 +1,54 @@
 package dto
 
+import "github.com/yoockh/go-game-rental-api/internal/model"
+
 type CreateGameRequest struct {
-	CategoryID        uint    `json:"category_id" validate:"required"`
-	Name              string  `json:"name" validate:"required,min=3"`
-	Description       string  `json:"description,omitempty"`
-	Platform          string  `json:"platform,omitempty"`
-	Stock             int     `json:"stock" validate:"required,min=0"`
-	RentalPricePerDay float64 `json:"rental_price_per_day" validate:"required,min=0"`
-	SecurityDeposit   float64 `json:"security_deposit" validate:"required,min=0"`
-	Condition         string  `json:"condition" validate:"required,oneof=excellent good fair"`
+	CategoryID        uint        `json:"category_id" validate:"required"`
+	Name              string      `json:"name" validate:"required,min=3"`
+	Description       string      `json:"description,omitempty"`
+	Platform          string      `json:"platform,omitempty"`
+	Stock             int         `json:"stock" validate:"required,min=0"`
+	RentalPricePerDay model.Money `json:"rental_price_per_day" validate:"required,min=0" swaggertype:"string"`
+	SecurityDeposit   model.Money `json:"security_deposit" validate:"required,min=0" swaggertype:"string"`
+	Condition         string      `json:"condition" validate:"required,oneof=excellent good fair"`
 }
 
 type UpdateGameRequest struct {
-	CategoryID        uint    `json:"category_id,omitempty"`
-	Name              string  `json:"name,omitempty"`
-	Description       string  `json:"description,omitempty"`
-	Platform          string  `json:"platform,omitempty"`
-	Stock             int     `json:"stock,omitempty"`
-	RentalPricePerDay float64 `json:"rental_price_per_day,omitempty"`
-	SecurityDeposit   float64 `json:"security_deposit,omitempty"`
-	Condition         string  `json:"condition,omitempty"`
+	CategoryID        uint        `json:"category_id,omitempty"`
+	Name              string      `json:"name,omitempty"`
+	Description       string      `json:"description,omitempty"`
+	Platform          string      `json:"platform,omitempty"`
+	Stock             int         `json:"stock,omitempty"`
+	RentalPricePerDay model.Money `json:"rental_price_per_day,omitempty" swaggertype:"string"`
+	SecurityDeposit   model.Money `json:"security_deposit,omitempty" swaggertype:"string"`
+	Condition         string      `json:"condition,omitempty"`
+}
+
+type DayAvailabilityDTO struct {
+	Date      string `json:"date"` // YYYY-MM-DD
+	Available int    `json:"available"`
+}
+
+type GameAvailabilityResponse struct {
+	GameID uint                 `json:"game_id"`
+	From   string               `json:"from"`
+	To     string               `json:"to"`
+	Days   []DayAvailabilityDTO `json:"days"`
+}
+
+func ToGameAvailabilityResponse(gameID uint, from, to string, days []model.GameAvailability) *GameAvailabilityResponse {
+	result := make([]DayAvailabilityDTO, len(days))
+	for i, day := range days {
+		result[i] = DayAvailabilityDTO{
+			Date:      day.Date.Format("2006-01-02"),
+			Available: day.Available,
+		}
+	}
+
+	return &GameAvailabilityResponse{
+		GameID: gameID,
+		From:   from,
+		To:     to,
+		Days:   result,
+	}
 }
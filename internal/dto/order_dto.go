@@ -0,0 +1,13 @@
+package dto
+
+type OrderItemRequest struct {
+	GameID    uint   `json:"game_id" validate:"required"`
+	StartDate string `json:"start_date" validate:"required"` // String format YYYY-MM-DD
+	EndDate   string `json:"end_date" validate:"required"`   // String format YYYY-MM-DD
+	Notes     string `json:"notes,omitempty"`
+}
+
+type CreateOrderRequest struct {
+	Items []OrderItemRequest `json:"items" validate:"required,min=1,max=10,dive"`
+	Notes string             `json:"notes,omitempty"`
+}
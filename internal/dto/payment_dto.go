@@ -1,15 +1,54 @@
 package dto
 
-import "github.com/yoockh/go-game-rental-api/internal/model"
+import (
+	"time"
 
+	"github.com/yoockh/go-game-rental-api/internal/model"
+)
+
+// CreatePaymentRequest pays with a provider, the wallet, or offline by cash or
+// bank transfer. UseWallet takes what the wallet holds first and charges the
+// rest with the provider.
 type CreatePaymentRequest struct {
-	Provider    model.PaymentProvider `json:"provider" validate:"required,oneof=stripe midtrans"`
+	Provider    model.PaymentProvider `json:"provider" validate:"required,oneof=stripe midtrans wallet offline"`
 	PaymentType string                `json:"payment_type,omitempty"`
+	UseWallet   bool                  `json:"use_wallet,omitempty"`
+}
+
+// RefundPaymentRequest refunds part of a payment, or everything still
+// refundable when amount is left out
+type RefundPaymentRequest struct {
+	Amount   model.Money `json:"amount,omitempty" validate:"omitempty,gt=0" swaggertype:"string"`
+	Reason   string      `json:"reason" validate:"required,max=500"`
+	ToWallet bool        `json:"to_wallet,omitempty"` // credit the customer's wallet instead of the payment method
+}
+
+// VerifyPaymentRequest approves or rejects an offline payment
+type VerifyPaymentRequest struct {
+	Approve *bool  `json:"approve" validate:"required"`
+	Reason  string `json:"reason,omitempty" validate:"max=500"` // shown to the customer when rejected
+}
+
+// PaymentProofResponse links to the transfer receipt of an offline payment
+type PaymentProofResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DisputeEvidenceRequest answers an open payment dispute
+type DisputeEvidenceRequest struct {
+	Evidence string `json:"evidence" validate:"required,max=20000"` // the case for keeping the payment, e.g. rental and return records
 }
 
+// PaymentWebhookRequest documents the Midtrans notification payload. signature_key
+// is SHA512(order_id + status_code + gross_amount + server key).
 type PaymentWebhookRequest struct {
-	ProviderPaymentID string  `json:"provider_payment_id" validate:"required"`
-	Status            string  `json:"status" validate:"required"`
-	PaymentMethod     *string `json:"payment_method,omitempty"`
-	FailureReason     *string `json:"failure_reason,omitempty"`
+	OrderID           string  `json:"order_id" validate:"required"`
+	TransactionID     string  `json:"transaction_id"`
+	TransactionStatus string  `json:"transaction_status" validate:"required"`
+	StatusCode        string  `json:"status_code" validate:"required"`
+	GrossAmount       string  `json:"gross_amount" validate:"required"`
+	SignatureKey      string  `json:"signature_key" validate:"required"`
+	PaymentType       *string `json:"payment_type,omitempty"`
+	FraudStatus       *string `json:"fraud_status,omitempty"`
 }
@@ -0,0 +1,25 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yoockh/go-game-rental-api/internal/model"
+)
+
+// PromoCodeRequest creates a promo code or replaces its settings. Percentage
+// codes use percent_off and optionally max_discount; fixed codes use amount_off.
+type PromoCodeRequest struct {
+	Code           string       `json:"code" validate:"required,min=3,max=50"`
+	Description    string       `json:"description,omitempty"`
+	DiscountType   string       `json:"discount_type" validate:"required,oneof=percentage fixed"`
+	PercentOff     int          `json:"percent_off,omitempty" validate:"gte=0,lte=100"`
+	AmountOff      model.Money  `json:"amount_off,omitempty" validate:"gte=0" swaggertype:"string"`
+	MaxDiscount    *model.Money `json:"max_discount,omitempty" swaggertype:"string"`
+	ValidFrom      *time.Time   `json:"valid_from,omitempty"`
+	ValidUntil     *time.Time   `json:"valid_until,omitempty"`
+	MaxUses        *int         `json:"max_uses,omitempty" validate:"omitempty,gte=1"`
+	MaxUsesPerUser *int         `json:"max_uses_per_user,omitempty" validate:"omitempty,gte=1"`
+	GameIDs        []uint       `json:"game_ids,omitempty"`
+	CategoryIDs    []uint       `json:"category_ids,omitempty"`
+	IsActive       *bool        `json:"is_active,omitempty"` // defaults to true
+}
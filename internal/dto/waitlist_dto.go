@@ -0,0 +1,6 @@
+package dto
+
+type JoinWaitlistRequest struct {
+	StartDate string `json:"start_date" validate:"required"` // String format YYYY-MM-DD
+	EndDate   string `json:"end_date" validate:"required"`   // String format YYYY-MM-DD
+}
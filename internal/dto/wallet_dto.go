@@ -0,0 +1,10 @@
+package dto
+
+import "github.com/yoockh/go-game-rental-api/internal/model"
+
+// WalletAdjustmentRequest credits (positive amount) or debits (negative amount)
+// a customer's wallet
+type WalletAdjustmentRequest struct {
+	Amount      model.Money `json:"amount" validate:"required" swaggertype:"string"`
+	Description string      `json:"description" validate:"required,max=500"`
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository/email"
 )
 
 // ============= MOCK USER SERVICE =============
@@ -86,6 +87,11 @@ func (m *MockEmailRepository) SendEmail(ctx context.Context, to, subject, plainT
 	return args.Error(0)
 }
 
+func (m *MockEmailRepository) SendEmailWithAttachments(ctx context.Context, to, subject, plainText, htmlContent string, attachments []email.Attachment) error {
+	args := m.Called(ctx, to, subject, plainText, htmlContent, attachments)
+	return args.Error(0)
+}
+
 func (m *MockEmailRepository) SendWithTemplate(ctx context.Context, to, templateName string, data map[string]interface{}) error {
 	args := m.Called(ctx, to, templateName, data)
 	return args.Error(0)
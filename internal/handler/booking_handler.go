@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -15,20 +17,26 @@ import (
 )
 
 type BookingHandler struct {
-	bookingService service.BookingService
-	validate       *validator.Validate
+	bookingService           service.BookingService
+	bookingChangeService     service.BookingChangeService
+	bookingSettlementService service.BookingSettlementService
+	invoiceService           service.InvoiceService
+	validate                 *validator.Validate
 }
 
-func NewBookingHandler(bookingService service.BookingService) *BookingHandler {
+func NewBookingHandler(bookingService service.BookingService, bookingChangeService service.BookingChangeService, bookingSettlementService service.BookingSettlementService, invoiceService service.InvoiceService) *BookingHandler {
 	return &BookingHandler{
-		bookingService: bookingService,
-		validate:       utils.GetValidator(),
+		bookingService:           bookingService,
+		bookingChangeService:     bookingChangeService,
+		bookingSettlementService: bookingSettlementService,
+		invoiceService:           invoiceService,
+		validate:                 utils.GetValidator(),
 	}
 }
 
 // CreateBooking godoc
 // @Summary Create booking
-// @Description Create a new game rental booking
+// @Description Create a new game rental booking. An optional promo_code takes its discount off the rental price (never the deposit) and is stored on the booking.
 // @Tags Bookings
 // @Accept json
 // @Produce json
@@ -66,7 +74,7 @@ func (h *BookingHandler) CreateBooking(c echo.Context) error {
 		Notes:     utils.PtrOrNil(req.Notes),
 	}
 
-	err = h.bookingService.Create(userID, bookingData)
+	err = h.bookingService.Create(userID, bookingData, req.PromoCode)
 	if err != nil {
 		return myResponse.BadRequest(c, err.Error())
 	}
@@ -147,17 +155,180 @@ func (h *BookingHandler) CancelBooking(c echo.Context) error {
 	return myResponse.Success(c, "Booking cancelled successfully", nil)
 }
 
+// ExtendBooking godoc
+// @Summary Extend booking
+// @Description Request a later end date for a confirmed or active booking. The extra days are held and a supplemental payment is created; the end date moves once it is paid.
+// @Tags Bookings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param booking_id path int true "Booking ID"
+// @Param request body dto.ExtendBookingRequest true "New end date"
+// @Success 201 {object} model.BookingDateChange "Extension requested successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid input or dates not available"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Booking not found"
+// @Router /bookings/{booking_id}/extend [post]
+func (h *BookingHandler) ExtendBooking(c echo.Context) error {
+	bookingID := myRequest.PathParamUint(c, "booking_id")
+	if bookingID == 0 {
+		return myResponse.BadRequest(c, "Invalid booking ID")
+	}
+
+	var req dto.ExtendBookingRequest
+	if err := c.Bind(&req); err != nil {
+		return myResponse.BadRequest(c, "Invalid input: "+err.Error())
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return myResponse.BadRequest(c, "Validation error: "+err.Error())
+	}
+
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		return myResponse.BadRequest(c, "Invalid end_date format (use YYYY-MM-DD)")
+	}
+
+	userID := echomw.CurrentUserID(c)
+	change, err := h.bookingChangeService.RequestExtension(userID, bookingID, endDate, req.PaymentType)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Created(c, "Extension requested successfully", change)
+}
+
+// RescheduleBooking godoc
+// @Summary Reschedule booking
+// @Description Move a pending or confirmed booking to new dates and reprice it. For a paid booking a higher price creates a supplemental payment (dates move once it is paid) and a lower price is refunded.
+// @Tags Bookings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param booking_id path int true "Booking ID"
+// @Param request body dto.RescheduleBookingRequest true "New dates"
+// @Success 200 {object} model.BookingDateChange "Booking rescheduled successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid input"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Booking not found"
+// @Router /bookings/{booking_id}/dates [patch]
+func (h *BookingHandler) RescheduleBooking(c echo.Context) error {
+	bookingID := myRequest.PathParamUint(c, "booking_id")
+	if bookingID == 0 {
+		return myResponse.BadRequest(c, "Invalid booking ID")
+	}
+
+	var req dto.RescheduleBookingRequest
+	if err := c.Bind(&req); err != nil {
+		return myResponse.BadRequest(c, "Invalid input: "+err.Error())
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return myResponse.BadRequest(c, "Validation error: "+err.Error())
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return myResponse.BadRequest(c, "Invalid start_date format (use YYYY-MM-DD)")
+	}
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		return myResponse.BadRequest(c, "Invalid end_date format (use YYYY-MM-DD)")
+	}
+
+	userID := echomw.CurrentUserID(c)
+	change, err := h.bookingChangeService.Reschedule(userID, bookingID, startDate, endDate, req.PaymentType)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	if change.Status == model.DateChangePending {
+		return myResponse.Success(c, "Reschedule requested, pay the price difference to apply it", change)
+	}
+	return myResponse.Success(c, "Booking rescheduled successfully", change)
+}
+
+// GetBookingHistory godoc
+// @Summary Get booking status history
+// @Description Get the status changes of a booking with actor, reason and timestamp (owner or admin)
+// @Tags Bookings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param booking_id path int true "Booking ID"
+// @Success 200 {array} model.BookingStatusHistory "Booking history retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid booking ID"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Booking not found"
+// @Router /bookings/{booking_id}/history [get]
+func (h *BookingHandler) GetBookingHistory(c echo.Context) error {
+	bookingID := myRequest.PathParamUint(c, "booking_id")
+	if bookingID == 0 {
+		return myResponse.BadRequest(c, "Invalid booking ID")
+	}
+
+	userID := echomw.CurrentUserID(c)
+	role := echomw.CurrentRole(c)
+
+	history, err := h.bookingService.GetHistory(userID, model.UserRole(role), bookingID)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Success(c, "Booking history retrieved successfully", history)
+}
+
+// GetBookingInvoice godoc
+// @Summary Download booking invoice
+// @Description Download the PDF invoice of a paid booking: sequential invoice number, line items, payment method and paid date (owner or admin). The first request issues the invoice if the payment confirmation did not.
+// @Tags Bookings
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param booking_id path int true "Booking ID"
+// @Success 200 {file} file "Invoice PDF"
+// @Failure 400 {object} map[string]interface{} "Invalid booking ID or booking not paid"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Booking not found"
+// @Router /bookings/{booking_id}/invoice [get]
+func (h *BookingHandler) GetBookingInvoice(c echo.Context) error {
+	bookingID := myRequest.PathParamUint(c, "booking_id")
+	if bookingID == 0 {
+		return myResponse.BadRequest(c, "Invalid booking ID")
+	}
+
+	userID := echomw.CurrentUserID(c)
+	role := echomw.CurrentRole(c)
+
+	invoice, pdf, err := h.invoiceService.GetBookingInvoice(userID, model.UserRole(role), bookingID)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", invoice.Number+".pdf"))
+	return c.Blob(http.StatusOK, "application/pdf", pdf)
+}
+
 // Admin endpoints
 // GetAllBookings godoc
 // @Summary Get all bookings
-// @Description Get list of all bookings (Admin only)
+// @Description Get list of all bookings with filters, customer search and sorting (Admin only)
 // @Tags Admin - Bookings
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
+// @Param status query string false "Booking status" Enums(pending, confirmed, active, completed, cancelled)
+// @Param user_id query int false "Customer ID"
+// @Param game_id query int false "Game ID"
+// @Param payment_status query string false "Payment status" Enums(pending, paid, failed, refunded)
+// @Param start_from query string false "Start date on or after (YYYY-MM-DD)"
+// @Param start_to query string false "Start date on or before (YYYY-MM-DD)"
+// @Param end_from query string false "End date on or after (YYYY-MM-DD)"
+// @Param end_to query string false "End date on or before (YYYY-MM-DD)"
+// @Param q query string false "Search customer email or name"
+// @Param sort query string false "Sort column" Enums(created_at, updated_at, start_date, end_date, payment_due_at) default(created_at)
+// @Param order query string false "Sort direction" Enums(asc, desc) default(desc)
 // @Success 200 {object} map[string]interface{} "Bookings retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid filter"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 403 {object} map[string]interface{} "Forbidden"
 // @Router /admin/bookings [get]
@@ -165,7 +336,15 @@ func (h *BookingHandler) GetAllBookings(c echo.Context) error {
 	params := utils.ParsePagination(c)
 	role := echomw.CurrentRole(c)
 
-	bookings, total, err := h.bookingService.GetAll(model.UserRole(role), params.Limit, params.Offset)
+	var query dto.AdminBookingListQuery
+	if err := c.Bind(&query); err != nil {
+		return myResponse.BadRequest(c, "Invalid filter: "+err.Error())
+	}
+	if err := h.validate.Struct(&query); err != nil {
+		return myResponse.BadRequest(c, "Validation error: "+err.Error())
+	}
+
+	bookings, total, err := h.bookingService.GetAll(model.UserRole(role), &query, params.Limit, params.Offset)
 	if err != nil {
 		return utils.MapServiceError(c, err)
 	}
@@ -176,13 +355,13 @@ func (h *BookingHandler) GetAllBookings(c echo.Context) error {
 
 // UpdateBookingStatus godoc
 // @Summary Update booking status
-// @Description Update booking status (Admin only)
+// @Description Update booking status (Admin only). Completing a booking records the return as of today without damage, use the return endpoint for anything else
 // @Tags Admin - Bookings
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "Booking ID"
-// @Param request body object{status=string} true "New status"
+// @Param request body dto.UpdateBookingStatusRequest true "New status"
 // @Success 200 {object} map[string]interface{} "Booking status updated successfully"
 // @Failure 400 {object} map[string]interface{} "Invalid booking ID"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
@@ -194,18 +373,156 @@ func (h *BookingHandler) UpdateBookingStatus(c echo.Context) error {
 		return myResponse.BadRequest(c, "Invalid booking ID")
 	}
 
-	var req struct {
-		Status model.BookingStatus `json:"status" validate:"required"`
-	}
+	var req dto.UpdateBookingStatusRequest
 	if err := c.Bind(&req); err != nil {
 		return myResponse.BadRequest(c, "Invalid input: "+err.Error())
 	}
+	if err := h.validate.Struct(&req); err != nil {
+		return myResponse.BadRequest(c, "Validation error: "+err.Error())
+	}
 
+	adminID := echomw.CurrentUserID(c)
 	role := echomw.CurrentRole(c)
-	err := h.bookingService.UpdateStatus(model.UserRole(role), bookingID, req.Status)
+
+	if req.Status == model.BookingCompleted {
+		settlement, err := h.bookingSettlementService.RecordReturn(adminID, model.UserRole(role), bookingID, time.Now(), model.Money{}, "", false)
+		if err != nil {
+			return utils.MapServiceError(c, err)
+		}
+		return myResponse.Success(c, "Booking status updated successfully", settlement)
+	}
+
+	err := h.bookingService.UpdateStatus(adminID, model.UserRole(role), bookingID, req.Status, req.Reason)
 	if err != nil {
 		return utils.MapServiceError(c, err)
 	}
 
 	return myResponse.Success(c, "Booking status updated successfully", nil)
 }
+
+// ReturnBooking godoc
+// @Summary Record game return
+// @Description Complete an active booking and settle its security deposit. Late fees and damage charges are deducted and the rest is refunded, or credited to the customer's wallet with to_wallet (Admin only)
+// @Tags Admin - Bookings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Booking ID"
+// @Param request body dto.ReturnBookingRequest true "Return details"
+// @Success 200 {object} model.BookingSettlement "Booking returned successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid input"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Booking not found"
+// @Router /admin/bookings/{id}/return [post]
+func (h *BookingHandler) ReturnBooking(c echo.Context) error {
+	bookingID := myRequest.PathParamUint(c, "id")
+	if bookingID == 0 {
+		return myResponse.BadRequest(c, "Invalid booking ID")
+	}
+
+	var req dto.ReturnBookingRequest
+	if err := c.Bind(&req); err != nil {
+		return myResponse.BadRequest(c, "Invalid input: "+err.Error())
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return myResponse.BadRequest(c, "Validation error: "+err.Error())
+	}
+
+	returnedAt := time.Now()
+	if req.ReturnedAt != "" {
+		parsed, err := time.Parse("2006-01-02", req.ReturnedAt)
+		if err != nil {
+			return myResponse.BadRequest(c, "Invalid returned_at format (use YYYY-MM-DD)")
+		}
+		returnedAt = parsed
+	}
+
+	adminID := echomw.CurrentUserID(c)
+	role := echomw.CurrentRole(c)
+	settlement, err := h.bookingSettlementService.RecordReturn(adminID, model.UserRole(role), bookingID, returnedAt, req.DamageCharge, req.DamageNotes, req.ToWallet)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Success(c, "Booking returned successfully", settlement)
+}
+
+// RetryDepositRefund godoc
+// @Summary Retry deposit refund
+// @Description Send a security deposit refund that failed when the game was returned again, or credit it to the customer's wallet with to_wallet (Admin only)
+// @Tags Admin - Bookings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Booking ID"
+// @Param request body dto.RetryDepositRefundRequest false "Refund destination"
+// @Success 200 {object} model.BookingSettlement "Deposit refund retried"
+// @Failure 400 {object} map[string]interface{} "Refund has not failed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Booking or settlement not found"
+// @Router /admin/bookings/{id}/return/refund [post]
+func (h *BookingHandler) RetryDepositRefund(c echo.Context) error {
+	bookingID := myRequest.PathParamUint(c, "id")
+	if bookingID == 0 {
+		return myResponse.BadRequest(c, "Invalid booking ID")
+	}
+
+	var req dto.RetryDepositRefundRequest
+	if err := c.Bind(&req); err != nil {
+		return myResponse.BadRequest(c, "Invalid input: "+err.Error())
+	}
+
+	adminID := echomw.CurrentUserID(c)
+	role := echomw.CurrentRole(c)
+	settlement, err := h.bookingSettlementService.RetryDepositRefund(adminID, model.UserRole(role), bookingID, req.ToWallet)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Success(c, "Deposit refund retried", settlement)
+}
+
+// GetTaxReport godoc
+// @Summary Get tax report
+// @Description Total the VAT of the bookings invoiced in a period, per tax rate, for tax filing (Admin only). Defaults to the current month up to today
+// @Tags Admin - Bookings
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "Invoiced on or after (YYYY-MM-DD)"
+// @Param to query string false "Invoiced on or before (YYYY-MM-DD)"
+// @Success 200 {object} dto.TaxReportResponse "Tax report retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid period"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Router /admin/bookings/tax-report [get]
+func (h *BookingHandler) GetTaxReport(c echo.Context) error {
+	today := time.Now().Truncate(24 * time.Hour)
+	from := today.AddDate(0, 0, 1-today.Day())
+	if raw := myRequest.QueryString(c, "from", ""); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return myResponse.BadRequest(c, "Invalid from format (use YYYY-MM-DD)")
+		}
+		from = parsed
+	}
+
+	to := today
+	if raw := myRequest.QueryString(c, "to", ""); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return myResponse.BadRequest(c, "Invalid to format (use YYYY-MM-DD)")
+		}
+		to = parsed
+	}
+
+	role := echomw.CurrentRole(c)
+	rates, err := h.invoiceService.GetTaxReport(model.UserRole(role), from, to)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	resp := dto.ToTaxReportResponse(from.Format("2006-01-02"), to.Format("2006-01-02"), rates)
+	return myResponse.Success(c, "Tax report retrieved successfully", resp)
+}
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"log"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
@@ -78,6 +80,58 @@ func (h *GameHandler) GetGameDetail(c echo.Context) error {
 	return myResponse.Success(c, "Game retrieved successfully", game)
 }
 
+// GetGameAvailability godoc
+// @Summary Get game availability
+// @Description Get the number of free copies per day for a date range (defaults to the next 30 days)
+// @Tags Games
+// @Accept json
+// @Produce json
+// @Param id path int true "Game ID"
+// @Param from query string false "Start date (YYYY-MM-DD)"
+// @Param to query string false "End date (YYYY-MM-DD)"
+// @Success 200 {object} dto.GameAvailabilityResponse "Availability retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid date range"
+// @Failure 404 {object} map[string]interface{} "Game not found"
+// @Router /games/{id}/availability [get]
+func (h *GameHandler) GetGameAvailability(c echo.Context) error {
+	gameID := myRequest.PathParamUint(c, "id")
+	if gameID == 0 {
+		return myResponse.BadRequest(c, "Invalid game ID")
+	}
+
+	from := time.Now().Truncate(24 * time.Hour)
+	if raw := myRequest.QueryString(c, "from", ""); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return myResponse.BadRequest(c, "Invalid from format (use YYYY-MM-DD)")
+		}
+		from = parsed
+	}
+
+	to := from.AddDate(0, 0, 30)
+	if raw := myRequest.QueryString(c, "to", ""); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return myResponse.BadRequest(c, "Invalid to format (use YYYY-MM-DD)")
+		}
+		to = parsed
+	}
+
+	days, err := h.gameService.GetAvailability(gameID, from, to)
+	if err != nil {
+		if errors.Is(err, service.ErrGameNotFound) {
+			return myResponse.NotFound(c, err.Error())
+		}
+		if errors.Is(err, service.ErrAvailabilityInvalidRange) {
+			return myResponse.BadRequest(c, err.Error())
+		}
+		return myResponse.InternalServerError(c, "Failed to retrieve availability")
+	}
+
+	resp := dto.ToGameAvailabilityResponse(gameID, from.Format("2006-01-02"), to.Format("2006-01-02"), days)
+	return myResponse.Success(c, "Availability retrieved successfully", resp)
+}
+
 // SearchGames godoc
 // @Summary Search games
 // @Description Search games by name, description, or platform
@@ -202,10 +256,10 @@ func (h *GameHandler) UpdateGame(c echo.Context) error {
 		game.Stock = req.Stock
 		game.AvailableStock += diff
 	}
-	if req.RentalPricePerDay > 0 {
+	if req.RentalPricePerDay.IsPositive() {
 		game.RentalPricePerDay = req.RentalPricePerDay
 	}
-	if req.SecurityDeposit > 0 {
+	if req.SecurityDeposit.IsPositive() {
 		game.SecurityDeposit = req.SecurityDeposit
 	}
 	if req.Condition != "" {
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	echomw "github.com/yoockh/go-api-utils/pkg-echo/middleware"
+	myRequest "github.com/yoockh/go-api-utils/pkg-echo/request"
+	myResponse "github.com/yoockh/go-api-utils/pkg-echo/response"
+	"github.com/yoockh/go-game-rental-api/internal/dto"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/service"
+	"github.com/yoockh/go-game-rental-api/internal/utils"
+)
+
+type OrderHandler struct {
+	orderService service.OrderService
+	validate     *validator.Validate
+}
+
+func NewOrderHandler(orderService service.OrderService) *OrderHandler {
+	return &OrderHandler{
+		orderService: orderService,
+		validate:     utils.GetValidator(),
+	}
+}
+
+// CreateOrder godoc
+// @Summary Create order
+// @Description Book several games at once. Every item is held for its dates and the order is paid with a single payment.
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.CreateOrderRequest true "Order items"
+// @Success 201 {object} model.Order "Order created successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid input"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /orders [post]
+func (h *OrderHandler) CreateOrder(c echo.Context) error {
+	var req dto.CreateOrderRequest
+	if err := c.Bind(&req); err != nil {
+		return myResponse.BadRequest(c, "Invalid input: "+err.Error())
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return myResponse.BadRequest(c, "Validation error: "+err.Error())
+	}
+
+	userID := echomw.CurrentUserID(c)
+	order := &model.Order{Notes: utils.PtrOrNil(req.Notes)}
+	for i, item := range req.Items {
+		startDate, err := time.Parse("2006-01-02", item.StartDate)
+		if err != nil {
+			return myResponse.BadRequest(c, fmt.Sprintf("Invalid start_date format in item %d (use YYYY-MM-DD)", i+1))
+		}
+		endDate, err := time.Parse("2006-01-02", item.EndDate)
+		if err != nil {
+			return myResponse.BadRequest(c, fmt.Sprintf("Invalid end_date format in item %d (use YYYY-MM-DD)", i+1))
+		}
+
+		order.Items = append(order.Items, model.Booking{
+			UserID:    userID,
+			GameID:    item.GameID,
+			StartDate: startDate,
+			EndDate:   endDate,
+			Notes:     utils.PtrOrNil(item.Notes),
+		})
+	}
+
+	if err := h.orderService.Create(userID, order); err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Created(c, "Order created successfully", order)
+}
+
+// GetMyOrders godoc
+// @Summary Get my orders
+// @Description Get list of current user's orders with their items
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} map[string]interface{} "Orders retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /orders/my [get]
+func (h *OrderHandler) GetMyOrders(c echo.Context) error {
+	userID := echomw.CurrentUserID(c)
+	if userID == 0 {
+		return myResponse.Unauthorized(c, "Unauthorized")
+	}
+
+	params := utils.ParsePagination(c)
+
+	orders, total, err := h.orderService.GetUserOrders(userID, params.Limit, params.Offset)
+	if err != nil {
+		return myResponse.InternalServerError(c, "Failed to retrieve orders")
+	}
+
+	meta := utils.CreateMeta(params, total)
+	return myResponse.Paginated(c, "Orders retrieved successfully", orders, meta)
+}
+
+// GetOrderDetail godoc
+// @Summary Get order detail
+// @Description Get an order with its items and payment
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param order_id path int true "Order ID"
+// @Success 200 {object} model.Order "Order retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid order ID"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Order not found"
+// @Router /orders/{order_id} [get]
+func (h *OrderHandler) GetOrderDetail(c echo.Context) error {
+	orderID := myRequest.PathParamUint(c, "order_id")
+	if orderID == 0 {
+		return myResponse.BadRequest(c, "Invalid order ID")
+	}
+
+	userID := echomw.CurrentUserID(c)
+	order, err := h.orderService.GetByID(userID, orderID)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Success(c, "Order retrieved successfully", order)
+}
+
+// CancelOrder godoc
+// @Summary Cancel order
+// @Description Cancel every item of an order that has not been handed over yet
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param order_id path int true "Order ID"
+// @Success 200 {object} map[string]interface{} "Order cancelled successfully"
+// @Failure 400 {object} map[string]interface{} "Order cannot be cancelled"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Order not found"
+// @Router /orders/{order_id}/cancel [patch]
+func (h *OrderHandler) CancelOrder(c echo.Context) error {
+	orderID := myRequest.PathParamUint(c, "order_id")
+	if orderID == 0 {
+		return myResponse.BadRequest(c, "Invalid order ID")
+	}
+
+	userID := echomw.CurrentUserID(c)
+	if err := h.orderService.Cancel(userID, orderID); err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Success(c, "Order cancelled successfully", nil)
+}
@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"errors"
+	"io"
+
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
 	echomw "github.com/yoockh/go-api-utils/pkg-echo/middleware"
 	myRequest "github.com/yoockh/go-api-utils/pkg-echo/request"
 	myResponse "github.com/yoockh/go-api-utils/pkg-echo/response"
@@ -14,19 +18,23 @@ import (
 
 type PaymentHandler struct {
 	paymentService service.PaymentService
+	refundService  service.RefundService
+	disputeService service.DisputeService
 	validate       *validator.Validate
 }
 
-func NewPaymentHandler(paymentService service.PaymentService) *PaymentHandler {
+func NewPaymentHandler(paymentService service.PaymentService, refundService service.RefundService, disputeService service.DisputeService) *PaymentHandler {
 	return &PaymentHandler{
 		paymentService: paymentService,
+		refundService:  refundService,
+		disputeService: disputeService,
 		validate:       utils.GetValidator(),
 	}
 }
 
 // CreatePayment godoc
 // @Summary Create payment
-// @Description Create payment for a booking
+// @Description Create a payment attempt for a booking. A failed attempt can be followed by a new one while the booking is held. The payment carries the instructions to complete it (VA numbers, QR string, deeplinks, bank account for offline payments, expiry).
 // @Tags Payments
 // @Accept json
 // @Produce json
@@ -49,7 +57,7 @@ func (h *PaymentHandler) CreatePayment(c echo.Context) error {
 		return myResponse.BadRequest(c, "Validation error: "+err.Error())
 	}
 
-	payment, err := h.paymentService.CreatePayment(userID, bookingID, req.Provider, req.PaymentType)
+	payment, err := h.paymentService.CreatePayment(userID, bookingID, req.Provider, req.PaymentType, req.UseWallet)
 	if err != nil {
 		return myResponse.Forbidden(c, err.Error()) // Return 403 jika service error
 	}
@@ -57,9 +65,45 @@ func (h *PaymentHandler) CreatePayment(c echo.Context) error {
 	return myResponse.Created(c, "Payment created successfully", payment)
 }
 
+// CreateOrderPayment godoc
+// @Summary Create order payment
+// @Description Create a single payment covering every item of an order, with the instructions to complete it
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param order_id path int true "Order ID"
+// @Param request body dto.CreatePaymentRequest true "Payment details"
+// @Success 201 {object} model.Payment "Payment created successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid input"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /orders/{order_id}/payments [post]
+func (h *PaymentHandler) CreateOrderPayment(c echo.Context) error {
+	userID := echomw.CurrentUserID(c)
+	orderID := myRequest.PathParamUint(c, "order_id")
+	if orderID == 0 {
+		return myResponse.BadRequest(c, "Invalid order ID")
+	}
+
+	var req dto.CreatePaymentRequest
+	if err := c.Bind(&req); err != nil {
+		return myResponse.BadRequest(c, "Invalid input: "+err.Error())
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return myResponse.BadRequest(c, "Validation error: "+err.Error())
+	}
+
+	payment, err := h.paymentService.CreateOrderPayment(userID, orderID, req.Provider, req.PaymentType, req.UseWallet)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Created(c, "Payment created successfully", payment)
+}
+
 // GetPaymentByBooking godoc
 // @Summary Get payment by booking
-// @Description Get payment information for a specific booking
+// @Description Get the current payment attempt of a booking: the attempt that paid, or else the latest. Every attempt is listed in the booking detail.
 // @Tags Payments
 // @Accept json
 // @Produce json
@@ -89,6 +133,52 @@ func (h *PaymentHandler) GetPaymentByBooking(c echo.Context) error {
 	return myResponse.Success(c, "Payment retrieved successfully", payment)
 }
 
+// UploadPaymentProof godoc
+// @Summary Upload transfer receipt
+// @Description Upload the transfer receipt of an offline payment (JPEG, PNG or PDF, at most 5MB). The payment then waits for an admin to verify it and the booking no longer expires; uploading again replaces the receipt until it is verified.
+// @Tags Payments
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment ID"
+// @Param receipt formData file true "Transfer receipt"
+// @Success 200 {object} model.Payment "Transfer receipt uploaded successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid file or payment not waiting for a receipt"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Payment not owned by user"
+// @Failure 404 {object} map[string]interface{} "Payment not found"
+// @Router /payments/{id}/proof [post]
+func (h *PaymentHandler) UploadPaymentProof(c echo.Context) error {
+	paymentID := myRequest.PathParamUint(c, "id")
+	if paymentID == 0 {
+		return myResponse.BadRequest(c, "Invalid payment ID")
+	}
+
+	file, err := c.FormFile("receipt")
+	if err != nil {
+		return myResponse.BadRequest(c, "Transfer receipt is required")
+	}
+	src, err := file.Open()
+	if err != nil {
+		return myResponse.BadRequest(c, "Invalid file: "+err.Error())
+	}
+	defer src.Close()
+
+	// Read one byte past the limit so oversized files are rejected, not cut
+	data, err := io.ReadAll(io.LimitReader(src, service.MaxPaymentProofSize+1))
+	if err != nil {
+		return myResponse.BadRequest(c, "Invalid file: "+err.Error())
+	}
+
+	userID := echomw.CurrentUserID(c)
+	payment, err := h.paymentService.UploadPaymentProof(userID, paymentID, file.Filename, data)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Success(c, "Transfer receipt uploaded successfully", payment)
+}
+
 // GetPaymentDetail godoc
 // @Summary Get payment detail
 // @Description Get detailed payment information (Admin only)
@@ -117,24 +207,105 @@ func (h *PaymentHandler) GetPaymentDetail(c echo.Context) error {
 	return myResponse.Success(c, "Payment retrieved successfully", payment)
 }
 
+// GetPaymentProof godoc
+// @Summary Get transfer receipt
+// @Description Get a short-lived link to the transfer receipt of an offline payment. Receipts are kept in a private bucket, so the link expires after a few minutes (Admin only)
+// @Tags Admin - Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment ID"
+// @Success 200 {object} dto.PaymentProofResponse "Transfer receipt link created successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid payment ID"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Payment or transfer receipt not found"
+// @Router /admin/payments/{id}/proof [get]
+func (h *PaymentHandler) GetPaymentProof(c echo.Context) error {
+	paymentID := myRequest.PathParamUint(c, "id")
+	if paymentID == 0 {
+		return myResponse.BadRequest(c, "Invalid payment ID")
+	}
+
+	role := echomw.CurrentRole(c)
+	url, expiresAt, err := h.paymentService.GetPaymentProofURL(model.UserRole(role), paymentID)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Success(c, "Transfer receipt link created successfully", dto.PaymentProofResponse{URL: url, ExpiresAt: expiresAt})
+}
+
+// RefundPayment godoc
+// @Summary Refund payment
+// @Description Refund a paid payment through its provider, in full or partially (Admin only). A full refund cancels the bookings it paid for that were not handed over yet.
+// @Tags Admin - Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment ID"
+// @Param request body dto.RefundPaymentRequest true "Refund amount (omit for a full refund) and reason"
+// @Success 201 {object} model.PaymentRefund "Payment refunded successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid input or refund rejected"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Payment not found"
+// @Router /admin/payments/{id}/refund [post]
+func (h *PaymentHandler) RefundPayment(c echo.Context) error {
+	paymentID := myRequest.PathParamUint(c, "id")
+	if paymentID == 0 {
+		return myResponse.BadRequest(c, "Invalid payment ID")
+	}
+
+	var req dto.RefundPaymentRequest
+	if err := c.Bind(&req); err != nil {
+		return myResponse.BadRequest(c, "Invalid input: "+err.Error())
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return myResponse.BadRequest(c, "Validation error: "+err.Error())
+	}
+
+	adminID := echomw.CurrentUserID(c)
+	role := echomw.CurrentRole(c)
+	refund, err := h.refundService.RefundPayment(adminID, model.UserRole(role), paymentID, req.Amount, req.Reason, req.ToWallet)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Created(c, "Payment refunded successfully", refund)
+}
+
 // PaymentWebhook godoc
 // @Summary Payment webhook
-// @Description Receive payment status updates from payment provider
+// @Description Receive payment status updates from a payment provider. /webhooks/payments is the Midtrans endpoint; other providers post to /webhooks/payments/{provider}.
 // @Tags Webhooks
 // @Accept json
 // @Produce json
-// @Param request body dto.PaymentWebhookRequest true "Webhook payload"
+// @Param provider path string false "Payment provider" Enums(midtrans, stripe)
+// @Param request body dto.PaymentWebhookRequest true "Webhook payload (Midtrans shape shown)"
 // @Success 200 {object} map[string]interface{} "Webhook processed successfully"
-// @Failure 400 {object} map[string]interface{} "Invalid webhook payload"
+// @Failure 400 {object} map[string]interface{} "Invalid webhook payload or amount mismatch"
+// @Failure 401 {object} map[string]interface{} "Invalid signature"
 // @Router /webhooks/payments [post]
+// @Router /webhooks/payments/{provider} [post]
 func (h *PaymentHandler) PaymentWebhook(c echo.Context) error {
-	var webhookData map[string]interface{}
-	if err := c.Bind(&webhookData); err != nil {
+	provider := model.ProviderMidtrans
+	if p := c.Param("provider"); p != "" {
+		provider = model.PaymentProvider(p)
+	}
+
+	// Signatures cover the body exactly as sent, so it is passed on unparsed
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
 		return myResponse.BadRequest(c, "Invalid webhook payload: "+err.Error())
 	}
 
-	// Pass raw data to service (ProcessWebhook expects interface{})
-	err := h.paymentService.ProcessWebhook(webhookData)
+	err = h.paymentService.ProcessWebhook(provider, c.Request().Header, body)
+	if errors.Is(err, service.ErrWebhookInvalidSignature) {
+		logrus.WithFields(logrus.Fields{
+			"remote_ip": c.RealIP(),
+			"provider":  provider,
+		}).Warn("Rejected payment webhook with invalid signature")
+		return myResponse.Unauthorized(c, err.Error())
+	}
 	if err != nil {
 		return myResponse.BadRequest(c, err.Error())
 	}
@@ -176,7 +347,7 @@ func (h *PaymentHandler) GetAllPayments(c echo.Context) error {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param status query string true "Payment status" Enums(pending, completed, failed, refunded)
+// @Param status query string true "Payment status" Enums(pending, paid, failed, refunded, partially_refunded, review)
 // @Param page query int false "Page number" default(1)
 // @Param limit query int false "Items per page" default(10)
 // @Success 200 {object} map[string]interface{} "Payments retrieved successfully"
@@ -196,3 +367,299 @@ func (h *PaymentHandler) GetPaymentsByStatus(c echo.Context) error {
 	meta := utils.CreateMeta(params, total)
 	return myResponse.Paginated(c, "Payments retrieved successfully", payments, meta)
 }
+
+// GetPaymentEvents godoc
+// @Summary Get payment events
+// @Description Get the stored payment provider notifications, optionally filtered by status (Admin only)
+// @Tags Admin - Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Event status" Enums(received, processed, ignored, failed, rejected)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} map[string]interface{} "Payment events retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Router /admin/payments/events [get]
+func (h *PaymentHandler) GetPaymentEvents(c echo.Context) error {
+	params := utils.ParsePagination(c)
+	status := c.QueryParam("status")
+	role := echomw.CurrentRole(c)
+
+	events, total, err := h.paymentService.GetEvents(model.UserRole(role), model.PaymentEventStatus(status), params.Limit, params.Offset)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	meta := utils.CreateMeta(params, total)
+	return myResponse.Paginated(c, "Payment events retrieved successfully", events, meta)
+}
+
+// ReplayPaymentEvent godoc
+// @Summary Replay payment event
+// @Description Apply a failed payment notification, or one stuck received by a crashed delivery, again (Admin only)
+// @Tags Admin - Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment event ID"
+// @Success 200 {object} model.PaymentEvent "Payment event replayed successfully"
+// @Failure 400 {object} map[string]interface{} "Event is not replayable or failed again"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Payment event not found"
+// @Router /admin/payments/events/{id}/replay [post]
+func (h *PaymentHandler) ReplayPaymentEvent(c echo.Context) error {
+	eventID := myRequest.PathParamUint(c, "id")
+	if eventID == 0 {
+		return myResponse.BadRequest(c, "Invalid payment event ID")
+	}
+
+	role := echomw.CurrentRole(c)
+	event, err := h.paymentService.ReplayEvent(model.UserRole(role), eventID)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Success(c, "Payment event replayed successfully", event)
+}
+
+// GetPaymentReconciliations godoc
+// @Summary Get payment reconciliation report
+// @Description Get the pending payments whose gateway status disagreed with ours and how they were resolved (Admin only)
+// @Tags Admin - Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param resolution query string false "Resolution" Enums(applied, skipped, failed, unresolved)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} map[string]interface{} "Reconciliation report retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Router /admin/payments/reconciliations [get]
+func (h *PaymentHandler) GetPaymentReconciliations(c echo.Context) error {
+	params := utils.ParsePagination(c)
+	resolution := c.QueryParam("resolution")
+	role := echomw.CurrentRole(c)
+
+	reports, total, err := h.paymentService.GetReconciliations(model.UserRole(role), model.ReconciliationResolution(resolution), params.Limit, params.Offset)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	meta := utils.CreateMeta(params, total)
+	return myResponse.Paginated(c, "Reconciliation report retrieved successfully", reports, meta)
+}
+
+// GetPaymentReviewQueue godoc
+// @Summary Get payments awaiting review
+// @Description Get, oldest first, the card payments the provider's fraud detection challenged (queue=fraud, approve or deny them) or the offline payments whose transfer receipt waits for verification (queue=offline, verify them). They are not confirmed until an admin decides (Admin only)
+// @Tags Admin - Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param queue query string false "Review queue" Enums(fraud, offline) default(fraud)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} map[string]interface{} "Review queue retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid review queue"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Router /admin/payments/reviews [get]
+func (h *PaymentHandler) GetPaymentReviewQueue(c echo.Context) error {
+	params := utils.ParsePagination(c)
+	queue := c.QueryParam("queue")
+	role := echomw.CurrentRole(c)
+
+	payments, total, err := h.paymentService.GetReviewQueue(model.UserRole(role), model.ReviewQueue(queue), params.Limit, params.Offset)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	meta := utils.CreateMeta(params, total)
+	return myResponse.Paginated(c, "Review queue retrieved successfully", payments, meta)
+}
+
+// ApprovePayment godoc
+// @Summary Approve challenged payment
+// @Description Accept a payment held for fraud review with the provider and confirm what it pays for (Admin only)
+// @Tags Admin - Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment ID"
+// @Success 200 {object} model.Payment "Payment approved successfully"
+// @Failure 400 {object} map[string]interface{} "Payment is not awaiting review or the provider refused"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Payment not found"
+// @Router /admin/payments/{id}/approve [post]
+func (h *PaymentHandler) ApprovePayment(c echo.Context) error {
+	return h.reviewPayment(c, true, "Payment approved successfully")
+}
+
+// DenyPayment godoc
+// @Summary Deny challenged payment
+// @Description Reject a payment held for fraud review with the provider; it fails like any other failed attempt (Admin only)
+// @Tags Admin - Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment ID"
+// @Success 200 {object} model.Payment "Payment denied successfully"
+// @Failure 400 {object} map[string]interface{} "Payment is not awaiting review or the provider refused"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Payment not found"
+// @Router /admin/payments/{id}/deny [post]
+func (h *PaymentHandler) DenyPayment(c echo.Context) error {
+	return h.reviewPayment(c, false, "Payment denied successfully")
+}
+
+// VerifyPayment godoc
+// @Summary Verify offline payment
+// @Description Approve or reject an offline payment after checking its transfer receipt, or the cash received at the counter. Approval confirms what it pays for; rejection fails it like any other failed attempt and emails the reason to the customer (Admin only)
+// @Tags Admin - Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment ID"
+// @Param request body dto.VerifyPaymentRequest true "Decision"
+// @Success 200 {object} model.Payment "Payment verified successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid input or payment not awaiting verification"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Payment not found"
+// @Router /admin/payments/{id}/verify [post]
+func (h *PaymentHandler) VerifyPayment(c echo.Context) error {
+	paymentID := myRequest.PathParamUint(c, "id")
+	if paymentID == 0 {
+		return myResponse.BadRequest(c, "Invalid payment ID")
+	}
+
+	var req dto.VerifyPaymentRequest
+	if err := c.Bind(&req); err != nil {
+		return myResponse.BadRequest(c, "Invalid input: "+err.Error())
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return myResponse.BadRequest(c, "Validation error: "+err.Error())
+	}
+
+	adminID := echomw.CurrentUserID(c)
+	role := echomw.CurrentRole(c)
+	payment, err := h.paymentService.VerifyOfflinePayment(adminID, model.UserRole(role), paymentID, *req.Approve, req.Reason)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Success(c, "Payment verified successfully", payment)
+}
+
+// GetPaymentDisputes godoc
+// @Summary Get payment disputes
+// @Description Get the chargebacks reported by payment providers, newest first, optionally filtered by status (Admin only)
+// @Tags Admin - Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Dispute status" Enums(open, under_review, won, lost)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} map[string]interface{} "Payment disputes retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid dispute status"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Router /admin/payments/disputes [get]
+func (h *PaymentHandler) GetPaymentDisputes(c echo.Context) error {
+	params := utils.ParsePagination(c)
+	status := c.QueryParam("status")
+	role := echomw.CurrentRole(c)
+
+	disputes, total, err := h.disputeService.GetDisputes(model.UserRole(role), model.DisputeStatus(status), params.Limit, params.Offset)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	meta := utils.CreateMeta(params, total)
+	return myResponse.Paginated(c, "Payment disputes retrieved successfully", disputes, meta)
+}
+
+// GetPaymentDispute godoc
+// @Summary Get payment dispute
+// @Description Get a chargeback with the payment it was opened against (Admin only)
+// @Tags Admin - Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Dispute ID"
+// @Success 200 {object} model.PaymentDispute "Payment dispute retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid dispute ID"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Dispute not found"
+// @Router /admin/payments/disputes/{id} [get]
+func (h *PaymentHandler) GetPaymentDispute(c echo.Context) error {
+	disputeID := myRequest.PathParamUint(c, "id")
+	if disputeID == 0 {
+		return myResponse.BadRequest(c, "Invalid dispute ID")
+	}
+
+	role := echomw.CurrentRole(c)
+	dispute, err := h.disputeService.GetDispute(model.UserRole(role), disputeID)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Success(c, "Payment dispute retrieved successfully", dispute)
+}
+
+// SubmitDisputeEvidence godoc
+// @Summary Submit dispute evidence
+// @Description Answer an open chargeback. Stripe disputes get the evidence submitted to the card issuer; for other providers send it through their dashboard, it is recorded here. The dispute moves to under review (Admin only)
+// @Tags Admin - Payments
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Dispute ID"
+// @Param request body dto.DisputeEvidenceRequest true "Evidence"
+// @Success 200 {object} model.PaymentDispute "Dispute evidence submitted successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid input, dispute not open or rejected by the provider"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Dispute not found"
+// @Failure 409 {object} map[string]interface{} "Dispute changed by another request"
+// @Router /admin/payments/disputes/{id}/evidence [post]
+func (h *PaymentHandler) SubmitDisputeEvidence(c echo.Context) error {
+	disputeID := myRequest.PathParamUint(c, "id")
+	if disputeID == 0 {
+		return myResponse.BadRequest(c, "Invalid dispute ID")
+	}
+
+	var req dto.DisputeEvidenceRequest
+	if err := c.Bind(&req); err != nil {
+		return myResponse.BadRequest(c, "Invalid input: "+err.Error())
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return myResponse.BadRequest(c, "Validation error: "+err.Error())
+	}
+
+	adminID := echomw.CurrentUserID(c)
+	role := echomw.CurrentRole(c)
+	dispute, err := h.disputeService.SubmitEvidence(adminID, model.UserRole(role), disputeID, req.Evidence)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Success(c, "Dispute evidence submitted successfully", dispute)
+}
+
+func (h *PaymentHandler) reviewPayment(c echo.Context, approve bool, message string) error {
+	paymentID := myRequest.PathParamUint(c, "id")
+	if paymentID == 0 {
+		return myResponse.BadRequest(c, "Invalid payment ID")
+	}
+
+	adminID := echomw.CurrentUserID(c)
+	role := echomw.CurrentRole(c)
+	payment, err := h.paymentService.ReviewPayment(adminID, model.UserRole(role), paymentID, approve)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Success(c, message, payment)
+}
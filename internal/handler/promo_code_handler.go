@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	echomw "github.com/yoockh/go-api-utils/pkg-echo/middleware"
+	myRequest "github.com/yoockh/go-api-utils/pkg-echo/request"
+	myResponse "github.com/yoockh/go-api-utils/pkg-echo/response"
+	"github.com/yoockh/go-game-rental-api/internal/dto"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/service"
+	"github.com/yoockh/go-game-rental-api/internal/utils"
+)
+
+type PromoCodeHandler struct {
+	promoCodeService service.PromoCodeService
+	validate         *validator.Validate
+}
+
+func NewPromoCodeHandler(promoCodeService service.PromoCodeService) *PromoCodeHandler {
+	return &PromoCodeHandler{
+		promoCodeService: promoCodeService,
+		validate:         utils.GetValidator(),
+	}
+}
+
+// GetAllPromoCodes godoc
+// @Summary Get promo codes
+// @Description Get all promo codes with how many active bookings used each (Admin only)
+// @Tags Admin - Promo Codes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} map[string]interface{} "Promo codes retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Router /admin/promo-codes [get]
+func (h *PromoCodeHandler) GetAllPromoCodes(c echo.Context) error {
+	params := utils.ParsePagination(c)
+	role := echomw.CurrentRole(c)
+
+	promos, total, err := h.promoCodeService.GetAll(model.UserRole(role), params.Limit, params.Offset)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	meta := utils.CreateMeta(params, total)
+	return myResponse.Paginated(c, "Promo codes retrieved successfully", promos, meta)
+}
+
+// GetPromoCodeDetail godoc
+// @Summary Get promo code detail
+// @Description Get a promo code with its usage count (Admin only)
+// @Tags Admin - Promo Codes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Promo code ID"
+// @Success 200 {object} model.PromoCode "Promo code retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid promo code ID"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Promo code not found"
+// @Router /admin/promo-codes/{id} [get]
+func (h *PromoCodeHandler) GetPromoCodeDetail(c echo.Context) error {
+	promoID := myRequest.PathParamUint(c, "id")
+	if promoID == 0 {
+		return myResponse.BadRequest(c, "Invalid promo code ID")
+	}
+
+	role := echomw.CurrentRole(c)
+	promo, err := h.promoCodeService.GetByID(model.UserRole(role), promoID)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Success(c, "Promo code retrieved successfully", promo)
+}
+
+// CreatePromoCode godoc
+// @Summary Create promo code
+// @Description Create a percentage or fixed discount code with an optional validity window, usage limits and game/category restrictions (Admin only). Codes are case-insensitive.
+// @Tags Admin - Promo Codes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.PromoCodeRequest true "Promo code details"
+// @Success 201 {object} model.PromoCode "Promo code created successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid input or code already exists"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Router /admin/promo-codes [post]
+func (h *PromoCodeHandler) CreatePromoCode(c echo.Context) error {
+	var req dto.PromoCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return myResponse.BadRequest(c, "Invalid input: "+err.Error())
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return myResponse.BadRequest(c, "Validation error: "+err.Error())
+	}
+
+	adminID := echomw.CurrentUserID(c)
+	role := echomw.CurrentRole(c)
+
+	promo := promoCodeFromRequest(&req)
+	if err := h.promoCodeService.Create(adminID, model.UserRole(role), promo); err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Created(c, "Promo code created successfully", promo)
+}
+
+// UpdatePromoCode godoc
+// @Summary Update promo code
+// @Description Replace the settings of a promo code (Admin only). Bookings that already used it keep their discount.
+// @Tags Admin - Promo Codes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Promo code ID"
+// @Param request body dto.PromoCodeRequest true "Promo code details"
+// @Success 200 {object} model.PromoCode "Promo code updated successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid input"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Promo code not found"
+// @Router /admin/promo-codes/{id} [put]
+func (h *PromoCodeHandler) UpdatePromoCode(c echo.Context) error {
+	promoID := myRequest.PathParamUint(c, "id")
+	if promoID == 0 {
+		return myResponse.BadRequest(c, "Invalid promo code ID")
+	}
+
+	var req dto.PromoCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return myResponse.BadRequest(c, "Invalid input: "+err.Error())
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return myResponse.BadRequest(c, "Validation error: "+err.Error())
+	}
+
+	role := echomw.CurrentRole(c)
+	promo, err := h.promoCodeService.Update(model.UserRole(role), promoID, promoCodeFromRequest(&req))
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Success(c, "Promo code updated successfully", promo)
+}
+
+// DeletePromoCode godoc
+// @Summary Delete promo code
+// @Description Delete a promo code no booking has used; deactivate used codes instead (Admin only)
+// @Tags Admin - Promo Codes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Promo code ID"
+// @Success 200 {object} map[string]interface{} "Promo code deleted successfully"
+// @Failure 400 {object} map[string]interface{} "Promo code has been used"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Promo code not found"
+// @Router /admin/promo-codes/{id} [delete]
+func (h *PromoCodeHandler) DeletePromoCode(c echo.Context) error {
+	promoID := myRequest.PathParamUint(c, "id")
+	if promoID == 0 {
+		return myResponse.BadRequest(c, "Invalid promo code ID")
+	}
+
+	role := echomw.CurrentRole(c)
+	if err := h.promoCodeService.Delete(model.UserRole(role), promoID); err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Success(c, "Promo code deleted successfully", nil)
+}
+
+func promoCodeFromRequest(req *dto.PromoCodeRequest) *model.PromoCode {
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+	return &model.PromoCode{
+		Code:           req.Code,
+		Description:    utils.PtrOrNil(req.Description),
+		DiscountType:   model.DiscountType(req.DiscountType),
+		PercentOff:     req.PercentOff,
+		AmountOff:      req.AmountOff,
+		MaxDiscount:    req.MaxDiscount,
+		ValidFrom:      req.ValidFrom,
+		ValidUntil:     req.ValidUntil,
+		MaxUses:        req.MaxUses,
+		MaxUsesPerUser: req.MaxUsesPerUser,
+		GameIDs:        req.GameIDs,
+		CategoryIDs:    req.CategoryIDs,
+		IsActive:       isActive,
+	}
+}
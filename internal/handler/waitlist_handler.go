@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	echomw "github.com/yoockh/go-api-utils/pkg-echo/middleware"
+	myRequest "github.com/yoockh/go-api-utils/pkg-echo/request"
+	myResponse "github.com/yoockh/go-api-utils/pkg-echo/response"
+	"github.com/yoockh/go-game-rental-api/internal/dto"
+	"github.com/yoockh/go-game-rental-api/internal/service"
+	"github.com/yoockh/go-game-rental-api/internal/utils"
+)
+
+type WaitlistHandler struct {
+	waitlistService service.WaitlistService
+	validate        *validator.Validate
+}
+
+func NewWaitlistHandler(waitlistService service.WaitlistService) *WaitlistHandler {
+	return &WaitlistHandler{
+		waitlistService: waitlistService,
+		validate:        utils.GetValidator(),
+	}
+}
+
+// JoinWaitlist godoc
+// @Summary Join game waitlist
+// @Description Queue for a game that has no copy free for the requested dates. When a copy frees up the first customer in line gets a time-limited hold.
+// @Tags Waitlist
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Game ID"
+// @Param request body dto.JoinWaitlistRequest true "Requested dates"
+// @Success 201 {object} model.WaitlistEntry "Joined waitlist successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid input"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Game not found"
+// @Router /games/{id}/waitlist [post]
+func (h *WaitlistHandler) JoinWaitlist(c echo.Context) error {
+	gameID := myRequest.PathParamUint(c, "id")
+	if gameID == 0 {
+		return myResponse.BadRequest(c, "Invalid game ID")
+	}
+
+	var req dto.JoinWaitlistRequest
+	if err := c.Bind(&req); err != nil {
+		return myResponse.BadRequest(c, "Invalid input: "+err.Error())
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return myResponse.BadRequest(c, "Validation error: "+err.Error())
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return myResponse.BadRequest(c, "Invalid start_date format (use YYYY-MM-DD)")
+	}
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		return myResponse.BadRequest(c, "Invalid end_date format (use YYYY-MM-DD)")
+	}
+
+	userID := echomw.CurrentUserID(c)
+	entry, err := h.waitlistService.Join(userID, gameID, startDate, endDate)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Created(c, "Joined waitlist successfully", entry)
+}
+
+// GetMyWaitlist godoc
+// @Summary Get my waitlist entries
+// @Description Get the current user's waitlist entries and holds
+// @Tags Waitlist
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.WaitlistEntry "Waitlist retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /waitlist/my [get]
+func (h *WaitlistHandler) GetMyWaitlist(c echo.Context) error {
+	userID := echomw.CurrentUserID(c)
+	if userID == 0 {
+		return myResponse.Unauthorized(c, "Unauthorized")
+	}
+
+	entries, err := h.waitlistService.GetUserEntries(userID)
+	if err != nil {
+		return myResponse.InternalServerError(c, "Failed to retrieve waitlist")
+	}
+
+	return myResponse.Success(c, "Waitlist retrieved successfully", entries)
+}
+
+// LeaveWaitlist godoc
+// @Summary Leave waitlist
+// @Description Leave the waitlist or give up an offered hold
+// @Tags Waitlist
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Waitlist entry ID"
+// @Success 200 {object} map[string]interface{} "Left waitlist successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid waitlist entry ID"
+// @Failure 404 {object} map[string]interface{} "Waitlist entry not found"
+// @Router /waitlist/{id} [delete]
+func (h *WaitlistHandler) LeaveWaitlist(c echo.Context) error {
+	entryID := myRequest.PathParamUint(c, "id")
+	if entryID == 0 {
+		return myResponse.BadRequest(c, "Invalid waitlist entry ID")
+	}
+
+	userID := echomw.CurrentUserID(c)
+	if err := h.waitlistService.Leave(userID, entryID); err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Success(c, "Left waitlist successfully", nil)
+}
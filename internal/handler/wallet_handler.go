@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	echomw "github.com/yoockh/go-api-utils/pkg-echo/middleware"
+	myRequest "github.com/yoockh/go-api-utils/pkg-echo/request"
+	myResponse "github.com/yoockh/go-api-utils/pkg-echo/response"
+	"github.com/yoockh/go-game-rental-api/internal/dto"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/service"
+	"github.com/yoockh/go-game-rental-api/internal/utils"
+)
+
+type WalletHandler struct {
+	walletService service.WalletService
+	validate      *validator.Validate
+}
+
+func NewWalletHandler(walletService service.WalletService) *WalletHandler {
+	return &WalletHandler{
+		walletService: walletService,
+		validate:      utils.GetValidator(),
+	}
+}
+
+// GetMyWallet godoc
+// @Summary Get my wallet
+// @Description Get the store credit balance of the current user with the ledger, newest entries first
+// @Tags Wallet
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} model.Wallet "Wallet retrieved successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Router /wallet [get]
+func (h *WalletHandler) GetMyWallet(c echo.Context) error {
+	params := utils.ParsePagination(c)
+	userID := echomw.CurrentUserID(c)
+
+	wallet, total, err := h.walletService.GetWallet(userID, params.Limit, params.Offset)
+	if err != nil {
+		return myResponse.InternalServerError(c, "Failed to retrieve wallet")
+	}
+
+	meta := utils.CreateMeta(params, total)
+	return myResponse.Paginated(c, "Wallet retrieved successfully", wallet, meta)
+}
+
+// GetUserWallet godoc
+// @Summary Get user wallet
+// @Description Get a customer's store credit balance with the ledger, newest entries first (Admin only)
+// @Tags Admin - Wallets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} model.Wallet "Wallet retrieved successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid user ID"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /admin/users/{id}/wallet [get]
+func (h *WalletHandler) GetUserWallet(c echo.Context) error {
+	userID := myRequest.PathParamUint(c, "id")
+	if userID == 0 {
+		return myResponse.BadRequest(c, "Invalid user ID")
+	}
+
+	params := utils.ParsePagination(c)
+	role := echomw.CurrentRole(c)
+
+	wallet, total, err := h.walletService.GetUserWallet(model.UserRole(role), userID, params.Limit, params.Offset)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	meta := utils.CreateMeta(params, total)
+	return myResponse.Paginated(c, "Wallet retrieved successfully", wallet, meta)
+}
+
+// AdjustUserWallet godoc
+// @Summary Adjust user wallet
+// @Description Credit (positive amount) or debit (negative amount) a customer's wallet, e.g. goodwill credit or a correction. A debit cannot take the balance below zero (Admin only)
+// @Tags Admin - Wallets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body dto.WalletAdjustmentRequest true "Adjustment"
+// @Success 201 {object} model.WalletEntry "Wallet adjusted successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid input or insufficient balance"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /admin/users/{id}/wallet/adjustments [post]
+func (h *WalletHandler) AdjustUserWallet(c echo.Context) error {
+	userID := myRequest.PathParamUint(c, "id")
+	if userID == 0 {
+		return myResponse.BadRequest(c, "Invalid user ID")
+	}
+
+	var req dto.WalletAdjustmentRequest
+	if err := c.Bind(&req); err != nil {
+		return myResponse.BadRequest(c, "Invalid input: "+err.Error())
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		return myResponse.BadRequest(c, "Validation error: "+err.Error())
+	}
+
+	adminID := echomw.CurrentUserID(c)
+	role := echomw.CurrentRole(c)
+
+	entry, err := h.walletService.Adjust(adminID, model.UserRole(role), userID, req.Amount, req.Description)
+	if err != nil {
+		return utils.MapServiceError(c, err)
+	}
+
+	return myResponse.Created(c, "Wallet adjusted successfully", entry)
+}
@@ -12,29 +12,89 @@ const (
 	BookingCancelled BookingStatus = "cancelled"
 )
 
+// ReservingBookingStatuses are the statuses in which a booking occupies a copy for its dates
+var ReservingBookingStatuses = []BookingStatus{BookingPending, BookingConfirmed, BookingActive}
+
+// bookingTransitions lists the statuses a booking may move to from each status.
+// Completed and cancelled bookings are final.
+var bookingTransitions = map[BookingStatus][]BookingStatus{
+	BookingPending:   {BookingConfirmed, BookingCancelled},
+	BookingConfirmed: {BookingActive, BookingCancelled},
+	BookingActive:    {BookingCompleted},
+	BookingCompleted: {},
+	BookingCancelled: {},
+}
+
+func (s BookingStatus) IsValid() bool {
+	_, ok := bookingTransitions[s]
+	return ok
+}
+
+func (s BookingStatus) CanTransitionTo(next BookingStatus) bool {
+	for _, allowed := range bookingTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
 type Booking struct {
 	ID               uint          `gorm:"primarykey" json:"id"`
 	UserID           uint          `gorm:"not null" json:"user_id"`
+	OrderID          *uint         `json:"order_id,omitempty"`
 	GameID           uint          `gorm:"not null" json:"game_id"`
 	StartDate        time.Time     `gorm:"type:date;not null" json:"start_date" validate:"required"`
 	EndDate          time.Time     `gorm:"type:date;not null" json:"end_date" validate:"required"`
 	RentalDays       int           `gorm:"not null" json:"rental_days"`
-	DailyPrice       float64       `gorm:"type:decimal(10,2);not null" json:"daily_price"`
-	TotalRentalPrice float64       `gorm:"type:decimal(10,2);not null" json:"total_rental_price"`
-	SecurityDeposit  float64       `gorm:"type:decimal(10,2);default:0" json:"security_deposit"`
-	TotalAmount      float64       `gorm:"type:decimal(10,2);not null" json:"total_amount"`
+	DailyPrice       Money         `gorm:"type:decimal(10,2);not null" json:"daily_price" swaggertype:"string"`
+	TotalRentalPrice Money         `gorm:"type:decimal(10,2);not null" json:"total_rental_price" swaggertype:"string"`
+	SecurityDeposit  Money         `gorm:"type:decimal(10,2);default:0" json:"security_deposit" swaggertype:"string"`
+	PromoCodeID      *uint         `json:"promo_code_id,omitempty"`
+	DiscountAmount   Money         `gorm:"type:decimal(10,2);not null;default:0" json:"discount_amount" swaggertype:"string"` // taken off the rental price
+	TaxRate          int64         `gorm:"not null;default:0" json:"tax_rate"`                                                // VAT percent applied when the booking was made
+	TaxInclusive     bool          `gorm:"not null;default:false" json:"tax_inclusive"`
+	TaxableAmount    Money         `gorm:"type:decimal(10,2);not null;default:0" json:"taxable_amount" swaggertype:"string"`
+	TaxAmount        Money         `gorm:"type:decimal(10,2);not null;default:0" json:"tax_amount" swaggertype:"string"` // added to the total unless inclusive
+	TotalAmount      Money         `gorm:"type:decimal(10,2);not null" json:"total_amount" swaggertype:"string"`
 	Status           BookingStatus `gorm:"type:booking_status;default:pending" json:"status"`
 	Notes            *string       `json:"notes,omitempty"`
+	PaymentDueAt     *time.Time    `json:"payment_due_at,omitempty"`
 	CreatedAt        time.Time     `json:"created_at"`
 	UpdatedAt        time.Time     `json:"updated_at"`
 
 	// Relationships
-	User    User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Game    Game     `gorm:"foreignKey:GameID" json:"game,omitempty"`
-	Payment *Payment `gorm:"foreignKey:BookingID" json:"payment,omitempty"`
-	Review  *Review  `gorm:"foreignKey:BookingID" json:"review,omitempty"`
+	User      User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Game      Game       `gorm:"foreignKey:GameID" json:"game,omitempty"`
+	Order     *Order     `gorm:"foreignKey:OrderID" json:"-"`
+	PromoCode *PromoCode `gorm:"foreignKey:PromoCodeID" json:"-"`
+	Payment   *Payment   `gorm:"foreignKey:BookingID" json:"payment,omitempty"` // the attempt that paid, or else the latest
+	Review    *Review    `gorm:"foreignKey:BookingID" json:"review,omitempty"`
+
+	Settlement *BookingSettlement `gorm:"foreignKey:BookingID" json:"settlement,omitempty"`
+
+	DateChanges []BookingDateChange `gorm:"foreignKey:BookingID" json:"date_changes,omitempty"`
+
+	PaymentAttempts []Payment `gorm:"foreignKey:BookingID" json:"payment_attempts,omitempty"` // oldest first
 }
 
 func (Booking) TableName() string {
 	return "bookings"
 }
+
+// TaxRule returns the tax rule the booking was priced with
+func (b *Booking) TaxRule() TaxRule {
+	return TaxRule{Rate: b.TaxRate, Inclusive: b.TaxInclusive}
+}
+
+// ChargedPayment returns the payment that paid for the booking: its own payment,
+// or the order payment when the booking is a line item of an order
+func (b *Booking) ChargedPayment() *Payment {
+	if b.Payment != nil {
+		return b.Payment
+	}
+	if b.Order != nil {
+		return b.Order.Payment
+	}
+	return nil
+}
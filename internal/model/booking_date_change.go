@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+type DateChangeType string
+
+const (
+	DateChangeExtension  DateChangeType = "extension"
+	DateChangeReschedule DateChangeType = "reschedule"
+)
+
+type DateChangeStatus string
+
+const (
+	DateChangePending DateChangeStatus = "pending"
+	DateChangeApplied DateChangeStatus = "applied"
+	DateChangeFailed  DateChangeStatus = "failed"
+)
+
+// BookingDateChange is a requested change to a booking's dates. While pending it
+// holds the days of the new range outside the current one; the booking itself
+// only changes once it is applied. A negative AmountDue is refunded.
+type BookingDateChange struct {
+	ID               uint             `gorm:"primaryKey" json:"id"`
+	BookingID        uint             `gorm:"not null" json:"booking_id"`
+	Type             DateChangeType   `gorm:"type:varchar(20);not null" json:"type"`
+	Status           DateChangeStatus `gorm:"type:varchar(20);default:pending" json:"status"`
+	OldStartDate     time.Time        `gorm:"type:date;not null" json:"old_start_date"`
+	OldEndDate       time.Time        `gorm:"type:date;not null" json:"old_end_date"`
+	NewStartDate     time.Time        `gorm:"type:date;not null" json:"new_start_date"`
+	NewEndDate       time.Time        `gorm:"type:date;not null" json:"new_end_date"`
+	RentalDays       int              `gorm:"not null" json:"rental_days"`
+	TotalRentalPrice Money            `gorm:"type:decimal(10,2);not null" json:"total_rental_price" swaggertype:"string"`
+	DiscountAmount   Money            `gorm:"type:decimal(10,2);not null;default:0" json:"discount_amount" swaggertype:"string"` // the promo discount at the new price
+	TaxAmount        Money            `gorm:"type:decimal(10,2);not null;default:0" json:"tax_amount" swaggertype:"string"`      // the booking's tax at the new price
+	AmountDue        Money            `gorm:"type:decimal(10,2);not null" json:"amount_due" swaggertype:"string"`                // includes the tax difference unless inclusive
+	PaymentID        *uint            `json:"payment_id,omitempty"`
+	RefundStatus     *RefundStatus    `gorm:"type:varchar(20)" json:"refund_status,omitempty"` // outcome of refunding a negative AmountDue
+	RefundError      *string          `gorm:"type:text" json:"refund_error,omitempty"`
+	ProviderRefundID *string          `json:"provider_refund_id,omitempty"`
+	RequestedBy      uint             `gorm:"not null" json:"requested_by"`
+	AppliedAt        *time.Time       `json:"applied_at,omitempty"`
+	CreatedAt        time.Time        `json:"created_at"`
+
+	// Relationships
+	Payment *Payment `gorm:"foreignKey:PaymentID" json:"payment,omitempty"`
+}
+
+func (BookingDateChange) TableName() string {
+	return "booking_date_changes"
+}
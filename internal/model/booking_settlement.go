@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+type DepositRefundStatus string
+
+const (
+	DepositRefundNone    DepositRefundStatus = "none"
+	DepositRefundPending DepositRefundStatus = "pending"
+	DepositRefunded      DepositRefundStatus = "refunded"
+	DepositRefundFailed  DepositRefundStatus = "failed"
+	DepositChargedBack   DepositRefundStatus = "charged_back" // kept because the customer took the payment back through a dispute
+)
+
+// BookingSettlement is the deposit breakdown recorded when a rented copy is returned
+type BookingSettlement struct {
+	ID                uint                `gorm:"primaryKey" json:"id"`
+	BookingID         uint                `gorm:"uniqueIndex;not null" json:"booking_id"`
+	ReturnedAt        time.Time           `gorm:"type:date;not null" json:"returned_at"`
+	LateDays          int                 `gorm:"not null;default:0" json:"late_days"`
+	LateFee           Money               `gorm:"type:decimal(10,2);not null;default:0" json:"late_fee" swaggertype:"string"`
+	DamageCharge      Money               `gorm:"type:decimal(10,2);not null;default:0" json:"damage_charge" swaggertype:"string"`
+	DamageNotes       *string             `gorm:"type:text" json:"damage_notes,omitempty"`
+	DepositAmount     Money               `gorm:"type:decimal(10,2);not null" json:"deposit_amount" swaggertype:"string"`
+	DepositRefund     Money               `gorm:"type:decimal(10,2);not null" json:"deposit_refund" swaggertype:"string"`
+	OutstandingAmount Money               `gorm:"type:decimal(10,2);not null;default:0" json:"outstanding_amount" swaggertype:"string"` // charges the deposit could not cover
+	RefundStatus      DepositRefundStatus `gorm:"type:varchar(20);default:none" json:"refund_status"`
+	RefundToWallet    bool                `gorm:"not null;default:false" json:"refund_to_wallet"` // deposit returned as store credit
+	ProviderRefundID  *string             `json:"provider_refund_id,omitempty"`
+	RefundError       *string             `gorm:"type:text" json:"refund_error,omitempty"`
+	SettledBy         uint                `gorm:"not null" json:"settled_by"`
+	CreatedAt         time.Time           `json:"created_at"`
+	UpdatedAt         time.Time           `json:"updated_at"`
+}
+
+func (BookingSettlement) TableName() string {
+	return "booking_settlements"
+}
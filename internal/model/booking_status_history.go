@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+type BookingStatusHistory struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	BookingID  uint           `gorm:"not null" json:"booking_id"`
+	FromStatus *BookingStatus `gorm:"type:booking_status" json:"from_status,omitempty"`
+	ToStatus   BookingStatus  `gorm:"type:booking_status;not null" json:"to_status"`
+	ChangedBy  *uint          `json:"changed_by,omitempty"` // nil for system changes
+	Reason     *string        `gorm:"type:text" json:"reason,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+
+	// Relationships
+	Actor *User `gorm:"foreignKey:ChangedBy" json:"actor,omitempty"`
+}
+
+func (BookingStatusHistory) TableName() string {
+	return "booking_status_history"
+}
@@ -22,9 +22,9 @@ type Game struct {
 	Description       *string       `gorm:"type:text" json:"description"`
 	Platform          *string       `gorm:"type:varchar(100)" json:"platform"`
 	Stock             int           `gorm:"not null;default:0" json:"stock"`
-	AvailableStock    int           `gorm:"not null;default:0" json:"available_stock"`
-	RentalPricePerDay float64       `gorm:"type:decimal(10,2);not null" json:"rental_price_per_day"`
-	SecurityDeposit   float64       `gorm:"type:decimal(10,2);not null" json:"security_deposit"`
+	AvailableStock    int           `gorm:"not null;default:0" json:"available_stock"` // copies on the shelf: stock minus copies handed out
+	RentalPricePerDay Money         `gorm:"type:decimal(10,2);not null" json:"rental_price_per_day" swaggertype:"string"`
+	SecurityDeposit   Money         `gorm:"type:decimal(10,2);not null" json:"security_deposit" swaggertype:"string"`
 	Condition         GameCondition `gorm:"type:varchar(20);not null" json:"condition"`
 
 	IsActive  bool      `gorm:"default:true" json:"is_active"`
@@ -35,3 +35,9 @@ type Game struct {
 func (Game) TableName() string {
 	return "games"
 }
+
+// GameAvailability is the number of free copies of a game on a single day
+type GameAvailability struct {
+	Date      time.Time `json:"date"`
+	Available int       `json:"available"`
+}
@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// Invoice is the numbered invoice of a paid booking, or of a paid date change
+// of one. Numbers run sequentially per year without gaps, e.g. INV-2026-000042.
+// The dates and amounts are copied from the booking when the invoice is
+// issued, so later changes to the booking never alter it: a date change gets
+// its own invoice for the difference, a credit note when it is refunded.
+type Invoice struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Number       string    `gorm:"type:varchar(30);uniqueIndex;not null" json:"number"`
+	BookingID    uint      `gorm:"not null" json:"booking_id"`
+	DateChangeID *uint     `gorm:"uniqueIndex" json:"date_change_id,omitempty"` // set on the invoice of a date change
+	PaymentID    uint      `gorm:"not null" json:"payment_id"`
+	IssuedAt     time.Time `gorm:"not null" json:"issued_at"`
+
+	// What was invoiced; for a date change, the difference it made
+	StartDate        time.Time `gorm:"type:date;not null" json:"start_date"`
+	EndDate          time.Time `gorm:"type:date;not null" json:"end_date"`
+	RentalDays       int       `gorm:"not null" json:"rental_days"`
+	DailyPrice       Money     `gorm:"type:decimal(10,2);not null" json:"daily_price" swaggertype:"string"`
+	TotalRentalPrice Money     `gorm:"type:decimal(10,2);not null" json:"total_rental_price" swaggertype:"string"`
+	SecurityDeposit  Money     `gorm:"type:decimal(10,2);not null;default:0" json:"security_deposit" swaggertype:"string"`
+	DiscountAmount   Money     `gorm:"type:decimal(10,2);not null;default:0" json:"discount_amount" swaggertype:"string"`
+	TaxRate          int64     `gorm:"not null;default:0" json:"tax_rate"`
+	TaxInclusive     bool      `gorm:"not null;default:false" json:"tax_inclusive"`
+	TaxableAmount    Money     `gorm:"type:decimal(10,2);not null;default:0" json:"taxable_amount" swaggertype:"string"`
+	TaxAmount        Money     `gorm:"type:decimal(10,2);not null;default:0" json:"tax_amount" swaggertype:"string"`
+	TotalAmount      Money     `gorm:"type:decimal(10,2);not null" json:"total_amount" swaggertype:"string"` // negative on a credit note
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func (Invoice) TableName() string {
+	return "invoices"
+}
+
+// TaxRule returns the tax rule the invoice was issued under
+func (i *Invoice) TaxRule() TaxRule {
+	return TaxRule{Rate: i.TaxRate, Inclusive: i.TaxInclusive}
+}
+
+// IsCreditNote reports whether the invoice credits the customer, for a date
+// change that made the booking cheaper
+func (i *Invoice) IsCreditNote() bool {
+	return i.TotalAmount.IsNegative()
+}
@@ -0,0 +1,252 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency code
+type Currency string
+
+const (
+	CurrencyIDR Currency = "IDR"
+	CurrencyUSD Currency = "USD"
+)
+
+// DefaultCurrency is the currency of every stored amount. The DECIMAL columns
+// do not record a currency, so amounts read from the database are in this one.
+const DefaultCurrency = CurrencyIDR
+
+// moneyScale is the number of decimals kept, the scale of the DECIMAL columns
+const moneyScale = 2
+
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+// Money is an exact amount of a currency, kept as an integer number of
+// hundredths so arithmetic never rounds. It is stored in the DECIMAL(…,2)
+// columns and serialized to JSON as a decimal string, e.g. "150000.00".
+// The zero value is zero in DefaultCurrency.
+type Money struct {
+	cents    int64
+	currency Currency
+}
+
+// NewMoney returns a whole amount of DefaultCurrency
+func NewMoney(amount int64) Money {
+	return Money{cents: amount * 100}
+}
+
+// MoneyFromCents returns an amount of DefaultCurrency given in hundredths
+func MoneyFromCents(cents int64) Money {
+	return Money{cents: cents}
+}
+
+// ParseMoney parses a decimal string such as "150000", "150000.5" or
+// "-12.30" in DefaultCurrency. More than two decimals are rejected unless they
+// are zeros, so a value never loses precision.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, fraction, _ := strings.Cut(digits, ".")
+	if whole == "" && fraction == "" || !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	if len(fraction) > moneyScale {
+		if strings.Trim(fraction[moneyScale:], "0") != "" {
+			return Money{}, fmt.Errorf("%w: %q has more than %d decimals", ErrInvalidMoney, s, moneyScale)
+		}
+		fraction = fraction[:moneyScale]
+	}
+	fraction += strings.Repeat("0", moneyScale-len(fraction))
+	if whole == "" {
+		whole = "0"
+	}
+
+	cents, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, s)
+	}
+	if negative {
+		cents = -cents
+	}
+	return Money{cents: cents}, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Currency returns the currency of the amount
+func (m Money) Currency() Currency {
+	if m.currency == "" {
+		return DefaultCurrency
+	}
+	return m.currency
+}
+
+// Cents returns the amount in hundredths
+func (m Money) Cents() int64 {
+	return m.cents
+}
+
+// Whole returns the amount in whole units and whether it had no fraction
+func (m Money) Whole() (int64, bool) {
+	return m.cents / 100, m.cents%100 == 0
+}
+
+func (m Money) Add(other Money) Money {
+	m.mustMatch(other)
+	return Money{cents: m.cents + other.cents, currency: m.currency}
+}
+
+func (m Money) Sub(other Money) Money {
+	m.mustMatch(other)
+	return Money{cents: m.cents - other.cents, currency: m.currency}
+}
+
+// Mul multiplies the amount by a whole quantity, e.g. a number of days
+func (m Money) Mul(quantity int64) Money {
+	return Money{cents: m.cents * quantity, currency: m.currency}
+}
+
+// Percent returns percent% of the amount rounded to the nearest hundredth,
+// halves away from zero
+func (m Money) Percent(percent int64) Money {
+	scaled := m.cents * percent
+	cents := scaled / 100
+	if remainder := scaled % 100; remainder >= 50 {
+		cents++
+	} else if remainder <= -50 {
+		cents--
+	}
+	return Money{cents: cents, currency: m.currency}
+}
+
+// Truncate drops the fraction of a unit, rounding toward zero
+func (m Money) Truncate() Money {
+	return Money{cents: m.cents - m.cents%100, currency: m.currency}
+}
+
+func (m Money) Neg() Money {
+	return Money{cents: -m.cents, currency: m.currency}
+}
+
+// Min returns the smaller of the two amounts
+func (m Money) Min(other Money) Money {
+	if other.LessThan(m) {
+		return other
+	}
+	return m
+}
+
+func (m Money) IsZero() bool     { return m.cents == 0 }
+func (m Money) IsPositive() bool { return m.cents > 0 }
+func (m Money) IsNegative() bool { return m.cents < 0 }
+
+func (m Money) Equal(other Money) bool {
+	return m.Currency() == other.Currency() && m.cents == other.cents
+}
+
+func (m Money) LessThan(other Money) bool {
+	m.mustMatch(other)
+	return m.cents < other.cents
+}
+
+func (m Money) GreaterThan(other Money) bool {
+	m.mustMatch(other)
+	return m.cents > other.cents
+}
+
+// mustMatch panics on arithmetic across currencies, which is always a bug
+func (m Money) mustMatch(other Money) {
+	if m.Currency() != other.Currency() {
+		panic(fmt.Sprintf("money: %s and %s amounts cannot be combined", m.Currency(), other.Currency()))
+	}
+}
+
+// String returns the amount as a decimal string with two decimals, e.g. "-12.30"
+func (m Money) String() string {
+	sign := ""
+	cents := m.cents
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// Display formats the amount for customers, e.g. "Rp 150000", "Rp 12.50" or
+// "$3.00". Rupiah amounts drop the decimals when they are whole.
+func (m Money) Display() string {
+	amount := m.String()
+	switch m.Currency() {
+	case CurrencyIDR:
+		return "Rp " + strings.TrimSuffix(amount, ".00")
+	case CurrencyUSD:
+		return "$" + amount
+	default:
+		return string(m.Currency()) + " " + amount
+	}
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON accepts a decimal string or a JSON number
+func (m *Money) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "null" || text == "" {
+		*m = Money{}
+		return nil
+	}
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores the amount as an exact decimal string
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+func (m *Money) Scan(value interface{}) error {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		*m = Money{}
+		return nil
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	case int64:
+		*m = NewMoney(v)
+		return nil
+	case float64:
+		text = strconv.FormatFloat(v, 'f', moneyScale, 64)
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidMoney, value)
+	}
+
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,94 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input string
+		cents int64
+	}{
+		{"150000", 15000000},
+		{"150000.00", 15000000},
+		{"12.5", 1250},
+		{"-12.30", -1230},
+		{".75", 75},
+		{"10.500", 1050},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			money, err := ParseMoney(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.cents, money.Cents())
+		})
+	}
+
+	for _, input := range []string{"", "abc", "1.234", "1,000", "1.2.3"} {
+		t.Run("invalid "+input, func(t *testing.T) {
+			_, err := ParseMoney(input)
+			assert.True(t, errors.Is(err, ErrInvalidMoney))
+		})
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	price := MoneyFromCents(1999)
+	total := price.Mul(3).Add(NewMoney(10)).Sub(MoneyFromCents(3))
+
+	assert.Equal(t, "69.94", total.String())
+	assert.True(t, total.Equal(MoneyFromCents(6994)))
+	assert.True(t, price.LessThan(total))
+	assert.Equal(t, price, total.Min(price))
+
+	assert.Panics(t, func() {
+		NewMoney(1).Add(Money{cents: 100, currency: CurrencyUSD})
+	})
+}
+
+func TestMoneyPercent(t *testing.T) {
+	assert.Equal(t, MoneyFromCents(1499985), NewMoney(99999).Percent(15))
+	assert.Equal(t, NewMoney(14999), NewMoney(99999).Percent(15).Truncate())
+	assert.Equal(t, MoneyFromCents(2), MoneyFromCents(15).Percent(10), "halves round away from zero")
+	assert.Equal(t, MoneyFromCents(-2), MoneyFromCents(-15).Percent(10))
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Amount Money `json:"amount"`
+	}{MoneyFromCents(15000050)})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"150000.50"}`, string(data))
+
+	var decoded struct {
+		FromString Money `json:"from_string"`
+		FromNumber Money `json:"from_number"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"from_string":"0.10","from_number":150000}`), &decoded))
+	assert.Equal(t, int64(10), decoded.FromString.Cents())
+	assert.Equal(t, int64(15000000), decoded.FromNumber.Cents())
+}
+
+func TestMoneyScan(t *testing.T) {
+	var money Money
+	require.NoError(t, money.Scan([]byte("150000.25")))
+	assert.Equal(t, int64(15000025), money.Cents())
+
+	require.NoError(t, money.Scan(int64(7)))
+	assert.Equal(t, int64(700), money.Cents())
+
+	value, err := money.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "7.00", value)
+}
+
+func TestMoneyDisplay(t *testing.T) {
+	assert.Equal(t, "Rp 150000", NewMoney(150000).Display())
+	assert.Equal(t, "Rp 12.50", MoneyFromCents(1250).Display())
+	assert.Equal(t, "$3.00", Money{cents: 300, currency: CurrencyUSD}.Display())
+}
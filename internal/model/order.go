@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Order groups several bookings (line items) that are checked out with a single payment
+type Order struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	UserID       uint       `gorm:"not null" json:"user_id"`
+	TotalAmount  Money      `gorm:"type:decimal(12,2);not null" json:"total_amount" swaggertype:"string"`
+	PaymentDueAt *time.Time `json:"payment_due_at,omitempty"`
+	Notes        *string    `json:"notes,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	// Relationships
+	User    User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Items   []Booking `gorm:"foreignKey:OrderID" json:"items,omitempty"`
+	Payment *Payment  `gorm:"foreignKey:OrderID" json:"payment,omitempty"` // the attempt that paid, or else the latest
+
+	PaymentAttempts []Payment `gorm:"foreignKey:OrderID" json:"payment_attempts,omitempty"` // oldest first
+}
+
+func (Order) TableName() string {
+	return "orders"
+}
@@ -11,6 +11,9 @@ const (
 	PaymentPaid     PaymentStatus = "paid"
 	PaymentFailed   PaymentStatus = "failed"
 	PaymentRefunded PaymentStatus = "refunded"
+	PaymentReview   PaymentStatus = "review" // held by fraud detection, or an offline payment awaiting verification, until an admin decides
+
+	PaymentPartiallyRefunded PaymentStatus = "partially_refunded"
 )
 
 type PaymentProvider string
@@ -18,25 +21,123 @@ type PaymentProvider string
 const (
 	ProviderStripe   PaymentProvider = "stripe"
 	ProviderMidtrans PaymentProvider = "midtrans"
+	ProviderWallet   PaymentProvider = "wallet"  // paid in full from the customer's wallet
+	ProviderOffline  PaymentProvider = "offline" // cash or direct bank transfer, verified by an admin
+)
+
+// ReviewQueue splits the payments in review by what the admin has to do
+type ReviewQueue string
+
+const (
+	ReviewQueueFraud   ReviewQueue = "fraud"   // gateway payments held by fraud detection, approved or denied with the gateway
+	ReviewQueueOffline ReviewQueue = "offline" // offline payments whose transfer receipt waits for verification
+)
+
+// PaymentPurpose tells a booking's own payment apart from supplemental charges
+type PaymentPurpose string
+
+const (
+	PaymentPurposeBooking    PaymentPurpose = "booking"
+	PaymentPurposeDateChange PaymentPurpose = "date_change"
+	PaymentPurposeOrder      PaymentPurpose = "order"
 )
 
 type Payment struct {
-	ID                uint            `gorm:"primarykey" json:"id"`
-	BookingID         uint            `gorm:"not null" json:"booking_id"`
-	Provider          PaymentProvider `gorm:"type:payment_provider;not null" json:"provider"`
-	ProviderPaymentID *string         `json:"provider_payment_id,omitempty"`
-	Amount            float64         `gorm:"type:decimal(12,2);not null" json:"amount"`
-	Status            PaymentStatus   `gorm:"type:payment_status;default:pending" json:"status"`
-	PaymentMethod     *string         `json:"payment_method,omitempty"`
-	PaidAt            *time.Time      `json:"paid_at,omitempty"`
-	FailedAt          *time.Time      `json:"failed_at,omitempty"`
-	FailureReason     *string         `json:"failure_reason,omitempty"`
-	CreatedAt         time.Time       `json:"created_at"`
+	ID                   uint                 `gorm:"primarykey" json:"id"`
+	BookingID            *uint                `json:"booking_id,omitempty"` // nil for order payments
+	OrderID              *uint                `json:"order_id,omitempty"`
+	Provider             PaymentProvider      `gorm:"type:payment_provider;not null" json:"provider"`
+	Purpose              PaymentPurpose       `gorm:"type:varchar(20);not null;default:booking" json:"purpose"`
+	ProviderPaymentID    *string              `json:"provider_payment_id,omitempty"`
+	Amount               Money                `gorm:"type:decimal(12,2);not null" json:"amount" swaggertype:"string"`
+	WalletAmount         Money                `gorm:"type:decimal(12,2);not null;default:0" json:"wallet_amount" swaggertype:"string"` // paid from the wallet, the rest through the provider
+	RefundedAmount       Money                `gorm:"type:decimal(12,2);not null;default:0" json:"refunded_amount" swaggertype:"string"`
+	WalletRefundedAmount Money                `gorm:"type:decimal(12,2);not null;default:0" json:"wallet_refunded_amount" swaggertype:"string"` // part of RefundedAmount credited to the wallet
+	ChargedBackAmount    Money                `gorm:"type:decimal(12,2);not null;default:0" json:"charged_back_amount" swaggertype:"string"`    // taken back through lost disputes
+	Status               PaymentStatus        `gorm:"type:payment_status;default:pending" json:"status"`
+	PaymentMethod        *string              `json:"payment_method,omitempty"`
+	Instructions         *PaymentInstructions `gorm:"type:jsonb;serializer:json" json:"instructions,omitempty"`
+	PaidAt               *time.Time           `json:"paid_at,omitempty"`
+	FailedAt             *time.Time           `json:"failed_at,omitempty"`
+	FailureReason        *string              `json:"failure_reason,omitempty"`
+	ProofPath            *string              `json:"-"` // transfer receipt of an offline payment, in the private receipt bucket
+	ProofUploadedAt      *time.Time           `json:"proof_uploaded_at,omitempty"`
+	ReviewedBy           *uint                `json:"reviewed_by,omitempty"` // admin who decided a fraud review or verified an offline payment
+	ReviewedAt           *time.Time           `json:"reviewed_at,omitempty"`
+	CreatedAt            time.Time            `json:"created_at"`
 
 	// Relationships
-	Booking Booking `gorm:"foreignKey:BookingID" json:"booking,omitempty"`
+	Booking *Booking `gorm:"foreignKey:BookingID" json:"booking,omitempty"`
+	Order   *Order   `gorm:"foreignKey:OrderID" json:"order,omitempty"`
+
+	Refunds []PaymentRefund `gorm:"foreignKey:PaymentID" json:"refunds,omitempty"`
+}
+
+// PaymentInstructions is what the customer needs to complete a pending payment,
+// as returned by the provider when the charge was created. Only the fields the
+// chosen payment method uses are set.
+type PaymentInstructions struct {
+	RedirectURL  string           `json:"redirect_url,omitempty"`
+	ClientSecret string           `json:"client_secret,omitempty"` // for confirming a Stripe payment on the client
+	VANumbers    []VirtualAccount `json:"va_numbers,omitempty"`
+	BillKey      string           `json:"bill_key,omitempty"` // Mandiri bill payment
+	BillerCode   string           `json:"biller_code,omitempty"`
+	PaymentCode  string           `json:"payment_code,omitempty"` // convenience store payment code
+	QRString     string           `json:"qr_string,omitempty"`
+	Actions      []PaymentAction  `json:"actions,omitempty"`       // deeplinks and QR code images
+	BankTransfer *BankTransfer    `json:"bank_transfer,omitempty"` // offline payments
+	ExpiresAt    *time.Time       `json:"expires_at,omitempty"`
+}
+
+// BankTransfer is where an offline payment is sent. Without an account the
+// customer pays in cash at the counter, quoting the reference either way.
+type BankTransfer struct {
+	Bank          string `json:"bank,omitempty"`
+	AccountNumber string `json:"account_number,omitempty"`
+	AccountName   string `json:"account_name,omitempty"`
+	Reference     string `json:"reference"`
+}
+
+type VirtualAccount struct {
+	Bank   string `json:"bank"`
+	Number string `json:"number"`
+}
+
+// PaymentAction is a link the customer follows to pay, e.g. an e-wallet deeplink
+type PaymentAction struct {
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	URL    string `json:"url"`
 }
 
 func (Payment) TableName() string {
 	return "payments"
 }
+
+// GatewayAmount is the part of the payment charged through the provider
+func (p *Payment) GatewayAmount() Money {
+	return p.Amount.Sub(p.WalletAmount)
+}
+
+// GatewayRefundableAmount is what can still be refunded through the provider.
+// Refunds credited to the wallet leave it untouched; chargebacks reduce it.
+func (p *Payment) GatewayRefundableAmount() Money {
+	refundable := p.GatewayAmount().Sub(p.RefundedAmount.Sub(p.WalletRefundedAmount)).Sub(p.ChargedBackAmount).Min(p.RefundableAmount())
+	if refundable.IsNegative() {
+		return Money{}
+	}
+	return refundable
+}
+
+// RefundableAmount is what can still be refunded of the payment, which is
+// neither refunded nor charged back
+func (p *Payment) RefundableAmount() Money {
+	if p.Status != PaymentPaid && p.Status != PaymentPartiallyRefunded {
+		return Money{}
+	}
+	refundable := p.Amount.Sub(p.RefundedAmount).Sub(p.ChargedBackAmount)
+	if refundable.IsNegative() {
+		return Money{}
+	}
+	return refundable
+}
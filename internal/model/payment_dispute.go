@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+type DisputeStatus string
+
+const (
+	DisputeOpen        DisputeStatus = "open"         // waiting for our evidence
+	DisputeUnderReview DisputeStatus = "under_review" // evidence submitted, the card issuer decides
+	DisputeWon         DisputeStatus = "won"
+	DisputeLost        DisputeStatus = "lost" // the customer got the money back
+)
+
+// PaymentDispute is a chargeback the customer's bank opened against a payment,
+// as reported by the payment provider. Disputes are unique per provider and
+// provider dispute ID; every status the provider reports updates the same row.
+type PaymentDispute struct {
+	ID                  uint            `gorm:"primaryKey" json:"id"`
+	PaymentID           uint            `gorm:"not null" json:"payment_id"`
+	Provider            PaymentProvider `gorm:"type:payment_provider;not null" json:"provider"`
+	ProviderDisputeID   string          `gorm:"not null" json:"provider_dispute_id"`
+	Status              DisputeStatus   `gorm:"type:varchar(20);not null;default:open" json:"status"`
+	Reason              *string         `gorm:"type:text" json:"reason,omitempty"` // as given by the provider, e.g. fraudulent
+	Amount              Money           `gorm:"type:decimal(12,2);not null" json:"amount" swaggertype:"string"`
+	EvidenceDueBy       *time.Time      `json:"evidence_due_by,omitempty"`
+	Evidence            *string         `gorm:"type:text" json:"evidence,omitempty"`
+	EvidenceSubmittedBy *uint           `json:"evidence_submitted_by,omitempty"`
+	EvidenceSubmittedAt *time.Time      `json:"evidence_submitted_at,omitempty"`
+	ResolvedAt          *time.Time      `json:"resolved_at,omitempty"`
+	CreatedAt           time.Time       `json:"created_at"`
+	UpdatedAt           time.Time       `json:"updated_at"`
+
+	// Relationships
+	Payment *Payment `gorm:"foreignKey:PaymentID" json:"payment,omitempty"`
+}
+
+func (PaymentDispute) TableName() string {
+	return "payment_disputes"
+}
+
+// IsResolved tells whether the issuer has decided the dispute
+func (d *PaymentDispute) IsResolved() bool {
+	return d.Status == DisputeWon || d.Status == DisputeLost
+}
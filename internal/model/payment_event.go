@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+type PaymentEventStatus string
+
+const (
+	PaymentEventReceived  PaymentEventStatus = "received" // being applied; can be replayed once stale
+	PaymentEventProcessed PaymentEventStatus = "processed"
+	PaymentEventIgnored   PaymentEventStatus = "ignored"  // valid, but the payment had already moved on
+	PaymentEventFailed    PaymentEventStatus = "failed"   // can be replayed
+	PaymentEventRejected  PaymentEventStatus = "rejected" // failed signature or amount verification
+)
+
+// PaymentEvent is a payment notification as received from the provider. Events
+// are unique per provider, transaction and transaction status, so redelivered
+// notifications are recognised instead of being applied twice.
+type PaymentEvent struct {
+	ID                uint               `gorm:"primaryKey" json:"id"`
+	Provider          PaymentProvider    `gorm:"type:payment_provider;not null" json:"provider"`
+	TransactionID     string             `gorm:"not null" json:"transaction_id"` // the gateway order ID when the provider sent none
+	GatewayOrderID    string             `gorm:"not null" json:"gateway_order_id"`
+	TransactionStatus string             `gorm:"not null" json:"transaction_status"`
+	GrossAmount       string             `json:"gross_amount"`
+	PaymentID         *uint              `json:"payment_id,omitempty"`
+	Payload           string             `gorm:"type:jsonb;not null" json:"payload"`
+	Status            PaymentEventStatus `gorm:"type:varchar(20);default:received" json:"status"`
+	Error             *string            `gorm:"type:text" json:"error,omitempty"`
+	Attempts          int                `gorm:"not null;default:0" json:"attempts"`
+	ProcessedAt       *time.Time         `json:"processed_at,omitempty"`
+	CreatedAt         time.Time          `json:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at"`
+}
+
+func (PaymentEvent) TableName() string {
+	return "payment_events"
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+type ReconciliationResolution string
+
+const (
+	ReconciliationApplied    ReconciliationResolution = "applied"    // the gateway status was applied to the payment
+	ReconciliationSkipped    ReconciliationResolution = "skipped"    // the payment left pending before it could be applied
+	ReconciliationFailed     ReconciliationResolution = "failed"     // applying the gateway status failed
+	ReconciliationUnresolved ReconciliationResolution = "unresolved" // the gateway status has no internal equivalent
+	ReconciliationRefunded   ReconciliationResolution = "refunded"   // the payment arrived after it was failed and was refunded
+)
+
+// PaymentReconciliation records a pending payment whose status at the gateway
+// disagreed with ours, or a payment the gateway took after we had failed it,
+// and what was done about it. A payment
+// that stays unresolved or failed keeps one open report, updated on every run.
+type PaymentReconciliation struct {
+	ID            uint                     `gorm:"primaryKey" json:"id"`
+	PaymentID     uint                     `gorm:"not null" json:"payment_id"`
+	LocalStatus   PaymentStatus            `gorm:"type:payment_status;not null" json:"local_status"`
+	GatewayStatus string                   `gorm:"not null" json:"gateway_status"`
+	Resolution    ReconciliationResolution `gorm:"type:varchar(20);not null" json:"resolution"`
+	Error         *string                  `gorm:"type:text" json:"error,omitempty"`
+	Attempts      int                      `gorm:"not null;default:1" json:"attempts"` // runs that found the mismatch
+	CreatedAt     time.Time                `json:"created_at"`
+	UpdatedAt     time.Time                `json:"updated_at"`
+
+	// Relationships
+	Payment *Payment `gorm:"foreignKey:PaymentID" json:"payment,omitempty"`
+}
+
+func (PaymentReconciliation) TableName() string {
+	return "payment_reconciliations"
+}
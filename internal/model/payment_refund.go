@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+type RefundStatus string
+
+const (
+	RefundPending   RefundStatus = "pending"
+	RefundSucceeded RefundStatus = "succeeded"
+	RefundFailed    RefundStatus = "failed"
+)
+
+// PaymentRefund is one refund of a payment, sent back through the payment
+// provider, credited to the customer's wallet, or split between the two. A
+// payment can have several partial refunds as long as they stay within its
+// amount.
+type PaymentRefund struct {
+	ID               uint         `gorm:"primaryKey" json:"id"`
+	PaymentID        uint         `gorm:"not null" json:"payment_id"`
+	Amount           Money        `gorm:"type:decimal(12,2);not null" json:"amount" swaggertype:"string"`
+	WalletAmount     Money        `gorm:"type:decimal(12,2);not null;default:0" json:"wallet_amount" swaggertype:"string"` // part credited to the wallet
+	Reason           string       `gorm:"type:text;not null" json:"reason"`
+	Status           RefundStatus `gorm:"type:varchar(20);default:pending" json:"status"`
+	ProviderRefundID *string      `json:"provider_refund_id,omitempty"`
+	FailureReason    *string      `gorm:"type:text" json:"failure_reason,omitempty"`
+	RefundedBy       *uint        `json:"refunded_by,omitempty"` // nil for system refunds
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+
+	// Relationships
+	Actor *User `gorm:"foreignKey:RefundedBy" json:"actor,omitempty"`
+}
+
+func (PaymentRefund) TableName() string {
+	return "payment_refunds"
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+type DiscountType string
+
+const (
+	DiscountPercentage DiscountType = "percentage"
+	DiscountFixed      DiscountType = "fixed"
+)
+
+// PromoCode is a discount customers can apply when booking. Limits count the
+// bookings that used the code and were not cancelled, so a cancelled or expired
+// booking gives its use back. Empty GameIDs and CategoryIDs mean every game.
+type PromoCode struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	Code           string       `gorm:"type:varchar(50);uniqueIndex;not null" json:"code"` // stored upper case
+	Description    *string      `gorm:"type:text" json:"description,omitempty"`
+	DiscountType   DiscountType `gorm:"type:varchar(20);not null" json:"discount_type"`
+	PercentOff     int          `gorm:"not null;default:0" json:"percent_off,omitempty"`                              // percentage codes, 1-100
+	AmountOff      Money        `gorm:"type:decimal(12,2);not null;default:0" json:"amount_off" swaggertype:"string"` // fixed codes
+	MaxDiscount    *Money       `gorm:"type:decimal(12,2)" json:"max_discount,omitempty" swaggertype:"string"`        // caps a percentage discount
+	ValidFrom      *time.Time   `json:"valid_from,omitempty"`
+	ValidUntil     *time.Time   `json:"valid_until,omitempty"`
+	MaxUses        *int         `json:"max_uses,omitempty"`
+	MaxUsesPerUser *int         `json:"max_uses_per_user,omitempty"`
+	GameIDs        []uint       `gorm:"type:jsonb;serializer:json" json:"game_ids,omitempty"`
+	CategoryIDs    []uint       `gorm:"type:jsonb;serializer:json" json:"category_ids,omitempty"`
+	IsActive       bool         `gorm:"default:true" json:"is_active"`
+	CreatedBy      uint         `gorm:"not null" json:"created_by"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+
+	UsedCount int64 `gorm:"->;-:migration" json:"used_count"` // filled by the admin list
+}
+
+func (PromoCode) TableName() string {
+	return "promo_codes"
+}
+
+// ValidAt reports whether the code is active and inside its validity window
+func (p *PromoCode) ValidAt(now time.Time) bool {
+	if !p.IsActive {
+		return false
+	}
+	if p.ValidFrom != nil && now.Before(*p.ValidFrom) {
+		return false
+	}
+	return p.ValidUntil == nil || now.Before(*p.ValidUntil)
+}
+
+// AppliesTo reports whether the code may be used for the game
+func (p *PromoCode) AppliesTo(game *Game) bool {
+	if len(p.GameIDs) == 0 && len(p.CategoryIDs) == 0 {
+		return true
+	}
+	return slices.Contains(p.GameIDs, game.ID) || slices.Contains(p.CategoryIDs, game.CategoryID)
+}
+
+// Discount is what the code takes off a rental price, in whole units and never
+// more than the price. The security deposit is never discounted.
+func (p *PromoCode) Discount(rentalPrice Money) Money {
+	var discount Money
+	switch p.DiscountType {
+	case DiscountPercentage:
+		discount = rentalPrice.Percent(int64(p.PercentOff)).Truncate()
+		if p.MaxDiscount != nil {
+			discount = discount.Min(*p.MaxDiscount)
+		}
+	case DiscountFixed:
+		discount = p.AmountOff
+	}
+	return discount.Min(rentalPrice)
+}
@@ -0,0 +1,51 @@
+package model
+
+// TaxRule is the VAT (PPN) charged on rentals. A zero rate charges no tax.
+// Exclusive prices get the tax added on top; inclusive prices already carry
+// it, so it is only itemized.
+type TaxRule struct {
+	Rate           int64 // percent
+	DepositTaxable bool  // the security deposit is part of the taxable amount
+	Inclusive      bool
+}
+
+// Tax returns the tax on a taxable amount rounded, halves away from zero, to
+// the smallest amount the currency is charged in: whole rupiah for IDR, which
+// gateways such as Midtrans only take in whole units, hundredths otherwise.
+// For inclusive prices it is the part of the amount that is tax:
+// amount × rate / (100 + rate).
+func (r TaxRule) Tax(taxable Money) Money {
+	if r.Rate <= 0 {
+		return Money{currency: taxable.currency}
+	}
+
+	unit := int64(1)
+	if taxable.Currency() == CurrencyIDR {
+		unit = 100
+	}
+	divisor := int64(100)
+	if r.Inclusive {
+		divisor += r.Rate
+	}
+	divisor *= unit
+
+	scaled := taxable.cents * r.Rate
+	units := scaled / divisor
+	if remainder := scaled % divisor; remainder*2 >= divisor {
+		units++
+	} else if remainder*2 <= -divisor {
+		units--
+	}
+	return Money{cents: units * unit, currency: taxable.currency}
+}
+
+// TaxTotal sums the invoices issued in a period at one tax rate. Credit notes
+// of date changes reduce it; other refunds do not, invoices are never withdrawn.
+type TaxTotal struct {
+	TaxRate       int64 `json:"tax_rate"`
+	TaxInclusive  bool  `json:"tax_inclusive"`
+	Invoices      int64 `json:"invoices"`
+	TaxableAmount Money `json:"taxable_amount" swaggertype:"string"`
+	TaxAmount     Money `json:"tax_amount" swaggertype:"string"`
+	TotalAmount   Money `json:"total_amount" swaggertype:"string"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaxRuleTax(t *testing.T) {
+	exclusive := TaxRule{Rate: 11}
+	assert.Equal(t, NewMoney(11000), exclusive.Tax(NewMoney(100000)))
+
+	inclusive := TaxRule{Rate: 11, Inclusive: true}
+	assert.Equal(t, NewMoney(11000), inclusive.Tax(NewMoney(111000)))
+	assert.Equal(t, NewMoney(10), inclusive.Tax(NewMoney(100)), "9.9099… rounds to whole rupiah")
+	assert.Equal(t, NewMoney(-10), inclusive.Tax(NewMoney(-100)))
+	assert.Equal(t, NewMoney(1223), inclusive.Tax(NewMoney(12345)), "1223.38… rounds down")
+
+	assert.Equal(t, NewMoney(1358), exclusive.Tax(NewMoney(12345)), "1357.95 rounds up")
+	assert.Equal(t, NewMoney(-1358), exclusive.Tax(NewMoney(-12345)))
+
+	assert.True(t, TaxRule{}.Tax(NewMoney(100000)).IsZero())
+}
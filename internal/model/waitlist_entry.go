@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+type WaitlistStatus string
+
+const (
+	WaitlistWaiting   WaitlistStatus = "waiting"
+	WaitlistOffered   WaitlistStatus = "offered"   // holding a copy until HoldExpiresAt
+	WaitlistConverted WaitlistStatus = "converted" // the hold became a booking
+	WaitlistExpired   WaitlistStatus = "expired"
+	WaitlistCancelled WaitlistStatus = "cancelled"
+)
+
+// WaitlistEntry queues a customer for a game that had no copy free for the dates they wanted
+type WaitlistEntry struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	GameID        uint           `gorm:"not null" json:"game_id"`
+	UserID        uint           `gorm:"not null" json:"user_id"`
+	StartDate     time.Time      `gorm:"type:date;not null" json:"start_date"`
+	EndDate       time.Time      `gorm:"type:date;not null" json:"end_date"`
+	Status        WaitlistStatus `gorm:"type:varchar(20);default:waiting" json:"status"`
+	OfferedAt     *time.Time     `json:"offered_at,omitempty"`
+	HoldExpiresAt *time.Time     `json:"hold_expires_at,omitempty"`
+	BookingID     *uint          `json:"booking_id,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Game Game `gorm:"foreignKey:GameID" json:"game,omitempty"`
+}
+
+func (WaitlistEntry) TableName() string {
+	return "waitlist_entries"
+}
@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// WalletEntryType tells what moved money in or out of a wallet
+type WalletEntryType string
+
+const (
+	WalletPayment        WalletEntryType = "payment"         // paid toward a booking or order
+	WalletPaymentRelease WalletEntryType = "payment_release" // given back when that payment failed
+	WalletRefund         WalletEntryType = "refund"          // refund or deposit return credited as store credit
+	WalletAdjustment     WalletEntryType = "adjustment"      // credit or correction by an admin
+)
+
+// Wallet is a customer's store credit balance. The balance always equals the sum
+// of the customer's ledger entries; it is kept alongside so a debit can be
+// checked against it atomically.
+type Wallet struct {
+	UserID    uint      `gorm:"primaryKey" json:"user_id"`
+	Balance   Money     `gorm:"type:decimal(12,2);not null;default:0" json:"balance" swaggertype:"string"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Entries []WalletEntry `gorm:"-" json:"entries,omitempty"` // newest first, one page of the ledger
+}
+
+func (Wallet) TableName() string {
+	return "wallets"
+}
+
+// WalletEntry is one line of the append-only wallet ledger: credits are
+// positive, debits negative. Entries are never changed; mistakes are corrected
+// with another entry.
+type WalletEntry struct {
+	ID           uint            `gorm:"primaryKey" json:"id"`
+	UserID       uint            `gorm:"not null;index" json:"user_id"`
+	Type         WalletEntryType `gorm:"type:varchar(20);not null" json:"type"`
+	Amount       Money           `gorm:"type:decimal(12,2);not null" json:"amount" swaggertype:"string"`
+	BalanceAfter Money           `gorm:"type:decimal(12,2);not null" json:"balance_after" swaggertype:"string"`
+	PaymentID    *uint           `json:"payment_id,omitempty"`
+	RefundID     *uint           `json:"refund_id,omitempty"`
+	Description  string          `gorm:"type:text;not null" json:"description"`
+	CreatedBy    *uint           `json:"created_by,omitempty"` // admin for adjustments, nil otherwise
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+func (WalletEntry) TableName() string {
+	return "wallet_entries"
+}
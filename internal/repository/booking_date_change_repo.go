@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"gorm.io/gorm"
+)
+
+type BookingDateChangeRepository interface {
+	Create(change *model.BookingDateChange) error
+	Update(change *model.BookingDateChange) error
+	GetByPaymentID(paymentID uint) (*model.BookingDateChange, error)
+	GetPendingByBookingID(bookingID uint) (*model.BookingDateChange, error)
+	GetExpiredPending(createdBefore time.Time, limit int) ([]*model.BookingDateChange, error)
+
+	// Status updates
+	MarkApplied(changeID uint) (bool, error)
+	MarkFailed(changeID uint) (bool, error)
+}
+
+type bookingDateChangeRepository struct {
+	db *gorm.DB
+}
+
+func NewBookingDateChangeRepository(db *gorm.DB) BookingDateChangeRepository {
+	return &bookingDateChangeRepository{db: db}
+}
+
+func (r *bookingDateChangeRepository) Create(change *model.BookingDateChange) error {
+	return r.db.Create(change).Error
+}
+
+func (r *bookingDateChangeRepository) Update(change *model.BookingDateChange) error {
+	return r.db.Save(change).Error
+}
+
+func (r *bookingDateChangeRepository) GetByPaymentID(paymentID uint) (*model.BookingDateChange, error) {
+	var change model.BookingDateChange
+	if err := r.db.Where("payment_id = ?", paymentID).First(&change).Error; err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+func (r *bookingDateChangeRepository) GetPendingByBookingID(bookingID uint) (*model.BookingDateChange, error) {
+	var change model.BookingDateChange
+	err := r.db.Where("booking_id = ? AND status = ?", bookingID, model.DateChangePending).
+		Preload("Payment").First(&change).Error
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
+
+// GetExpiredPending returns pending changes requested before the cutoff whose
+// payment was neither paid nor taken into review, oldest first
+func (r *bookingDateChangeRepository) GetExpiredPending(createdBefore time.Time, limit int) ([]*model.BookingDateChange, error) {
+	var changes []*model.BookingDateChange
+	err := r.db.Preload("Payment").
+		Where("status = ? AND created_at < ?", model.DateChangePending, createdBefore).
+		Where("NOT EXISTS (SELECT 1 FROM payments p WHERE p.id = booking_date_changes.payment_id AND p.status IN ?)", []model.PaymentStatus{model.PaymentPaid, model.PaymentReview}).
+		Order("created_at ASC").Limit(limit).Find(&changes).Error
+	return changes, err
+}
+
+// MarkApplied moves a pending change to applied, reporting false if it was no longer pending
+func (r *bookingDateChangeRepository) MarkApplied(changeID uint) (bool, error) {
+	result := r.db.Model(&model.BookingDateChange{}).
+		Where("id = ? AND status = ?", changeID, model.DateChangePending).
+		Updates(map[string]interface{}{
+			"status":     model.DateChangeApplied,
+			"applied_at": gorm.Expr("CURRENT_TIMESTAMP"),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// MarkFailed moves a pending change to failed, releasing the days it held
+func (r *bookingDateChangeRepository) MarkFailed(changeID uint) (bool, error) {
+	result := r.db.Model(&model.BookingDateChange{}).
+		Where("id = ? AND status = ?", changeID, model.DateChangePending).
+		Update("status", model.DateChangeFailed)
+	return result.RowsAffected > 0, result.Error
+}
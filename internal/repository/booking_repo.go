@@ -1,10 +1,31 @@
 package repository
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/yoockh/go-game-rental-api/internal/model"
 	"gorm.io/gorm"
 )
 
+// BookingFilter narrows the admin booking list. Zero values mean no filter.
+type BookingFilter struct {
+	Status        model.BookingStatus
+	UserID        uint
+	GameID        uint
+	PaymentStatus model.PaymentStatus
+	StartFrom     *time.Time
+	StartTo       *time.Time
+	EndFrom       *time.Time
+	EndTo         *time.Time
+	Search        string // matched against customer email and name
+	SortBy        string // one of BookingSortColumns, created_at when empty
+	SortAsc       bool
+}
+
+// BookingSortColumns are the date columns the booking list can be sorted by
+var BookingSortColumns = []string{"created_at", "updated_at", "start_date", "end_date", "payment_due_at"}
+
 type BookingRepository interface {
 	// Basic CRUD
 	Create(booking *model.Booking) error
@@ -13,12 +34,14 @@ type BookingRepository interface {
 
 	// Query methods
 	GetUserBookings(userID uint, limit, offset int) ([]*model.Booking, error)
-	GetAllBookings(limit, offset int) ([]*model.Booking, error)
+	GetAllBookings(filter BookingFilter, limit, offset int) ([]*model.Booking, error)
 	CountUserBookings(userID uint) (int64, error)
-	Count() (int64, error)
+	Count(filter BookingFilter) (int64, error)
+	GetExpiredPending(now time.Time, limit int) ([]*model.Booking, error)
 
 	// Status updates
-	UpdateStatus(bookingID uint, status model.BookingStatus) error
+	UpdateStatusFrom(bookingID uint, from, to model.BookingStatus) (bool, error)
+	UpdateDates(booking *model.Booking) error
 }
 
 type bookingRepository struct {
@@ -29,13 +52,25 @@ func NewBookingRepository(db *gorm.DB) BookingRepository {
 	return &bookingRepository{db: db}
 }
 
+// preloadBookingPayment preloads the booking's own authoritative payment attempt,
+// skipping supplemental charges
+func preloadBookingPayment(db *gorm.DB) *gorm.DB {
+	return db.Preload("Payment", currentAttempt("booking_id"), model.PaymentPurposeBooking)
+}
+
 func (r *bookingRepository) Create(booking *model.Booking) error {
 	return r.db.Create(booking).Error
 }
 
 func (r *bookingRepository) GetByID(id uint) (*model.Booking, error) {
 	var booking model.Booking
-	if err := r.db.Preload("User").Preload("Game").Preload("Payment").First(&booking, id).Error; err != nil {
+	if err := r.db.Preload("User").Preload("Game").Scopes(preloadBookingPayment).Preload("Settlement").Preload("PromoCode").
+		Preload("Order.Payment", currentAttempt("order_id"), model.PaymentPurposeOrder).
+		Preload("PaymentAttempts", func(db *gorm.DB) *gorm.DB {
+			return db.Where("purpose = ?", model.PaymentPurposeBooking).Order("created_at, id")
+		}).
+		Preload("DateChanges", func(db *gorm.DB) *gorm.DB { return db.Order("created_at DESC") }).
+		First(&booking, id).Error; err != nil {
 		return nil, err
 	}
 	return &booking, nil
@@ -47,30 +82,116 @@ func (r *bookingRepository) Update(booking *model.Booking) error {
 
 func (r *bookingRepository) GetUserBookings(userID uint, limit, offset int) ([]*model.Booking, error) {
 	var bookings []*model.Booking
-	err := r.db.Where("user_id = ?", userID).Preload("Game").Preload("Payment").
+	err := r.db.Where("user_id = ?", userID).Preload("Game").Scopes(preloadBookingPayment).
 		Order("created_at DESC").Limit(limit).Offset(offset).Find(&bookings).Error
 	return bookings, err
 }
 
-func (r *bookingRepository) GetAllBookings(limit, offset int) ([]*model.Booking, error) {
+func (r *bookingRepository) GetAllBookings(filter BookingFilter, limit, offset int) ([]*model.Booking, error) {
+	sortBy := "created_at"
+	for _, column := range BookingSortColumns {
+		if filter.SortBy == column {
+			sortBy = column
+		}
+	}
+	direction := "DESC"
+	if filter.SortAsc {
+		direction = "ASC"
+	}
+
 	var bookings []*model.Booking
-	err := r.db.Preload("User").Preload("Game").Preload("Payment").
-		Order("created_at DESC").Limit(limit).Offset(offset).Find(&bookings).Error
+	err := r.db.Preload("User").Preload("Game").Scopes(preloadBookingPayment, filterBookings(filter)).
+		Order(fmt.Sprintf("bookings.%s %s NULLS LAST, bookings.id %s", sortBy, direction, direction)).
+		Limit(limit).Offset(offset).Find(&bookings).Error
 	return bookings, err
 }
 
+// filterBookings applies a BookingFilter. GetAllBookings and Count share it so
+// the page and the total always agree.
+func filterBookings(filter BookingFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.Status != "" {
+			db = db.Where("bookings.status = ?", filter.Status)
+		}
+		if filter.UserID != 0 {
+			db = db.Where("bookings.user_id = ?", filter.UserID)
+		}
+		if filter.GameID != 0 {
+			db = db.Where("bookings.game_id = ?", filter.GameID)
+		}
+		if filter.PaymentStatus != "" {
+			// The booking's own payment, or the order payment for order line items
+			db = db.Where(`EXISTS (SELECT 1 FROM payments p
+				WHERE (p.booking_id = bookings.id AND p.purpose = ?
+					OR p.order_id = bookings.order_id AND p.purpose = ?)
+				AND p.status = ?)`,
+				model.PaymentPurposeBooking, model.PaymentPurposeOrder, filter.PaymentStatus)
+		}
+		if filter.StartFrom != nil {
+			db = db.Where("bookings.start_date >= ?", filter.StartFrom.Format("2006-01-02"))
+		}
+		if filter.StartTo != nil {
+			db = db.Where("bookings.start_date <= ?", filter.StartTo.Format("2006-01-02"))
+		}
+		if filter.EndFrom != nil {
+			db = db.Where("bookings.end_date >= ?", filter.EndFrom.Format("2006-01-02"))
+		}
+		if filter.EndTo != nil {
+			db = db.Where("bookings.end_date <= ?", filter.EndTo.Format("2006-01-02"))
+		}
+		if filter.Search != "" {
+			pattern := "%" + filter.Search + "%"
+			db = db.Where("EXISTS (SELECT 1 FROM users u WHERE u.id = bookings.user_id AND (u.email ILIKE ? OR u.full_name ILIKE ?))",
+				pattern, pattern)
+		}
+		return db
+	}
+}
+
 func (r *bookingRepository) CountUserBookings(userID uint) (int64, error) {
 	var count int64
 	err := r.db.Model(&model.Booking{}).Where("user_id = ?", userID).Count(&count).Error
 	return count, err
 }
 
-func (r *bookingRepository) Count() (int64, error) {
+func (r *bookingRepository) Count(filter BookingFilter) (int64, error) {
 	var count int64
-	err := r.db.Model(&model.Booking{}).Count(&count).Error
+	err := r.db.Model(&model.Booking{}).Scopes(filterBookings(filter)).Count(&count).Error
 	return count, err
 }
 
-func (r *bookingRepository) UpdateStatus(bookingID uint, status model.BookingStatus) error {
-	return r.db.Model(&model.Booking{}).Where("id = ?", bookingID).Update("status", status).Error
+// GetExpiredPending returns pending bookings whose payment deadline has passed
+// without any paid payment or one held for review
+func (r *bookingRepository) GetExpiredPending(now time.Time, limit int) ([]*model.Booking, error) {
+	var bookings []*model.Booking
+	err := r.db.Preload("User").Preload("Game").Scopes(preloadBookingPayment).
+		Where("status = ? AND payment_due_at < ?", model.BookingPending, now).
+		Where("order_id IS NULL"). // order line items expire with their order
+		Where("NOT EXISTS (SELECT 1 FROM payments p WHERE p.booking_id = bookings.id AND p.status IN ?)", []model.PaymentStatus{model.PaymentPaid, model.PaymentReview}).
+		Order("payment_due_at ASC").Limit(limit).Find(&bookings).Error
+	return bookings, err
+}
+
+// UpdateDates saves the booking's dates and the prices derived from them
+func (r *bookingRepository) UpdateDates(booking *model.Booking) error {
+	return r.db.Model(&model.Booking{}).Where("id = ?", booking.ID).Updates(map[string]interface{}{
+		"start_date":         booking.StartDate,
+		"end_date":           booking.EndDate,
+		"rental_days":        booking.RentalDays,
+		"total_rental_price": booking.TotalRentalPrice,
+		"discount_amount":    booking.DiscountAmount,
+		"taxable_amount":     booking.TaxableAmount,
+		"tax_amount":         booking.TaxAmount,
+		"total_amount":       booking.TotalAmount,
+	}).Error
+}
+
+// UpdateStatusFrom changes the status only if the booking is still in the expected
+// status, reporting false when another request changed it first
+func (r *bookingRepository) UpdateStatusFrom(bookingID uint, from, to model.BookingStatus) (bool, error) {
+	result := r.db.Model(&model.Booking{}).Where("id = ? AND status = ?", bookingID, from).Update("status", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
 }
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"gorm.io/gorm"
+)
+
+type BookingSettlementRepository interface {
+	Create(settlement *model.BookingSettlement) error
+	GetByBookingID(bookingID uint) (*model.BookingSettlement, error)
+	Update(settlement *model.BookingSettlement) error
+
+	// Status updates
+	UpdateRefundStatusFrom(settlementID uint, from, to model.DepositRefundStatus) (bool, error)
+}
+
+type bookingSettlementRepository struct {
+	db *gorm.DB
+}
+
+func NewBookingSettlementRepository(db *gorm.DB) BookingSettlementRepository {
+	return &bookingSettlementRepository{db: db}
+}
+
+func (r *bookingSettlementRepository) Create(settlement *model.BookingSettlement) error {
+	return r.db.Create(settlement).Error
+}
+
+func (r *bookingSettlementRepository) GetByBookingID(bookingID uint) (*model.BookingSettlement, error) {
+	var settlement model.BookingSettlement
+	if err := r.db.Where("booking_id = ?", bookingID).First(&settlement).Error; err != nil {
+		return nil, err
+	}
+	return &settlement, nil
+}
+
+func (r *bookingSettlementRepository) Update(settlement *model.BookingSettlement) error {
+	return r.db.Save(settlement).Error
+}
+
+// UpdateRefundStatusFrom moves the deposit refund from one status to another,
+// reporting false if it was no longer in the from status
+func (r *bookingSettlementRepository) UpdateRefundStatusFrom(settlementID uint, from, to model.DepositRefundStatus) (bool, error) {
+	result := r.db.Model(&model.BookingSettlement{}).
+		Where("id = ? AND refund_status = ?", settlementID, from).
+		Update("refund_status", to)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"gorm.io/gorm"
+)
+
+type BookingStatusHistoryRepository interface {
+	Create(entry *model.BookingStatusHistory) error
+	GetByBookingID(bookingID uint) ([]*model.BookingStatusHistory, error)
+}
+
+type bookingStatusHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewBookingStatusHistoryRepository(db *gorm.DB) BookingStatusHistoryRepository {
+	return &bookingStatusHistoryRepository{db: db}
+}
+
+func (r *bookingStatusHistoryRepository) Create(entry *model.BookingStatusHistory) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *bookingStatusHistoryRepository) GetByBookingID(bookingID uint) ([]*model.BookingStatusHistory, error) {
+	var entries []*model.BookingStatusHistory
+	err := r.db.Where("booking_id = ?", bookingID).Preload("Actor").
+		Order("created_at ASC, id ASC").Find(&entries).Error
+	return entries, err
+}
@@ -2,6 +2,7 @@ package email
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"regexp"
@@ -19,9 +20,17 @@ var (
 
 type EmailRepository interface {
 	SendEmail(ctx context.Context, to, subject, plainText, htmlContent string) error
+	SendEmailWithAttachments(ctx context.Context, to, subject, plainText, htmlContent string, attachments []Attachment) error
 	SendWithTemplate(ctx context.Context, to, templateID string, dynamicData map[string]interface{}) error
 }
 
+// Attachment is a file attached to an email, e.g. an invoice PDF
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
 type SendGridRepository struct {
 	client   *sendgrid.Client
 	fromName string
@@ -48,6 +57,10 @@ func NewSendGridRepository() (*SendGridRepository, error) {
 }
 
 func (s *SendGridRepository) SendEmail(ctx context.Context, to, subject, plainText, htmlContent string) error {
+	return s.SendEmailWithAttachments(ctx, to, subject, plainText, htmlContent, nil)
+}
+
+func (s *SendGridRepository) SendEmailWithAttachments(ctx context.Context, to, subject, plainText, htmlContent string, attachments []Attachment) error {
 	_ = ctx // ctx unused - SendGrid client doesn't support context timeout
 	if !isValidEmail(to) {
 		return fmt.Errorf("invalid email address: %s", to)
@@ -61,6 +74,14 @@ func (s *SendGridRepository) SendEmail(ctx context.Context, to, subject, plainTe
 	from := mail.NewEmail(s.fromName, s.fromAddr)
 	toEmail := mail.NewEmail("", to)
 	message := mail.NewSingleEmail(from, subject, toEmail, plainText, htmlContent)
+	for _, attachment := range attachments {
+		a := mail.NewAttachment()
+		a.SetFilename(attachment.Filename)
+		a.SetType(attachment.ContentType)
+		a.SetDisposition("attachment")
+		a.SetContent(base64.StdEncoding.EncodeToString(attachment.Content))
+		message.AddAttachment(a)
+	}
 
 	resp, err := s.client.Send(message)
 	if err != nil {
@@ -135,6 +156,7 @@ type MockEmail struct {
 	HTMLContent string
 	TemplateID  string
 	Data        map[string]interface{}
+	Attachments []Attachment
 }
 
 func (m *MockEmailRepository) SendEmail(ctx context.Context, to, subject, plainText, htmlContent string) error {
@@ -147,6 +169,17 @@ func (m *MockEmailRepository) SendEmail(ctx context.Context, to, subject, plainT
 	return nil
 }
 
+func (m *MockEmailRepository) SendEmailWithAttachments(ctx context.Context, to, subject, plainText, htmlContent string, attachments []Attachment) error {
+	m.SentEmails = append(m.SentEmails, MockEmail{
+		To:          to,
+		Subject:     subject,
+		PlainText:   plainText,
+		HTMLContent: htmlContent,
+		Attachments: attachments,
+	})
+	return nil
+}
+
 func (m *MockEmailRepository) SendWithTemplate(ctx context.Context, to, templateID string, dynamicData map[string]interface{}) error {
 	m.SentEmails = append(m.SentEmails, MockEmail{
 		To:         to,
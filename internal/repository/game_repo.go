@@ -1,10 +1,17 @@
 package repository
 
 import (
+	"errors"
+	"time"
+
 	"github.com/yoockh/go-game-rental-api/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrNoStockAvailable is returned by ReserveStock when no copy is left on the shelf
+var ErrNoStockAvailable = errors.New("no copy available on the shelf")
+
 type GameRepository interface {
 	// Basic CRUD
 	Create(game *model.Game) error
@@ -18,7 +25,9 @@ type GameRepository interface {
 	Count() (int64, error)
 
 	// Stock management
-	CheckAvailability(gameID uint) (bool, error)
+	LockForUpdate(gameID uint) (*model.Game, error)
+	CheckAvailability(gameID uint, startDate, endDate time.Time) (bool, error)
+	GetAvailability(gameID uint, from, to time.Time) ([]model.GameAvailability, error)
 	ReserveStock(gameID uint) error
 	ReleaseStock(gameID uint) error
 }
@@ -87,17 +96,89 @@ func (r *gameRepository) Count() (int64, error) {
 	return count, err
 }
 
-func (r *gameRepository) CheckAvailability(gameID uint) (bool, error) {
+// LockForUpdate loads the game with a row lock (SELECT ... FOR UPDATE) so that
+// concurrent transactions touching the same game are serialized. Only meaningful
+// inside a transaction.
+func (r *gameRepository) LockForUpdate(gameID uint) (*model.Game, error) {
 	var game model.Game
-	if err := r.db.Select("available_stock").First(&game, gameID).Error; err != nil {
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&game, gameID).Error; err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+func (r *gameRepository) CheckAvailability(gameID uint, startDate, endDate time.Time) (bool, error) {
+	days, err := r.GetAvailability(gameID, startDate, endDate)
+	if err != nil {
 		return false, err
 	}
-	return game.AvailableStock > 0, nil
+	if len(days) == 0 {
+		return false, gorm.ErrRecordNotFound
+	}
+
+	for _, day := range days {
+		if day.Available <= 0 {
+			return false, nil
+		}
+	}
+	return true, nil
 }
 
+// GetAvailability returns free copies per day between from and to (inclusive),
+// computed from games.stock minus bookings whose date range covers that day
+func (r *gameRepository) GetAvailability(gameID uint, from, to time.Time) ([]model.GameAvailability, error) {
+	var days []model.GameAvailability
+	err := r.db.Raw(`
+		WITH reservations AS (
+			SELECT start_date, end_date
+			FROM bookings
+			WHERE game_id = ? AND status IN ?
+			UNION ALL
+			-- pending date changes hold the days of the new range outside the current one
+			SELECT c.new_start_date, LEAST(c.new_end_date, c.old_start_date - 1)
+			FROM booking_date_changes c
+			JOIN bookings b ON b.id = c.booking_id
+			WHERE b.game_id = ? AND b.status IN ? AND c.status = ? AND c.new_start_date < c.old_start_date
+			UNION ALL
+			SELECT GREATEST(c.new_start_date, c.old_end_date + 1), c.new_end_date
+			FROM booking_date_changes c
+			JOIN bookings b ON b.id = c.booking_id
+			WHERE b.game_id = ? AND b.status IN ? AND c.status = ? AND c.new_end_date > c.old_end_date
+			UNION ALL
+			-- waitlist holds keep a copy for the customer they were offered to
+			SELECT start_date, end_date
+			FROM waitlist_entries
+			WHERE game_id = ? AND status = ? AND hold_expires_at > NOW()
+		)
+		SELECT d::date AS date, GREATEST(g.stock - COUNT(res.start_date), 0) AS available
+		FROM games g
+		CROSS JOIN generate_series(?::date, ?::date, interval '1 day') AS d
+		LEFT JOIN reservations res ON res.start_date <= d::date AND res.end_date >= d::date
+		WHERE g.id = ?
+		GROUP BY d, g.stock
+		ORDER BY d`,
+		gameID, model.ReservingBookingStatuses,
+		gameID, model.ReservingBookingStatuses, model.DateChangePending,
+		gameID, model.ReservingBookingStatuses, model.DateChangePending,
+		gameID, model.WaitlistOffered,
+		from.Format("2006-01-02"), to.Format("2006-01-02"),
+		gameID,
+	).Scan(&days).Error
+	return days, err
+}
+
+// ReserveStock takes one copy off the shelf. available_stock counts the copies
+// physically on the shelf; date-range availability is answered by CheckAvailability.
 func (r *gameRepository) ReserveStock(gameID uint) error {
-	return r.db.Model(&model.Game{}).Where("id = ? AND available_stock > 0", gameID).
-		Update("available_stock", gorm.Expr("available_stock - 1")).Error
+	result := r.db.Model(&model.Game{}).Where("id = ? AND available_stock > 0", gameID).
+		Update("available_stock", gorm.Expr("available_stock - 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNoStockAvailable
+	}
+	return nil
 }
 
 func (r *gameRepository) ReleaseStock(gameID uint) error {
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"gorm.io/gorm"
+)
+
+type InvoiceRepository interface {
+	// GetByBookingID returns the invoice of the booking itself, not those of its
+	// date changes
+	GetByBookingID(bookingID uint) (*model.Invoice, error)
+
+	// Issue numbers the invoice with the next number of its issue year and
+	// creates it. It must run in a transaction: the counter row stays locked
+	// until commit and a rollback gives the number back, so numbers have no gaps.
+	Issue(invoice *model.Invoice) error
+
+	// SumTax totals the invoices issued in [from, to) per tax rate, as they
+	// were issued; credit notes count negative
+	SumTax(from, to time.Time) ([]model.TaxTotal, error)
+}
+
+type invoiceRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceRepository(db *gorm.DB) InvoiceRepository {
+	return &invoiceRepository{db: db}
+}
+
+func (r *invoiceRepository) GetByBookingID(bookingID uint) (*model.Invoice, error) {
+	var invoice model.Invoice
+	if err := r.db.Where("booking_id = ? AND date_change_id IS NULL", bookingID).First(&invoice).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (r *invoiceRepository) Issue(invoice *model.Invoice) error {
+	year := invoice.IssuedAt.Year()
+
+	var next int64
+	if err := r.db.Raw(`INSERT INTO invoice_counters (year, last_number) VALUES (?, 1)
+		ON CONFLICT (year) DO UPDATE SET last_number = invoice_counters.last_number + 1
+		RETURNING last_number`, year).Scan(&next).Error; err != nil {
+		return err
+	}
+
+	invoice.Number = fmt.Sprintf("INV-%d-%06d", year, next)
+	return r.db.Create(invoice).Error
+}
+
+func (r *invoiceRepository) SumTax(from, to time.Time) ([]model.TaxTotal, error) {
+	var totals []model.TaxTotal
+	err := r.db.Table("invoices").
+		Select(`tax_rate, tax_inclusive, COUNT(*) AS invoices,
+			SUM(taxable_amount) AS taxable_amount,
+			SUM(tax_amount) AS tax_amount,
+			SUM(total_amount) AS total_amount`).
+		Where("issued_at >= ? AND issued_at < ?", from, to).
+		Group("tax_rate, tax_inclusive").
+		Order("tax_rate, tax_inclusive").
+		Scan(&totals).Error
+	return totals, err
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"gorm.io/gorm"
+)
+
+type OrderRepository interface {
+	Create(order *model.Order) error
+	GetByID(id uint) (*model.Order, error)
+	GetUserOrders(userID uint, limit, offset int) ([]*model.Order, error)
+	CountUserOrders(userID uint) (int64, error)
+	GetExpiredPending(now time.Time, limit int) ([]*model.Order, error)
+}
+
+type orderRepository struct {
+	db *gorm.DB
+}
+
+func NewOrderRepository(db *gorm.DB) OrderRepository {
+	return &orderRepository{db: db}
+}
+
+// preloadOrderRelations preloads the line items with their games and the order's
+// authoritative payment attempt
+func preloadOrderRelations(db *gorm.DB) *gorm.DB {
+	return db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Preload("Items.Game").
+		Preload("Payment", currentAttempt("order_id"), model.PaymentPurposeOrder)
+}
+
+func (r *orderRepository) Create(order *model.Order) error {
+	return r.db.Omit("Items", "Payment", "PaymentAttempts").Create(order).Error
+}
+
+func (r *orderRepository) GetByID(id uint) (*model.Order, error) {
+	var order model.Order
+	if err := r.db.Preload("User").Scopes(preloadOrderRelations).
+		Preload("PaymentAttempts", func(db *gorm.DB) *gorm.DB {
+			return db.Where("purpose = ?", model.PaymentPurposeOrder).Order("created_at, id")
+		}).
+		First(&order, id).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *orderRepository) GetUserOrders(userID uint, limit, offset int) ([]*model.Order, error) {
+	var orders []*model.Order
+	err := r.db.Where("user_id = ?", userID).Scopes(preloadOrderRelations).
+		Order("created_at DESC").Limit(limit).Offset(offset).Find(&orders).Error
+	return orders, err
+}
+
+func (r *orderRepository) CountUserOrders(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Order{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// GetExpiredPending returns orders past their payment deadline that still have
+// pending line items and no paid payment or one held for review
+func (r *orderRepository) GetExpiredPending(now time.Time, limit int) ([]*model.Order, error) {
+	var orders []*model.Order
+	err := r.db.Preload("User").Scopes(preloadOrderRelations).
+		Where("payment_due_at < ?", now).
+		Where("EXISTS (SELECT 1 FROM bookings b WHERE b.order_id = orders.id AND b.status = ?)", model.BookingPending).
+		Where("NOT EXISTS (SELECT 1 FROM payments p WHERE p.order_id = orders.id AND p.status IN ?)", []model.PaymentStatus{model.PaymentPaid, model.PaymentReview}).
+		Order("payment_due_at ASC").Limit(limit).Find(&orders).Error
+	return orders, err
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"gorm.io/gorm"
+)
+
+type PaymentDisputeRepository interface {
+	Create(dispute *model.PaymentDispute) error
+	GetByID(id uint) (*model.PaymentDispute, error)
+	GetByProviderID(provider model.PaymentProvider, providerDisputeID string) (*model.PaymentDispute, error)
+	Update(dispute *model.PaymentDispute) error
+	UpdateStatusFrom(disputeID uint, from, to model.DisputeStatus) (bool, error)
+	RecordEvidence(disputeID uint, evidence string, adminID uint) (bool, error)
+
+	GetAll(status model.DisputeStatus, limit, offset int) ([]*model.PaymentDispute, error)
+	Count(status model.DisputeStatus) (int64, error)
+}
+
+type paymentDisputeRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentDisputeRepository(db *gorm.DB) PaymentDisputeRepository {
+	return &paymentDisputeRepository{db: db}
+}
+
+func (r *paymentDisputeRepository) Create(dispute *model.PaymentDispute) error {
+	return r.db.Create(dispute).Error
+}
+
+func (r *paymentDisputeRepository) GetByID(id uint) (*model.PaymentDispute, error) {
+	var dispute model.PaymentDispute
+	if err := r.db.Preload("Payment").First(&dispute, id).Error; err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+func (r *paymentDisputeRepository) GetByProviderID(provider model.PaymentProvider, providerDisputeID string) (*model.PaymentDispute, error) {
+	var dispute model.PaymentDispute
+	err := r.db.Where("provider = ? AND provider_dispute_id = ?", provider, providerDisputeID).First(&dispute).Error
+	if err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+func (r *paymentDisputeRepository) Update(dispute *model.PaymentDispute) error {
+	return r.db.Save(dispute).Error
+}
+
+// UpdateStatusFrom moves a dispute to a new status only if it is still in the
+// expected one, stamping resolved_at when it is won or lost. It reports false
+// when another request changed the dispute first.
+func (r *paymentDisputeRepository) UpdateStatusFrom(disputeID uint, from, to model.DisputeStatus) (bool, error) {
+	updates := map[string]interface{}{"status": to}
+	if to == model.DisputeWon || to == model.DisputeLost {
+		updates["resolved_at"] = time.Now()
+	}
+
+	result := r.db.Model(&model.PaymentDispute{}).
+		Where("id = ? AND status = ?", disputeID, from).
+		Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RecordEvidence stores the evidence answering an open dispute and moves it to
+// under review. It reports false when the dispute is no longer open.
+func (r *paymentDisputeRepository) RecordEvidence(disputeID uint, evidence string, adminID uint) (bool, error) {
+	result := r.db.Model(&model.PaymentDispute{}).
+		Where("id = ? AND status = ?", disputeID, model.DisputeOpen).
+		Updates(map[string]interface{}{
+			"status":                model.DisputeUnderReview,
+			"evidence":              evidence,
+			"evidence_submitted_by": adminID,
+			"evidence_submitted_at": time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *paymentDisputeRepository) GetAll(status model.DisputeStatus, limit, offset int) ([]*model.PaymentDispute, error) {
+	var disputes []*model.PaymentDispute
+	query := r.db.Preload("Payment").Order("created_at DESC, id DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Limit(limit).Offset(offset).Find(&disputes).Error
+	return disputes, err
+}
+
+func (r *paymentDisputeRepository) Count(status model.DisputeStatus) (int64, error) {
+	var count int64
+	query := r.db.Model(&model.PaymentDispute{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PaymentEventRepository interface {
+	Create(event *model.PaymentEvent) error
+	CreateIfNew(event *model.PaymentEvent) (bool, error)
+	GetByID(id uint) (*model.PaymentEvent, error)
+	GetByKey(provider model.PaymentProvider, transactionID, transactionStatus string) (*model.PaymentEvent, error)
+	Update(event *model.PaymentEvent) error
+
+	GetAll(status model.PaymentEventStatus, limit, offset int) ([]*model.PaymentEvent, error)
+	Count(status model.PaymentEventStatus) (int64, error)
+}
+
+type paymentEventRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentEventRepository(db *gorm.DB) PaymentEventRepository {
+	return &paymentEventRepository{db: db}
+}
+
+func (r *paymentEventRepository) Create(event *model.PaymentEvent) error {
+	return r.db.Create(event).Error
+}
+
+// CreateIfNew stores the event unless a non-rejected event with the same
+// provider, transaction and transaction status exists, reporting false for
+// such duplicates
+func (r *paymentEventRepository) CreateIfNew(event *model.PaymentEvent) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "provider"}, {Name: "transaction_id"}, {Name: "transaction_status"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "status <> 'rejected'"}}},
+		DoNothing:   true,
+	}).Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *paymentEventRepository) GetByID(id uint) (*model.PaymentEvent, error) {
+	var event model.PaymentEvent
+	if err := r.db.First(&event, id).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *paymentEventRepository) GetByKey(provider model.PaymentProvider, transactionID, transactionStatus string) (*model.PaymentEvent, error) {
+	var event model.PaymentEvent
+	err := r.db.Where("provider = ? AND transaction_id = ? AND transaction_status = ? AND status <> ?",
+		provider, transactionID, transactionStatus, model.PaymentEventRejected).
+		First(&event).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *paymentEventRepository) Update(event *model.PaymentEvent) error {
+	return r.db.Save(event).Error
+}
+
+func (r *paymentEventRepository) GetAll(status model.PaymentEventStatus, limit, offset int) ([]*model.PaymentEvent, error) {
+	var events []*model.PaymentEvent
+	query := r.db.Order("created_at DESC, id DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Limit(limit).Offset(offset).Find(&events).Error
+	return events, err
+}
+
+func (r *paymentEventRepository) Count(status model.PaymentEventStatus) (int64, error) {
+	var count int64
+	query := r.db.Model(&model.PaymentEvent{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"gorm.io/gorm"
+)
+
+type PaymentReconciliationRepository interface {
+	Create(report *model.PaymentReconciliation) error
+	Update(report *model.PaymentReconciliation) error
+	GetOpenByPaymentID(paymentID uint) (*model.PaymentReconciliation, error)
+	GetAll(resolution model.ReconciliationResolution, limit, offset int) ([]*model.PaymentReconciliation, error)
+	Count(resolution model.ReconciliationResolution) (int64, error)
+}
+
+type paymentReconciliationRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentReconciliationRepository(db *gorm.DB) PaymentReconciliationRepository {
+	return &paymentReconciliationRepository{db: db}
+}
+
+func (r *paymentReconciliationRepository) Create(report *model.PaymentReconciliation) error {
+	return r.db.Create(report).Error
+}
+
+func (r *paymentReconciliationRepository) Update(report *model.PaymentReconciliation) error {
+	return r.db.Save(report).Error
+}
+
+// GetOpenByPaymentID returns the latest unresolved or failed report of a
+// payment
+func (r *paymentReconciliationRepository) GetOpenByPaymentID(paymentID uint) (*model.PaymentReconciliation, error) {
+	var report model.PaymentReconciliation
+	err := r.db.Where("payment_id = ? AND resolution IN ?", paymentID,
+		[]model.ReconciliationResolution{model.ReconciliationUnresolved, model.ReconciliationFailed}).
+		Order("id DESC").First(&report).Error
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *paymentReconciliationRepository) GetAll(resolution model.ReconciliationResolution, limit, offset int) ([]*model.PaymentReconciliation, error) {
+	var reports []*model.PaymentReconciliation
+	query := r.db.Preload("Payment").Order("created_at DESC, id DESC")
+	if resolution != "" {
+		query = query.Where("resolution = ?", resolution)
+	}
+	err := query.Limit(limit).Offset(offset).Find(&reports).Error
+	return reports, err
+}
+
+func (r *paymentReconciliationRepository) Count(resolution model.ReconciliationResolution) (int64, error) {
+	var count int64
+	query := r.db.Model(&model.PaymentReconciliation{})
+	if resolution != "" {
+		query = query.Where("resolution = ?", resolution)
+	}
+	err := query.Count(&count).Error
+	return count, err
+}
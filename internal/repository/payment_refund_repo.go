@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"gorm.io/gorm"
+)
+
+type PaymentRefundRepository interface {
+	Create(refund *model.PaymentRefund) error
+	Update(refund *model.PaymentRefund) error
+	GetByPaymentID(paymentID uint) ([]*model.PaymentRefund, error)
+}
+
+type paymentRefundRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentRefundRepository(db *gorm.DB) PaymentRefundRepository {
+	return &paymentRefundRepository{db: db}
+}
+
+func (r *paymentRefundRepository) Create(refund *model.PaymentRefund) error {
+	return r.db.Create(refund).Error
+}
+
+func (r *paymentRefundRepository) Update(refund *model.PaymentRefund) error {
+	return r.db.Save(refund).Error
+}
+
+func (r *paymentRefundRepository) GetByPaymentID(paymentID uint) ([]*model.PaymentRefund, error) {
+	var refunds []*model.PaymentRefund
+	err := r.db.Preload("Actor").Where("payment_id = ?", paymentID).Order("created_at, id").Find(&refunds).Error
+	return refunds, err
+}
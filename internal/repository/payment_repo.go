@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/yoockh/go-game-rental-api/internal/model"
 	"gorm.io/gorm"
 )
@@ -19,10 +22,19 @@ type PaymentRepository interface {
 	GetAllPayments(limit, offset int) ([]*model.Payment, error)
 	CountAllPayments() (int64, error)
 	CountByStatus(status model.PaymentStatus) (int64, error)
+	GetReviewQueue(queue model.ReviewQueue, limit, offset int) ([]*model.Payment, error)
+	CountReviewQueue(queue model.ReviewQueue) (int64, error)
+	GetStalePending(createdBefore time.Time, limit int) ([]*model.Payment, error)
 
 	// Status updates
 	MarkAsPaid(paymentID uint, providerPaymentID string, paymentMethod string) error
 	MarkAsFailed(paymentID uint, failureReason string) error
+	UpdateStatusFrom(paymentID uint, from, to model.PaymentStatus) (bool, error)
+	AdjustRefundedAmount(paymentID uint, delta model.Money) (bool, error)
+	MarkReviewed(paymentID uint, adminID uint) error
+	AttachProof(paymentID uint, proofPath string) (bool, error)
+	AddWalletRefundedAmount(paymentID uint, amount model.Money) error
+	AddChargedBackAmount(paymentID uint, amount model.Money) (bool, error)
 }
 
 type paymentRepository struct {
@@ -49,6 +61,8 @@ func (r *paymentRepository) GetByID(id uint) (*model.Payment, error) {
 func (r *paymentRepository) GetByIDWithRelations(id uint) (*model.Payment, error) {
 	var payment model.Payment
 	err := r.db.Preload("Booking").Preload("Booking.User").Preload("Booking.Game").
+		Preload("Refunds", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).
+		Preload("Order").Preload("Order.User").Preload("Order.Items.Game").
 		Where("id = ?", id).First(&payment).Error
 	if err != nil {
 		return nil, err
@@ -60,9 +74,21 @@ func (r *paymentRepository) Update(payment *model.Payment) error {
 	return r.db.Save(payment).Error
 }
 
+// currentAttempt is a condition on payments, taking the purpose as its argument,
+// that keeps only the authoritative payment attempt of each booking or order
+// (column is booking_id or order_id): the attempt that took the money, or else
+// the latest one
+func currentAttempt(column string) string {
+	return fmt.Sprintf(`payments.purpose = ? AND payments.id = (SELECT p.id FROM payments p
+		WHERE p.%[1]s = payments.%[1]s AND p.purpose = payments.purpose
+		ORDER BY p.status IN ('paid', 'partially_refunded', 'refunded') DESC, p.created_at DESC, p.id DESC
+		LIMIT 1)`, column)
+}
+
+// GetByBookingID returns the booking's authoritative payment attempt
 func (r *paymentRepository) GetByBookingID(bookingID uint) (*model.Payment, error) {
 	var payment model.Payment
-	err := r.db.Where("booking_id = ?", bookingID).First(&payment).Error
+	err := r.db.Where("booking_id = ?", bookingID).Where(currentAttempt("booking_id"), model.PaymentPurposeBooking).First(&payment).Error
 	if err != nil {
 		return nil, err
 	}
@@ -85,6 +111,42 @@ func (r *paymentRepository) GetPaymentsByStatus(status model.PaymentStatus, limi
 	return payments, err
 }
 
+// GetReviewQueue returns the payments in review of one queue, oldest first so
+// they are worked in the order they arrived
+func (r *paymentRepository) GetReviewQueue(queue model.ReviewQueue, limit, offset int) ([]*model.Payment, error) {
+	var payments []*model.Payment
+	err := r.db.Preload("Booking").Scopes(inReviewQueue(queue)).
+		Order("created_at ASC, id ASC").Limit(limit).Offset(offset).Find(&payments).Error
+	return payments, err
+}
+
+func (r *paymentRepository) CountReviewQueue(queue model.ReviewQueue) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Payment{}).Scopes(inReviewQueue(queue)).Count(&count).Error
+	return count, err
+}
+
+// inReviewQueue narrows to the payments in review of one queue: offline
+// payments wait for verification, the others were held by fraud detection
+func inReviewQueue(queue model.ReviewQueue) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("status = ?", model.PaymentReview)
+		if queue == model.ReviewQueueOffline {
+			return db.Where("provider = ?", model.ProviderOffline)
+		}
+		return db.Where("provider <> ?", model.ProviderOffline)
+	}
+}
+
+// GetStalePending returns pending gateway payments created before the cutoff,
+// oldest first
+func (r *paymentRepository) GetStalePending(createdBefore time.Time, limit int) ([]*model.Payment, error) {
+	var payments []*model.Payment
+	err := r.db.Where("status = ? AND provider_payment_id IS NOT NULL AND created_at < ?", model.PaymentPending, createdBefore).
+		Order("created_at ASC").Limit(limit).Find(&payments).Error
+	return payments, err
+}
+
 func (r *paymentRepository) MarkAsPaid(paymentID uint, providerPaymentID string, paymentMethod string) error {
 	return r.db.Model(&model.Payment{}).Where("id = ?", paymentID).Updates(map[string]interface{}{
 		"status":              model.PaymentPaid,
@@ -102,6 +164,88 @@ func (r *paymentRepository) MarkAsFailed(paymentID uint, failureReason string) e
 	}).Error
 }
 
+// UpdateStatusFrom changes the status only if the payment is still in the
+// expected status, stamping paid_at or failed_at, and reports false otherwise
+func (r *paymentRepository) UpdateStatusFrom(paymentID uint, from, to model.PaymentStatus) (bool, error) {
+	updates := map[string]interface{}{"status": to}
+	switch to {
+	case model.PaymentPaid:
+		updates["paid_at"] = gorm.Expr("CURRENT_TIMESTAMP")
+	case model.PaymentFailed:
+		updates["failed_at"] = gorm.Expr("CURRENT_TIMESTAMP")
+	}
+
+	result := r.db.Model(&model.Payment{}).Where("id = ? AND status = ?", paymentID, from).Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// MarkReviewed records the admin who decided a fraud review
+func (r *paymentRepository) MarkReviewed(paymentID uint, adminID uint) error {
+	return r.db.Model(&model.Payment{}).Where("id = ?", paymentID).Updates(map[string]interface{}{
+		"reviewed_by": adminID,
+		"reviewed_at": gorm.Expr("CURRENT_TIMESTAMP"),
+	}).Error
+}
+
+// AttachProof stores the transfer receipt of an offline payment waiting for
+// verification, and reports false once the payment has been decided
+func (r *paymentRepository) AttachProof(paymentID uint, proofPath string) (bool, error) {
+	result := r.db.Model(&model.Payment{}).Where("id = ? AND status = ?", paymentID, model.PaymentReview).Updates(map[string]interface{}{
+		"proof_path":        proofPath,
+		"proof_uploaded_at": gorm.Expr("CURRENT_TIMESTAMP"),
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// AdjustRefundedAmount adds delta (negative to give it back) to the refunded
+// amount of a paid payment and derives its status from the result: refunded
+// once refunds and chargebacks together take back the whole amount. It reports
+// false when the refunded amount would leave the 0..amount range, less what was
+// charged back, so concurrent refunds can never exceed the payment.
+func (r *paymentRepository) AdjustRefundedAmount(paymentID uint, delta model.Money) (bool, error) {
+	result := r.db.Model(&model.Payment{}).
+		Where("id = ? AND status IN ?", paymentID, []model.PaymentStatus{model.PaymentPaid, model.PaymentPartiallyRefunded, model.PaymentRefunded}).
+		Where("refunded_amount + ? >= 0 AND refunded_amount + charged_back_amount + ? <= amount", delta, delta).
+		Updates(map[string]interface{}{
+			"refunded_amount": gorm.Expr("refunded_amount + ?", delta),
+			"status": gorm.Expr(`CASE WHEN refunded_amount + charged_back_amount + ? >= amount THEN ?::payment_status
+				WHEN refunded_amount + ? > 0 THEN ?::payment_status
+				ELSE ?::payment_status END`,
+				delta, model.PaymentRefunded, delta, model.PaymentPartiallyRefunded, model.PaymentPaid),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// AddChargedBackAmount records that amount of a paid payment was taken back
+// through a lost dispute. Like refunds it reports false when the payment has
+// not that much left, so a payment is never refunded and charged back twice.
+func (r *paymentRepository) AddChargedBackAmount(paymentID uint, amount model.Money) (bool, error) {
+	result := r.db.Model(&model.Payment{}).
+		Where("id = ? AND status IN ?", paymentID, []model.PaymentStatus{model.PaymentPaid, model.PaymentPartiallyRefunded}).
+		Where("refunded_amount + charged_back_amount + ? <= amount", amount).
+		Update("charged_back_amount", gorm.Expr("charged_back_amount + ?", amount))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// AddWalletRefundedAmount records that part of the refunded amount was
+// credited to the customer's wallet
+func (r *paymentRepository) AddWalletRefundedAmount(paymentID uint, amount model.Money) error {
+	return r.db.Model(&model.Payment{}).Where("id = ?", paymentID).
+		Update("wallet_refunded_amount", gorm.Expr("wallet_refunded_amount + ?", amount)).Error
+}
+
 func (r *paymentRepository) GetAllPayments(limit, offset int) ([]*model.Payment, error) {
 	var payments []*model.Payment
 	err := r.db.Preload("Booking").Order("created_at DESC").
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PromoCodeRepository interface {
+	// Basic CRUD
+	Create(promo *model.PromoCode) error
+	GetByID(id uint) (*model.PromoCode, error)
+	GetByCode(code string) (*model.PromoCode, error)
+	GetAll(limit, offset int) ([]*model.PromoCode, error)
+	Count() (int64, error)
+	Update(promo *model.PromoCode) error
+	Delete(id uint) error
+
+	// Redemption
+	LockByCode(code string) (*model.PromoCode, error)
+	CountUses(promoID uint) (int64, error)
+	CountUserUses(promoID, userID uint) (int64, error)
+	IsReferenced(promoID uint) (bool, error)
+}
+
+type promoCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewPromoCodeRepository(db *gorm.DB) PromoCodeRepository {
+	return &promoCodeRepository{db: db}
+}
+
+// usingBookings selects the bookings that count against a code's limits
+func (r *promoCodeRepository) usingBookings(promoID uint) *gorm.DB {
+	return r.db.Model(&model.Booking{}).Where("promo_code_id = ? AND status <> ?", promoID, model.BookingCancelled)
+}
+
+func (r *promoCodeRepository) Create(promo *model.PromoCode) error {
+	return r.db.Create(promo).Error
+}
+
+func (r *promoCodeRepository) GetByID(id uint) (*model.PromoCode, error) {
+	var promo model.PromoCode
+	if err := r.db.First(&promo, id).Error; err != nil {
+		return nil, err
+	}
+	return &promo, nil
+}
+
+func (r *promoCodeRepository) GetByCode(code string) (*model.PromoCode, error) {
+	var promo model.PromoCode
+	if err := r.db.Where("code = ?", code).First(&promo).Error; err != nil {
+		return nil, err
+	}
+	return &promo, nil
+}
+
+func (r *promoCodeRepository) GetAll(limit, offset int) ([]*model.PromoCode, error) {
+	var promos []*model.PromoCode
+	err := r.db.Select("promo_codes.*, (SELECT COUNT(*) FROM bookings b WHERE b.promo_code_id = promo_codes.id AND b.status <> ?) AS used_count", model.BookingCancelled).
+		Order("created_at DESC").Limit(limit).Offset(offset).Find(&promos).Error
+	return promos, err
+}
+
+func (r *promoCodeRepository) Count() (int64, error) {
+	var count int64
+	err := r.db.Model(&model.PromoCode{}).Count(&count).Error
+	return count, err
+}
+
+func (r *promoCodeRepository) Update(promo *model.PromoCode) error {
+	return r.db.Save(promo).Error
+}
+
+func (r *promoCodeRepository) Delete(id uint) error {
+	return r.db.Delete(&model.PromoCode{}, id).Error
+}
+
+// LockByCode loads a code with a row lock so concurrent bookings check its
+// limits one at a time. Only meaningful inside a transaction.
+func (r *promoCodeRepository) LockByCode(code string) (*model.PromoCode, error) {
+	var promo model.PromoCode
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&promo).Error; err != nil {
+		return nil, err
+	}
+	return &promo, nil
+}
+
+func (r *promoCodeRepository) CountUses(promoID uint) (int64, error) {
+	var count int64
+	err := r.usingBookings(promoID).Count(&count).Error
+	return count, err
+}
+
+func (r *promoCodeRepository) CountUserUses(promoID, userID uint) (int64, error) {
+	var count int64
+	err := r.usingBookings(promoID).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// IsReferenced reports whether any booking, cancelled ones included, used the code
+func (r *promoCodeRepository) IsReferenced(promoID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Booking{}).Where("promo_code_id = ?", promoID).Limit(1).Count(&count).Error
+	return count > 0, err
+}
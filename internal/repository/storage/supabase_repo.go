@@ -3,6 +3,7 @@ package storage
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -22,6 +23,9 @@ type StorageRepository interface {
 	UploadFile(ctx context.Context, destinationPath string, fileName string, contentType string, data []byte) (string, error)
 	DeleteFile(ctx context.Context, destinationPath string) error
 	GetPublicURL(path string) string
+	// CreateSignedURL returns a link to a stored file that expires after expiresIn,
+	// for files in a private bucket
+	CreateSignedURL(ctx context.Context, path string, expiresIn time.Duration) (string, error)
 }
 
 type SupabaseRepository struct {
@@ -33,9 +37,18 @@ type SupabaseRepository struct {
 }
 
 func NewSupabaseRepository() (*SupabaseRepository, error) {
+	return newSupabaseRepository(os.Getenv("SUPABASE_STORAGE_BUCKET"))
+}
+
+// NewSupabasePrivateRepository stores files in SUPABASE_PRIVATE_BUCKET, a bucket
+// without public access whose files are only reachable through signed URLs
+func NewSupabasePrivateRepository() (*SupabaseRepository, error) {
+	return newSupabaseRepository(os.Getenv("SUPABASE_PRIVATE_BUCKET"))
+}
+
+func newSupabaseRepository(bucket string) (*SupabaseRepository, error) {
 	baseURL := os.Getenv("SUPABASE_URL")
 	apiKey := os.Getenv("SUPABASE_SERVICE_KEY")
-	bucket := os.Getenv("SUPABASE_STORAGE_BUCKET")
 
 	if baseURL == "" || apiKey == "" || bucket == "" {
 		return nil, fmt.Errorf("supabase not configured: missing URL, SERVICE_KEY, or BUCKET")
@@ -162,6 +175,43 @@ func (s *SupabaseRepository) GetPublicURL(path string) string {
 	return fmt.Sprintf("%s/%s", s.publicURL, path)
 }
 
+func (s *SupabaseRepository) CreateSignedURL(ctx context.Context, path string, expiresIn time.Duration) (string, error) {
+	body, err := json.Marshal(map[string]int{"expiresIn": int(expiresIn.Seconds())})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode sign request: %w", err)
+	}
+
+	signURL := fmt.Sprintf("%s/storage/v1/object/sign/%s/%s", s.baseURL, s.bucket, path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, signURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create sign request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("apiKey", s.apiKey)
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		logrus.WithError(err).WithField("path", path).Error("Supabase sign failed")
+		return "", fmt.Errorf("sign request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("sign failed: status=%d body=%s", resp.StatusCode, string(respBody))
+	}
+
+	var signed struct {
+		SignedURL string `json:"signedURL"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&signed); err != nil {
+		return "", fmt.Errorf("failed to decode sign response: %w", err)
+	}
+	// The signed path is relative to the storage API
+	return fmt.Sprintf("%s/storage/v1%s", s.baseURL, signed.SignedURL), nil
+}
+
 type MockStorageRepository struct {
 	UploadedFiles []MockFile
 }
@@ -189,4 +239,8 @@ func (m *MockStorageRepository) DeleteFile(ctx context.Context, destinationPath
 
 func (m *MockStorageRepository) GetPublicURL(path string) string {
 	return "https://mock-storage.com/" + path
+}
+
+func (m *MockStorageRepository) CreateSignedURL(ctx context.Context, path string, expiresIn time.Duration) (string, error) {
+	return "https://mock-storage.com/" + path + "?token=mock", nil
 }
\ No newline at end of file
@@ -4,19 +4,117 @@ import (
 	"context"
 	"crypto/sha512"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	midtrans "github.com/midtrans/midtrans-go"
 	"github.com/midtrans/midtrans-go/coreapi"
 	"github.com/sirupsen/logrus"
+	"github.com/yoockh/go-game-rental-api/internal/model"
 )
 
+// ErrInvalidSignature is returned by ParseNotification when the notification
+// was not signed by the provider
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// TransactionRepository is a payment gateway. There is one implementation per
+// provider, looked up through a Registry.
 type TransactionRepository interface {
-	CreateCharge(ctx context.Context, orderID string, grossAmount int64, paymentType string, params map[string]interface{}) (string, string, error)
+	// CreateCharge creates a charge and returns its provider transaction ID with
+	// the instructions the customer needs to pay it
+	CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error)
+	// GetStatus returns the provider's own status of the transaction, see MapStatus
 	GetStatus(ctx context.Context, transactionID string) (string, error)
-	VerifyNotification(orderID, statusCode, grossAmount, signatureKey string) bool
+	// ParseNotification decodes and authenticates a webhook request. On
+	// ErrInvalidSignature the decoded notification is returned as well.
+	ParseNotification(header http.Header, body []byte) (*Notification, error)
+	// MapStatus maps a provider status to paid, pending, failed, refunded,
+	// review (held by fraud detection) or, for chargebacks, DisputeStatusPrefix
+	// followed by the dispute status. Anything else is returned unchanged.
+	MapStatus(providerStatus string) string
+	Refund(ctx context.Context, transactionID string, refundKey string, amount model.Money, reason string) (string, error)
+	// CancelCharge stops the customer from paying a charge we no longer
+	// accept, e.g. once its payment attempt has been failed on our side
+	CancelCharge(ctx context.Context, transactionID string) error
+}
+
+// FraudReviewer is implemented by gateways whose fraud detection can hold a
+// payment for review. The decision is reported back like any other status
+// change.
+type FraudReviewer interface {
+	ApproveTransaction(ctx context.Context, transactionID string) error
+	DenyTransaction(ctx context.Context, transactionID string) error
+}
+
+// DisputeStatusPrefix starts the statuses MapStatus returns for chargebacks,
+// followed by a model.DisputeStatus, e.g. "dispute_lost"
+const DisputeStatusPrefix = "dispute_"
+
+// DisputeParser is implemented by gateways whose dispute notifications carry
+// more than the transaction they are about. Without it a dispute is known by
+// its transaction ID.
+type DisputeParser interface {
+	ParseDispute(payload []byte) (*Dispute, error)
+}
+
+// DisputeResponder is implemented by gateways that take dispute evidence
+// through their API. Evidence for other gateways is sent outside the API and
+// only recorded.
+type DisputeResponder interface {
+	SubmitDisputeEvidence(ctx context.Context, disputeID string, evidence string) error
+}
+
+// Dispute is a chargeback as described by a dispute notification
+type Dispute struct {
+	ID            string
+	Reason        string
+	EvidenceDueBy *time.Time // nil when the provider gave no deadline
+}
+
+// ChargeRequest describes a charge to create
+type ChargeRequest struct {
+	OrderID     string
+	Amount      model.Money
+	PaymentType string
+	Items       []ChargeItem    // what is paid for, adding up to Amount
+	Customer    *ChargeCustomer // nil when unknown
+	ExpiresAt   *time.Time      // when to stop accepting payment, nil for the provider default
+}
+
+// ChargeItem is a line of a charge. Discounts are lines with a negative price.
+type ChargeItem struct {
+	ID       string
+	Name     string
+	Price    model.Money // per unit
+	Quantity int
+}
+
+// ChargeCustomer is who pays a charge
+type ChargeCustomer struct {
+	Name    string
+	Email   string
+	Phone   string
+	Address string
+}
+
+// Charge is a charge created with a provider
+type Charge struct {
+	TransactionID string
+	Instructions  *model.PaymentInstructions // nil when the provider returned nothing to act on
+}
+
+// Notification is a payment notification decoded by a provider
+type Notification struct {
+	TransactionID     string // the reference stored as the payment's provider_payment_id
+	OrderID           string // the order ID the charge was created with
+	TransactionStatus string // the provider's own status, see MapStatus
+	GrossAmount       string // in major currency units, as sent
 }
 
 type MidtransRepository struct {
@@ -45,44 +143,183 @@ func NewMidtransRepository() (*MidtransRepository, error) {
 	}, nil
 }
 
-func (m *MidtransRepository) CreateCharge(ctx context.Context, orderID string, grossAmount int64, paymentType string, params map[string]interface{}) (string, string, error) {
+func (m *MidtransRepository) CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error) {
 	_ = ctx // ctx unused - Midtrans SDK doesn't support context
+	grossAmount, err := midtransAmount(req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
 	// Log unknown payment types but allow them
 	knownTypes := map[string]bool{
 		"credit_card": true, "bank_transfer": true, "echannel": true,
 		"gopay": true, "shopeepay": true, "qris": true,
 	}
-	if !knownTypes[paymentType] {
-		logrus.WithField("payment_type", paymentType).Warn("Unknown payment type, proceeding anyway")
+	if !knownTypes[req.PaymentType] {
+		logrus.WithField("payment_type", req.PaymentType).Warn("Unknown payment type, proceeding anyway")
 	}
 
-	req := &coreapi.ChargeReq{
-		PaymentType: coreapi.CoreapiPaymentType(paymentType),
+	chargeReq := &coreapi.ChargeReq{
+		PaymentType: coreapi.CoreapiPaymentType(req.PaymentType),
 		TransactionDetails: midtrans.TransactionDetails{
-			OrderID:  orderID,
+			OrderID:  req.OrderID,
 			GrossAmt: grossAmount,
 		},
+		Items:           midtransItems(req.Items, grossAmount),
+		CustomerDetails: midtransCustomer(req.Customer),
+		CustomExpiry:    midtransExpiry(req.ExpiresAt, time.Now()),
 	}
 
-	resp, err := m.core.ChargeTransaction(req)
+	resp, err := m.core.ChargeTransaction(chargeReq)
 	if err != nil {
-		logrus.WithError(err).WithField("order_id", orderID).Error("Midtrans charge failed")
-		return "", "", fmt.Errorf("payment gateway error: %w", err)
+		logrus.WithError(err).WithField("order_id", req.OrderID).Error("Midtrans charge failed")
+		return nil, fmt.Errorf("payment gateway error: %w", err)
 	}
 
 	logrus.WithFields(logrus.Fields{
-		"order_id":           orderID,
+		"order_id":           req.OrderID,
 		"transaction_id":     resp.TransactionID,
 		"transaction_status": resp.TransactionStatus,
 		"fraud_status":       resp.FraudStatus,
 	}).Info("Midtrans charge created")
 
-	var redirect string
-	if resp.RedirectURL != "" {
-		redirect = resp.RedirectURL
+	return &Charge{TransactionID: resp.TransactionID, Instructions: midtransInstructions(resp)}, nil
+}
+
+// midtransMaxText is the longest item ID or name Midtrans accepts
+const midtransMaxText = 50
+
+// midtransItems converts the charge lines. Midtrans rejects a charge whose
+// items do not add up to the gross amount, so lines that cannot be sent exactly
+// are left out altogether rather than failing the charge.
+func midtransItems(items []ChargeItem, grossAmount int64) *[]midtrans.ItemDetails {
+	if len(items) == 0 {
+		return nil
+	}
+
+	details := make([]midtrans.ItemDetails, 0, len(items))
+	var total int64
+	for _, item := range items {
+		price, err := midtransAmount(item.Price)
+		if err != nil || item.Quantity < 1 {
+			logrus.WithField("item_id", item.ID).Warn("Charge item cannot be sent to Midtrans, leaving item details out")
+			return nil
+		}
+		details = append(details, midtrans.ItemDetails{
+			ID:    truncate(item.ID, midtransMaxText),
+			Name:  truncate(item.Name, midtransMaxText),
+			Price: price,
+			Qty:   int32(item.Quantity),
+		})
+		total += price * int64(item.Quantity)
+	}
+	if total != grossAmount {
+		logrus.WithFields(logrus.Fields{"items_total": total, "gross_amount": grossAmount}).
+			Warn("Charge items do not add up to the amount, leaving item details out")
+		return nil
+	}
+	return &details
+}
+
+func midtransCustomer(customer *ChargeCustomer) *midtrans.CustomerDetails {
+	if customer == nil {
+		return nil
+	}
+
+	firstName, lastName, _ := strings.Cut(strings.TrimSpace(customer.Name), " ")
+	details := &midtrans.CustomerDetails{
+		FName: firstName,
+		LName: strings.TrimSpace(lastName),
+		Email: customer.Email,
+		Phone: customer.Phone,
 	}
+	if customer.Address != "" {
+		details.BillAddr = &midtrans.CustomerAddress{
+			FName:       details.FName,
+			LName:       details.LName,
+			Phone:       customer.Phone,
+			Address:     customer.Address,
+			CountryCode: "IDN",
+		}
+	}
+	return details
+}
 
-	return resp.TransactionID, redirect, nil
+// midtransExpiry makes the charge expire at expiresAt, rounded up to the
+// minute. Midtrans counts the duration from order_time.
+func midtransExpiry(expiresAt *time.Time, now time.Time) *coreapi.CustomExpiry {
+	if expiresAt == nil {
+		return nil
+	}
+
+	minutes := int((expiresAt.Sub(now) + time.Minute - 1) / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+	return &coreapi.CustomExpiry{
+		OrderTime:      now.In(jakarta).Format(midtransOrderTimeLayout),
+		ExpiryDuration: minutes,
+		Unit:           "minute",
+	}
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
+
+// midtransAmount converts an amount for Midtrans, which only takes whole rupiah.
+// A fraction is an error rather than being truncated away.
+func midtransAmount(amount model.Money) (int64, error) {
+	if amount.Currency() != model.CurrencyIDR {
+		return 0, fmt.Errorf("midtrans only charges IDR, got %s", amount.Currency())
+	}
+	whole, exact := amount.Whole()
+	if !exact {
+		return 0, fmt.Errorf("midtrans only charges whole rupiah, got %s", amount)
+	}
+	return whole, nil
+}
+
+// midtransExpiryLayout is how Midtrans formats expiry_time, in Jakarta time
+const midtransExpiryLayout = "2006-01-02 15:04:05"
+
+// midtransOrderTimeLayout is the custom_expiry order_time format
+const midtransOrderTimeLayout = "2006-01-02 15:04:05 -0700"
+
+var jakarta = time.FixedZone("WIB", 7*60*60)
+
+// midtransInstructions collects what the customer needs from a charge response:
+// VA numbers for bank transfers, bill key for Mandiri, payment code for
+// convenience stores, QR string and deeplinks for QRIS and e-wallets
+func midtransInstructions(resp *coreapi.ChargeResponse) *model.PaymentInstructions {
+	instructions := &model.PaymentInstructions{
+		RedirectURL: resp.RedirectURL,
+		BillKey:     resp.BillKey,
+		BillerCode:  resp.BillerCode,
+		PaymentCode: resp.PaymentCode,
+		QRString:    resp.QRString,
+	}
+	for _, va := range resp.VaNumbers {
+		instructions.VANumbers = append(instructions.VANumbers, model.VirtualAccount{Bank: va.Bank, Number: va.VANumber})
+	}
+	if resp.PermataVaNumber != "" {
+		instructions.VANumbers = append(instructions.VANumbers, model.VirtualAccount{Bank: "permata", Number: resp.PermataVaNumber})
+	}
+	for _, action := range resp.Actions {
+		instructions.Actions = append(instructions.Actions, model.PaymentAction{Name: action.Name, Method: action.Method, URL: action.URL})
+	}
+	if resp.ExpiryTime != "" {
+		if expiresAt, err := time.ParseInLocation(midtransExpiryLayout, resp.ExpiryTime, jakarta); err == nil {
+			instructions.ExpiresAt = &expiresAt
+		} else {
+			logrus.WithError(err).WithField("expiry_time", resp.ExpiryTime).Warn("Unparseable Midtrans expiry time")
+		}
+	}
+	return instructions
 }
 
 func (m *MidtransRepository) GetStatus(ctx context.Context, transactionID string) (string, error) {
@@ -92,44 +329,180 @@ func (m *MidtransRepository) GetStatus(ctx context.Context, transactionID string
 		logrus.WithError(err).WithField("transaction_id", transactionID).Error("Midtrans status check failed")
 		return "", fmt.Errorf("failed to check payment status: %w", err)
 	}
-	return resp.TransactionStatus, nil
+	return midtransStatus(resp.TransactionStatus, resp.FraudStatus), nil
+}
+
+// ApproveTransaction accepts a card payment Midtrans' fraud detection challenged
+func (m *MidtransRepository) ApproveTransaction(ctx context.Context, transactionID string) error {
+	_ = ctx // ctx unused - Midtrans SDK doesn't support context
+	if _, err := m.core.ApproveTransaction(transactionID); err != nil {
+		logrus.WithError(err).WithField("transaction_id", transactionID).Error("Midtrans approve failed")
+		return fmt.Errorf("failed to approve payment: %w", err)
+	}
+	return nil
+}
+
+// DenyTransaction rejects a card payment Midtrans' fraud detection challenged
+func (m *MidtransRepository) DenyTransaction(ctx context.Context, transactionID string) error {
+	_ = ctx // ctx unused - Midtrans SDK doesn't support context
+	if _, err := m.core.DenyTransaction(transactionID); err != nil {
+		logrus.WithError(err).WithField("transaction_id", transactionID).Error("Midtrans deny failed")
+		return fmt.Errorf("failed to deny payment: %w", err)
+	}
+	return nil
+}
+
+// CancelCharge expires a pending transaction, so its VA number or payment code
+// stops taking payments
+func (m *MidtransRepository) CancelCharge(ctx context.Context, transactionID string) error {
+	_ = ctx // ctx unused - Midtrans SDK doesn't support context
+	if _, err := m.core.ExpireTransaction(transactionID); err != nil {
+		logrus.WithError(err).WithField("transaction_id", transactionID).Error("Midtrans expire failed")
+		return fmt.Errorf("failed to cancel payment: %w", err)
+	}
+	return nil
 }
 
+func (m *MidtransRepository) ParseNotification(header http.Header, body []byte) (*Notification, error) {
+	notification, statusCode, signatureKey, err := parseMidtransNotification(body)
+	if err != nil {
+		return nil, err
+	}
+	if signatureKey == "" || !m.VerifyNotification(notification.OrderID, statusCode, notification.GrossAmount, signatureKey) {
+		return notification, ErrInvalidSignature
+	}
+	return notification, nil
+}
+
+func (m *MidtransRepository) MapStatus(providerStatus string) string {
+	return MapStatusToInternal(providerStatus)
+}
+
+// VerifyNotification checks signature_key, which Midtrans computes as
+// SHA512(order_id + status_code + gross_amount + server key)
 func (m *MidtransRepository) VerifyNotification(orderID, statusCode, grossAmount, signatureKey string) bool {
 	sum := sha512.Sum512([]byte(orderID + statusCode + grossAmount + m.serverKey))
 	expected := hex.EncodeToString(sum[:])
 	return strings.EqualFold(expected, signatureKey)
 }
 
+// Refund returns part or all of a settled transaction. refundKey makes retries
+// idempotent on Midtrans' side; the returned string is the provider's refund reference.
+func (m *MidtransRepository) Refund(ctx context.Context, transactionID string, refundKey string, amount model.Money, reason string) (string, error) {
+	_ = ctx // ctx unused - Midtrans SDK doesn't support context
+	refundAmount, err := midtransAmount(amount)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := m.core.RefundTransaction(transactionID, &coreapi.RefundReq{
+		RefundKey: refundKey,
+		Amount:    refundAmount,
+		Reason:    reason,
+	})
+	if err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"transaction_id": transactionID,
+			"refund_key":     refundKey,
+			"amount":         amount.String(),
+		}).Error("Midtrans refund failed")
+		return "", fmt.Errorf("refund failed: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"transaction_id": transactionID,
+		"refund_key":     resp.RefundKey,
+		"refund_amount":  resp.RefundAmount,
+	}).Info("Midtrans refund created")
+
+	if resp.RefundChargebackUUID != "" {
+		return resp.RefundChargebackUUID, nil
+	}
+	return refundKey, nil
+}
+
 type MockTransactionRepository struct {
-	Charges []MockCharge
+	Charges   []ChargeRequest
+	Refunds   []MockRefund
+	Reviews   []MockReview
+	Cancelled []string
 }
 
-type MockCharge struct {
-	OrderID     string
-	Amount      int64
-	PaymentType string
-	Params      map[string]interface{}
+type MockReview struct {
+	TransactionID string
+	Approved      bool
+}
+
+type MockRefund struct {
+	TransactionID string
+	RefundKey     string
+	Amount        model.Money
+	Reason        string
 }
 
-func (m *MockTransactionRepository) CreateCharge(ctx context.Context, orderID string, grossAmount int64, paymentType string, params map[string]interface{}) (string, string, error) {
+func (m *MockTransactionRepository) CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error) {
 	_ = ctx // ctx unused in mock
-	m.Charges = append(m.Charges, MockCharge{
-		OrderID:     orderID,
-		Amount:      grossAmount,
-		PaymentType: paymentType,
-		Params:      params,
-	})
-	return "mock-tx-" + orderID, "https://mock-payment.com/redirect", nil
+	m.Charges = append(m.Charges, req)
+	expiresAt := time.Now().Add(24 * time.Hour)
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+	return &Charge{
+		TransactionID: "mock-tx-" + req.OrderID,
+		Instructions: &model.PaymentInstructions{
+			RedirectURL: "https://mock-payment.com/redirect",
+			VANumbers:   []model.VirtualAccount{{Bank: "bca", Number: "12345" + req.OrderID}},
+			ExpiresAt:   &expiresAt,
+		},
+	}, nil
 }
 
 func (m *MockTransactionRepository) GetStatus(ctx context.Context, transactionID string) (string, error) {
 	_ = ctx // ctx unused in mock
-	return "paid", nil // Always paid for testing
+	return "pending", nil // Never settles, so reconciliation leaves mock payments alone
 }
 
-func (m *MockTransactionRepository) VerifyNotification(orderID, statusCode, grossAmount, signatureKey string) bool {
-	return true // Always valid for testing
+// ParseNotification rejects every notification: without a server key the
+// mock cannot tell a real notification from a forged one
+func (m *MockTransactionRepository) ParseNotification(header http.Header, body []byte) (*Notification, error) {
+	notification, _, _, err := parseMidtransNotification(body)
+	if err != nil {
+		return nil, err
+	}
+	return notification, ErrInvalidSignature
+}
+
+func (m *MockTransactionRepository) MapStatus(providerStatus string) string {
+	return MapStatusToInternal(providerStatus)
+}
+
+func (m *MockTransactionRepository) ApproveTransaction(ctx context.Context, transactionID string) error {
+	_ = ctx // ctx unused in mock
+	m.Reviews = append(m.Reviews, MockReview{TransactionID: transactionID, Approved: true})
+	return nil
+}
+
+func (m *MockTransactionRepository) DenyTransaction(ctx context.Context, transactionID string) error {
+	_ = ctx // ctx unused in mock
+	m.Reviews = append(m.Reviews, MockReview{TransactionID: transactionID})
+	return nil
+}
+
+func (m *MockTransactionRepository) Refund(ctx context.Context, transactionID string, refundKey string, amount model.Money, reason string) (string, error) {
+	_ = ctx // ctx unused in mock
+	m.Refunds = append(m.Refunds, MockRefund{
+		TransactionID: transactionID,
+		RefundKey:     refundKey,
+		Amount:        amount,
+		Reason:        reason,
+	})
+	return "mock-refund-" + refundKey, nil
+}
+
+func (m *MockTransactionRepository) CancelCharge(ctx context.Context, transactionID string) error {
+	_ = ctx // ctx unused in mock
+	m.Cancelled = append(m.Cancelled, transactionID)
+	return nil
 }
 
 // MapStatusToInternal maps Midtrans status to internal status
@@ -137,11 +510,67 @@ func MapStatusToInternal(midtransStatus string) string {
 	switch midtransStatus {
 	case "capture", "settlement":
 		return "paid"
+	case "challenge":
+		return "review"
 	case "pending":
 		return "pending"
 	case "deny", "cancel", "expire", "failure":
 		return "failed"
+	case "refund", "partial_refund":
+		return "refunded"
+	case "chargeback", "partial_chargeback":
+		// Midtrans only reports chargebacks once the bank has decided them
+		return DisputeStatusPrefix + string(model.DisputeLost)
 	default:
 		return midtransStatus
 	}
-}
\ No newline at end of file
+}
+
+// parseMidtransNotification decodes a Midtrans notification body, returning
+// the fields its signature covers alongside
+func parseMidtransNotification(body []byte) (*Notification, string, string, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, "", "", fmt.Errorf("invalid notification payload: %w", err)
+	}
+
+	notification := &Notification{
+		TransactionID:     stringField(data, "transaction_id"),
+		OrderID:           stringField(data, "order_id"),
+		TransactionStatus: midtransStatus(stringField(data, "transaction_status"), stringField(data, "fraud_status")),
+		GrossAmount:       stringField(data, "gross_amount"),
+	}
+	if notification.OrderID == "" {
+		return nil, "", "", errors.New("missing order_id in notification")
+	}
+	if notification.TransactionStatus == "" {
+		return nil, "", "", errors.New("missing transaction_status in notification")
+	}
+	if notification.TransactionID == "" {
+		notification.TransactionID = notification.OrderID
+	}
+
+	return notification, stringField(data, "status_code"), stringField(data, "signature_key"), nil
+}
+
+// midtransStatus reports a card capture that fraud detection challenged as
+// "challenge", so it is never taken as paid. Once the challenge is approved
+// Midtrans sends the capture again with fraud_status accept.
+func midtransStatus(transactionStatus, fraudStatus string) string {
+	if transactionStatus == "capture" && fraudStatus == "challenge" {
+		return "challenge"
+	}
+	return transactionStatus
+}
+
+// stringField reads a notification field that should be a string. Numbers
+// sent unquoted are formatted back as sent.
+func stringField(data map[string]interface{}, key string) string {
+	switch v := data[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
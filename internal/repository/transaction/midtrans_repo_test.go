@@ -0,0 +1,113 @@
+package transaction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/midtrans/midtrans-go/coreapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+)
+
+func TestMidtransInstructions(t *testing.T) {
+	t.Run("bank transfer", func(t *testing.T) {
+		instructions := midtransInstructions(&coreapi.ChargeResponse{
+			VaNumbers:  []coreapi.VANumber{{Bank: "bca", VANumber: "12345678901"}},
+			ExpiryTime: "2026-10-18 10:00:00",
+		})
+
+		require.Len(t, instructions.VANumbers, 1)
+		assert.Equal(t, "bca", instructions.VANumbers[0].Bank)
+		assert.Equal(t, "12345678901", instructions.VANumbers[0].Number)
+		require.NotNil(t, instructions.ExpiresAt)
+		assert.True(t, instructions.ExpiresAt.Equal(time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC)), "expiry is in Jakarta time")
+	})
+
+	t.Run("e-wallet", func(t *testing.T) {
+		instructions := midtransInstructions(&coreapi.ChargeResponse{
+			QRString: "00020101021126",
+			Actions: []coreapi.Action{
+				{Name: "generate-qr-code", Method: "GET", URL: "https://api.midtrans.com/qr"},
+				{Name: "deeplink-redirect", Method: "GET", URL: "gojek://gopay/merchanttransfer"},
+			},
+		})
+
+		assert.Equal(t, "00020101021126", instructions.QRString)
+		require.Len(t, instructions.Actions, 2)
+		assert.Equal(t, "deeplink-redirect", instructions.Actions[1].Name)
+		assert.Nil(t, instructions.ExpiresAt)
+	})
+
+	t.Run("permata", func(t *testing.T) {
+		instructions := midtransInstructions(&coreapi.ChargeResponse{PermataVaNumber: "8562000087926752"})
+
+		require.Len(t, instructions.VANumbers, 1)
+		assert.Equal(t, "permata", instructions.VANumbers[0].Bank)
+	})
+}
+
+func TestMidtransItems(t *testing.T) {
+	items := []ChargeItem{
+		{ID: "game-1", Name: "The Legend of Zelda: Tears of the Kingdom (Nintendo Switch) rental", Price: model.NewMoney(50000), Quantity: 3},
+		{ID: "deposit-1", Name: "Security deposit", Price: model.NewMoney(100000), Quantity: 1},
+		{ID: "promo-2", Name: "Promo discount", Price: model.NewMoney(-15000), Quantity: 1},
+	}
+
+	details := midtransItems(items, 235000)
+	require.NotNil(t, details)
+	require.Len(t, *details, 3)
+	assert.Len(t, []rune((*details)[0].Name), midtransMaxText)
+	assert.Equal(t, int32(3), (*details)[0].Qty)
+	assert.Equal(t, int64(-15000), (*details)[2].Price)
+
+	assert.Nil(t, midtransItems(items, 250000), "items that do not add up are left out")
+	assert.Nil(t, midtransItems([]ChargeItem{{ID: "x", Name: "x", Price: model.MoneyFromCents(150), Quantity: 1}}, 1), "fractions are left out")
+}
+
+func TestMidtransCustomer(t *testing.T) {
+	details := midtransCustomer(&ChargeCustomer{Name: "Budi Santoso Putra", Email: "budi@example.com", Phone: "0812", Address: "Jl. Merdeka 1"})
+
+	assert.Equal(t, "Budi", details.FName)
+	assert.Equal(t, "Santoso Putra", details.LName)
+	assert.Equal(t, "budi@example.com", details.Email)
+	require.NotNil(t, details.BillAddr)
+	assert.Equal(t, "Jl. Merdeka 1", details.BillAddr.Address)
+
+	assert.Nil(t, midtransCustomer(&ChargeCustomer{Name: "Budi"}).BillAddr)
+	assert.Nil(t, midtransCustomer(nil))
+}
+
+func TestMidtransExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 17, 3, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(30*time.Minute + 10*time.Second)
+
+	expiry := midtransExpiry(&expiresAt, now)
+	require.NotNil(t, expiry)
+	assert.Equal(t, "2026-10-17 10:00:00 +0700", expiry.OrderTime)
+	assert.Equal(t, 31, expiry.ExpiryDuration, "rounded up so the charge outlives the hold")
+	assert.Equal(t, "minute", expiry.Unit)
+
+	assert.Nil(t, midtransExpiry(nil, now))
+}
+
+func TestParseNotification_ChallengedCapture(t *testing.T) {
+	notification, _, _, err := parseMidtransNotification([]byte(`{"order_id":"booking-3","transaction_status":"capture","fraud_status":"challenge","gross_amount":"150000.00"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "challenge", notification.TransactionStatus)
+	assert.Equal(t, "review", MapStatusToInternal(notification.TransactionStatus))
+
+	notification, _, _, err = parseMidtransNotification([]byte(`{"order_id":"booking-3","transaction_status":"capture","fraud_status":"accept","gross_amount":"150000.00"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "paid", MapStatusToInternal(notification.TransactionStatus))
+}
+
+func TestMapStatusToInternal_Chargeback(t *testing.T) {
+	assert.Equal(t, "dispute_lost", MapStatusToInternal("chargeback"))
+	assert.Equal(t, "dispute_lost", MapStatusToInternal("partial_chargeback"))
+}
+
+func TestMockParseNotification_RejectsEveryNotification(t *testing.T) {
+	_, err := (&MockTransactionRepository{}).ParseNotification(nil, []byte(`{"order_id":"booking-3","transaction_status":"settlement","gross_amount":"150000.00"}`))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
@@ -0,0 +1,26 @@
+package transaction
+
+import "fmt"
+
+// Registry holds the gateway of every configured payment provider
+type Registry struct {
+	gateways map[string]TransactionRepository
+}
+
+func NewRegistry() *Registry {
+	return &Registry{gateways: make(map[string]TransactionRepository)}
+}
+
+// Register makes gateway the one used for provider, replacing any earlier one
+func (r *Registry) Register(provider string, gateway TransactionRepository) {
+	r.gateways[provider] = gateway
+}
+
+// Get returns the gateway of provider, or an error when it is not configured
+func (r *Registry) Get(provider string) (TransactionRepository, error) {
+	gateway, ok := r.gateways[provider]
+	if !ok {
+		return nil, fmt.Errorf("payment provider not configured: %s", provider)
+	}
+	return gateway, nil
+}
@@ -0,0 +1,432 @@
+package transaction
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+)
+
+const (
+	stripeDefaultBaseURL = "https://api.stripe.com"
+
+	// stripeSignatureTolerance is how old a signed webhook may be before it is
+	// treated as a replay
+	stripeSignatureTolerance = 5 * time.Minute
+)
+
+// stripeZeroDecimal lists the currencies Stripe takes in major units
+var stripeZeroDecimal = map[string]bool{
+	"bif": true, "clp": true, "djf": true, "gnf": true, "jpy": true, "kmf": true,
+	"krw": true, "mga": true, "pyg": true, "rwf": true, "ugx": true, "vnd": true,
+	"vuv": true, "xaf": true, "xof": true, "xpf": true,
+}
+
+// StripeRepository charges through Stripe PaymentIntents using the REST API
+type StripeRepository struct {
+	client        *http.Client
+	baseURL       string
+	secretKey     string
+	webhookSecret string
+}
+
+// NewStripeRepository reads STRIPE_SECRET_KEY and STRIPE_WEBHOOK_SECRET.
+// STRIPE_API_BASE defaults to the Stripe API.
+func NewStripeRepository() (*StripeRepository, error) {
+	key := os.Getenv("STRIPE_SECRET_KEY")
+	webhookSecret := os.Getenv("STRIPE_WEBHOOK_SECRET")
+	if key == "" || webhookSecret == "" {
+		return nil, fmt.Errorf("stripe not configured: missing STRIPE_SECRET_KEY or STRIPE_WEBHOOK_SECRET")
+	}
+
+	baseURL := os.Getenv("STRIPE_API_BASE")
+	if baseURL == "" {
+		baseURL = stripeDefaultBaseURL
+	}
+
+	return &StripeRepository{
+		client:        &http.Client{Timeout: 30 * time.Second},
+		baseURL:       strings.TrimRight(baseURL, "/"),
+		secretKey:     key,
+		webhookSecret: webhookSecret,
+	}, nil
+}
+
+type stripePaymentIntent struct {
+	ID           string            `json:"id"`
+	Amount       int64             `json:"amount"`
+	Currency     string            `json:"currency"`
+	Status       string            `json:"status"`
+	ClientSecret string            `json:"client_secret"`
+	Metadata     map[string]string `json:"metadata"`
+	NextAction   *struct {
+		RedirectToURL *struct {
+			URL string `json:"url"`
+		} `json:"redirect_to_url"`
+	} `json:"next_action"`
+}
+
+type stripeError struct {
+	Error struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// CreateCharge creates a PaymentIntent. The payment type restricts it to one
+// payment method type (e.g. card); empty lets Stripe offer every enabled method.
+// The items become the description and the customer's email gets the receipt;
+// PaymentIntents do not expire, so once our own deadline passes the intent is
+// cancelled with CancelCharge. The instructions carry the client secret for confirming on the client, and the
+// redirect URL when Stripe asks for one.
+func (s *StripeRepository) CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error) {
+	minor, err := stripeAmount(req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(minor, 10))
+	form.Set("currency", strings.ToLower(string(req.Amount.Currency())))
+	form.Set("metadata[order_id]", req.OrderID)
+	if req.PaymentType != "" && req.PaymentType != "bank_transfer" {
+		form.Set("payment_method_types[]", req.PaymentType)
+	} else {
+		form.Set("automatic_payment_methods[enabled]", "true")
+	}
+	if len(req.Items) > 0 {
+		names := make([]string, len(req.Items))
+		for i, item := range req.Items {
+			names[i] = item.Name
+		}
+		form.Set("description", strings.Join(names, ", "))
+	}
+	if req.Customer != nil && req.Customer.Email != "" {
+		form.Set("receipt_email", req.Customer.Email)
+	}
+
+	var intent stripePaymentIntent
+	if err := s.do(ctx, http.MethodPost, "/v1/payment_intents", form, "", &intent); err != nil {
+		logrus.WithError(err).WithField("order_id", req.OrderID).Error("Stripe charge failed")
+		return nil, fmt.Errorf("payment gateway error: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"order_id":       req.OrderID,
+		"transaction_id": intent.ID,
+		"status":         intent.Status,
+	}).Info("Stripe payment intent created")
+
+	instructions := &model.PaymentInstructions{ClientSecret: intent.ClientSecret}
+	if intent.NextAction != nil && intent.NextAction.RedirectToURL != nil {
+		instructions.RedirectURL = intent.NextAction.RedirectToURL.URL
+	}
+	return &Charge{TransactionID: intent.ID, Instructions: instructions}, nil
+}
+
+func (s *StripeRepository) GetStatus(ctx context.Context, transactionID string) (string, error) {
+	var intent stripePaymentIntent
+	if err := s.do(ctx, http.MethodGet, "/v1/payment_intents/"+url.PathEscape(transactionID), nil, "", &intent); err != nil {
+		logrus.WithError(err).WithField("transaction_id", transactionID).Error("Stripe status check failed")
+		return "", fmt.Errorf("failed to check payment status: %w", err)
+	}
+	return intent.Status, nil
+}
+
+// CancelCharge cancels a PaymentIntent, so it can no longer be confirmed
+func (s *StripeRepository) CancelCharge(ctx context.Context, transactionID string) error {
+	var intent stripePaymentIntent
+	if err := s.do(ctx, http.MethodPost, "/v1/payment_intents/"+url.PathEscape(transactionID)+"/cancel", url.Values{}, "", &intent); err != nil {
+		logrus.WithError(err).WithField("transaction_id", transactionID).Error("Stripe cancel failed")
+		return fmt.Errorf("failed to cancel payment: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"transaction_id": intent.ID,
+		"status":         intent.Status,
+	}).Info("Stripe payment intent cancelled")
+	return nil
+}
+
+// Refund refunds part or all of a PaymentIntent. refundKey is sent as the
+// idempotency key, so retries never refund twice.
+func (s *StripeRepository) Refund(ctx context.Context, transactionID string, refundKey string, amount model.Money, reason string) (string, error) {
+	minor, err := stripeAmount(amount)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("payment_intent", transactionID)
+	form.Set("amount", strconv.FormatInt(minor, 10))
+	form.Set("metadata[reason]", reason)
+
+	var refund struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/v1/refunds", form, refundKey, &refund); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"transaction_id": transactionID,
+			"refund_key":     refundKey,
+			"amount":         amount.String(),
+		}).Error("Stripe refund failed")
+		return "", fmt.Errorf("refund failed: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"transaction_id": transactionID,
+		"refund_id":      refund.ID,
+		"status":         refund.Status,
+	}).Info("Stripe refund created")
+	return refund.ID, nil
+}
+
+// stripeEvent is a webhook event; Data.Object is a PaymentIntent, a charge or
+// a dispute
+type stripeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object struct {
+			ID              string            `json:"id"`
+			Object          string            `json:"object"`
+			Amount          int64             `json:"amount"`
+			Currency        string            `json:"currency"`
+			Status          string            `json:"status"`
+			Reason          string            `json:"reason"`
+			PaymentIntent   string            `json:"payment_intent"`
+			Metadata        map[string]string `json:"metadata"`
+			EvidenceDetails struct {
+				DueBy int64 `json:"due_by"`
+			} `json:"evidence_details"`
+		} `json:"object"`
+	} `json:"data"`
+}
+
+// ParseNotification verifies the Stripe-Signature header and decodes a
+// payment_intent.*, charge.refunded or charge.dispute.* event. The event type
+// is the transaction status, except for disputes: every charge.dispute.* event
+// carries the dispute's current status, so "charge.dispute.<status>" is used
+// and a dispute moving on is never mistaken for a redelivery.
+func (s *StripeRepository) ParseNotification(header http.Header, body []byte) (*Notification, error) {
+	var event stripeEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("invalid notification payload: %w", err)
+	}
+	if event.Type == "" {
+		return nil, errors.New("missing type in notification")
+	}
+
+	object := event.Data.Object
+	notification := &Notification{
+		TransactionID:     object.ID,
+		OrderID:           object.Metadata["order_id"],
+		TransactionStatus: event.Type,
+		GrossAmount:       formatStripeAmount(object.Amount, object.Currency),
+	}
+	switch object.Object {
+	case "charge":
+		notification.TransactionID = object.PaymentIntent
+	case "dispute":
+		notification.TransactionID = object.PaymentIntent
+		notification.TransactionStatus = "charge.dispute." + object.Status
+	}
+	if notification.TransactionID == "" {
+		return nil, errors.New("missing payment intent in notification")
+	}
+
+	if err := s.verifySignature(header.Get("Stripe-Signature"), body, time.Now()); err != nil {
+		return notification, err
+	}
+	return notification, nil
+}
+
+// MapStatus maps both event types and PaymentIntent statuses
+func (s *StripeRepository) MapStatus(providerStatus string) string {
+	switch providerStatus {
+	case "payment_intent.succeeded", "succeeded":
+		return "paid"
+	case "payment_intent.created", "payment_intent.processing", "payment_intent.requires_action",
+		"processing", "requires_payment_method", "requires_confirmation", "requires_action", "requires_capture":
+		return "pending"
+	case "payment_intent.payment_failed", "payment_intent.canceled", "canceled":
+		return "failed"
+	case "charge.refunded":
+		return "refunded"
+	case "charge.dispute.needs_response", "charge.dispute.warning_needs_response":
+		return DisputeStatusPrefix + string(model.DisputeOpen)
+	case "charge.dispute.under_review", "charge.dispute.warning_under_review":
+		return DisputeStatusPrefix + string(model.DisputeUnderReview)
+	case "charge.dispute.won", "charge.dispute.warning_closed":
+		// An inquiry closed without a chargeback took no money
+		return DisputeStatusPrefix + string(model.DisputeWon)
+	case "charge.dispute.lost":
+		return DisputeStatusPrefix + string(model.DisputeLost)
+	default:
+		return providerStatus
+	}
+}
+
+// ParseDispute reads the dispute of a charge.dispute.* event
+func (s *StripeRepository) ParseDispute(payload []byte) (*Dispute, error) {
+	var event stripeEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid notification payload: %w", err)
+	}
+
+	object := event.Data.Object
+	if object.Object != "dispute" || object.ID == "" {
+		return nil, errors.New("notification is not about a dispute")
+	}
+
+	dispute := &Dispute{ID: object.ID, Reason: object.Reason}
+	if object.EvidenceDetails.DueBy > 0 {
+		dueBy := time.Unix(object.EvidenceDetails.DueBy, 0)
+		dispute.EvidenceDueBy = &dueBy
+	}
+	return dispute, nil
+}
+
+// SubmitDisputeEvidence sends evidence as the dispute's explanation and submits
+// it to the card issuer right away
+func (s *StripeRepository) SubmitDisputeEvidence(ctx context.Context, disputeID string, evidence string) error {
+	form := url.Values{}
+	form.Set("evidence[uncategorized_text]", evidence)
+	form.Set("submit", "true")
+
+	var dispute struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/v1/disputes/"+url.PathEscape(disputeID), form, "", &dispute); err != nil {
+		logrus.WithError(err).WithField("dispute_id", disputeID).Error("Stripe dispute evidence submission failed")
+		return fmt.Errorf("failed to submit dispute evidence: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"dispute_id": dispute.ID,
+		"status":     dispute.Status,
+	}).Info("Stripe dispute evidence submitted")
+	return nil
+}
+
+// verifySignature checks a Stripe-Signature header ("t=<unix>,v1=<hex>,...")
+// against HMAC-SHA256 of "<t>.<body>" with the webhook secret
+func (s *StripeRepository) verifySignature(header string, body []byte, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > stripeSignatureTolerance || age < -stripeSignatureTolerance {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.webhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// do sends a form-encoded request to the Stripe API and decodes the JSON reply into out
+func (s *StripeRepository) do(ctx context.Context, method, path string, form url.Values, idempotencyKey string, out interface{}) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.secretKey, "")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var apiErr stripeError
+		if json.Unmarshal(respBody, &apiErr) == nil && apiErr.Error.Message != "" {
+			return fmt.Errorf("stripe %s: %s", apiErr.Error.Type, apiErr.Error.Message)
+		}
+		return fmt.Errorf("stripe returned HTTP %d", resp.StatusCode)
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// stripeAmount converts an amount to the smallest unit Stripe takes for its
+// currency: hundredths, or whole units for zero-decimal currencies, where a
+// fraction is an error rather than being truncated away
+func stripeAmount(amount model.Money) (int64, error) {
+	if !stripeZeroDecimal[strings.ToLower(string(amount.Currency()))] {
+		return amount.Cents(), nil
+	}
+	whole, exact := amount.Whole()
+	if !exact {
+		return 0, fmt.Errorf("stripe only charges whole %s, got %s", amount.Currency(), amount)
+	}
+	return whole, nil
+}
+
+// formatStripeAmount formats an amount in Stripe's smallest unit as a decimal
+// string in major units
+func formatStripeAmount(amount int64, currency string) string {
+	if stripeZeroDecimal[strings.ToLower(currency)] {
+		return strconv.FormatInt(amount, 10)
+	}
+	return fmt.Sprintf("%d.%02d", amount/100, amount%100)
+}
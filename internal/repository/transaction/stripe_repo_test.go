@@ -0,0 +1,176 @@
+package transaction
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+)
+
+func newTestStripe(t *testing.T, handler http.HandlerFunc) *StripeRepository {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	t.Setenv("STRIPE_SECRET_KEY", "sk_test")
+	t.Setenv("STRIPE_WEBHOOK_SECRET", "whsec_test")
+	t.Setenv("STRIPE_API_BASE", server.URL)
+	repo, err := NewStripeRepository()
+	require.NoError(t, err)
+	return repo
+}
+
+func stripeSignature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%d.", timestamp)))
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestNewStripeRepository_RequiresKeys(t *testing.T) {
+	t.Setenv("STRIPE_SECRET_KEY", "sk_test")
+	t.Setenv("STRIPE_WEBHOOK_SECRET", "")
+
+	_, err := NewStripeRepository()
+	assert.Error(t, err)
+}
+
+func TestStripeCreateCharge(t *testing.T) {
+	stripe := newTestStripe(t, func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		assert.Equal(t, "sk_test", user)
+		assert.Equal(t, "/v1/payment_intents", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "15000", r.PostForm.Get("amount")) // minor units
+		assert.Equal(t, "idr", r.PostForm.Get("currency"))
+		assert.Equal(t, "booking-3", r.PostForm.Get("metadata[order_id]"))
+		assert.Equal(t, "card", r.PostForm.Get("payment_method_types[]"))
+		assert.Equal(t, "Zelda rental", r.PostForm.Get("description"))
+		assert.Equal(t, "budi@example.com", r.PostForm.Get("receipt_email"))
+
+		fmt.Fprint(w, `{"id":"pi_1","status":"requires_payment_method","client_secret":"pi_1_secret"}`)
+	})
+
+	charge, err := stripe.CreateCharge(context.Background(), ChargeRequest{
+		OrderID:     "booking-3",
+		Amount:      model.NewMoney(150),
+		PaymentType: "card",
+		Items:       []ChargeItem{{ID: "game-1", Name: "Zelda rental", Price: model.NewMoney(150), Quantity: 1}},
+		Customer:    &ChargeCustomer{Name: "Budi", Email: "budi@example.com"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "pi_1", charge.TransactionID)
+	assert.Equal(t, "pi_1_secret", charge.Instructions.ClientSecret)
+}
+
+func TestStripeRefund_SendsIdempotencyKey(t *testing.T) {
+	stripe := newTestStripe(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "refund-7", r.Header.Get("Idempotency-Key"))
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "pi_1", r.PostForm.Get("payment_intent"))
+		assert.Equal(t, "5000", r.PostForm.Get("amount"))
+
+		fmt.Fprint(w, `{"id":"re_1","status":"succeeded"}`)
+	})
+
+	refundID, err := stripe.Refund(context.Background(), "pi_1", "refund-7", model.NewMoney(50), "damaged")
+	require.NoError(t, err)
+	assert.Equal(t, "re_1", refundID)
+}
+
+func TestStripeCancelCharge(t *testing.T) {
+	stripe := newTestStripe(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/payment_intents/pi_1/cancel", r.URL.Path)
+
+		fmt.Fprint(w, `{"id":"pi_1","status":"canceled"}`)
+	})
+
+	require.NoError(t, stripe.CancelCharge(context.Background(), "pi_1"))
+}
+
+func TestStripeGetStatus_SurfacesAPIError(t *testing.T) {
+	stripe := newTestStripe(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":{"type":"invalid_request_error","message":"No such payment_intent"}}`)
+	})
+
+	_, err := stripe.GetStatus(context.Background(), "pi_missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "No such payment_intent")
+}
+
+func TestStripeParseNotification(t *testing.T) {
+	stripe := newTestStripe(t, nil)
+	body := []byte(`{"id":"evt_1","type":"payment_intent.succeeded","data":{"object":{"id":"pi_1","object":"payment_intent","amount":15000,"currency":"usd","metadata":{"order_id":"booking-3"}}}}`)
+	now := time.Now().Unix()
+
+	t.Run("valid signature", func(t *testing.T) {
+		header := http.Header{"Stripe-Signature": {stripeSignature("whsec_test", now, body)}}
+		notification, err := stripe.ParseNotification(header, body)
+		require.NoError(t, err)
+		assert.Equal(t, "pi_1", notification.TransactionID)
+		assert.Equal(t, "booking-3", notification.OrderID)
+		assert.Equal(t, "150.00", notification.GrossAmount)
+		assert.Equal(t, "paid", stripe.MapStatus(notification.TransactionStatus))
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		header := http.Header{"Stripe-Signature": {stripeSignature("whsec_guessed", now, body)}}
+		notification, err := stripe.ParseNotification(header, body)
+		assert.True(t, errors.Is(err, ErrInvalidSignature))
+		assert.NotNil(t, notification) // still returned for the security log
+	})
+
+	t.Run("stale timestamp", func(t *testing.T) {
+		header := http.Header{"Stripe-Signature": {stripeSignature("whsec_test", now-int64(time.Hour.Seconds()), body)}}
+		_, err := stripe.ParseNotification(header, body)
+		assert.True(t, errors.Is(err, ErrInvalidSignature))
+	})
+}
+
+func TestStripeParseNotification_Dispute(t *testing.T) {
+	stripe := newTestStripe(t, nil)
+	body := []byte(`{"id":"evt_2","type":"charge.dispute.created","data":{"object":{"id":"dp_1","object":"dispute","amount":5000,"currency":"usd","status":"needs_response","reason":"fraudulent","payment_intent":"pi_1","evidence_details":{"due_by":1893456000}}}}`)
+	header := http.Header{"Stripe-Signature": {stripeSignature("whsec_test", time.Now().Unix(), body)}}
+
+	notification, err := stripe.ParseNotification(header, body)
+	require.NoError(t, err)
+	assert.Equal(t, "pi_1", notification.TransactionID)
+	assert.Equal(t, "charge.dispute.needs_response", notification.TransactionStatus)
+	assert.Equal(t, "50.00", notification.GrossAmount)
+	assert.Equal(t, "dispute_open", stripe.MapStatus(notification.TransactionStatus))
+
+	dispute, err := stripe.ParseDispute(body)
+	require.NoError(t, err)
+	assert.Equal(t, "dp_1", dispute.ID)
+	assert.Equal(t, "fraudulent", dispute.Reason)
+	require.NotNil(t, dispute.EvidenceDueBy)
+	assert.Equal(t, int64(1893456000), dispute.EvidenceDueBy.Unix())
+
+	assert.Equal(t, "dispute_under_review", stripe.MapStatus("charge.dispute.under_review"))
+	assert.Equal(t, "dispute_won", stripe.MapStatus("charge.dispute.warning_closed"))
+	assert.Equal(t, "dispute_lost", stripe.MapStatus("charge.dispute.lost"))
+}
+
+func TestStripeSubmitDisputeEvidence(t *testing.T) {
+	stripe := newTestStripe(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/disputes/dp_1", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "Game returned on time, signed receipt attached", r.PostForm.Get("evidence[uncategorized_text]"))
+		assert.Equal(t, "true", r.PostForm.Get("submit"))
+
+		fmt.Fprint(w, `{"id":"dp_1","status":"under_review"}`)
+	})
+
+	require.NoError(t, stripe.SubmitDisputeEvidence(context.Background(), "dp_1", "Game returned on time, signed receipt attached"))
+}
@@ -0,0 +1,60 @@
+package repository
+
+import "gorm.io/gorm"
+
+// Repositories groups the repositories that can take part in one transaction.
+// Inside TxManager.WithTransaction every field is bound to the same transaction.
+type Repositories struct {
+	Bookings       BookingRepository
+	BookingHistory BookingStatusHistoryRepository
+	DateChanges    BookingDateChangeRepository
+	Games          GameRepository
+	Payments       PaymentRepository
+	Refunds        PaymentRefundRepository
+	PaymentEvents  PaymentEventRepository
+	Reconciliation PaymentReconciliationRepository
+	Orders         OrderRepository
+	Waitlist       WaitlistRepository
+	Settlements    BookingSettlementRepository
+	Invoices       InvoiceRepository
+	PromoCodes     PromoCodeRepository
+	Wallets        WalletRepository
+	Disputes       PaymentDisputeRepository
+}
+
+type TxManager interface {
+	// WithTransaction runs fn in a single database transaction. It commits when fn
+	// returns nil and rolls back otherwise. fn must only use the repositories it is
+	// given, never ones bound to the root connection.
+	WithTransaction(fn func(repos Repositories) error) error
+}
+
+type txManager struct {
+	db *gorm.DB
+}
+
+func NewTxManager(db *gorm.DB) TxManager {
+	return &txManager{db: db}
+}
+
+func (m *txManager) WithTransaction(fn func(repos Repositories) error) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		return fn(Repositories{
+			Bookings:       NewBookingRepository(tx),
+			BookingHistory: NewBookingStatusHistoryRepository(tx),
+			DateChanges:    NewBookingDateChangeRepository(tx),
+			Games:          NewGameRepository(tx),
+			Payments:       NewPaymentRepository(tx),
+			Refunds:        NewPaymentRefundRepository(tx),
+			PaymentEvents:  NewPaymentEventRepository(tx),
+			Reconciliation: NewPaymentReconciliationRepository(tx),
+			Orders:         NewOrderRepository(tx),
+			Waitlist:       NewWaitlistRepository(tx),
+			Settlements:    NewBookingSettlementRepository(tx),
+			Invoices:       NewInvoiceRepository(tx),
+			PromoCodes:     NewPromoCodeRepository(tx),
+			Wallets:        NewWalletRepository(tx),
+			Disputes:       NewPaymentDisputeRepository(tx),
+		})
+	})
+}
@@ -16,6 +16,7 @@ type UserRepository interface {
 	UpdateRole(userID uint, newRole model.UserRole) error
 	UpdateActiveStatus(userID uint, isActive bool) error
 	Count() (int64, error)
+	GetActiveByRoles(roles ...model.UserRole) ([]*model.User, error)
 }
 
 type userRepository struct {
@@ -74,3 +75,11 @@ func (r *userRepository) Count() (int64, error) {
 	err := r.db.Model(&model.User{}).Count(&count).Error
 	return count, err
 }
+
+// GetActiveByRoles lists the active users holding any of roles, e.g. the admins
+// to notify
+func (r *userRepository) GetActiveByRoles(roles ...model.UserRole) ([]*model.User, error) {
+	var users []*model.User
+	err := r.db.Where("role IN ? AND is_active = ?", roles, true).Order("id").Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"gorm.io/gorm"
+)
+
+type WaitlistRepository interface {
+	Create(entry *model.WaitlistEntry) error
+	GetByID(id uint) (*model.WaitlistEntry, error)
+	GetUserEntries(userID uint) ([]*model.WaitlistEntry, error)
+	GetActiveByUserAndGame(userID, gameID uint) (*model.WaitlistEntry, error)
+	GetWaitingByGame(gameID uint) ([]*model.WaitlistEntry, error)
+	GetGamesWithWaiting() ([]uint, error)
+	GetExpiredHolds(now time.Time, limit int) ([]*model.WaitlistEntry, error)
+
+	// Status updates
+	Offer(entryID uint, holdExpiresAt time.Time) (bool, error)
+	UpdateStatusFrom(entryID uint, from, to model.WaitlistStatus) (bool, error)
+	MarkConverted(entryID uint, bookingID uint) error
+}
+
+type waitlistRepository struct {
+	db *gorm.DB
+}
+
+func NewWaitlistRepository(db *gorm.DB) WaitlistRepository {
+	return &waitlistRepository{db: db}
+}
+
+func (r *waitlistRepository) Create(entry *model.WaitlistEntry) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *waitlistRepository) GetByID(id uint) (*model.WaitlistEntry, error) {
+	var entry model.WaitlistEntry
+	if err := r.db.First(&entry, id).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *waitlistRepository) GetUserEntries(userID uint) ([]*model.WaitlistEntry, error) {
+	var entries []*model.WaitlistEntry
+	err := r.db.Preload("Game").Where("user_id = ?", userID).Order("created_at DESC").Find(&entries).Error
+	return entries, err
+}
+
+// GetActiveByUserAndGame returns the user's waiting or offered entry for a game
+func (r *waitlistRepository) GetActiveByUserAndGame(userID, gameID uint) (*model.WaitlistEntry, error) {
+	var entry model.WaitlistEntry
+	err := r.db.Where("user_id = ? AND game_id = ? AND status IN ?", userID, gameID,
+		[]model.WaitlistStatus{model.WaitlistWaiting, model.WaitlistOffered}).
+		First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetWaitingByGame returns the queue for a game, first come first served
+func (r *waitlistRepository) GetWaitingByGame(gameID uint) ([]*model.WaitlistEntry, error) {
+	var entries []*model.WaitlistEntry
+	err := r.db.Preload("User").Preload("Game").
+		Where("game_id = ? AND status = ?", gameID, model.WaitlistWaiting).
+		Order("created_at ASC, id ASC").Find(&entries).Error
+	return entries, err
+}
+
+func (r *waitlistRepository) GetGamesWithWaiting() ([]uint, error) {
+	var gameIDs []uint
+	err := r.db.Model(&model.WaitlistEntry{}).Where("status = ?", model.WaitlistWaiting).
+		Distinct().Pluck("game_id", &gameIDs).Error
+	return gameIDs, err
+}
+
+func (r *waitlistRepository) GetExpiredHolds(now time.Time, limit int) ([]*model.WaitlistEntry, error) {
+	var entries []*model.WaitlistEntry
+	err := r.db.Preload("User").Preload("Game").
+		Where("status = ? AND hold_expires_at < ?", model.WaitlistOffered, now).
+		Order("hold_expires_at ASC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+// Offer turns a waiting entry into a hold, reporting false if it is no longer waiting
+func (r *waitlistRepository) Offer(entryID uint, holdExpiresAt time.Time) (bool, error) {
+	result := r.db.Model(&model.WaitlistEntry{}).
+		Where("id = ? AND status = ?", entryID, model.WaitlistWaiting).
+		Updates(map[string]interface{}{
+			"status":          model.WaitlistOffered,
+			"offered_at":      time.Now(),
+			"hold_expires_at": holdExpiresAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *waitlistRepository) UpdateStatusFrom(entryID uint, from, to model.WaitlistStatus) (bool, error) {
+	result := r.db.Model(&model.WaitlistEntry{}).Where("id = ? AND status = ?", entryID, from).Update("status", to)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *waitlistRepository) MarkConverted(entryID uint, bookingID uint) error {
+	return r.db.Model(&model.WaitlistEntry{}).Where("id = ?", entryID).Updates(map[string]interface{}{
+		"status":     model.WaitlistConverted,
+		"booking_id": bookingID,
+	}).Error
+}
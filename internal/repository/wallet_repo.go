@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"gorm.io/gorm"
+)
+
+type WalletRepository interface {
+	// GetByUserID returns the wallet, or an empty one when the user has never
+	// had any credit
+	GetByUserID(userID uint) (*model.Wallet, error)
+	GetEntries(userID uint, limit, offset int) ([]*model.WalletEntry, error)
+	CountEntries(userID uint) (int64, error)
+
+	// Post appends entry to the ledger and moves the balance by its amount. It
+	// reports false, writing nothing, when a debit would take the balance below
+	// zero, so concurrent debits can never overdraw the wallet.
+	Post(entry *model.WalletEntry) (bool, error)
+}
+
+type walletRepository struct {
+	db *gorm.DB
+}
+
+func NewWalletRepository(db *gorm.DB) WalletRepository {
+	return &walletRepository{db: db}
+}
+
+func (r *walletRepository) GetByUserID(userID uint) (*model.Wallet, error) {
+	var wallets []model.Wallet
+	if err := r.db.Where("user_id = ?", userID).Limit(1).Find(&wallets).Error; err != nil {
+		return nil, err
+	}
+	if len(wallets) == 0 {
+		return &model.Wallet{UserID: userID}, nil
+	}
+	return &wallets[0], nil
+}
+
+func (r *walletRepository) GetEntries(userID uint, limit, offset int) ([]*model.WalletEntry, error) {
+	var entries []*model.WalletEntry
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Limit(limit).Offset(offset).Find(&entries).Error
+	return entries, err
+}
+
+func (r *walletRepository) CountEntries(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.WalletEntry{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func (r *walletRepository) Post(entry *model.WalletEntry) (bool, error) {
+	posted := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`INSERT INTO wallets (user_id, balance) VALUES (?, 0) ON CONFLICT (user_id) DO NOTHING`, entry.UserID).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&model.Wallet{}).
+			Where("user_id = ? AND balance + ? >= 0", entry.UserID, entry.Amount).
+			Updates(map[string]interface{}{
+				"balance":    gorm.Expr("balance + ?", entry.Amount),
+				"updated_at": gorm.Expr("CURRENT_TIMESTAMP"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		// The update holds the row lock, so this is the balance the entry left
+		var wallet model.Wallet
+		if err := tx.Where("user_id = ?", entry.UserID).First(&wallet).Error; err != nil {
+			return err
+		}
+		entry.BalanceAfter = wallet.Balance
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+		posted = true
+		return nil
+	})
+	return posted, err
+}
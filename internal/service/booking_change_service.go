@@ -0,0 +1,640 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
+	"github.com/yoockh/go-game-rental-api/internal/utils"
+)
+
+var (
+	ErrDateChangeNotAllowed = errors.New("only confirmed or active bookings can be extended")
+	ErrDateChangeInvalidEnd = errors.New("new end date must be after the current end date")
+	ErrDateChangePending    = errors.New("booking already has a pending date change")
+	ErrDateChangeNotFound   = errors.New("date change not found")
+
+	ErrRescheduleNotAllowed      = errors.New("only pending or confirmed bookings can be rescheduled")
+	ErrRescheduleUnchanged       = errors.New("new dates are the same as the current dates")
+	ErrRescheduleAwaitingPayment = errors.New("cannot reschedule while the booking payment is in progress")
+	ErrDateChangeNoRefundRoute   = errors.New("booking has no payment to refund the difference to")
+)
+
+type BookingChangeService interface {
+	// Customer
+	RequestExtension(userID uint, bookingID uint, newEndDate time.Time, paymentType string) (*model.BookingDateChange, error)
+	Reschedule(userID uint, bookingID uint, newStartDate, newEndDate time.Time, paymentType string) (*model.BookingDateChange, error)
+
+	// System (for payment)
+	ApplyPaidChange(repos repository.Repositories, paymentID uint) (AfterCommit, error)
+	FailChange(repos repository.Repositories, paymentID uint) (AfterCommit, error)
+	ExpireUnpaidChanges() (int, error)
+}
+
+type bookingChangeService struct {
+	txManager      repository.TxManager
+	bookingRepo    repository.BookingRepository
+	dateChangeRepo repository.BookingDateChangeRepository
+	paymentRepo    repository.PaymentRepository
+	gateways       *transaction.Registry
+	refundService  RefundService
+	emailRepo      email.EmailRepository
+	paymentWindow  time.Duration
+}
+
+// NewBookingChangeService creates the date change service. paymentWindow is how
+// long a pending change holds its days before it expires unpaid.
+func NewBookingChangeService(
+	txManager repository.TxManager,
+	bookingRepo repository.BookingRepository,
+	dateChangeRepo repository.BookingDateChangeRepository,
+	paymentRepo repository.PaymentRepository,
+	gateways *transaction.Registry,
+	refundService RefundService,
+	emailRepo email.EmailRepository,
+	paymentWindow time.Duration,
+) BookingChangeService {
+	return &bookingChangeService{
+		txManager:      txManager,
+		bookingRepo:    bookingRepo,
+		dateChangeRepo: dateChangeRepo,
+		paymentRepo:    paymentRepo,
+		gateways:       gateways,
+		refundService:  refundService,
+		emailRepo:      emailRepo,
+		paymentWindow:  paymentWindow,
+	}
+}
+
+// RequestExtension holds the extra days and opens a supplemental payment for
+// them. The booking's end date only moves once that payment is paid.
+func (s *bookingChangeService) RequestExtension(userID uint, bookingID uint, newEndDate time.Time, paymentType string) (*model.BookingDateChange, error) {
+	booking, err := s.bookingRepo.GetByID(bookingID)
+	if err != nil {
+		return nil, ErrBookingNotFound
+	}
+
+	if booking.UserID != userID {
+		return nil, ErrBookingNotOwned
+	}
+
+	if !changeAllowed(model.DateChangeExtension, booking.Status) {
+		return nil, ErrDateChangeNotAllowed
+	}
+
+	if !newEndDate.After(booking.EndDate) {
+		return nil, ErrDateChangeInvalidEnd
+	}
+
+	extraDays := int(newEndDate.Sub(booking.EndDate).Hours() / 24)
+	rentalDays := booking.RentalDays + extraDays
+
+	change := &model.BookingDateChange{
+		BookingID:        booking.ID,
+		Type:             model.DateChangeExtension,
+		Status:           model.DateChangePending,
+		OldStartDate:     booking.StartDate,
+		OldEndDate:       booking.EndDate,
+		NewStartDate:     booking.StartDate,
+		NewEndDate:       newEndDate,
+		RentalDays:       rentalDays,
+		TotalRentalPrice: booking.DailyPrice.Mul(int64(rentalDays)),
+		RequestedBy:      userID,
+	}
+	priceChange(booking, change)
+	if err := s.requestPaidChange(booking, change, paymentType); err != nil {
+		return nil, err
+	}
+	txID := *change.Payment.ProviderPaymentID
+
+	// SEND EMAIL: Extension payment instruction
+	go func() {
+		subject := "Rental Extension Requested - Game Rental"
+		htmlContent := fmt.Sprintf(`
+			<h1>Extend Your Rental</h1>
+			<p>Hi %s,</p>
+			<p>We are holding <strong>%s</strong> for you until %s.</p>
+			<h3>Payment Details:</h3>
+			<ul>
+				<li><strong>Order ID:</strong> %s</li>
+				<li><strong>Extra days:</strong> %d</li>
+				<li><strong>Amount:</strong> %s</li>
+			</ul>
+			%s
+			<p>Your return date changes once the payment is completed.</p>
+		`, booking.User.FullName, booking.Game.Name, newEndDate.Format("2006-01-02"), txID, extraDays, change.AmountDue.Display(), instructionsHTML(change.Payment.Instructions))
+
+		plainText := fmt.Sprintf("Extension requested for %s until %s. Amount: %s%s", booking.Game.Name, newEndDate.Format("2006-01-02"), change.AmountDue.Display(), instructionsText(change.Payment.Instructions))
+
+		if err := s.emailRepo.SendEmail(context.Background(), booking.User.Email, subject, plainText, htmlContent); err != nil {
+			logrus.WithError(err).Error("Failed to send extension instruction email")
+		}
+	}()
+
+	return change, nil
+}
+
+// Reschedule moves a booking that has not been picked up yet to new dates and
+// reprices it. Unpaid bookings are simply repriced. For paid bookings a higher
+// price opens a supplemental payment for the difference and the dates only
+// move once it is paid; a lower price moves the dates right away and refunds
+// the difference through the booking payment.
+func (s *bookingChangeService) Reschedule(userID uint, bookingID uint, newStartDate, newEndDate time.Time, paymentType string) (*model.BookingDateChange, error) {
+	booking, err := s.bookingRepo.GetByID(bookingID)
+	if err != nil {
+		return nil, ErrBookingNotFound
+	}
+
+	if booking.UserID != userID {
+		return nil, ErrBookingNotOwned
+	}
+
+	if !changeAllowed(model.DateChangeReschedule, booking.Status) {
+		return nil, ErrRescheduleNotAllowed
+	}
+
+	// An unpaid line item shares its payment with the rest of the order
+	if booking.OrderID != nil && booking.Status == model.BookingPending {
+		return nil, ErrBookingInOrder
+	}
+
+	if newStartDate.After(newEndDate) || newStartDate.Before(time.Now().Truncate(24*time.Hour)) {
+		return nil, ErrBookingInvalidDate
+	}
+
+	if newStartDate.Equal(booking.StartDate) && newEndDate.Equal(booking.EndDate) {
+		return nil, ErrRescheduleUnchanged
+	}
+
+	// The amount of a created charge cannot change anymore
+	if payment := booking.ChargedPayment(); booking.Status == model.BookingPending && payment != nil &&
+		(payment.Status == model.PaymentPending || payment.Status == model.PaymentReview) {
+		return nil, ErrRescheduleAwaitingPayment
+	}
+
+	rentalDays := int(newEndDate.Sub(newStartDate).Hours()/24) + 1
+	change := &model.BookingDateChange{
+		BookingID:        booking.ID,
+		Type:             model.DateChangeReschedule,
+		Status:           model.DateChangePending,
+		OldStartDate:     booking.StartDate,
+		OldEndDate:       booking.EndDate,
+		NewStartDate:     newStartDate,
+		NewEndDate:       newEndDate,
+		RentalDays:       rentalDays,
+		TotalRentalPrice: booking.DailyPrice.Mul(int64(rentalDays)),
+		RequestedBy:      userID,
+	}
+	priceChange(booking, change)
+
+	paid := booking.Status == model.BookingConfirmed
+	if paid && change.AmountDue.IsPositive() {
+		if err := s.requestPaidChange(booking, change, paymentType); err != nil {
+			return nil, err
+		}
+
+		// SEND EMAIL: Reschedule payment instruction
+		go func() {
+			subject := "Booking Reschedule Requested - Game Rental"
+			htmlContent := fmt.Sprintf(`
+				<h1>Reschedule Your Booking</h1>
+				<p>Hi %s,</p>
+				<p>We are holding <strong>%s</strong> for you from %s to %s.</p>
+				<h3>Payment Details:</h3>
+				<ul>
+					<li><strong>Order ID:</strong> %s</li>
+					<li><strong>Price difference:</strong> %s</li>
+				</ul>
+				%s
+				<p>Your booking moves to the new dates once the payment is completed.</p>
+			`, booking.User.FullName, booking.Game.Name, newStartDate.Format("2006-01-02"), newEndDate.Format("2006-01-02"), *change.Payment.ProviderPaymentID, change.AmountDue.Display(), instructionsHTML(change.Payment.Instructions))
+
+			plainText := fmt.Sprintf("Reschedule requested for %s to %s - %s. Amount: %s%s", booking.Game.Name, newStartDate.Format("2006-01-02"), newEndDate.Format("2006-01-02"), change.AmountDue.Display(), instructionsText(change.Payment.Instructions))
+
+			if err := s.emailRepo.SendEmail(context.Background(), booking.User.Email, subject, plainText, htmlContent); err != nil {
+				logrus.WithError(err).Error("Failed to send reschedule instruction email")
+			}
+		}()
+
+		return change, nil
+	}
+
+	var invoice *model.Invoice
+	err = s.txManager.WithTransaction(func(repos repository.Repositories) error {
+		if err := checkChangeAvailability(repos, booking, change); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		change.Status = model.DateChangeApplied
+		change.AppliedAt = &now
+		if err := repos.DateChanges.Create(change); err != nil {
+			return err
+		}
+
+		// The difference is credited against the payment of the booking
+		if payment := booking.ChargedPayment(); paid && payment != nil && !change.AmountDue.IsZero() {
+			var err error
+			if invoice, err = issueChangeInvoice(repos, booking, change, payment.ID); err != nil {
+				return err
+			}
+		}
+
+		applyDateChange(booking, change)
+		return repos.Bookings.UpdateDates(booking)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var refund *model.PaymentRefund
+	if paid && change.AmountDue.IsNegative() {
+		refund = s.refundDifference(booking, change)
+	}
+
+	// SEND EMAIL: Booking rescheduled
+	go func() {
+		subject := "Booking Rescheduled - Game Rental"
+		refundLine := ""
+		if refund != nil {
+			refundLine = fmt.Sprintf("<p>The price difference of %s has been refunded %s.</p>", change.AmountDue.Neg().Display(), refundDestination(refund))
+		} else if change.RefundStatus != nil {
+			refundLine = fmt.Sprintf("<p>The price difference of %s is owed to you. We could not refund it automatically, so our team will refund it and let you know.</p>", change.AmountDue.Neg().Display())
+		}
+		htmlContent := fmt.Sprintf(`
+			<h1>Booking Rescheduled</h1>
+			<p>Hi %s,</p>
+			<p>Your rental of <strong>%s</strong> now runs from <strong>%s</strong> to <strong>%s</strong> (%d days).</p>
+			<p><strong>New total:</strong> %s</p>
+			%s
+		`, booking.User.FullName, booking.Game.Name, booking.StartDate.Format("2006-01-02"), booking.EndDate.Format("2006-01-02"), booking.RentalDays, booking.TotalAmount.Display(), refundLine)
+
+		plainText := fmt.Sprintf("Your rental of %s now runs from %s to %s", booking.Game.Name, booking.StartDate.Format("2006-01-02"), booking.EndDate.Format("2006-01-02"))
+
+		attachments := invoiceAttachments(invoice, booking, nil)
+		if err := s.emailRepo.SendEmailWithAttachments(context.Background(), booking.User.Email, subject, plainText, htmlContent, attachments); err != nil {
+			logrus.WithError(err).Error("Failed to send reschedule confirmation email")
+		}
+	}()
+
+	return change, nil
+}
+
+// ApplyPaidChange moves the booking to the new dates once the supplemental
+// payment was marked paid in the caller's transaction. Repeated notifications
+// for the same payment are ignored.
+func (s *bookingChangeService) ApplyPaidChange(repos repository.Repositories, paymentID uint) (AfterCommit, error) {
+	change, err := repos.DateChanges.GetByPaymentID(paymentID)
+	if err != nil {
+		return nil, ErrDateChangeNotFound
+	}
+
+	booking, err := repos.Bookings.GetByID(change.BookingID)
+	if err != nil {
+		return nil, ErrBookingNotFound
+	}
+
+	if !changeAllowed(change.Type, booking.Status) {
+		logrus.WithFields(logrus.Fields{
+			"date_change_id": change.ID,
+			"booking_id":     booking.ID,
+			"booking_status": booking.Status,
+		}).Warn("Date change paid after booking closed, needs manual refund")
+		_, err := repos.DateChanges.MarkFailed(change.ID)
+		return nil, err
+	}
+
+	applied, err := repos.DateChanges.MarkApplied(change.ID)
+	if err != nil || !applied {
+		return nil, err
+	}
+
+	payment, err := repos.Payments.GetByID(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	invoice, err := issueChangeInvoice(repos, booking, change, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	applyDateChange(booking, change)
+	if err := repos.Bookings.UpdateDates(booking); err != nil {
+		return nil, err
+	}
+
+	// SEND EMAIL: Date change applied
+	return func() {
+		go func() {
+			subject := "Rental Extended - Game Rental"
+			htmlContent := fmt.Sprintf(`
+				<h1>Rental Extended</h1>
+				<p>Hi %s,</p>
+				<p>Your rental of <strong>%s</strong> now ends on <strong>%s</strong> (%d days).</p>
+			`, booking.User.FullName, booking.Game.Name, booking.EndDate.Format("2006-01-02"), booking.RentalDays)
+			plainText := fmt.Sprintf("Your rental of %s now ends on %s", booking.Game.Name, booking.EndDate.Format("2006-01-02"))
+
+			if change.Type == model.DateChangeReschedule {
+				subject = "Booking Rescheduled - Game Rental"
+				htmlContent = fmt.Sprintf(`
+					<h1>Booking Rescheduled</h1>
+					<p>Hi %s,</p>
+					<p>We received the price difference. Your rental of <strong>%s</strong> now runs from <strong>%s</strong> to <strong>%s</strong> (%d days).</p>
+					<p><strong>New total:</strong> %s</p>
+				`, booking.User.FullName, booking.Game.Name, booking.StartDate.Format("2006-01-02"), booking.EndDate.Format("2006-01-02"), booking.RentalDays, booking.TotalAmount.Display())
+				plainText = fmt.Sprintf("Your rental of %s now runs from %s to %s", booking.Game.Name, booking.StartDate.Format("2006-01-02"), booking.EndDate.Format("2006-01-02"))
+			}
+
+			attachments := invoiceAttachments(invoice, booking, payment)
+			if err := s.emailRepo.SendEmailWithAttachments(context.Background(), booking.User.Email, subject, plainText, htmlContent, attachments); err != nil {
+				logrus.WithError(err).Error("Failed to send date change confirmation email")
+			}
+		}()
+	}, nil
+}
+
+// FailChange releases the days held by a change whose payment was marked
+// failed in the caller's transaction. The booking itself is left untouched.
+func (s *bookingChangeService) FailChange(repos repository.Repositories, paymentID uint) (AfterCommit, error) {
+	change, err := repos.DateChanges.GetByPaymentID(paymentID)
+	if err != nil {
+		return nil, ErrDateChangeNotFound
+	}
+
+	_, err = repos.DateChanges.MarkFailed(change.ID)
+	return nil, err
+}
+
+// ExpireUnpaidChanges fails pending changes whose payment window has passed,
+// with their pending payment, so the days they held are free again and the
+// booking can take another change. The payment's charge is cancelled at the
+// gateway. The customer is notified.
+func (s *bookingChangeService) ExpireUnpaidChanges() (int, error) {
+	changes, err := s.dateChangeRepo.GetExpiredPending(time.Now().Add(-s.paymentWindow), expiryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, change := range changes {
+		var failed bool
+		var failedPayment *model.Payment
+		err := s.txManager.WithTransaction(func(repos repository.Repositories) error {
+			var err error
+			if failed, err = repos.DateChanges.MarkFailed(change.ID); err != nil || !failed {
+				return err
+			}
+			if change.Payment != nil && change.Payment.Status == model.PaymentPending {
+				failedPayment = change.Payment
+				return failPendingPayment(repos, change.Payment, "payment window expired")
+			}
+			return nil
+		})
+		if err != nil {
+			logrus.WithError(err).WithField("date_change_id", change.ID).Warn("Failed to expire date change")
+			continue
+		}
+		if !failed {
+			continue
+		}
+		cancelGatewayCharge(s.gateways, failedPayment)
+		expired++
+
+		// SEND EMAIL: Date change expired
+		go func(change *model.BookingDateChange) {
+			booking, err := s.bookingRepo.GetByID(change.BookingID)
+			if err != nil {
+				logrus.WithError(err).WithField("booking_id", change.BookingID).Error("Failed to load booking for date change expired email")
+				return
+			}
+
+			subject := "Date Change Expired - Game Rental"
+			htmlContent := fmt.Sprintf(`
+				<h1>Date Change Expired</h1>
+				<p>Hi %s,</p>
+				<p>We did not receive payment for moving your rental of <strong>%s</strong> to %s - %s in time, so the request has been cancelled.</p>
+				<p>Your booking keeps its dates, %s to %s. You are welcome to request the change again.</p>
+			`, booking.User.FullName, booking.Game.Name, change.NewStartDate.Format("2006-01-02"), change.NewEndDate.Format("2006-01-02"), booking.StartDate.Format("2006-01-02"), booking.EndDate.Format("2006-01-02"))
+
+			plainText := fmt.Sprintf("Your date change for %s expired because payment was not received in time. Your booking keeps its dates.", booking.Game.Name)
+
+			if err := s.emailRepo.SendEmail(context.Background(), booking.User.Email, subject, plainText, htmlContent); err != nil {
+				logrus.WithError(err).Error("Failed to send date change expired email")
+			}
+		}(change)
+	}
+
+	if expired > 0 {
+		logrus.WithField("count", expired).Info("Expired unpaid date changes")
+	}
+	return expired, nil
+}
+
+// requestPaidChange holds the new days and opens a supplemental payment for the
+// change's AmountDue with the provider the booking was paid with, or Midtrans
+// when it was paid from the wallet or offline, which have no charge to open.
+// On return change.Payment carries the provider reference.
+func (s *bookingChangeService) requestPaidChange(booking *model.Booking, change *model.BookingDateChange, paymentType string) error {
+	payment := &model.Payment{
+		BookingID: &booking.ID,
+		Provider:  model.ProviderMidtrans,
+		Purpose:   model.PaymentPurposeDateChange,
+		Amount:    change.AmountDue,
+		Status:    model.PaymentPending,
+	}
+	if charged := booking.ChargedPayment(); charged != nil && charged.Provider != model.ProviderWallet && charged.Provider != model.ProviderOffline {
+		payment.Provider = charged.Provider
+	}
+
+	gateway, err := s.gateways.Get(string(payment.Provider))
+	if err != nil {
+		return err
+	}
+
+	err = s.txManager.WithTransaction(func(repos repository.Repositories) error {
+		if err := checkChangeAvailability(repos, booking, change); err != nil {
+			return err
+		}
+
+		if err := repos.Payments.Create(payment); err != nil {
+			return err
+		}
+
+		change.PaymentID = &payment.ID
+		return repos.DateChanges.Create(change)
+	})
+	if err != nil {
+		return err
+	}
+
+	if paymentType == "" && payment.Provider == model.ProviderMidtrans {
+		paymentType = "bank_transfer"
+	}
+
+	orderID := fmt.Sprintf("booking-%d-change-%d", booking.ID, change.ID)
+	charge, err := gateway.CreateCharge(context.Background(), transaction.ChargeRequest{
+		OrderID:     orderID,
+		Amount:      change.AmountDue,
+		PaymentType: paymentType,
+		Items: []transaction.ChargeItem{{
+			ID:       fmt.Sprintf("booking-%d-change-%d", booking.ID, change.ID),
+			Name:     fmt.Sprintf("Date change for booking #%d", booking.ID),
+			Price:    change.AmountDue,
+			Quantity: 1,
+		}},
+		Customer: chargeCustomer(&booking.User),
+	})
+	if err != nil {
+		// Give the held days back, nobody can pay for this change
+		if _, markErr := s.dateChangeRepo.MarkFailed(change.ID); markErr != nil {
+			logrus.WithError(markErr).WithField("date_change_id", change.ID).Error("Failed to release date change")
+		}
+		if markErr := s.paymentRepo.MarkAsFailed(payment.ID, err.Error()); markErr != nil {
+			logrus.WithError(markErr).WithField("payment_id", payment.ID).Error("Failed to mark payment as failed")
+		}
+		return fmt.Errorf("%s payment gateway error: %w", payment.Provider, err)
+	}
+
+	payment.ProviderPaymentID = &charge.TransactionID
+	payment.Instructions = charge.Instructions
+	if err := s.paymentRepo.Update(payment); err != nil {
+		return err
+	}
+	change.Payment = payment
+	return nil
+}
+
+// refundDifference refunds a negative AmountDue through the payment that paid
+// for the booking, or to the customer's wallet when it was paid offline, and
+// stores the outcome on the change. A failed refund is left for an admin to
+// refund by hand; the new dates stay in place. It returns the refund, nil when
+// it failed.
+func (s *bookingChangeService) refundDifference(booking *model.Booking, change *model.BookingDateChange) *model.PaymentRefund {
+	logger := logrus.WithFields(logrus.Fields{
+		"booking_id":     booking.ID,
+		"date_change_id": change.ID,
+		"amount":         change.AmountDue.Neg().String(),
+	})
+
+	status := model.RefundFailed
+	err := ErrDateChangeNoRefundRoute
+	var refund *model.PaymentRefund
+	if payment := booking.ChargedPayment(); payment != nil {
+		// Offline payments cannot be refunded through a provider
+		toWallet := payment.Provider == model.ProviderOffline
+		if refund, err = s.refundService.Refund(payment, change.AmountDue.Neg(), "booking rescheduled", &change.RequestedBy, toWallet); err == nil {
+			status = model.RefundSucceeded
+			change.ProviderRefundID = refund.ProviderRefundID
+		}
+	}
+	change.RefundStatus = &status
+	if err != nil {
+		change.RefundError = utils.PtrOrNil(err.Error())
+		logger.WithError(err).Warn("Reschedule refund failed, needs manual refund")
+	}
+
+	if err := s.dateChangeRepo.Update(change); err != nil {
+		logger.WithError(err).Error("Failed to save reschedule refund")
+	}
+	return refund
+}
+
+// checkChangeAvailability locks the game and checks that no other change is
+// pending and that the days of the new range outside the booking's current
+// range are free. The days the booking already holds need no check.
+func checkChangeAvailability(repos repository.Repositories, booking *model.Booking, change *model.BookingDateChange) error {
+	if _, err := repos.Games.LockForUpdate(booking.GameID); err != nil {
+		return err
+	}
+
+	if pending, _ := repos.DateChanges.GetPendingByBookingID(booking.ID); pending != nil {
+		return ErrDateChangePending
+	}
+
+	var segments [][2]time.Time
+	if change.NewStartDate.Before(change.OldStartDate) {
+		end := change.OldStartDate.AddDate(0, 0, -1)
+		if change.NewEndDate.Before(end) {
+			end = change.NewEndDate
+		}
+		segments = append(segments, [2]time.Time{change.NewStartDate, end})
+	}
+	if change.NewEndDate.After(change.OldEndDate) {
+		start := change.OldEndDate.AddDate(0, 0, 1)
+		if change.NewStartDate.After(start) {
+			start = change.NewStartDate
+		}
+		segments = append(segments, [2]time.Time{start, change.NewEndDate})
+	}
+
+	for _, segment := range segments {
+		available, err := repos.Games.CheckAvailability(booking.GameID, segment[0], segment[1])
+		if err != nil {
+			return err
+		}
+		if !available {
+			return ErrGameStockInsufficient
+		}
+	}
+	return nil
+}
+
+// priceChange prices a change from its rental price: the booking's promo
+// code is applied to the new price again, the tax is the booking's tax at the
+// new discounted price, and AmountDue is the difference to what the booking
+// costs now, tax included unless inclusive. A refund is never more than was
+// paid for the rental.
+func priceChange(booking *model.Booking, change *model.BookingDateChange) {
+	change.DiscountAmount = changeDiscount(booking, change.TotalRentalPrice)
+	difference := change.TotalRentalPrice.Sub(change.DiscountAmount).Sub(booking.TotalRentalPrice.Sub(booking.DiscountAmount))
+	change.AmountDue = difference
+
+	rule := booking.TaxRule()
+	change.TaxAmount = rule.Tax(booking.TaxableAmount.Add(difference))
+	if !rule.Inclusive {
+		change.AmountDue = change.AmountDue.Add(change.TaxAmount.Sub(booking.TaxAmount))
+	}
+
+	if rentalPaid := booking.TotalAmount.Sub(booking.SecurityDeposit); change.AmountDue.Neg().GreaterThan(rentalPaid) {
+		change.AmountDue = rentalPaid.Neg()
+	}
+}
+
+// changeDiscount is the booking's promo discount at a new rental price. When
+// the promo code is gone the discount given is kept, up to the new price.
+func changeDiscount(booking *model.Booking, rentalPrice model.Money) model.Money {
+	if booking.PromoCode != nil {
+		return booking.PromoCode.Discount(rentalPrice)
+	}
+	return booking.DiscountAmount.Min(rentalPrice)
+}
+
+// applyDateChange copies the change's dates and prices onto the booking
+func applyDateChange(booking *model.Booking, change *model.BookingDateChange) {
+	booking.TotalAmount = booking.TotalAmount.Add(change.AmountDue)
+	booking.TaxableAmount = booking.TaxableAmount.Add(change.TotalRentalPrice.Sub(change.DiscountAmount).Sub(booking.TotalRentalPrice.Sub(booking.DiscountAmount)))
+	booking.TaxAmount = change.TaxAmount
+	booking.StartDate = change.NewStartDate
+	booking.EndDate = change.NewEndDate
+	booking.RentalDays = change.RentalDays
+	booking.TotalRentalPrice = change.TotalRentalPrice
+	booking.DiscountAmount = change.DiscountAmount
+}
+
+// changeAllowed reports whether a booking in the given status can take a date
+// change of the given type. Extensions are possible until the copy is returned,
+// a reschedule only before pickup.
+func changeAllowed(changeType model.DateChangeType, status model.BookingStatus) bool {
+	switch changeType {
+	case model.DateChangeReschedule:
+		return status == model.BookingPending || status == model.BookingConfirmed
+	default:
+		return status == model.BookingConfirmed || status == model.BookingActive
+	}
+}
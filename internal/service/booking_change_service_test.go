@@ -0,0 +1,173 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
+)
+
+type changeFixture struct {
+	svc      *bookingChangeService
+	bookings *fakeBookingStore
+	changes  *fakeDateChangeRepo
+	payments *fakePaymentRepo
+	invoices *fakeInvoiceRepo
+	refunds  *MockRefundService
+}
+
+// newChangeFixture is the date change service over an in-memory store holding
+// bookings of game 1, with payment as the one payment on file
+func newChangeFixture(payment *model.Payment, bookings ...*model.Booking) *changeFixture {
+	store := &fakeBookingStore{bookings: bookings}
+	changes := &fakeDateChangeRepo{}
+	payments := &fakePaymentRepo{payment: payment}
+	refunds := &MockRefundService{}
+	invoices := &fakeInvoiceRepo{}
+	games := &fakeGameRepo{game: &model.Game{ID: 1, Stock: 1}, bookings: store}
+	txManager := &fakeTxManager{repos: repository.Repositories{Bookings: store, DateChanges: changes, Payments: payments, Invoices: invoices, Games: games}}
+	svc := NewBookingChangeService(txManager, store, changes, payments, transaction.NewRegistry(), refunds, &email.MockEmailRepository{}, 24*time.Hour).(*bookingChangeService)
+	return &changeFixture{svc: svc, bookings: store, changes: changes, payments: payments, invoices: invoices, refunds: refunds}
+}
+
+// paidBookingOf is a confirmed three-day booking of game 1 from start, paid
+// through Midtrans
+func paidBookingOf(start time.Time) *model.Booking {
+	providerID := "tx-1"
+	return &model.Booking{
+		ID: 1, UserID: 5, GameID: 1, Status: model.BookingConfirmed,
+		StartDate: start, EndDate: start.AddDate(0, 0, 2), RentalDays: 3,
+		DailyPrice: model.NewMoney(50000), TotalRentalPrice: model.NewMoney(150000), TaxableAmount: model.NewMoney(150000),
+		SecurityDeposit: model.NewMoney(100000), TotalAmount: model.NewMoney(250000),
+		Payment: &model.Payment{ID: 1, Provider: model.ProviderMidtrans, Status: model.PaymentPaid, Amount: model.NewMoney(250000), ProviderPaymentID: &providerID},
+	}
+}
+
+// ============= TEST RESCHEDULE REFUNDS =============
+func TestReschedule_ShorterStaysRescheduledWhenRefundFails(t *testing.T) {
+	start := time.Now().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	f := newChangeFixture(nil, paidBookingOf(start))
+	f.refunds.On("Refund", mock.Anything, model.NewMoney(50000), "booking rescheduled", mock.Anything, false).
+		Return(nil, errors.New("refund window closed"))
+
+	change, err := f.svc.Reschedule(5, 1, start, start.AddDate(0, 0, 1), "")
+	require.NoError(t, err)
+	assert.Equal(t, model.DateChangeApplied, change.Status)
+	assert.Equal(t, model.NewMoney(-50000), change.AmountDue)
+	require.NotNil(t, change.RefundStatus)
+	assert.Equal(t, model.RefundFailed, *change.RefundStatus)
+	assert.Equal(t, "refund window closed", *change.RefundError)
+	assert.Equal(t, model.RefundFailed, *f.changes.changes[0].RefundStatus, "the outcome is stored")
+	assert.Equal(t, 2, f.bookings.bookings[0].RentalDays)
+}
+
+func TestReschedule_ShorterRefundsDifference(t *testing.T) {
+	start := time.Now().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	f := newChangeFixture(nil, paidBookingOf(start))
+	providerRefundID := "rf-1"
+	f.refunds.On("Refund", mock.Anything, model.NewMoney(50000), "booking rescheduled", mock.Anything, false).
+		Return(&model.PaymentRefund{Status: model.RefundSucceeded, ProviderRefundID: &providerRefundID}, nil)
+
+	change, err := f.svc.Reschedule(5, 1, start, start.AddDate(0, 0, 1), "")
+	require.NoError(t, err)
+	assert.Equal(t, model.RefundSucceeded, *change.RefundStatus)
+	assert.Nil(t, change.RefundError)
+	assert.Equal(t, "rf-1", *change.ProviderRefundID)
+}
+
+func TestReschedule_ShorterIssuesCreditNote(t *testing.T) {
+	start := time.Now().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	f := newChangeFixture(nil, paidBookingOf(start))
+	f.refunds.On("Refund", mock.Anything, model.NewMoney(50000), "booking rescheduled", mock.Anything, false).
+		Return(&model.PaymentRefund{Status: model.RefundSucceeded}, nil)
+
+	change, err := f.svc.Reschedule(5, 1, start, start.AddDate(0, 0, 1), "")
+	require.NoError(t, err)
+
+	require.Len(t, f.invoices.invoices, 2)
+	original, creditNote := f.invoices.invoices[0], f.invoices.invoices[1]
+	assert.Nil(t, original.DateChangeID)
+	assert.Equal(t, 3, original.RentalDays, "the booking is invoiced as it was paid")
+	assert.Equal(t, model.NewMoney(250000), original.TotalAmount)
+
+	require.NotNil(t, creditNote.DateChangeID)
+	assert.Equal(t, change.ID, *creditNote.DateChangeID)
+	assert.True(t, creditNote.IsCreditNote())
+	assert.Equal(t, -1, creditNote.RentalDays)
+	assert.Equal(t, model.NewMoney(-50000), creditNote.TotalAmount)
+	assert.Equal(t, uint(1), creditNote.PaymentID)
+}
+
+func TestReschedule_ShorterRefundsOfflinePaymentToWallet(t *testing.T) {
+	start := time.Now().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	booking := paidBookingOf(start)
+	booking.Payment.Provider = model.ProviderOffline
+	booking.Payment.ProviderPaymentID = nil
+	f := newChangeFixture(nil, booking)
+	f.refunds.On("Refund", mock.Anything, model.NewMoney(50000), "booking rescheduled", mock.Anything, true).
+		Return(&model.PaymentRefund{Status: model.RefundSucceeded, WalletAmount: model.NewMoney(50000)}, nil)
+
+	change, err := f.svc.Reschedule(5, 1, start, start.AddDate(0, 0, 1), "")
+	require.NoError(t, err)
+	assert.Equal(t, model.RefundSucceeded, *change.RefundStatus)
+	f.refunds.AssertCalled(t, "Refund", mock.Anything, model.NewMoney(50000), "booking rescheduled", mock.Anything, true)
+}
+
+// ============= TEST PROMO DISCOUNT =============
+func TestReschedule_ShorterRecomputesPromoDiscount(t *testing.T) {
+	start := time.Now().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	booking := paidBookingOf(start)
+	// Half off: 150000 rental, 75000 paid for it
+	booking.PromoCode = &model.PromoCode{DiscountType: model.DiscountPercentage, PercentOff: 50}
+	booking.DiscountAmount = model.NewMoney(75000)
+	booking.TaxableAmount = model.NewMoney(75000)
+	booking.TotalAmount = model.NewMoney(175000)
+	f := newChangeFixture(nil, booking)
+	f.refunds.On("Refund", mock.Anything, model.NewMoney(50000), "booking rescheduled", mock.Anything, false).
+		Return(&model.PaymentRefund{Status: model.RefundSucceeded}, nil)
+
+	// One day at 50000, half off
+	change, err := f.svc.Reschedule(5, 1, start, start, "")
+	require.NoError(t, err)
+	assert.Equal(t, model.NewMoney(25000), change.DiscountAmount)
+	assert.Equal(t, model.NewMoney(-50000), change.AmountDue, "not the 100000 the rental price went down by")
+	f.refunds.AssertCalled(t, "Refund", mock.Anything, model.NewMoney(50000), "booking rescheduled", mock.Anything, false)
+
+	rescheduled := f.bookings.bookings[0]
+	assert.Equal(t, model.NewMoney(25000), rescheduled.DiscountAmount)
+	assert.Equal(t, model.NewMoney(25000), rescheduled.TaxableAmount)
+	assert.Equal(t, model.NewMoney(125000), rescheduled.TotalAmount)
+}
+
+// ============= TEST EXPIRY =============
+func TestExpireUnpaidChanges_FailsChangeAndPayment(t *testing.T) {
+	paymentID := uint(1)
+	f := newChangeFixture(&model.Payment{ID: paymentID, Purpose: model.PaymentPurposeDateChange, Status: model.PaymentPending},
+		&model.Booking{ID: 1, Status: model.BookingConfirmed}, &model.Booking{ID: 2, Status: model.BookingConfirmed})
+	f.changes.changes = []*model.BookingDateChange{
+		{ID: 1, BookingID: 1, Status: model.DateChangePending, PaymentID: &paymentID,
+			Payment: &model.Payment{ID: paymentID, Status: model.PaymentPending}, CreatedAt: time.Now().Add(-25 * time.Hour)},
+		{ID: 2, BookingID: 2, Status: model.DateChangePending, CreatedAt: time.Now().Add(-time.Hour)},
+	}
+
+	expired, err := f.svc.ExpireUnpaidChanges()
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	assert.Equal(t, model.DateChangeFailed, f.changes.changes[0].Status)
+	assert.Equal(t, model.PaymentFailed, f.payments.payment.Status)
+	assert.Equal(t, model.DateChangePending, f.changes.changes[1].Status, "still inside its payment window")
+
+	_, err = f.changes.GetPendingByBookingID(1)
+	assert.Error(t, err, "the booking can take another change")
+
+	expired, err = f.svc.ExpireUnpaidChanges()
+	require.NoError(t, err)
+	assert.Zero(t, expired)
+}
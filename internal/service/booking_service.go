@@ -4,12 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/yoockh/go-game-rental-api/internal/dto"
 	"github.com/yoockh/go-game-rental-api/internal/model"
 	"github.com/yoockh/go-game-rental-api/internal/repository"
 	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
+	"github.com/yoockh/go-game-rental-api/internal/utils"
 )
 
 var (
@@ -18,88 +22,140 @@ var (
 	ErrBookingInvalidDate    = errors.New("invalid booking dates")
 	ErrBookingCannotCancel   = errors.New("cannot cancel booking in current status")
 	ErrGameStockInsufficient = errors.New("insufficient stock")
+	ErrBookingInOrder        = errors.New("booking is part of an order, cancel the order instead")
+
+	ErrBookingInvalidFilterDate = errors.New("invalid date filter (use YYYY-MM-DD)")
 )
 
 type BookingService interface {
 	// Customer
-	Create(userID uint, bookingData *model.Booking) error
+	Create(userID uint, bookingData *model.Booking, promoCode string) error
 	GetUserBookings(userID uint, limit, offset int) ([]*model.Booking, int64, error)
 	GetByID(userID uint, bookingID uint) (*model.Booking, error)
 	Cancel(userID uint, bookingID uint) error
+	GetHistory(requestorID uint, requestorRole model.UserRole, bookingID uint) ([]*model.BookingStatusHistory, error)
 
 	// Admin
-	GetAll(requestorRole model.UserRole, limit, offset int) ([]*model.Booking, int64, error)
-	UpdateStatus(requestorRole model.UserRole, bookingID uint, status model.BookingStatus) error
-
-	// System (for payment)
-	ConfirmPayment(bookingID uint) error
-	FailPayment(bookingID uint) error
+	GetAll(requestorRole model.UserRole, query *dto.AdminBookingListQuery, limit, offset int) ([]*model.Booking, int64, error)
+	UpdateStatus(requestorID uint, requestorRole model.UserRole, bookingID uint, status model.BookingStatus, reason string) error
+
+	// System (for payment). ConfirmPayment and FailPayment run in the caller's
+	// transaction; the returned AfterCommit must run once it has committed.
+	ConfirmPayment(repos repository.Repositories, bookingID uint) (AfterCommit, error)
+	FailPayment(repos repository.Repositories, bookingID uint) (AfterCommit, error)
+	ChargeBack(repos repository.Repositories, bookingID uint) (AfterCommit, error)
+	CancelRefunded(actorID uint, bookingID uint) error
+	ExpireUnpaidBookings() (int, error)
 }
 
+// expiryBatchSize caps how many bookings a single expiry run processes
+const expiryBatchSize = 100
+
 type bookingService struct {
-	bookingRepo repository.BookingRepository
-	gameRepo    repository.GameRepository
-	userRepo    repository.UserRepository
-	emailRepo   email.EmailRepository
+	txManager       repository.TxManager
+	bookingRepo     repository.BookingRepository
+	historyRepo     repository.BookingStatusHistoryRepository
+	gameRepo        repository.GameRepository
+	userRepo        repository.UserRepository
+	waitlistService WaitlistService
+	gateways        *transaction.Registry
+	emailRepo       email.EmailRepository
+	paymentWindow   time.Duration
+	taxRule         model.TaxRule
 }
 
+// NewBookingService creates the booking service. paymentWindow is how long a
+// pending booking holds its dates before it expires unpaid; taxRule prices the
+// VAT of new bookings.
 func NewBookingService(
+	txManager repository.TxManager,
 	bookingRepo repository.BookingRepository,
+	historyRepo repository.BookingStatusHistoryRepository,
 	gameRepo repository.GameRepository,
 	userRepo repository.UserRepository,
+	waitlistService WaitlistService,
+	gateways *transaction.Registry,
 	emailRepo email.EmailRepository,
+	paymentWindow time.Duration,
+	taxRule model.TaxRule,
 ) BookingService {
 	return &bookingService{
-		bookingRepo: bookingRepo,
-		gameRepo:    gameRepo,
-		userRepo:    userRepo,
-		emailRepo:   emailRepo,
+		txManager:       txManager,
+		bookingRepo:     bookingRepo,
+		historyRepo:     historyRepo,
+		gameRepo:        gameRepo,
+		userRepo:        userRepo,
+		waitlistService: waitlistService,
+		gateways:        gateways,
+		emailRepo:       emailRepo,
+		paymentWindow:   paymentWindow,
+		taxRule:         taxRule,
 	}
 }
 
-func (s *bookingService) Create(userID uint, bookingData *model.Booking) error {
+// Create books a game for the customer. A non-empty promoCode takes its
+// discount off the rental price; an unusable code fails the booking.
+func (s *bookingService) Create(userID uint, bookingData *model.Booking, promoCode string) error {
 	game, err := s.gameRepo.GetByID(bookingData.GameID)
 	if err != nil {
 		return ErrGameNotFound
 	}
 
-	if !game.IsActive {
-		return errors.New("game is not available for booking")
-	}
-
-	if bookingData.StartDate.After(bookingData.EndDate) || bookingData.StartDate.Before(time.Now().Truncate(24*time.Hour)) {
-		return ErrBookingInvalidDate
-	}
-
-	available, err := s.gameRepo.CheckAvailability(game.ID)
-	if err != nil {
+	paymentDueAt := time.Now().Add(s.paymentWindow)
+	if err := prepareBooking(bookingData, game, userID, paymentDueAt); err != nil {
 		return err
 	}
-	if !available {
-		return ErrGameStockInsufficient
-	}
-
-	rentalDays := int(bookingData.EndDate.Sub(bookingData.StartDate).Hours()/24) + 1
-	totalRentalPrice := float64(rentalDays) * game.RentalPricePerDay
-	totalAmount := totalRentalPrice + game.SecurityDeposit
+	rentalDays := bookingData.RentalDays
+
+	// Lock the game row so concurrent bookings for the same game are checked and
+	// inserted one at a time; otherwise two requests could both see the last copy free
+	err = s.txManager.WithTransaction(func(repos repository.Repositories) error {
+		if _, err := repos.Games.LockForUpdate(game.ID); err != nil {
+			return err
+		}
+
+		// A waitlist hold covering these dates is released to this booking
+		hold, err := claimWaitlistHold(repos, userID, bookingData)
+		if err != nil {
+			return err
+		}
+
+		available, err := repos.Games.CheckAvailability(game.ID, bookingData.StartDate, bookingData.EndDate)
+		if err != nil {
+			return err
+		}
+		if !available {
+			return ErrGameStockInsufficient
+		}
+
+		if promoCode != "" {
+			if err := applyPromoCode(repos, promoCode, bookingData, game, time.Now()); err != nil {
+				return err
+			}
+		}
+		applyTax(bookingData, s.taxRule)
 
-	bookingData.UserID = userID
-	bookingData.RentalDays = rentalDays
-	bookingData.DailyPrice = game.RentalPricePerDay
-	bookingData.TotalRentalPrice = totalRentalPrice
-	bookingData.SecurityDeposit = game.SecurityDeposit
-	bookingData.TotalAmount = totalAmount
-	bookingData.Status = model.BookingPending
+		if err := repos.Bookings.Create(bookingData); err != nil {
+			return err
+		}
 
-	err = s.gameRepo.ReserveStock(game.ID)
+		if hold != nil {
+			if err := repos.Waitlist.MarkConverted(hold.ID, bookingData.ID); err != nil {
+				return err
+			}
+		}
+
+		return repos.BookingHistory.Create(&model.BookingStatusHistory{
+			BookingID: bookingData.ID,
+			ToStatus:  model.BookingPending,
+			ChangedBy: &userID,
+			Reason:    utils.PtrOrNil("booking created"),
+		})
+	})
 	if err != nil {
 		return err
 	}
 
-	if err := s.bookingRepo.Create(bookingData); err != nil {
-		return err
-	}
-
 	// SEND EMAIL: Booking confirmation
 	user, _ := s.userRepo.GetByID(userID)
 	if user != nil {
@@ -109,6 +165,11 @@ func (s *bookingService) Create(userID uint, bookingData *model.Booking) error {
 			if game.Platform != nil {
 				platform = *game.Platform
 			}
+			discountLine := ""
+			if bookingData.DiscountAmount.IsPositive() {
+				discountLine = fmt.Sprintf("<li><strong>Discount:</strong> -%s</li>", bookingData.DiscountAmount.Display())
+			}
+			discountLine += taxLineHTML(bookingData)
 			htmlContent := fmt.Sprintf(`
 				<h1>Booking Confirmation</h1>
 				<p>Hi %s,</p>
@@ -118,12 +179,13 @@ func (s *bookingService) Create(userID uint, bookingData *model.Booking) error {
 					<li><strong>Game:</strong> %s</li>
 					<li><strong>Platform:</strong> %s</li>
 					<li><strong>Period:</strong> %s to %s (%d days)</li>
-					<li><strong>Total:</strong> Rp %.0f</li>
+					%s
+					<li><strong>Total:</strong> %s</li>
 				</ul>
-				<p><strong>Next:</strong> Please complete the payment.</p>
-			`, user.FullName, game.Name, platform, bookingData.StartDate.Format("2006-01-02"), bookingData.EndDate.Format("2006-01-02"), rentalDays, totalAmount)
+				<p><strong>Next:</strong> Please complete the payment before %s.</p>
+			`, user.FullName, game.Name, platform, bookingData.StartDate.Format("2006-01-02"), bookingData.EndDate.Format("2006-01-02"), rentalDays, discountLine, bookingData.TotalAmount.Display(), paymentDueAt.Format("2006-01-02 15:04"))
 
-			plainText := fmt.Sprintf("Booking confirmed for %s. Total: Rp %.0f", game.Name, totalAmount)
+			plainText := fmt.Sprintf("Booking confirmed for %s. Total: %s", game.Name, bookingData.TotalAmount.Display())
 
 			if err := s.emailRepo.SendEmail(context.Background(), user.Email, subject, plainText, htmlContent); err != nil {
 				logrus.WithError(err).Error("Failed to send booking email")
@@ -171,39 +233,160 @@ func (s *bookingService) Cancel(userID uint, bookingID uint) error {
 		return ErrBookingCannotCancel
 	}
 
-	err = s.gameRepo.ReleaseStock(booking.GameID)
+	// An unpaid line item shares its payment with the rest of the order
+	if booking.OrderID != nil && booking.Status == model.BookingPending {
+		return ErrBookingInOrder
+	}
+
+	// Money was taken for a held payment; it is approved or denied first
+	if booking.Payment != nil && booking.Payment.Status == model.PaymentReview {
+		return ErrPaymentUnderReview
+	}
+
+	if err := s.cancel(booking, &userID, "cancelled by customer"); err != nil {
+		return err
+	}
+
+	s.waitlistService.NotifyCapacityReleased(booking.GameID)
+	return nil
+}
+
+// cancel cancels a booking together with the payments still open for it: its
+// pending payment attempt and the supplemental payment of a pending date
+// change, whose charges are cancelled at the gateway once the cancellation has
+// committed. A payment that arrives for a cancelled booking anyway is refunded
+// in full.
+func (s *bookingService) cancel(booking *model.Booking, actorID *uint, reason string) error {
+	var openPayments []*model.Payment
+	err := s.txManager.WithTransaction(func(repos repository.Repositories) error {
+		openPayments = nil
+		if err := transitionBooking(repos, booking, model.BookingCancelled, actorID, reason); err != nil {
+			return err
+		}
+		if booking.Payment != nil && booking.Payment.Status == model.PaymentPending {
+			openPayments = append(openPayments, booking.Payment)
+			if err := failPendingPayment(repos, booking.Payment, reason); err != nil {
+				return err
+			}
+		}
+
+		changePayment, err := failPendingChange(repos, booking.ID, reason)
+		if changePayment != nil {
+			openPayments = append(openPayments, changePayment)
+		}
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
-	return s.bookingRepo.UpdateStatus(bookingID, model.BookingCancelled)
+	booking.Status = model.BookingCancelled
+	for _, payment := range openPayments {
+		cancelGatewayCharge(s.gateways, payment)
+	}
+	return nil
+}
+
+// failPendingChange fails the pending date change of a booking being cancelled,
+// if it has one, together with its supplemental payment, in the caller's
+// transaction. It returns that payment when it was still open, so its charge
+// can be cancelled after commit.
+func failPendingChange(repos repository.Repositories, bookingID uint, reason string) (*model.Payment, error) {
+	change, _ := repos.DateChanges.GetPendingByBookingID(bookingID)
+	if change == nil {
+		return nil, nil
+	}
+	if _, err := repos.DateChanges.MarkFailed(change.ID); err != nil {
+		return nil, err
+	}
+	if change.Payment == nil || change.Payment.Status != model.PaymentPending {
+		return nil, nil
+	}
+	return change.Payment, failPendingPayment(repos, change.Payment, reason)
+}
+
+func (s *bookingService) GetHistory(requestorID uint, requestorRole model.UserRole, bookingID uint) ([]*model.BookingStatusHistory, error) {
+	booking, err := s.bookingRepo.GetByID(bookingID)
+	if err != nil {
+		return nil, ErrBookingNotFound
+	}
+
+	if !s.canManageBookings(requestorRole) && booking.UserID != requestorID {
+		return nil, ErrBookingNotOwned
+	}
+
+	return s.historyRepo.GetByBookingID(bookingID)
 }
 
-func (s *bookingService) GetAll(requestorRole model.UserRole, limit, offset int) ([]*model.Booking, int64, error) {
+func (s *bookingService) GetAll(requestorRole model.UserRole, query *dto.AdminBookingListQuery, limit, offset int) ([]*model.Booking, int64, error) {
 	if !s.canManageBookings(requestorRole) {
 		return nil, 0, ErrInsufficientPermission
 	}
 
-	bookings, err := s.bookingRepo.GetAllBookings(limit, offset)
+	filter := repository.BookingFilter{
+		Status:        model.BookingStatus(query.Status),
+		UserID:        query.UserID,
+		GameID:        query.GameID,
+		PaymentStatus: model.PaymentStatus(query.PaymentStatus),
+		Search:        strings.TrimSpace(query.Search),
+		SortBy:        query.Sort,
+		SortAsc:       query.Order == "asc",
+	}
+
+	dateFilters := []struct {
+		raw    string
+		target **time.Time
+	}{
+		{query.StartFrom, &filter.StartFrom},
+		{query.StartTo, &filter.StartTo},
+		{query.EndFrom, &filter.EndFrom},
+		{query.EndTo, &filter.EndTo},
+	}
+	for _, f := range dateFilters {
+		if f.raw == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", f.raw)
+		if err != nil {
+			return nil, 0, ErrBookingInvalidFilterDate
+		}
+		*f.target = &date
+	}
+
+	bookings, err := s.bookingRepo.GetAllBookings(filter, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	count, err := s.bookingRepo.Count()
+	count, err := s.bookingRepo.Count(filter)
 	return bookings, count, err
 }
 
-func (s *bookingService) UpdateStatus(requestorRole model.UserRole, bookingID uint, status model.BookingStatus) error {
+func (s *bookingService) UpdateStatus(requestorID uint, requestorRole model.UserRole, bookingID uint, status model.BookingStatus, reason string) error {
 	if !s.canManageBookings(requestorRole) {
 		return ErrInsufficientPermission
 	}
 
+	if !status.IsValid() {
+		return ErrBookingInvalidStatus
+	}
+
+	// Completion settles the deposit, see BookingSettlementService.RecordReturn
+	if status == model.BookingCompleted {
+		return ErrBookingReturnRequired
+	}
+
 	booking, err := s.bookingRepo.GetByID(bookingID)
 	if err != nil {
 		return ErrBookingNotFound
 	}
 
-	if err := s.bookingRepo.UpdateStatus(bookingID, status); err != nil {
+	if status == model.BookingCancelled {
+		err = s.cancel(booking, &requestorID, reason)
+	} else {
+		err = s.transition(booking, status, &requestorID, reason)
+	}
+	if err != nil {
 		return err
 	}
 
@@ -217,8 +400,6 @@ func (s *bookingService) UpdateStatus(requestorRole model.UserRole, bookingID ui
 			switch status {
 			case model.BookingActive:
 				statusMsg = "Your game is ready!"
-			case model.BookingCompleted:
-				statusMsg = "Thank you!"
 			}
 
 			htmlContent := fmt.Sprintf(`
@@ -240,29 +421,35 @@ func (s *bookingService) UpdateStatus(requestorRole model.UserRole, bookingID ui
 	return nil
 }
 
-func (s *bookingService) ConfirmPayment(bookingID uint) error {
-	booking, err := s.bookingRepo.GetByID(bookingID)
+// ConfirmPayment confirms a pending booking whose payment was just marked paid
+// in the caller's transaction
+func (s *bookingService) ConfirmPayment(repos repository.Repositories, bookingID uint) (AfterCommit, error) {
+	booking, err := repos.Bookings.GetByID(bookingID)
 	if err != nil {
-		return ErrBookingNotFound
+		return nil, ErrBookingNotFound
 	}
 
 	if booking.Status != model.BookingPending {
-		return errors.New("booking is not in pending status")
+		return nil, errors.New("booking is not in pending status")
 	}
 
-	if err := s.bookingRepo.UpdateStatus(bookingID, model.BookingConfirmed); err != nil {
-		return err
+	if err := transitionBooking(repos, booking, model.BookingConfirmed, nil, "payment confirmed"); err != nil {
+		return nil, err
 	}
+	booking.Status = model.BookingConfirmed
 
-	// SEND EMAIL: Payment confirmed
-	user, _ := s.userRepo.GetByID(booking.UserID)
-	game, _ := s.gameRepo.GetByID(booking.GameID)
-	if user != nil && game != nil {
+	invoice, err := issueInvoice(repos, booking)
+	if err != nil {
+		return nil, err
+	}
+
+	// SEND EMAIL: Payment confirmed, with the invoice attached
+	return func() {
 		go func() {
 			subject := "Payment Confirmed - Game Rental"
 			platform := "Unknown"
-			if game.Platform != nil {
-				platform = *game.Platform
+			if booking.Game.Platform != nil {
+				platform = *booking.Game.Platform
 			}
 			htmlContent := fmt.Sprintf(`
 				<h1>Payment Successful!</h1>
@@ -273,33 +460,246 @@ func (s *bookingService) ConfirmPayment(bookingID uint) error {
 					<li><strong>Game:</strong> %s</li>
 					<li><strong>Platform:</strong> %s</li>
 					<li><strong>Period:</strong> %s to %s</li>
-					<li><strong>Amount:</strong> Rp %.0f</li>
+					%s
+					<li><strong>Amount:</strong> %s</li>
 				</ul>
-			`, user.FullName, game.Name, platform, booking.StartDate.Format("2006-01-02"), booking.EndDate.Format("2006-01-02"), booking.TotalAmount)
+				<p>Your invoice %s is attached.</p>
+			`, booking.User.FullName, booking.Game.Name, platform, booking.StartDate.Format("2006-01-02"), booking.EndDate.Format("2006-01-02"), taxLineHTML(booking), booking.TotalAmount.Display(), invoice.Number)
 
-			plainText := fmt.Sprintf("Payment confirmed for %s", game.Name)
+			plainText := fmt.Sprintf("Payment confirmed for %s. Your invoice %s is attached.", booking.Game.Name, invoice.Number)
 
-			if err := s.emailRepo.SendEmail(context.Background(), user.Email, subject, plainText, htmlContent); err != nil {
+			attachments := invoiceAttachments(invoice, booking, booking.ChargedPayment())
+			if err := s.emailRepo.SendEmailWithAttachments(context.Background(), booking.User.Email, subject, plainText, htmlContent, attachments); err != nil {
 				logrus.WithError(err).Error("Failed to send payment confirmation email")
 			}
 		}()
+	}, nil
+}
+
+// FailPayment handles a booking payment attempt that was just marked failed in
+// the caller's transaction. While the payment window is open the booking stays
+// pending so the customer can try again; otherwise it is cancelled.
+func (s *bookingService) FailPayment(repos repository.Repositories, bookingID uint) (AfterCommit, error) {
+	booking, err := repos.Bookings.GetByID(bookingID)
+	if err != nil {
+		return nil, ErrBookingNotFound
 	}
 
-	return nil
+	if booking.Status == model.BookingPending && paymentWindowOpen(booking.PaymentDueAt) {
+		// SEND EMAIL: Payment failed, try again
+		return func() {
+			go func() {
+				subject := "Payment Failed - Game Rental"
+				htmlContent := fmt.Sprintf(`
+					<h1>Payment Failed</h1>
+					<p>Hi %s,</p>
+					<p>Your payment for <strong>%s</strong> (%s to %s) did not go through.</p>
+					<p>We are holding your booking until %s. Please try again, with another payment method if needed.</p>
+				`, booking.User.FullName, booking.Game.Name, booking.StartDate.Format("2006-01-02"), booking.EndDate.Format("2006-01-02"), booking.PaymentDueAt.Format("2006-01-02 15:04"))
+
+				plainText := fmt.Sprintf("Your payment for %s failed. Please try again before %s.", booking.Game.Name, booking.PaymentDueAt.Format("2006-01-02 15:04"))
+
+				if err := s.emailRepo.SendEmail(context.Background(), booking.User.Email, subject, plainText, htmlContent); err != nil {
+					logrus.WithError(err).Error("Failed to send payment failed email")
+				}
+			}()
+		}, nil
+	}
+
+	if err := transitionBooking(repos, booking, model.BookingCancelled, nil, "payment failed"); err != nil {
+		return nil, err
+	}
+	booking.Status = model.BookingCancelled
+
+	return func() {
+		s.waitlistService.NotifyCapacityReleased(booking.GameID)
+	}, nil
+}
+
+// ChargeBack cancels a booking whose payment was taken back through a lost
+// dispute, in the caller's transaction. Bookings that were already handed over
+// keep their status, the return settles them.
+func (s *bookingService) ChargeBack(repos repository.Repositories, bookingID uint) (AfterCommit, error) {
+	booking, err := repos.Bookings.GetByID(bookingID)
+	if err != nil {
+		return nil, ErrBookingNotFound
+	}
+
+	if booking.Status != model.BookingPending && booking.Status != model.BookingConfirmed {
+		return nil, nil
+	}
+
+	if err := transitionBooking(repos, booking, model.BookingCancelled, nil, "payment charged back"); err != nil {
+		return nil, err
+	}
+	booking.Status = model.BookingCancelled
+
+	return func() {
+		s.waitlistService.NotifyCapacityReleased(booking.GameID)
+	}, nil
 }
 
-func (s *bookingService) FailPayment(bookingID uint) error {
+// CancelRefunded cancels a booking whose payment was refunded in full. Bookings
+// that were already handed over keep their status, the return settles them.
+func (s *bookingService) CancelRefunded(actorID uint, bookingID uint) error {
 	booking, err := s.bookingRepo.GetByID(bookingID)
 	if err != nil {
 		return ErrBookingNotFound
 	}
 
-	err = s.gameRepo.ReleaseStock(booking.GameID)
-	if err != nil {
+	if booking.Status != model.BookingPending && booking.Status != model.BookingConfirmed {
+		return nil
+	}
+
+	if err := s.transition(booking, model.BookingCancelled, &actorID, "payment refunded"); err != nil {
 		return err
 	}
 
-	return s.bookingRepo.UpdateStatus(bookingID, model.BookingCancelled)
+	s.waitlistService.NotifyCapacityReleased(booking.GameID)
+	return nil
+}
+
+// ExpireUnpaidBookings cancels pending bookings whose payment window has passed,
+// fails their pending payment, cancels its charge at the gateway and notifies
+// the customer. Cancelling frees the
+// dates the booking held; stock is untouched because no copy left the shelf.
+func (s *bookingService) ExpireUnpaidBookings() (int, error) {
+	bookings, err := s.bookingRepo.GetExpiredPending(time.Now(), expiryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	releasedGames := make(map[uint]bool)
+	for _, booking := range bookings {
+		var failedPayment *model.Payment
+		err := s.txManager.WithTransaction(func(repos repository.Repositories) error {
+			if err := transitionBooking(repos, booking, model.BookingCancelled, nil, "payment window expired"); err != nil {
+				return err
+			}
+			if booking.Payment != nil && booking.Payment.Status == model.PaymentPending {
+				failedPayment = booking.Payment
+				return failPendingPayment(repos, booking.Payment, "payment window expired")
+			}
+			return nil
+		})
+		if err != nil {
+			logrus.WithError(err).WithField("booking_id", booking.ID).Warn("Failed to expire booking")
+			continue
+		}
+		booking.Status = model.BookingCancelled
+		cancelGatewayCharge(s.gateways, failedPayment)
+		releasedGames[booking.GameID] = true
+		expired++
+
+		// SEND EMAIL: Booking expired
+		go func(booking *model.Booking) {
+			subject := "Booking Expired - Game Rental"
+			htmlContent := fmt.Sprintf(`
+				<h1>Booking Expired</h1>
+				<p>Hi %s,</p>
+				<p>We did not receive payment for your booking of <strong>%s</strong> (%s to %s) in time, so it has been cancelled.</p>
+				<p>You are welcome to create a new booking if the game is still available.</p>
+			`, booking.User.FullName, booking.Game.Name, booking.StartDate.Format("2006-01-02"), booking.EndDate.Format("2006-01-02"))
+
+			plainText := fmt.Sprintf("Your booking for %s expired because payment was not received in time.", booking.Game.Name)
+
+			if err := s.emailRepo.SendEmail(context.Background(), booking.User.Email, subject, plainText, htmlContent); err != nil {
+				logrus.WithError(err).Error("Failed to send booking expired email")
+			}
+		}(booking)
+	}
+
+	for gameID := range releasedGames {
+		s.waitlistService.NotifyCapacityReleased(gameID)
+	}
+
+	if expired > 0 {
+		logrus.WithField("count", expired).Info("Expired unpaid bookings")
+	}
+	return expired, nil
+}
+
+// claimWaitlistHold marks the customer's active waitlist hold for the game as
+// converted when it covers the booking dates, so the hold no longer counts
+// against availability. It returns nil when there is no matching hold.
+func claimWaitlistHold(repos repository.Repositories, userID uint, booking *model.Booking) (*model.WaitlistEntry, error) {
+	hold, err := repos.Waitlist.GetActiveByUserAndGame(userID, booking.GameID)
+	if err != nil || hold.Status != model.WaitlistOffered {
+		return nil, nil
+	}
+
+	if hold.HoldExpiresAt == nil || hold.HoldExpiresAt.Before(time.Now()) ||
+		booking.StartDate.Before(hold.StartDate) || booking.EndDate.After(hold.EndDate) {
+		return nil, nil
+	}
+
+	claimed, err := repos.Waitlist.UpdateStatusFrom(hold.ID, model.WaitlistOffered, model.WaitlistConverted)
+	if err != nil || !claimed {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// prepareBooking validates the requested dates against the game and fills in
+// the prices, owner, status and payment deadline of a new booking
+func prepareBooking(booking *model.Booking, game *model.Game, userID uint, paymentDueAt time.Time) error {
+	if !game.IsActive {
+		return errors.New("game is not available for booking")
+	}
+
+	if booking.StartDate.After(booking.EndDate) || booking.StartDate.Before(time.Now().Truncate(24*time.Hour)) {
+		return ErrBookingInvalidDate
+	}
+
+	rentalDays := int(booking.EndDate.Sub(booking.StartDate).Hours()/24) + 1
+	totalRentalPrice := game.RentalPricePerDay.Mul(int64(rentalDays))
+
+	booking.UserID = userID
+	booking.RentalDays = rentalDays
+	booking.DailyPrice = game.RentalPricePerDay
+	booking.TotalRentalPrice = totalRentalPrice
+	booking.SecurityDeposit = game.SecurityDeposit
+	booking.TotalAmount = totalRentalPrice.Add(game.SecurityDeposit)
+	booking.Status = model.BookingPending
+	booking.PaymentDueAt = &paymentDueAt
+	return nil
+}
+
+// applyTax prices the VAT of a new booking once its discount is known. The
+// taxable amount is the discounted rental price, plus the deposit when the rule
+// taxes it; exclusive tax is added to the total.
+func applyTax(booking *model.Booking, rule model.TaxRule) {
+	taxable := booking.TotalRentalPrice.Sub(booking.DiscountAmount)
+	if rule.DepositTaxable {
+		taxable = taxable.Add(booking.SecurityDeposit)
+	}
+
+	booking.TaxRate = rule.Rate
+	booking.TaxInclusive = rule.Inclusive
+	booking.TaxableAmount = taxable
+	booking.TaxAmount = rule.Tax(taxable)
+	if !rule.Inclusive {
+		booking.TotalAmount = booking.TotalAmount.Add(booking.TaxAmount)
+	}
+}
+
+// taxLabel names a tax line, e.g. "VAT 11%" or "VAT 11% (included)"
+func taxLabel(rule model.TaxRule) string {
+	label := fmt.Sprintf("VAT %d%%", rule.Rate)
+	if rule.Inclusive {
+		label += " (included)"
+	}
+	return label
+}
+
+// taxLineHTML is the email list item itemizing the booking's tax, empty when
+// it has none
+func taxLineHTML(booking *model.Booking) string {
+	if !booking.TaxAmount.IsPositive() {
+		return ""
+	}
+	return fmt.Sprintf("<li><strong>%s:</strong> %s</li>", taxLabel(booking.TaxRule()), booking.TaxAmount.Display())
 }
 
 func (s *bookingService) canManageBookings(role model.UserRole) bool {
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
+)
+
+// newBookingFixture is a booking service over an in-memory store holding
+// bookings of game
+func newBookingFixture(game *model.Game, bookings ...*model.Booking) (*bookingService, *fakeBookingStore, *fakeTxManager) {
+	store := &fakeBookingStore{bookings: bookings}
+	gameRepo := &fakeGameRepo{game: game, bookings: store}
+	historyRepo := &fakeHistoryRepo{}
+	txManager := &fakeTxManager{repos: repository.Repositories{
+		Bookings:       store,
+		BookingHistory: historyRepo,
+		Games:          gameRepo,
+		Waitlist:       &fakeWaitlistRepo{},
+		DateChanges:    &fakeDateChangeRepo{},
+	}}
+	svc := NewBookingService(txManager, store, historyRepo, gameRepo, &fakeUserRepo{}, nil, transaction.NewRegistry(), &email.MockEmailRepository{}, 24*time.Hour, model.TaxRule{}).(*bookingService)
+	return svc, store, txManager
+}
+
+// ============= TEST LAST COPY =============
+func TestCreate_RejectsOverlapOnceAllCopiesBooked(t *testing.T) {
+	game := &model.Game{ID: 1, Stock: 2, AvailableStock: 2, RentalPricePerDay: model.NewMoney(10000), SecurityDeposit: model.NewMoney(50000), IsActive: true}
+	svc, bookings, _ := newBookingFixture(game)
+
+	start := time.Now().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	end := start.AddDate(0, 0, 2)
+
+	assert.NoError(t, svc.Create(1, &model.Booking{GameID: 1, StartDate: start, EndDate: end}, ""))
+	assert.NoError(t, svc.Create(2, &model.Booking{GameID: 1, StartDate: start.AddDate(0, 0, 1), EndDate: end.AddDate(0, 0, 1)}, ""), "the last copy")
+	assert.ErrorIs(t, svc.Create(3, &model.Booking{GameID: 1, StartDate: end, EndDate: end.AddDate(0, 0, 2)}, ""), ErrGameStockInsufficient)
+	assert.Len(t, bookings.bookings, 2)
+	assert.Equal(t, 2, game.AvailableStock, "dates are held, no copy leaves the shelf before handover")
+}
+
+// ============= TEST CONCURRENT BOOKINGS =============
+func TestCreate_ConcurrentBookingsForLastCopy(t *testing.T) {
+	game := &model.Game{ID: 1, Stock: 1, AvailableStock: 1, RentalPricePerDay: model.NewMoney(10000), SecurityDeposit: model.NewMoney(50000), IsActive: true}
+	svc, bookings, txManager := newBookingFixture(game)
+	// Transactions overlap; only the game row lock keeps them apart
+	svc.txManager = newLockingTxManager(txManager.repos)
+
+	start := time.Now().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	end := start.AddDate(0, 0, 2)
+
+	const attempts = 20
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		successes int
+		rejected  int
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(userID uint) {
+			defer wg.Done()
+			err := svc.Create(userID, &model.Booking{GameID: game.ID, StartDate: start, EndDate: end}, "")
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				successes++
+			case errors.Is(err, ErrGameStockInsufficient):
+				rejected++
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(uint(i + 1))
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, successes)
+	assert.Equal(t, attempts-1, rejected)
+	assert.Len(t, bookings.bookings, 1)
+}
+
+// ============= TEST NON-OVERLAPPING BOOKINGS =============
+func TestCreate_SingleCopyDifferentDates(t *testing.T) {
+	game := &model.Game{ID: 1, Stock: 1, RentalPricePerDay: model.NewMoney(10000), IsActive: true}
+	svc, _, _ := newBookingFixture(game)
+
+	nextWeek := time.Now().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	nextMonth := nextWeek.AddDate(0, 1, 0)
+
+	assert.NoError(t, svc.Create(1, &model.Booking{GameID: 1, StartDate: nextMonth, EndDate: nextMonth.AddDate(0, 0, 2)}, ""))
+	assert.NoError(t, svc.Create(2, &model.Booking{GameID: 1, StartDate: nextWeek, EndDate: nextWeek.AddDate(0, 0, 2)}, ""))
+	assert.ErrorIs(t, svc.Create(3, &model.Booking{GameID: 1, StartDate: nextWeek.AddDate(0, 0, 1), EndDate: nextWeek.AddDate(0, 0, 3)}, ""), ErrGameStockInsufficient)
+}
+
+// ============= TEST FAILED PAYMENT ATTEMPTS =============
+func TestFailPayment_KeepsBookingHeldForRetry(t *testing.T) {
+	dueAt := time.Now().Add(time.Hour)
+	svc, bookings, txManager := newBookingFixture(nil, &model.Booking{ID: 1, Status: model.BookingPending, PaymentDueAt: &dueAt})
+
+	after, err := svc.FailPayment(txManager.repos, 1)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, after, "the customer is told to try again")
+	assert.Equal(t, model.BookingPending, bookings.bookings[0].Status)
+}
+
+func TestFailPayment_CancelsOnceWindowHasPassed(t *testing.T) {
+	dueAt := time.Now().Add(-time.Minute)
+	svc, bookings, txManager := newBookingFixture(nil, &model.Booking{ID: 1, Status: model.BookingPending, PaymentDueAt: &dueAt})
+
+	_, err := svc.FailPayment(txManager.repos, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, model.BookingCancelled, bookings.bookings[0].Status)
+}
+
+// ============= TEST EXPIRY =============
+func TestExpireUnpaidBookings_FreesDatesWithoutTouchingStock(t *testing.T) {
+	start := time.Now().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	end := start.AddDate(0, 0, 2)
+	dueAt := time.Now().Add(-time.Minute)
+	txID := "tx-1"
+	game := &model.Game{ID: 1, Stock: 1, AvailableStock: 1, RentalPricePerDay: model.NewMoney(10000), IsActive: true}
+	svc, bookings, txManager := newBookingFixture(game, &model.Booking{
+		ID: 1, UserID: 1, GameID: 1, Status: model.BookingPending, StartDate: start, EndDate: end, PaymentDueAt: &dueAt,
+		Payment: &model.Payment{ID: 1, Status: model.PaymentPending, Provider: model.ProviderMidtrans, ProviderPaymentID: &txID},
+	})
+	payments := &fakePaymentRepo{payment: &model.Payment{ID: 1, Status: model.PaymentPending}}
+	txManager.repos.Payments = payments
+	gateway := &transaction.MockTransactionRepository{}
+	svc.gateways.Register(string(model.ProviderMidtrans), gateway)
+	waitlist := &MockWaitlistService{}
+	waitlist.On("NotifyCapacityReleased", uint(1)).Return()
+	svc.waitlistService = waitlist
+
+	assert.ErrorIs(t, svc.Create(2, &model.Booking{GameID: 1, StartDate: start, EndDate: end}, ""), ErrGameStockInsufficient)
+
+	expired, err := svc.ExpireUnpaidBookings()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	assert.Equal(t, model.BookingCancelled, bookings.bookings[0].Status)
+	assert.Equal(t, model.PaymentFailed, payments.payment.Status)
+	assert.Equal(t, []string{"tx-1"}, gateway.Cancelled, "the charge can no longer be paid")
+	waitlist.AssertCalled(t, "NotifyCapacityReleased", uint(1))
+
+	assert.NoError(t, svc.Create(2, &model.Booking{GameID: 1, StartDate: start, EndDate: end}, ""), "the expired booking no longer holds the dates")
+	assert.Equal(t, 1, game.AvailableStock, "no copy left the shelf, so none comes back")
+}
+
+// ============= TEST CANCEL =============
+func TestCancel_FailsPendingPaymentAndCancelsCharge(t *testing.T) {
+	start := time.Now().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	txID := "tx-1"
+	svc, bookings, txManager := newBookingFixture(&model.Game{ID: 1, Stock: 1}, &model.Booking{
+		ID: 1, UserID: 1, GameID: 1, Status: model.BookingPending, StartDate: start, EndDate: start.AddDate(0, 0, 2),
+		Payment: &model.Payment{ID: 1, Status: model.PaymentPending, Provider: model.ProviderMidtrans, ProviderPaymentID: &txID},
+	})
+	payments := &fakePaymentRepo{payment: &model.Payment{ID: 1, Status: model.PaymentPending}}
+	txManager.repos.Payments = payments
+	gateway := &transaction.MockTransactionRepository{}
+	svc.gateways.Register(string(model.ProviderMidtrans), gateway)
+	waitlist := &MockWaitlistService{}
+	waitlist.On("NotifyCapacityReleased", uint(1)).Return()
+	svc.waitlistService = waitlist
+
+	assert.NoError(t, svc.Cancel(1, 1))
+	assert.Equal(t, model.BookingCancelled, bookings.bookings[0].Status)
+	assert.Equal(t, model.PaymentFailed, payments.payment.Status, "a payment arriving later is refunded")
+	assert.Equal(t, []string{"tx-1"}, gateway.Cancelled)
+}
+
+// ============= TEST TAX =============
+func TestApplyTax(t *testing.T) {
+	newBooking := func() *model.Booking {
+		return &model.Booking{
+			TotalRentalPrice: model.NewMoney(150000),
+			SecurityDeposit:  model.NewMoney(100000),
+			DiscountAmount:   model.NewMoney(50000),
+			TotalAmount:      model.NewMoney(200000),
+		}
+	}
+
+	exclusive := newBooking()
+	applyTax(exclusive, model.TaxRule{Rate: 11})
+	assert.Equal(t, model.NewMoney(100000), exclusive.TaxableAmount, "the discounted rental, not the deposit")
+	assert.Equal(t, model.NewMoney(11000), exclusive.TaxAmount)
+	assert.Equal(t, model.NewMoney(211000), exclusive.TotalAmount)
+	assert.Equal(t, "VAT 11%", taxLabel(exclusive.TaxRule()))
+
+	withDeposit := newBooking()
+	applyTax(withDeposit, model.TaxRule{Rate: 11, DepositTaxable: true})
+	assert.Equal(t, model.NewMoney(22000), withDeposit.TaxAmount)
+	assert.Equal(t, model.NewMoney(222000), withDeposit.TotalAmount)
+
+	inclusive := newBooking()
+	applyTax(inclusive, model.TaxRule{Rate: 11, Inclusive: true})
+	assert.Equal(t, model.NewMoney(9910), inclusive.TaxAmount, "100000 × 11/111 in whole rupiah")
+	assert.Equal(t, model.NewMoney(200000), inclusive.TotalAmount)
+	assert.Equal(t, "VAT 11% (included)", taxLabel(inclusive.TaxRule()))
+
+	// 11% of 12345 is 1357.95, which Midtrans could not charge
+	oddPrice := &model.Booking{TotalRentalPrice: model.NewMoney(12345), TotalAmount: model.NewMoney(12345)}
+	applyTax(oddPrice, model.TaxRule{Rate: 11})
+	assert.Equal(t, model.NewMoney(1358), oddPrice.TaxAmount)
+	assert.Equal(t, model.NewMoney(13703), oddPrice.TotalAmount)
+	_, whole := oddPrice.TotalAmount.Whole()
+	assert.True(t, whole)
+}
+
+func TestPriceChange(t *testing.T) {
+	booking := &model.Booking{
+		TotalRentalPrice: model.NewMoney(150000),
+		SecurityDeposit:  model.NewMoney(100000),
+		DiscountAmount:   model.NewMoney(50000),
+		TotalAmount:      model.NewMoney(200000),
+	}
+	applyTax(booking, model.TaxRule{Rate: 11})
+
+	// One more day at 50000
+	change := &model.BookingDateChange{TotalRentalPrice: model.NewMoney(200000)}
+	priceChange(booking, change)
+	assert.Equal(t, model.NewMoney(50000), change.DiscountAmount, "kept without the promo code")
+	assert.Equal(t, model.NewMoney(16500), change.TaxAmount)
+	assert.Equal(t, model.NewMoney(55500), change.AmountDue)
+
+	applyDateChange(booking, change)
+	assert.Equal(t, model.NewMoney(150000), booking.TaxableAmount)
+	assert.Equal(t, model.NewMoney(16500), booking.TaxAmount)
+	assert.Equal(t, model.NewMoney(266500), booking.TotalAmount)
+
+	// A booking that cost less than its rental price implies never refunds more than was paid
+	underpaid := &model.Booking{
+		TotalRentalPrice: model.NewMoney(150000), SecurityDeposit: model.NewMoney(100000),
+		TaxableAmount: model.NewMoney(150000), TotalAmount: model.NewMoney(110000),
+	}
+	change = &model.BookingDateChange{TotalRentalPrice: model.NewMoney(50000)}
+	priceChange(underpaid, change)
+	assert.Equal(t, model.NewMoney(-10000), change.AmountDue)
+
+	// One more day at 12345 adds 1357.95 of tax, charged as whole rupiah
+	odd := &model.Booking{TotalRentalPrice: model.NewMoney(12345), TotalAmount: model.NewMoney(12345)}
+	applyTax(odd, model.TaxRule{Rate: 11})
+	change = &model.BookingDateChange{TotalRentalPrice: model.NewMoney(24690)}
+	priceChange(odd, change)
+	assert.Equal(t, model.NewMoney(2716), change.TaxAmount)
+	assert.Equal(t, model.NewMoney(13703), change.AmountDue)
+}
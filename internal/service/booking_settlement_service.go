@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+	"github.com/yoockh/go-game-rental-api/internal/utils"
+)
+
+var (
+	ErrReturnBeforeStart       = errors.New("return date cannot be before the booking start date")
+	ErrReturnInFuture          = errors.New("return date cannot be in the future")
+	ErrDamageChargeNegative    = errors.New("damage charge cannot be negative")
+	ErrBookingReturnRequired   = errors.New("completing a booking requires recording the return")
+	ErrSettlementNoRefundRoute = errors.New("booking has no gateway payment to refund the deposit to")
+	ErrSettlementNotFound      = errors.New("settlement not found")
+	ErrDepositRefundNotFailed  = errors.New("only a failed deposit refund can be retried")
+)
+
+type BookingSettlementService interface {
+	// Admin
+	RecordReturn(adminID uint, adminRole model.UserRole, bookingID uint, returnedAt time.Time, damageCharge model.Money, damageNotes string, toWallet bool) (*model.BookingSettlement, error)
+	RetryDepositRefund(adminID uint, adminRole model.UserRole, bookingID uint, toWallet bool) (*model.BookingSettlement, error)
+}
+
+type bookingSettlementService struct {
+	txManager       repository.TxManager
+	bookingRepo     repository.BookingRepository
+	settlementRepo  repository.BookingSettlementRepository
+	waitlistService WaitlistService
+	refundService   RefundService
+	emailRepo       email.EmailRepository
+}
+
+func NewBookingSettlementService(
+	txManager repository.TxManager,
+	bookingRepo repository.BookingRepository,
+	settlementRepo repository.BookingSettlementRepository,
+	waitlistService WaitlistService,
+	refundService RefundService,
+	emailRepo email.EmailRepository,
+) BookingSettlementService {
+	return &bookingSettlementService{
+		txManager:       txManager,
+		bookingRepo:     bookingRepo,
+		settlementRepo:  settlementRepo,
+		waitlistService: waitlistService,
+		refundService:   refundService,
+		emailRepo:       emailRepo,
+	}
+}
+
+// RecordReturn completes an active booking and settles its deposit. Late fees
+// and damage charges are taken from the deposit and the rest is refunded
+// through the provider of the booking payment, or credited to the customer's
+// wallet with toWallet, as far as the payment was not charged back. A failed
+// refund does not undo the return; it stays failed on the settlement until an
+// admin retries it with RetryDepositRefund.
+func (s *bookingSettlementService) RecordReturn(adminID uint, adminRole model.UserRole, bookingID uint, returnedAt time.Time, damageCharge model.Money, damageNotes string, toWallet bool) (*model.BookingSettlement, error) {
+	if adminRole != model.RoleAdmin && adminRole != model.RoleSuperAdmin {
+		return nil, ErrInsufficientPermission
+	}
+
+	if damageCharge.IsNegative() {
+		return nil, ErrDamageChargeNegative
+	}
+
+	booking, err := s.bookingRepo.GetByID(bookingID)
+	if err != nil {
+		return nil, ErrBookingNotFound
+	}
+
+	returnedAt = returnedAt.Truncate(24 * time.Hour)
+	if returnedAt.Before(booking.StartDate) {
+		return nil, ErrReturnBeforeStart
+	}
+	if returnedAt.After(time.Now().Truncate(24 * time.Hour)) {
+		return nil, ErrReturnInFuture
+	}
+
+	settlement := calculateSettlement(booking, returnedAt, damageCharge)
+	if payment := booking.ChargedPayment(); payment != nil && settlement.RefundStatus == model.DepositRefundPending {
+		// A customer who took the payment back through a dispute has had the deposit back already
+		withholdChargedBackDeposit(settlement, payment)
+	}
+	settlement.DamageNotes = utils.PtrOrNil(damageNotes)
+	settlement.SettledBy = adminID
+	settlement.RefundToWallet = toWallet && settlement.RefundStatus == model.DepositRefundPending
+
+	err = s.txManager.WithTransaction(func(repos repository.Repositories) error {
+		if err := transitionBooking(repos, booking, model.BookingCompleted, &adminID, "game returned"); err != nil {
+			return err
+		}
+		return repos.Settlements.Create(settlement)
+	})
+	if err != nil {
+		return nil, err
+	}
+	booking.Status = model.BookingCompleted
+
+	// The returned copy is back on the shelf
+	s.waitlistService.NotifyCapacityReleased(booking.GameID)
+
+	if settlement.RefundStatus == model.DepositRefundPending {
+		s.refundDeposit(booking, settlement, adminID)
+	}
+
+	// SEND EMAIL: Return settlement
+	refundDestination := ""
+	switch {
+	case settlement.RefundStatus == model.DepositRefundFailed:
+		refundDestination = " (pending, we will email you once it is sent)"
+	case settlement.RefundToWallet:
+		refundDestination = " (to your wallet)"
+	}
+	go func() {
+		subject := "Game Returned - Game Rental"
+		htmlContent := fmt.Sprintf(`
+			<h1>Thanks for Returning %s</h1>
+			<p>Hi %s,</p>
+			<h3>Deposit Settlement:</h3>
+			<ul>
+				<li><strong>Returned on:</strong> %s</li>
+				<li><strong>Security deposit:</strong> %s</li>
+				<li><strong>Late fee (%d days):</strong> %s</li>
+				<li><strong>Damage charge:</strong> %s</li>
+				<li><strong>Deposit refund%s:</strong> %s</li>
+				<li><strong>Outstanding amount:</strong> %s</li>
+			</ul>
+		`, booking.Game.Name, booking.User.FullName, settlement.ReturnedAt.Format("2006-01-02"),
+			settlement.DepositAmount.Display(), settlement.LateDays, settlement.LateFee.Display(), settlement.DamageCharge.Display(),
+			refundDestination, settlement.DepositRefund.Display(), settlement.OutstandingAmount.Display())
+
+		plainText := fmt.Sprintf("%s returned on %s. Deposit refund%s: %s", booking.Game.Name, settlement.ReturnedAt.Format("2006-01-02"), refundDestination, settlement.DepositRefund.Display())
+
+		if err := s.emailRepo.SendEmail(context.Background(), booking.User.Email, subject, plainText, htmlContent); err != nil {
+			logrus.WithError(err).Error("Failed to send return settlement email")
+		}
+	}()
+
+	return settlement, nil
+}
+
+// RetryDepositRefund sends a deposit refund that failed at return again, to
+// the provider of the booking payment or, with toWallet, to the customer's
+// wallet. The customer is emailed once it goes through.
+func (s *bookingSettlementService) RetryDepositRefund(adminID uint, adminRole model.UserRole, bookingID uint, toWallet bool) (*model.BookingSettlement, error) {
+	if adminRole != model.RoleAdmin && adminRole != model.RoleSuperAdmin {
+		return nil, ErrInsufficientPermission
+	}
+
+	booking, err := s.bookingRepo.GetByID(bookingID)
+	if err != nil {
+		return nil, ErrBookingNotFound
+	}
+
+	settlement := booking.Settlement
+	if settlement == nil {
+		return nil, ErrSettlementNotFound
+	}
+	if settlement.RefundStatus != model.DepositRefundFailed {
+		return nil, ErrDepositRefundNotFailed
+	}
+
+	// Claim the retry so two admins cannot refund the deposit twice
+	claimed, err := s.settlementRepo.UpdateRefundStatusFrom(settlement.ID, model.DepositRefundFailed, model.DepositRefundPending)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrDepositRefundNotFailed
+	}
+	settlement.RefundStatus = model.DepositRefundPending
+	settlement.RefundError = nil
+	settlement.RefundToWallet = toWallet
+
+	// The customer may have taken the payment back since the return
+	if payment := booking.ChargedPayment(); payment != nil {
+		withholdChargedBackDeposit(settlement, payment)
+	}
+	if settlement.RefundStatus == model.DepositChargedBack {
+		if err := s.settlementRepo.Update(settlement); err != nil {
+			return nil, err
+		}
+		return settlement, nil
+	}
+
+	s.refundDeposit(booking, settlement, adminID)
+	if settlement.RefundStatus != model.DepositRefunded {
+		return settlement, nil
+	}
+
+	// SEND EMAIL: Deposit refunded
+	go func() {
+		destination := "your original payment method"
+		if settlement.RefundToWallet {
+			destination = "your wallet"
+		}
+		subject := "Deposit Refunded - Game Rental"
+		htmlContent := fmt.Sprintf(`
+			<h1>Deposit Refunded</h1>
+			<p>Hi %s,</p>
+			<p>The security deposit refund of <strong>%s</strong> for your rental of <strong>%s</strong> has been sent to %s.</p>
+		`, booking.User.FullName, settlement.DepositRefund.Display(), booking.Game.Name, destination)
+
+		plainText := fmt.Sprintf("Your deposit refund of %s for %s has been sent to %s", settlement.DepositRefund.Display(), booking.Game.Name, destination)
+
+		if err := s.emailRepo.SendEmail(context.Background(), booking.User.Email, subject, plainText, htmlContent); err != nil {
+			logrus.WithError(err).Error("Failed to send deposit refunded email")
+		}
+	}()
+
+	return settlement, nil
+}
+
+// refundDeposit sends the deposit refund to the provider, or the wallet, and
+// stores the outcome on the settlement
+func (s *bookingSettlementService) refundDeposit(booking *model.Booking, settlement *model.BookingSettlement, actorID uint) {
+	payment := booking.ChargedPayment()
+	if payment == nil {
+		settlement.RefundStatus = model.DepositRefundFailed
+		settlement.RefundError = utils.PtrOrNil(ErrSettlementNoRefundRoute.Error())
+	} else {
+		refund, err := s.refundService.Refund(payment, settlement.DepositRefund, "security deposit refund", &actorID, settlement.RefundToWallet)
+		if err != nil {
+			settlement.RefundStatus = model.DepositRefundFailed
+			settlement.RefundError = utils.PtrOrNil(err.Error())
+		} else {
+			settlement.RefundStatus = model.DepositRefunded
+			settlement.ProviderRefundID = refund.ProviderRefundID
+		}
+	}
+
+	if err := s.settlementRepo.Update(settlement); err != nil {
+		logrus.WithError(err).WithField("booking_id", booking.ID).Error("Failed to save deposit refund result")
+	}
+	if settlement.RefundStatus == model.DepositRefundFailed {
+		logrus.WithFields(logrus.Fields{
+			"booking_id": booking.ID,
+			"amount":     settlement.DepositRefund.String(),
+			"reason":     *settlement.RefundError,
+		}).Warn("Deposit refund failed, needs a retry")
+	}
+}
+
+// calculateSettlement charges DailyPrice for every day past EndDate, adds the
+// damage charge and takes both out of the security deposit. Charges above the
+// deposit are reported as outstanding.
+func calculateSettlement(booking *model.Booking, returnedAt time.Time, damageCharge model.Money) *model.BookingSettlement {
+	lateDays := 0
+	if returnedAt.After(booking.EndDate) {
+		lateDays = int(returnedAt.Sub(booking.EndDate).Hours() / 24)
+	}
+	lateFee := booking.DailyPrice.Mul(int64(lateDays))
+	charges := lateFee.Add(damageCharge)
+
+	settlement := &model.BookingSettlement{
+		BookingID:     booking.ID,
+		ReturnedAt:    returnedAt,
+		LateDays:      lateDays,
+		LateFee:       lateFee,
+		DamageCharge:  damageCharge,
+		DepositAmount: booking.SecurityDeposit,
+		RefundStatus:  model.DepositRefundNone,
+	}
+
+	if !charges.LessThan(booking.SecurityDeposit) {
+		settlement.OutstandingAmount = charges.Sub(booking.SecurityDeposit)
+	} else {
+		settlement.DepositRefund = booking.SecurityDeposit.Sub(charges)
+		settlement.RefundStatus = model.DepositRefundPending
+	}
+	return settlement
+}
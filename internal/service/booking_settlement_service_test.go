@@ -0,0 +1,79 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+)
+
+// ============= TEST SETTLEMENT CALCULATION =============
+func TestCalculateSettlement(t *testing.T) {
+	endDate := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	booking := &model.Booking{ID: 1, EndDate: endDate, DailyPrice: model.NewMoney(10000), SecurityDeposit: model.NewMoney(50000)}
+
+	tests := []struct {
+		name        string
+		returnedAt  time.Time
+		damage      int64
+		lateDays    int
+		refund      int64
+		outstanding int64
+		status      model.DepositRefundStatus
+	}{
+		{"on time", endDate, 0, 0, 50000, 0, model.DepositRefundPending},
+		{"early", endDate.AddDate(0, 0, -2), 0, 0, 50000, 0, model.DepositRefundPending},
+		{"late with damage", endDate.AddDate(0, 0, 2), 5000, 2, 25000, 0, model.DepositRefundPending},
+		{"charges use up deposit", endDate.AddDate(0, 0, 5), 0, 5, 0, 0, model.DepositRefundNone},
+		{"charges exceed deposit", endDate.AddDate(0, 0, 4), 30000, 4, 0, 20000, model.DepositRefundNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settlement := calculateSettlement(booking, tt.returnedAt, model.NewMoney(tt.damage))
+			assert.Equal(t, tt.lateDays, settlement.LateDays)
+			assert.Equal(t, booking.DailyPrice.Mul(int64(tt.lateDays)), settlement.LateFee)
+			assert.Equal(t, model.NewMoney(tt.refund), settlement.DepositRefund)
+			assert.Equal(t, model.NewMoney(tt.outstanding), settlement.OutstandingAmount)
+			assert.Equal(t, tt.status, settlement.RefundStatus)
+		})
+	}
+}
+
+// ============= TEST DEPOSIT REFUND RETRY =============
+func TestRetryDepositRefund(t *testing.T) {
+	payment := &model.Payment{ID: 1, Amount: model.NewMoney(150000), Status: model.PaymentPaid}
+	failed := &model.BookingSettlement{ID: 1, BookingID: 1, DepositRefund: model.NewMoney(50000), RefundStatus: model.DepositRefundFailed}
+	bookings := &fakeBookingStore{bookings: []*model.Booking{{ID: 1, Status: model.BookingCompleted, Payment: payment, Settlement: failed}}}
+	settlements := &fakeSettlementRepo{settlements: []*model.BookingSettlement{failed}}
+	refunds := &MockRefundService{}
+	svc := NewBookingSettlementService(&fakeTxManager{}, bookings, settlements, nil, refunds, &email.MockEmailRepository{})
+
+	_, err := svc.RetryDepositRefund(9, model.RoleCustomer, 1, false)
+	assert.ErrorIs(t, err, ErrInsufficientPermission)
+
+	providerRefundID := "rf-1"
+	refunds.On("Refund", mock.Anything, model.NewMoney(50000), "security deposit refund", mock.Anything, true).
+		Return(nil, errors.New("refund window closed")).Once()
+	refunds.On("Refund", mock.Anything, model.NewMoney(50000), "security deposit refund", mock.Anything, false).
+		Return(&model.PaymentRefund{ProviderRefundID: &providerRefundID}, nil).Once()
+
+	settlement, err := svc.RetryDepositRefund(9, model.RoleAdmin, 1, true)
+	require.NoError(t, err)
+	assert.Equal(t, model.DepositRefundFailed, settlement.RefundStatus, "failed again, still retryable")
+	require.NotNil(t, settlement.RefundError)
+
+	settlement, err = svc.RetryDepositRefund(9, model.RoleAdmin, 1, false)
+	require.NoError(t, err)
+	assert.Equal(t, model.DepositRefunded, settlement.RefundStatus)
+	assert.Equal(t, model.DepositRefunded, settlements.settlements[0].RefundStatus)
+	refunds.AssertExpectations(t)
+
+	_, err = svc.RetryDepositRefund(9, model.RoleAdmin, 1, false)
+	assert.ErrorIs(t, err, ErrDepositRefundNotFailed)
+}
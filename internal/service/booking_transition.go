@@ -0,0 +1,114 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/utils"
+)
+
+var (
+	ErrBookingInvalidStatus     = errors.New("invalid booking status")
+	ErrBookingInvalidTransition = errors.New("invalid booking status transition")
+	ErrBookingStatusConflict    = errors.New("booking status was changed by another request")
+	ErrBookingNotPaid           = errors.New("cannot confirm booking without a paid payment")
+	ErrBookingPeriodEnded       = errors.New("cannot activate booking after its rental period ended")
+)
+
+// AfterCommit runs the side effects of a change made in a caller-owned
+// transaction (emails, waitlist notifications) once that transaction has
+// committed. A nil AfterCommit has nothing to run.
+type AfterCommit func()
+
+// run calls the side effects if there are any
+func (a AfterCommit) run() {
+	if a != nil {
+		a()
+	}
+}
+
+// transition runs transitionBooking in its own database transaction and updates
+// the in-memory booking once it has committed
+func (s *bookingService) transition(booking *model.Booking, to model.BookingStatus, actorID *uint, reason string) error {
+	err := s.txManager.WithTransaction(func(repos repository.Repositories) error {
+		return transitionBooking(repos, booking, to, actorID, reason)
+	})
+	if err != nil {
+		return err
+	}
+
+	booking.Status = to
+	return nil
+}
+
+// transitionBooking moves a booking to a new status using the given repositories.
+// It checks the transition table and the guard for the target status, runs the
+// side effects of the transition and records the change in the status history.
+// actorID is nil for system changes. The caller owns the transaction.
+func transitionBooking(repos repository.Repositories, booking *model.Booking, to model.BookingStatus, actorID *uint, reason string) error {
+	from := booking.Status
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("%w: %s to %s", ErrBookingInvalidTransition, from, to)
+	}
+
+	if err := checkTransitionGuard(booking, to); err != nil {
+		return err
+	}
+
+	updated, err := repos.Bookings.UpdateStatusFrom(booking.ID, from, to)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrBookingStatusConflict
+	}
+
+	if err := applyTransitionEffects(repos, booking, to); err != nil {
+		return err
+	}
+
+	return repos.BookingHistory.Create(&model.BookingStatusHistory{
+		BookingID:  booking.ID,
+		FromStatus: &from,
+		ToStatus:   to,
+		ChangedBy:  actorID,
+		Reason:     utils.PtrOrNil(reason),
+	})
+}
+
+func checkTransitionGuard(booking *model.Booking, to model.BookingStatus) error {
+	switch to {
+	case model.BookingConfirmed:
+		payment := booking.ChargedPayment()
+		if payment == nil || payment.Status != model.PaymentPaid {
+			return ErrBookingNotPaid
+		}
+	case model.BookingActive:
+		today := time.Now().Truncate(24 * time.Hour)
+		if booking.EndDate.Before(today) {
+			return ErrBookingPeriodEnded
+		}
+	}
+	return nil
+}
+
+// applyTransitionEffects keeps the shelf count in sync: a copy leaves the shelf
+// at handover and comes back on return. Pending and confirmed bookings only hold
+// their date range, so cancelling them has no stock effect.
+func applyTransitionEffects(repos repository.Repositories, booking *model.Booking, to model.BookingStatus) error {
+	switch to {
+	case model.BookingActive:
+		if err := repos.Games.ReserveStock(booking.GameID); err != nil {
+			if errors.Is(err, repository.ErrNoStockAvailable) {
+				return ErrGameStockInsufficient
+			}
+			return err
+		}
+	case model.BookingCompleted:
+		return repos.Games.ReleaseStock(booking.GameID)
+	}
+	return nil
+}
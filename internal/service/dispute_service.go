@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
+)
+
+var (
+	ErrDisputeNotFound            = errors.New("dispute not found")
+	ErrDisputeInvalidStatus       = errors.New("invalid dispute status")
+	ErrDisputeNotAwaitingEvidence = errors.New("dispute is not waiting for evidence")
+	ErrDisputeEvidenceRequired    = errors.New("evidence cannot be empty")
+	ErrDisputeStatusConflict      = errors.New("dispute was changed by another request")
+	ErrDisputePaymentConflict     = errors.New("payment was changed by another request")
+)
+
+type DisputeService interface {
+	// Admin
+	GetDisputes(requestorRole model.UserRole, status model.DisputeStatus, limit, offset int) ([]*model.PaymentDispute, int64, error)
+	GetDispute(requestorRole model.UserRole, disputeID uint) (*model.PaymentDispute, error)
+	SubmitEvidence(adminID uint, requestorRole model.UserRole, disputeID uint, evidence string) (*model.PaymentDispute, error)
+
+	// System (for payment). RecordDispute runs in the caller's transaction and
+	// reports whether anything changed; the returned AfterCommit must run once
+	// it has committed.
+	RecordDispute(repos repository.Repositories, payment *model.Payment, report *model.PaymentDispute) (bool, AfterCommit, error)
+}
+
+type disputeService struct {
+	txManager      repository.TxManager
+	disputeRepo    repository.PaymentDisputeRepository
+	userRepo       repository.UserRepository
+	bookingService BookingService
+	orderService   OrderService
+	gateways       *transaction.Registry
+	emailRepo      email.EmailRepository
+}
+
+func NewDisputeService(
+	txManager repository.TxManager,
+	disputeRepo repository.PaymentDisputeRepository,
+	userRepo repository.UserRepository,
+	bookingService BookingService,
+	orderService OrderService,
+	gateways *transaction.Registry,
+	emailRepo email.EmailRepository,
+) DisputeService {
+	return &disputeService{
+		txManager:      txManager,
+		disputeRepo:    disputeRepo,
+		userRepo:       userRepo,
+		bookingService: bookingService,
+		orderService:   orderService,
+		gateways:       gateways,
+		emailRepo:      emailRepo,
+	}
+}
+
+func (s *disputeService) GetDisputes(requestorRole model.UserRole, status model.DisputeStatus, limit, offset int) ([]*model.PaymentDispute, int64, error) {
+	if !s.canManageDisputes(requestorRole) {
+		return nil, 0, ErrInsufficientPermission
+	}
+
+	if status != "" && !validDisputeStatus(status) {
+		return nil, 0, ErrDisputeInvalidStatus
+	}
+
+	disputes, err := s.disputeRepo.GetAll(status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := s.disputeRepo.Count(status)
+	return disputes, count, err
+}
+
+func (s *disputeService) GetDispute(requestorRole model.UserRole, disputeID uint) (*model.PaymentDispute, error) {
+	if !s.canManageDisputes(requestorRole) {
+		return nil, ErrInsufficientPermission
+	}
+
+	dispute, err := s.disputeRepo.GetByID(disputeID)
+	if err != nil {
+		return nil, ErrDisputeNotFound
+	}
+	return dispute, nil
+}
+
+// SubmitEvidence answers an open dispute. Gateways that take evidence through
+// their API get it submitted to the card issuer; for the others the admin
+// sends it through the provider's dashboard and it is recorded here. Either
+// way the dispute moves to under review.
+func (s *disputeService) SubmitEvidence(adminID uint, requestorRole model.UserRole, disputeID uint, evidence string) (*model.PaymentDispute, error) {
+	if !s.canManageDisputes(requestorRole) {
+		return nil, ErrInsufficientPermission
+	}
+
+	if evidence == "" {
+		return nil, ErrDisputeEvidenceRequired
+	}
+
+	dispute, err := s.disputeRepo.GetByID(disputeID)
+	if err != nil {
+		return nil, ErrDisputeNotFound
+	}
+	if dispute.Status != model.DisputeOpen {
+		return nil, ErrDisputeNotAwaitingEvidence
+	}
+
+	gateway, err := s.gateways.Get(string(dispute.Provider))
+	if err != nil {
+		return nil, err
+	}
+	if responder, ok := gateway.(transaction.DisputeResponder); ok {
+		if err := responder.SubmitDisputeEvidence(context.Background(), dispute.ProviderDisputeID, evidence); err != nil {
+			return nil, err
+		}
+	}
+
+	recorded, err := s.disputeRepo.RecordEvidence(dispute.ID, evidence, adminID)
+	if err != nil {
+		return nil, err
+	}
+	if !recorded {
+		return nil, ErrDisputeStatusConflict
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"dispute_id": dispute.ID,
+		"payment_id": dispute.PaymentID,
+		"admin_id":   adminID,
+	}).Info("Dispute evidence submitted")
+
+	return s.disputeRepo.GetByID(disputeID)
+}
+
+// RecordDispute stores a dispute the provider reported against payment, or
+// moves a known one on to the reported status. Reports that do not move a
+// dispute on, e.g. an update after it was decided, change nothing. Admins are
+// notified of every change. A lost dispute is applied to the payment and what
+// it paid for, see chargeBack.
+func (s *disputeService) RecordDispute(repos repository.Repositories, payment *model.Payment, report *model.PaymentDispute) (bool, AfterCommit, error) {
+	if !validDisputeStatus(report.Status) {
+		return false, nil, ErrDisputeInvalidStatus
+	}
+
+	dispute, err := repos.Disputes.GetByProviderID(report.Provider, report.ProviderDisputeID)
+	if err != nil {
+		dispute = report
+		dispute.PaymentID = payment.ID
+		if dispute.IsResolved() {
+			now := time.Now()
+			dispute.ResolvedAt = &now
+		}
+		if err := repos.Disputes.Create(dispute); err != nil {
+			return false, nil, err
+		}
+	} else {
+		if !disputeTransitionAllowed(dispute.Status, report.Status) {
+			return false, nil, nil
+		}
+		updated, err := repos.Disputes.UpdateStatusFrom(dispute.ID, dispute.Status, report.Status)
+		if err != nil {
+			return false, nil, err
+		}
+		if !updated {
+			return false, nil, ErrDisputeStatusConflict
+		}
+		dispute.Status = report.Status
+		if report.EvidenceDueBy != nil {
+			dispute.EvidenceDueBy = report.EvidenceDueBy
+			if err := repos.Disputes.Update(dispute); err != nil {
+				return false, nil, err
+			}
+		}
+	}
+
+	var released AfterCommit
+	if dispute.Status == model.DisputeLost {
+		if released, err = s.chargeBack(repos, payment.ID, dispute); err != nil {
+			return false, nil, err
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"dispute_id": dispute.ID,
+		"payment_id": payment.ID,
+		"status":     dispute.Status,
+		"amount":     dispute.Amount.String(),
+	}).Warn("Payment dispute recorded")
+
+	return true, func() {
+		released.run()
+		s.notifyAdmins(dispute)
+	}, nil
+}
+
+// chargeBack applies a lost dispute: the disputed amount, as far as it was not
+// refunded already, is charged back on the payment, bookings that were not
+// handed over yet are cancelled and deposits still waiting to be refunded are
+// kept up to what the customer took back. Copies already out are settled on
+// return, where the deposit refund is capped the same way.
+func (s *disputeService) chargeBack(repos repository.Repositories, paymentID uint, dispute *model.PaymentDispute) (AfterCommit, error) {
+	payment, err := repos.Payments.GetByIDWithRelations(paymentID)
+	if err != nil {
+		return nil, ErrPaymentNotFound
+	}
+
+	amount := dispute.Amount.Min(payment.RefundableAmount())
+	if amount.IsPositive() {
+		charged, err := repos.Payments.AddChargedBackAmount(payment.ID, amount)
+		if err != nil {
+			return nil, err
+		}
+		if !charged {
+			return nil, ErrDisputePaymentConflict
+		}
+		payment.ChargedBackAmount = payment.ChargedBackAmount.Add(amount)
+	}
+
+	var bookings []*model.Booking
+	var released AfterCommit
+	switch payment.Purpose {
+	case model.PaymentPurposeBooking:
+		if released, err = s.bookingService.ChargeBack(repos, *payment.BookingID); err != nil {
+			return nil, err
+		}
+		if payment.Booking != nil {
+			bookings = append(bookings, payment.Booking)
+		}
+	case model.PaymentPurposeOrder:
+		if released, err = s.orderService.ChargeBack(repos, *payment.OrderID); err != nil {
+			return nil, err
+		}
+		if payment.Order != nil {
+			for i := range payment.Order.Items {
+				bookings = append(bookings, &payment.Order.Items[i])
+			}
+		}
+	}
+
+	for _, booking := range bookings {
+		if booking.Status != model.BookingCompleted {
+			continue
+		}
+		settlement, err := repos.Settlements.GetByBookingID(booking.ID)
+		if err != nil {
+			continue
+		}
+		if settlement.RefundStatus != model.DepositRefundPending && settlement.RefundStatus != model.DepositRefundFailed {
+			continue
+		}
+		if withholdChargedBackDeposit(settlement, payment) {
+			if err := repos.Settlements.Update(settlement); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return released, nil
+}
+
+// withholdChargedBackDeposit caps the deposit refund of a settlement at what is
+// left of a charged back payment, reporting whether it changed. A deposit kept
+// in full is marked charged back rather than waiting for a refund.
+func withholdChargedBackDeposit(settlement *model.BookingSettlement, payment *model.Payment) bool {
+	if !payment.ChargedBackAmount.IsPositive() {
+		return false
+	}
+	refundable := payment.RefundableAmount()
+	if !settlement.DepositRefund.GreaterThan(refundable) {
+		return false
+	}
+
+	settlement.DepositRefund = refundable
+	if settlement.DepositRefund.IsZero() {
+		settlement.RefundStatus = model.DepositChargedBack
+		settlement.RefundError = nil
+	}
+	return true
+}
+
+// notifyAdmins emails every active admin about a dispute that was opened or
+// moved on
+func (s *disputeService) notifyAdmins(dispute *model.PaymentDispute) {
+	admins, err := s.userRepo.GetActiveByRoles(model.RoleAdmin, model.RoleSuperAdmin)
+	if err != nil {
+		logrus.WithError(err).WithField("dispute_id", dispute.ID).Error("Failed to load admins for dispute notification")
+		return
+	}
+
+	reason := "not given"
+	if dispute.Reason != nil {
+		reason = *dispute.Reason
+	}
+	dueBy := "not given"
+	if dispute.EvidenceDueBy != nil {
+		dueBy = dispute.EvidenceDueBy.Format("2006-01-02 15:04")
+	}
+
+	// SEND EMAIL: Dispute opened or updated
+	go func() {
+		subject := fmt.Sprintf("Payment Dispute %s - Game Rental", disputeStatusTitle(dispute.Status))
+		htmlContent := fmt.Sprintf(`
+			<h1>Payment Dispute %s</h1>
+			<p>A dispute against payment #%d is now <strong>%s</strong>.</p>
+			<ul>
+				<li><strong>Dispute:</strong> #%d (%s %s)</li>
+				<li><strong>Amount:</strong> %s</li>
+				<li><strong>Reason:</strong> %s</li>
+				<li><strong>Evidence due by:</strong> %s</li>
+			</ul>
+		`, disputeStatusTitle(dispute.Status), dispute.PaymentID, dispute.Status,
+			dispute.ID, dispute.Provider, dispute.ProviderDisputeID, dispute.Amount.Display(), reason, dueBy)
+
+		plainText := fmt.Sprintf("Dispute #%d against payment #%d is now %s. Amount: %s", dispute.ID, dispute.PaymentID, dispute.Status, dispute.Amount.Display())
+
+		for _, admin := range admins {
+			if err := s.emailRepo.SendEmail(context.Background(), admin.Email, subject, plainText, htmlContent); err != nil {
+				logrus.WithError(err).WithField("admin_id", admin.ID).Error("Failed to send dispute notification email")
+			}
+		}
+	}()
+}
+
+func (s *disputeService) canManageDisputes(role model.UserRole) bool {
+	return role == model.RoleAdmin || role == model.RoleSuperAdmin
+}
+
+// disputeTransitionAllowed tells whether a reported status moves a dispute on.
+// Won and lost are final; an inquiry can still turn into a chargeback while
+// it is being reviewed.
+func disputeTransitionAllowed(from, to model.DisputeStatus) bool {
+	switch from {
+	case model.DisputeOpen:
+		return to == model.DisputeUnderReview || to == model.DisputeWon || to == model.DisputeLost
+	case model.DisputeUnderReview:
+		return to == model.DisputeOpen || to == model.DisputeWon || to == model.DisputeLost
+	}
+	return false
+}
+
+func validDisputeStatus(status model.DisputeStatus) bool {
+	switch status {
+	case model.DisputeOpen, model.DisputeUnderReview, model.DisputeWon, model.DisputeLost:
+		return true
+	}
+	return false
+}
+
+// disputeStatusTitle is how a dispute status reads in an email subject
+func disputeStatusTitle(status model.DisputeStatus) string {
+	switch status {
+	case model.DisputeOpen:
+		return "Opened"
+	case model.DisputeUnderReview:
+		return "Under Review"
+	case model.DisputeWon:
+		return "Won"
+	case model.DisputeLost:
+		return "Lost"
+	}
+	return "Updated"
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
+)
+
+func newDisputeService(txManager repository.TxManager, disputes *fakeDisputeRepo, bookings BookingService, gateways *transaction.Registry) *disputeService {
+	return NewDisputeService(txManager, disputes, &fakeUserRepo{users: []*model.User{{ID: 1, Email: "admin@example.com", Role: model.RoleAdmin, IsActive: true}}}, bookings, nil, gateways, &email.MockEmailRepository{}).(*disputeService)
+}
+
+// ============= TEST WEBHOOK DISPUTES =============
+func TestProcessWebhook_ChargebackCancelsBooking(t *testing.T) {
+	f := newWebhookFixture(t)
+	f.payments.payment.Status = model.PaymentPaid
+
+	require.NoError(t, f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signed("chargeback", "150000.00")))
+
+	require.Len(t, f.disputes.disputes, 1)
+	dispute := f.disputes.disputes[0]
+	assert.Equal(t, model.DisputeLost, dispute.Status)
+	assert.Equal(t, "tx-3", dispute.ProviderDisputeID)
+	assert.Equal(t, "chargeback", *dispute.Reason)
+	assert.NotNil(t, dispute.ResolvedAt)
+
+	assert.True(t, f.payments.payment.ChargedBackAmount.Equal(model.NewMoney(150000)))
+	assert.Equal(t, model.PaymentPaid, f.payments.payment.Status)
+	f.bookings.AssertNumberOfCalls(t, "ChargeBack", 1)
+	assert.Equal(t, model.PaymentEventProcessed, f.events.events[0].Status)
+
+	// Redelivered, the chargeback is not taken twice
+	require.NoError(t, f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signed("chargeback", "150000.00")))
+	f.bookings.AssertNumberOfCalls(t, "ChargeBack", 1)
+	assert.Len(t, f.disputes.disputes, 1)
+}
+
+func TestProcessWebhook_ChargebackAboveGatewayAmountRejected(t *testing.T) {
+	f := newWebhookFixture(t)
+	f.payments.payment.Status = model.PaymentPaid
+	f.payments.payment.WalletAmount = model.NewMoney(50000)
+
+	err := f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signed("chargeback", "150000.00"))
+	assert.ErrorIs(t, err, ErrWebhookAmountMismatch)
+	assert.Empty(t, f.disputes.disputes)
+	assert.Equal(t, model.PaymentEventRejected, f.events.events[0].Status)
+}
+
+// ============= TEST DISPUTE LIFECYCLE =============
+func TestRecordDispute_EvidenceThenWon(t *testing.T) {
+	f := newWebhookFixture(t)
+	f.payments.payment.Status = model.PaymentPaid
+	svc := f.svc.disputeService.(*disputeService)
+	payment := f.payments.payment
+
+	report := func(status model.DisputeStatus) *model.PaymentDispute {
+		return &model.PaymentDispute{Provider: model.ProviderMidtrans, ProviderDisputeID: "dp_1", Status: status, Amount: model.NewMoney(150000)}
+	}
+	record := func(status model.DisputeStatus) bool {
+		var applied bool
+		err := svc.txManager.WithTransaction(func(repos repository.Repositories) error {
+			var err error
+			applied, _, err = svc.RecordDispute(repos, payment, report(status))
+			return err
+		})
+		require.NoError(t, err)
+		return applied
+	}
+
+	assert.True(t, record(model.DisputeOpen))
+
+	dispute, err := svc.SubmitEvidence(1, model.RoleAdmin, 1, "Game returned on time, signed receipt attached")
+	require.NoError(t, err)
+	assert.Equal(t, model.DisputeUnderReview, dispute.Status)
+	assert.Equal(t, uint(1), *dispute.EvidenceSubmittedBy)
+
+	_, err = svc.SubmitEvidence(1, model.RoleAdmin, 1, "again")
+	assert.ErrorIs(t, err, ErrDisputeNotAwaitingEvidence)
+
+	assert.False(t, record(model.DisputeUnderReview)) // the provider confirming what we did
+	assert.True(t, record(model.DisputeWon))
+	assert.False(t, record(model.DisputeLost)) // won is final
+
+	assert.Equal(t, model.DisputeWon, f.disputes.disputes[0].Status)
+	assert.True(t, payment.ChargedBackAmount.IsZero())
+	f.bookings.AssertNotCalled(t, "ChargeBack", mock.Anything, mock.Anything)
+}
+
+func TestSubmitEvidence_RequiresAdmin(t *testing.T) {
+	svc := newDisputeService(&fakeTxManager{}, &fakeDisputeRepo{}, nil, transaction.NewRegistry())
+
+	_, err := svc.SubmitEvidence(5, model.RoleCustomer, 1, "evidence")
+	assert.ErrorIs(t, err, ErrInsufficientPermission)
+}
+
+// ============= TEST DEPOSIT WITHHOLDING =============
+func TestWithholdChargedBackDeposit(t *testing.T) {
+	payment := &model.Payment{Amount: model.NewMoney(200000), Status: model.PaymentPaid}
+	settlement := &model.BookingSettlement{DepositRefund: model.NewMoney(50000), RefundStatus: model.DepositRefundPending}
+
+	assert.False(t, withholdChargedBackDeposit(settlement, payment), "nothing charged back")
+
+	payment.ChargedBackAmount = model.NewMoney(170000)
+	require.True(t, withholdChargedBackDeposit(settlement, payment))
+	assert.True(t, settlement.DepositRefund.Equal(model.NewMoney(30000)))
+	assert.Equal(t, model.DepositRefundPending, settlement.RefundStatus)
+
+	payment.ChargedBackAmount = model.NewMoney(200000)
+	require.True(t, withholdChargedBackDeposit(settlement, payment))
+	assert.True(t, settlement.DepositRefund.IsZero())
+	assert.Equal(t, model.DepositChargedBack, settlement.RefundStatus)
+}
@@ -0,0 +1,911 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/yoockh/go-game-rental-api/internal/dto"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
+	"gorm.io/gorm"
+)
+
+// Test doubles shared by the service tests. Services called by the service
+// under test are testify mocks, as in the handler tests. Repositories are kept
+// in memory instead, so conditional updates behave like the real queries; they
+// embed the repository interface and only implement the methods tests reach.
+
+// ============= MOCK BOOKING SERVICE =============
+type MockBookingService struct {
+	mock.Mock
+}
+
+func (m *MockBookingService) Create(userID uint, bookingData *model.Booking, promoCode string) error {
+	args := m.Called(userID, bookingData, promoCode)
+	return args.Error(0)
+}
+
+func (m *MockBookingService) GetUserBookings(userID uint, limit, offset int) ([]*model.Booking, int64, error) {
+	args := m.Called(userID, limit, offset)
+	return args.Get(0).([]*model.Booking), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockBookingService) GetByID(userID uint, bookingID uint) (*model.Booking, error) {
+	args := m.Called(userID, bookingID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Booking), args.Error(1)
+}
+
+func (m *MockBookingService) Cancel(userID uint, bookingID uint) error {
+	args := m.Called(userID, bookingID)
+	return args.Error(0)
+}
+
+func (m *MockBookingService) GetHistory(requestorID uint, requestorRole model.UserRole, bookingID uint) ([]*model.BookingStatusHistory, error) {
+	args := m.Called(requestorID, requestorRole, bookingID)
+	return args.Get(0).([]*model.BookingStatusHistory), args.Error(1)
+}
+
+func (m *MockBookingService) GetAll(requestorRole model.UserRole, query *dto.AdminBookingListQuery, limit, offset int) ([]*model.Booking, int64, error) {
+	args := m.Called(requestorRole, query, limit, offset)
+	return args.Get(0).([]*model.Booking), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockBookingService) UpdateStatus(requestorID uint, requestorRole model.UserRole, bookingID uint, status model.BookingStatus, reason string) error {
+	args := m.Called(requestorID, requestorRole, bookingID, status, reason)
+	return args.Error(0)
+}
+
+func (m *MockBookingService) ConfirmPayment(repos repository.Repositories, bookingID uint) (AfterCommit, error) {
+	args := m.Called(repos, bookingID)
+	return nil, args.Error(0)
+}
+
+func (m *MockBookingService) FailPayment(repos repository.Repositories, bookingID uint) (AfterCommit, error) {
+	args := m.Called(repos, bookingID)
+	return nil, args.Error(0)
+}
+
+func (m *MockBookingService) ChargeBack(repos repository.Repositories, bookingID uint) (AfterCommit, error) {
+	args := m.Called(repos, bookingID)
+	return nil, args.Error(0)
+}
+
+func (m *MockBookingService) CancelRefunded(actorID uint, bookingID uint) error {
+	args := m.Called(actorID, bookingID)
+	return args.Error(0)
+}
+
+func (m *MockBookingService) ExpireUnpaidBookings() (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
+}
+
+// ============= MOCK WAITLIST SERVICE =============
+type MockWaitlistService struct {
+	mock.Mock
+}
+
+func (m *MockWaitlistService) Join(userID uint, gameID uint, startDate, endDate time.Time) (*model.WaitlistEntry, error) {
+	args := m.Called(userID, gameID, startDate, endDate)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.WaitlistEntry), args.Error(1)
+}
+
+func (m *MockWaitlistService) GetUserEntries(userID uint) ([]*model.WaitlistEntry, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]*model.WaitlistEntry), args.Error(1)
+}
+
+func (m *MockWaitlistService) Leave(userID uint, entryID uint) error {
+	args := m.Called(userID, entryID)
+	return args.Error(0)
+}
+
+func (m *MockWaitlistService) NotifyCapacityReleased(gameID uint) {
+	m.Called(gameID)
+}
+
+func (m *MockWaitlistService) ProcessHolds() (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
+}
+
+// ============= MOCK REFUND SERVICE =============
+type MockRefundService struct {
+	mock.Mock
+}
+
+func (m *MockRefundService) RefundPayment(adminID uint, adminRole model.UserRole, paymentID uint, amount model.Money, reason string, toWallet bool) (*model.PaymentRefund, error) {
+	args := m.Called(adminID, adminRole, paymentID, amount, reason, toWallet)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.PaymentRefund), args.Error(1)
+}
+
+func (m *MockRefundService) Refund(payment *model.Payment, amount model.Money, reason string, actorID *uint, toWallet bool) (*model.PaymentRefund, error) {
+	args := m.Called(payment, amount, reason, actorID, toWallet)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.PaymentRefund), args.Error(1)
+}
+
+// ============= IN-MEMORY REPOSITORIES =============
+type fakeTxManager struct {
+	mu    sync.Mutex
+	repos repository.Repositories
+}
+
+// snapshotter is an in-memory repository that can undo what a failed
+// transaction wrote to it
+type snapshotter interface {
+	snapshot() (restore func())
+}
+
+func (m *fakeTxManager) WithTransaction(fn func(repos repository.Repositories) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var restores []func()
+	for _, repo := range []any{m.repos.Bookings, m.repos.Orders} {
+		if s, ok := repo.(snapshotter); ok {
+			restores = append(restores, s.snapshot())
+		}
+	}
+	err := fn(m.repos)
+	if err != nil {
+		// Roll back like the database would
+		for _, restore := range restores {
+			restore()
+		}
+	}
+	return err
+}
+
+// lockingTxManager runs transactions concurrently, like the database, and
+// only serializes them on the game rows they lock with LockForUpdate; the
+// locks are held until the transaction ends
+type lockingTxManager struct {
+	repos repository.Repositories
+	mu    sync.Mutex
+	rows  map[uint]*sync.Mutex
+}
+
+func newLockingTxManager(repos repository.Repositories) *lockingTxManager {
+	return &lockingTxManager{repos: repos, rows: map[uint]*sync.Mutex{}}
+}
+
+func (m *lockingTxManager) WithTransaction(fn func(repos repository.Repositories) error) error {
+	games := &lockingGameRepo{GameRepository: m.repos.Games, tx: m}
+	defer func() {
+		for _, row := range games.held {
+			row.Unlock()
+		}
+	}()
+
+	repos := m.repos
+	repos.Games = games
+	return fn(repos)
+}
+
+func (m *lockingTxManager) row(id uint) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.rows[id] == nil {
+		m.rows[id] = &sync.Mutex{}
+	}
+	return m.rows[id]
+}
+
+// lockingGameRepo is a game repository inside one lockingTxManager transaction
+type lockingGameRepo struct {
+	repository.GameRepository
+	tx   *lockingTxManager
+	held []*sync.Mutex
+}
+
+func (r *lockingGameRepo) LockForUpdate(id uint) (*model.Game, error) {
+	row := r.tx.row(id)
+	row.Lock()
+	r.held = append(r.held, row)
+	return r.GameRepository.LockForUpdate(id)
+}
+
+func (r *lockingGameRepo) CheckAvailability(gameID uint, startDate, endDate time.Time) (bool, error) {
+	available, err := r.GameRepository.CheckAvailability(gameID, startDate, endDate)
+	// Widen the gap between check and insert so a missing lock shows up
+	time.Sleep(time.Millisecond)
+	return available, err
+}
+
+type fakeBookingStore struct {
+	repository.BookingRepository
+	mu       sync.Mutex
+	bookings []*model.Booking
+}
+
+func (r *fakeBookingStore) snapshot() func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	bookings := slices.Clone(r.bookings)
+	values := make([]model.Booking, len(bookings))
+	for i, b := range bookings {
+		values[i] = *b
+	}
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i, b := range bookings {
+			*b = values[i]
+		}
+		r.bookings = bookings
+	}
+}
+
+func (r *fakeBookingStore) Create(booking *model.Booking) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	booking.ID = uint(len(r.bookings) + 1)
+	r.bookings = append(r.bookings, booking)
+	return nil
+}
+
+func (r *fakeBookingStore) GetByID(id uint) (*model.Booking, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, b := range r.bookings {
+		if b.ID == id {
+			found := *b
+			return &found, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeBookingStore) UpdateStatusFrom(bookingID uint, from, to model.BookingStatus) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, b := range r.bookings {
+		if b.ID == bookingID && b.Status == from {
+			b.Status = to
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeBookingStore) GetExpiredPending(now time.Time, limit int) ([]*model.Booking, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var expired []*model.Booking
+	for _, b := range r.bookings {
+		if b.Status == model.BookingPending && b.PaymentDueAt != nil && b.PaymentDueAt.Before(now) {
+			found := *b
+			expired = append(expired, &found)
+		}
+	}
+	return expired, nil
+}
+
+func (r *fakeBookingStore) UpdateDates(booking *model.Booking) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, b := range r.bookings {
+		if b.ID == booking.ID {
+			stored := *booking
+			r.bookings[i] = &stored
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+// overlapping counts the bookings holding a copy of the game on any of the
+// days, like the availability query
+func (r *fakeBookingStore) overlapping(gameID uint, start, end time.Time) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for _, b := range r.bookings {
+		if b.GameID == gameID && slices.Contains(model.ReservingBookingStatuses, b.Status) && !b.StartDate.After(end) && !b.EndDate.Before(start) {
+			count++
+		}
+	}
+	return count
+}
+
+// fakeOrderRepo keeps orders without their items, which live in the booking
+// store as in the database, and loads them with items and payment
+type fakeOrderRepo struct {
+	repository.OrderRepository
+	orders   []*model.Order
+	bookings *fakeBookingStore
+	payments *fakePaymentRepo
+}
+
+func (r *fakeOrderRepo) snapshot() func() {
+	orders := slices.Clone(r.orders)
+	return func() { r.orders = orders }
+}
+
+func (r *fakeOrderRepo) Create(order *model.Order) error {
+	order.ID = uint(len(r.orders) + 1)
+	stored := *order
+	stored.Items = nil
+	r.orders = append(r.orders, &stored)
+	return nil
+}
+
+func (r *fakeOrderRepo) GetByID(id uint) (*model.Order, error) {
+	for _, o := range r.orders {
+		if o.ID != id {
+			continue
+		}
+		found := *o
+		found.Items = nil
+		r.bookings.mu.Lock()
+		for _, b := range r.bookings.bookings {
+			if b.OrderID != nil && *b.OrderID == id {
+				found.Items = append(found.Items, *b)
+			}
+		}
+		r.bookings.mu.Unlock()
+		if r.payments != nil && r.payments.payment != nil && r.payments.payment.OrderID != nil && *r.payments.payment.OrderID == id {
+			payment := *r.payments.payment
+			found.Payment = &payment
+		}
+		return &found, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeOrderRepo) GetExpiredPending(now time.Time, limit int) ([]*model.Order, error) {
+	var expired []*model.Order
+	for _, o := range r.orders {
+		if o.PaymentDueAt == nil || !o.PaymentDueAt.Before(now) {
+			continue
+		}
+		order, _ := r.GetByID(o.ID)
+		if countItems(order, model.BookingPending) > 0 {
+			expired = append(expired, order)
+		}
+	}
+	return expired, nil
+}
+
+type fakeGameRepo struct {
+	repository.GameRepository
+	game     *model.Game
+	bookings *fakeBookingStore
+}
+
+func (r *fakeGameRepo) GetByID(id uint) (*model.Game, error) {
+	if r.game == nil || id != r.game.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.game, nil
+}
+
+func (r *fakeGameRepo) LockForUpdate(id uint) (*model.Game, error) {
+	return r.GetByID(id)
+}
+
+func (r *fakeGameRepo) CheckAvailability(gameID uint, startDate, endDate time.Time) (bool, error) {
+	return r.bookings.overlapping(gameID, startDate, endDate) < r.game.Stock, nil
+}
+
+type fakeHistoryRepo struct {
+	repository.BookingStatusHistoryRepository
+}
+
+func (r *fakeHistoryRepo) Create(entry *model.BookingStatusHistory) error {
+	return nil
+}
+
+type fakeWaitlistRepo struct {
+	repository.WaitlistRepository
+}
+
+func (r *fakeWaitlistRepo) GetActiveByUserAndGame(userID, gameID uint) (*model.WaitlistEntry, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+// fakeUserRepo finds only the users it is given; without one no email is sent
+type fakeUserRepo struct {
+	repository.UserRepository
+	users []*model.User
+}
+
+func (r *fakeUserRepo) GetByID(id uint) (*model.User, error) {
+	for _, u := range r.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUserRepo) GetActiveByRoles(roles ...model.UserRole) ([]*model.User, error) {
+	var users []*model.User
+	for _, u := range r.users {
+		for _, role := range roles {
+			if u.IsActive && u.Role == role {
+				users = append(users, u)
+			}
+		}
+	}
+	return users, nil
+}
+
+// fakePaymentRepo holds the one payment a test works on
+type fakePaymentRepo struct {
+	repository.PaymentRepository
+	payment       *model.Payment
+	statusChanges int
+}
+
+// Create makes the new payment the one the repo holds, as the next attempt
+func (r *fakePaymentRepo) Create(payment *model.Payment) error {
+	payment.ID = 1
+	if r.payment != nil {
+		payment.ID = r.payment.ID + 1
+	}
+	r.payment = payment
+	return nil
+}
+
+func (r *fakePaymentRepo) Update(payment *model.Payment) error {
+	r.payment = payment
+	return nil
+}
+
+func (r *fakePaymentRepo) GetByID(id uint) (*model.Payment, error) {
+	if r.payment.ID != id {
+		return nil, gorm.ErrRecordNotFound
+	}
+	payment := *r.payment
+	return &payment, nil
+}
+
+func (r *fakePaymentRepo) GetByIDWithRelations(id uint) (*model.Payment, error) {
+	payment, err := r.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	payment.Booking = &model.Booking{ID: *payment.BookingID, UserID: 5, User: model.User{ID: 5, FullName: "Customer", Email: "customer@example.com"}}
+	return payment, nil
+}
+
+func (r *fakePaymentRepo) GetByProviderPaymentID(providerPaymentID string) (*model.Payment, error) {
+	if r.payment.ProviderPaymentID == nil || *r.payment.ProviderPaymentID != providerPaymentID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	payment := *r.payment
+	return &payment, nil
+}
+
+func (r *fakePaymentRepo) GetStalePending(createdBefore time.Time, limit int) ([]*model.Payment, error) {
+	if r.payment.Status != model.PaymentPending {
+		return nil, nil
+	}
+	payment := *r.payment
+	return []*model.Payment{&payment}, nil
+}
+
+func (r *fakePaymentRepo) UpdateStatusFrom(paymentID uint, from, to model.PaymentStatus) (bool, error) {
+	if r.payment.Status != from {
+		return false, nil
+	}
+	r.payment.Status = to
+	r.statusChanges++
+	return true, nil
+}
+
+func (r *fakePaymentRepo) MarkAsFailed(paymentID uint, failureReason string) error {
+	r.payment.Status = model.PaymentFailed
+	r.payment.FailureReason = &failureReason
+	return nil
+}
+
+func (r *fakePaymentRepo) MarkReviewed(paymentID uint, adminID uint) error {
+	now := time.Now()
+	r.payment.ReviewedBy = &adminID
+	r.payment.ReviewedAt = &now
+	return nil
+}
+
+func (r *fakePaymentRepo) AttachProof(paymentID uint, proofPath string) (bool, error) {
+	if r.payment.Status != model.PaymentReview {
+		return false, nil
+	}
+	now := time.Now()
+	r.payment.ProofPath = &proofPath
+	r.payment.ProofUploadedAt = &now
+	return true, nil
+}
+
+func (r *fakePaymentRepo) AdjustRefundedAmount(paymentID uint, delta model.Money) (bool, error) {
+	refunded := r.payment.RefundedAmount.Add(delta)
+	if refunded.IsNegative() || refunded.Add(r.payment.ChargedBackAmount).GreaterThan(r.payment.Amount) {
+		return false, nil
+	}
+	r.payment.RefundedAmount = refunded
+	switch {
+	case !refunded.Add(r.payment.ChargedBackAmount).LessThan(r.payment.Amount):
+		r.payment.Status = model.PaymentRefunded
+	case refunded.IsPositive():
+		r.payment.Status = model.PaymentPartiallyRefunded
+	default:
+		r.payment.Status = model.PaymentPaid
+	}
+	return true, nil
+}
+
+func (r *fakePaymentRepo) GetReviewQueue(queue model.ReviewQueue, limit, offset int) ([]*model.Payment, error) {
+	if r.payment == nil || r.payment.Status != model.PaymentReview ||
+		(r.payment.Provider == model.ProviderOffline) != (queue == model.ReviewQueueOffline) {
+		return nil, nil
+	}
+	payment := *r.payment
+	return []*model.Payment{&payment}, nil
+}
+
+func (r *fakePaymentRepo) CountReviewQueue(queue model.ReviewQueue) (int64, error) {
+	payments, err := r.GetReviewQueue(queue, 1, 0)
+	return int64(len(payments)), err
+}
+
+func (r *fakePaymentRepo) AddWalletRefundedAmount(paymentID uint, amount model.Money) error {
+	r.payment.WalletRefundedAmount = r.payment.WalletRefundedAmount.Add(amount)
+	return nil
+}
+
+func (r *fakePaymentRepo) AddChargedBackAmount(paymentID uint, amount model.Money) (bool, error) {
+	if r.payment.Amount.Sub(r.payment.RefundedAmount).Sub(r.payment.ChargedBackAmount).LessThan(amount) {
+		return false, nil
+	}
+	r.payment.ChargedBackAmount = r.payment.ChargedBackAmount.Add(amount)
+	return true, nil
+}
+
+type fakeRefundRepo struct {
+	repository.PaymentRefundRepository
+	refunds []*model.PaymentRefund
+}
+
+func (r *fakeRefundRepo) Create(refund *model.PaymentRefund) error {
+	refund.ID = uint(len(r.refunds) + 1)
+	r.refunds = append(r.refunds, refund)
+	return nil
+}
+
+func (r *fakeRefundRepo) Update(refund *model.PaymentRefund) error {
+	return nil
+}
+
+type fakeEventRepo struct {
+	repository.PaymentEventRepository
+	events []*model.PaymentEvent
+}
+
+func (r *fakeEventRepo) Create(event *model.PaymentEvent) error {
+	event.ID = uint(len(r.events) + 1)
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeEventRepo) CreateIfNew(event *model.PaymentEvent) (bool, error) {
+	if _, err := r.GetByKey(event.Provider, event.TransactionID, event.TransactionStatus); err == nil {
+		return false, nil
+	}
+	return true, r.Create(event)
+}
+
+func (r *fakeEventRepo) GetByKey(provider model.PaymentProvider, transactionID, transactionStatus string) (*model.PaymentEvent, error) {
+	for _, e := range r.events {
+		if e.Provider == provider && e.TransactionID == transactionID && e.TransactionStatus == transactionStatus && e.Status != model.PaymentEventRejected {
+			return e, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeEventRepo) GetByID(id uint) (*model.PaymentEvent, error) {
+	if int(id) > len(r.events) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.events[id-1], nil
+}
+
+func (r *fakeEventRepo) Update(event *model.PaymentEvent) error {
+	return nil
+}
+
+type fakeReconciliationRepo struct {
+	repository.PaymentReconciliationRepository
+	reports []*model.PaymentReconciliation
+}
+
+func (r *fakeReconciliationRepo) Create(report *model.PaymentReconciliation) error {
+	report.ID = uint(len(r.reports) + 1)
+	r.reports = append(r.reports, report)
+	return nil
+}
+
+func (r *fakeReconciliationRepo) Update(report *model.PaymentReconciliation) error {
+	return nil
+}
+
+func (r *fakeReconciliationRepo) GetOpenByPaymentID(paymentID uint) (*model.PaymentReconciliation, error) {
+	for i := len(r.reports) - 1; i >= 0; i-- {
+		report := r.reports[i]
+		if report.PaymentID == paymentID && (report.Resolution == model.ReconciliationUnresolved || report.Resolution == model.ReconciliationFailed) {
+			return report, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type fakeDisputeRepo struct {
+	repository.PaymentDisputeRepository
+	disputes []*model.PaymentDispute
+}
+
+func (r *fakeDisputeRepo) Create(dispute *model.PaymentDispute) error {
+	dispute.ID = uint(len(r.disputes) + 1)
+	r.disputes = append(r.disputes, dispute)
+	return nil
+}
+
+func (r *fakeDisputeRepo) GetByID(id uint) (*model.PaymentDispute, error) {
+	if id == 0 || int(id) > len(r.disputes) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	dispute := *r.disputes[id-1]
+	return &dispute, nil
+}
+
+func (r *fakeDisputeRepo) GetByProviderID(provider model.PaymentProvider, providerDisputeID string) (*model.PaymentDispute, error) {
+	for _, d := range r.disputes {
+		if d.Provider == provider && d.ProviderDisputeID == providerDisputeID {
+			dispute := *d
+			return &dispute, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeDisputeRepo) Update(dispute *model.PaymentDispute) error {
+	stored := *dispute
+	r.disputes[dispute.ID-1] = &stored
+	return nil
+}
+
+func (r *fakeDisputeRepo) UpdateStatusFrom(disputeID uint, from, to model.DisputeStatus) (bool, error) {
+	dispute := r.disputes[disputeID-1]
+	if dispute.Status != from {
+		return false, nil
+	}
+	dispute.Status = to
+	return true, nil
+}
+
+func (r *fakeDisputeRepo) RecordEvidence(disputeID uint, evidence string, adminID uint) (bool, error) {
+	dispute := r.disputes[disputeID-1]
+	if dispute.Status != model.DisputeOpen {
+		return false, nil
+	}
+	now := time.Now()
+	dispute.Status = model.DisputeUnderReview
+	dispute.Evidence = &evidence
+	dispute.EvidenceSubmittedBy = &adminID
+	dispute.EvidenceSubmittedAt = &now
+	return true, nil
+}
+
+type fakeDateChangeRepo struct {
+	repository.BookingDateChangeRepository
+	changes []*model.BookingDateChange
+}
+
+func (r *fakeDateChangeRepo) Create(change *model.BookingDateChange) error {
+	change.ID = uint(len(r.changes) + 1)
+	change.CreatedAt = time.Now()
+	r.changes = append(r.changes, change)
+	return nil
+}
+
+func (r *fakeDateChangeRepo) Update(change *model.BookingDateChange) error {
+	stored := *change
+	r.changes[change.ID-1] = &stored
+	return nil
+}
+
+func (r *fakeDateChangeRepo) GetPendingByBookingID(bookingID uint) (*model.BookingDateChange, error) {
+	for _, c := range r.changes {
+		if c.BookingID == bookingID && c.Status == model.DateChangePending {
+			return c, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeDateChangeRepo) GetExpiredPending(createdBefore time.Time, limit int) ([]*model.BookingDateChange, error) {
+	var expired []*model.BookingDateChange
+	for _, c := range r.changes {
+		if c.Status == model.DateChangePending && c.CreatedAt.Before(createdBefore) {
+			change := *c
+			expired = append(expired, &change)
+		}
+	}
+	return expired, nil
+}
+
+func (r *fakeDateChangeRepo) MarkFailed(changeID uint) (bool, error) {
+	change := r.changes[changeID-1]
+	if change.Status != model.DateChangePending {
+		return false, nil
+	}
+	change.Status = model.DateChangeFailed
+	return true, nil
+}
+
+type fakeSettlementRepo struct {
+	repository.BookingSettlementRepository
+	settlements []*model.BookingSettlement
+}
+
+func (r *fakeSettlementRepo) Update(settlement *model.BookingSettlement) error {
+	stored := *settlement
+	r.settlements[settlement.ID-1] = &stored
+	return nil
+}
+
+func (r *fakeSettlementRepo) UpdateRefundStatusFrom(settlementID uint, from, to model.DepositRefundStatus) (bool, error) {
+	settlement := r.settlements[settlementID-1]
+	if settlement.RefundStatus != from {
+		return false, nil
+	}
+	settlement.RefundStatus = to
+	return true, nil
+}
+
+type fakeInvoiceRepo struct {
+	repository.InvoiceRepository
+	invoices []*model.Invoice
+}
+
+func (r *fakeInvoiceRepo) GetByBookingID(bookingID uint) (*model.Invoice, error) {
+	for _, invoice := range r.invoices {
+		if invoice.BookingID == bookingID && invoice.DateChangeID == nil {
+			return invoice, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeInvoiceRepo) Issue(invoice *model.Invoice) error {
+	invoice.ID = uint(len(r.invoices) + 1)
+	invoice.Number = fmt.Sprintf("INV-%d-%06d", invoice.IssuedAt.Year(), len(r.invoices)+1)
+	r.invoices = append(r.invoices, invoice)
+	return nil
+}
+
+type fakeWalletRepo struct {
+	repository.WalletRepository
+	mu       sync.Mutex
+	balances map[uint]model.Money
+	entries  []*model.WalletEntry
+}
+
+func newFakeWalletRepo() *fakeWalletRepo {
+	return &fakeWalletRepo{balances: map[uint]model.Money{}}
+}
+
+func (r *fakeWalletRepo) GetByUserID(userID uint) (*model.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &model.Wallet{UserID: userID, Balance: r.balances[userID]}, nil
+}
+
+func (r *fakeWalletRepo) Post(entry *model.WalletEntry) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	balance := r.balances[entry.UserID].Add(entry.Amount)
+	if balance.IsNegative() {
+		return false, nil
+	}
+	r.balances[entry.UserID] = balance
+	entry.ID = uint(len(r.entries) + 1)
+	entry.BalanceAfter = balance
+	r.entries = append(r.entries, entry)
+	return true, nil
+}
+
+// fakePromoRepo counts uses from the bookings in the store, like the real query
+type fakePromoRepo struct {
+	repository.PromoCodeRepository
+	promo    *model.PromoCode
+	bookings *fakeBookingStore
+}
+
+func (r *fakePromoRepo) LockByCode(code string) (*model.PromoCode, error) {
+	if code != r.promo.Code {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.promo, nil
+}
+
+func (r *fakePromoRepo) CountUses(promoID uint) (int64, error) {
+	return r.CountUserUses(promoID, 0)
+}
+
+func (r *fakePromoRepo) CountUserUses(promoID, userID uint) (int64, error) {
+	var count int64
+	for _, b := range r.bookings.bookings {
+		if b.PromoCodeID != nil && *b.PromoCodeID == promoID && b.Status != model.BookingCancelled && (userID == 0 || b.UserID == userID) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ============= GATEWAYS =============
+
+// statusGateway is the real Midtrans gateway, so signatures are checked the
+// way production checks them, with a canned transaction status
+type statusGateway struct {
+	*transaction.MidtransRepository
+	status    string
+	decisions []bool
+	refunds   []string // refund keys
+	refundErr error
+	cancelled []string
+}
+
+func (g *statusGateway) GetStatus(ctx context.Context, transactionID string) (string, error) {
+	return g.status, nil
+}
+
+func (g *statusGateway) ApproveTransaction(ctx context.Context, transactionID string) error {
+	g.decisions = append(g.decisions, true)
+	return nil
+}
+
+func (g *statusGateway) DenyTransaction(ctx context.Context, transactionID string) error {
+	g.decisions = append(g.decisions, false)
+	return nil
+}
+
+func (g *statusGateway) Refund(ctx context.Context, transactionID string, refundKey string, amount model.Money, reason string) (string, error) {
+	if g.refundErr != nil {
+		return "", g.refundErr
+	}
+	g.refunds = append(g.refunds, refundKey)
+	return "rf-" + refundKey, nil
+}
+
+func (g *statusGateway) CancelCharge(ctx context.Context, transactionID string) error {
+	g.cancelled = append(g.cancelled, transactionID)
+	return nil
+}
+
+type rejectingGateway struct {
+	transaction.TransactionRepository
+}
+
+func (g *rejectingGateway) Refund(ctx context.Context, transactionID string, refundKey string, amount model.Money, reason string) (string, error) {
+	return "", errors.New("refund window closed")
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"errors"
+	"time"
 
 	"github.com/yoockh/go-game-rental-api/internal/model"
 	"github.com/yoockh/go-game-rental-api/internal/repository"
@@ -11,13 +12,18 @@ var (
 	ErrGameNotFound               = errors.New("game not found")
 	ErrGameInsufficientPermission = errors.New("insufficient permission")
 	ErrGameNotOwned               = errors.New("you don't own this game")
+	ErrAvailabilityInvalidRange   = errors.New("invalid availability range")
 )
 
+// maxAvailabilityDays caps how many days a single availability query may span
+const maxAvailabilityDays = 92
+
 type GameService interface {
 	// Public
 	GetAll(limit, offset int) ([]*model.Game, int64, error)
 	Search(query string, limit, offset int) ([]*model.Game, error)
 	GetByID(gameID uint) (*model.Game, error)
+	GetAvailability(gameID uint, from, to time.Time) ([]model.GameAvailability, error)
 
 	// Admin
 	Create(adminID uint, requestorRole model.UserRole, gameData *model.Game) error
@@ -51,6 +57,18 @@ func (s *gameService) GetByID(gameID uint) (*model.Game, error) {
 	return s.gameRepo.GetByID(gameID)
 }
 
+func (s *gameService) GetAvailability(gameID uint, from, to time.Time) ([]model.GameAvailability, error) {
+	if to.Before(from) || to.Sub(from) > maxAvailabilityDays*24*time.Hour {
+		return nil, ErrAvailabilityInvalidRange
+	}
+
+	if _, err := s.gameRepo.GetByID(gameID); err != nil {
+		return nil, ErrGameNotFound
+	}
+
+	return s.gameRepo.GetAvailability(gameID, from, to)
+}
+
 func (s *gameService) Create(adminID uint, requestorRole model.UserRole, gameData *model.Game) error {
 	if !s.canManageGames(requestorRole) {
 		return ErrGameInsufficientPermission
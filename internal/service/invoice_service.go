@@ -0,0 +1,348 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+	"github.com/yoockh/go-game-rental-api/internal/utils"
+)
+
+var (
+	ErrInvoiceNotPaid        = errors.New("invoice is available once the booking is paid")
+	ErrTaxReportInvalidRange = errors.New("invalid tax report period")
+)
+
+type InvoiceService interface {
+	// Customer (own bookings) and admin
+	GetBookingInvoice(requestorID uint, requestorRole model.UserRole, bookingID uint) (*model.Invoice, []byte, error)
+
+	// Admin
+	GetTaxReport(requestorRole model.UserRole, from, to time.Time) ([]model.TaxTotal, error)
+}
+
+type invoiceService struct {
+	txManager   repository.TxManager
+	bookingRepo repository.BookingRepository
+	invoiceRepo repository.InvoiceRepository
+}
+
+func NewInvoiceService(txManager repository.TxManager, bookingRepo repository.BookingRepository, invoiceRepo repository.InvoiceRepository) InvoiceService {
+	return &invoiceService{
+		txManager:   txManager,
+		bookingRepo: bookingRepo,
+		invoiceRepo: invoiceRepo,
+	}
+}
+
+// GetBookingInvoice returns the invoice of a paid booking with its PDF. Bookings
+// confirmed before invoices were issued, and order line items, get their
+// invoice issued on the first request.
+func (s *invoiceService) GetBookingInvoice(requestorID uint, requestorRole model.UserRole, bookingID uint) (*model.Invoice, []byte, error) {
+	booking, err := s.bookingRepo.GetByID(bookingID)
+	if err != nil {
+		return nil, nil, ErrBookingNotFound
+	}
+
+	if requestorRole != model.RoleAdmin && requestorRole != model.RoleSuperAdmin && booking.UserID != requestorID {
+		return nil, nil, ErrBookingNotOwned
+	}
+
+	invoice, err := s.invoiceRepo.GetByBookingID(bookingID)
+	if err != nil {
+		err = s.txManager.WithTransaction(func(repos repository.Repositories) error {
+			invoice, err = issueInvoice(repos, booking)
+			return err
+		})
+		if errors.Is(err, ErrInvoiceNotPaid) {
+			return nil, nil, err
+		}
+		if err != nil {
+			// A concurrent request may have issued it first
+			if invoice, err = s.invoiceRepo.GetByBookingID(bookingID); err != nil {
+				return nil, nil, fmt.Errorf("failed to issue invoice: %w", err)
+			}
+		}
+	}
+
+	return invoice, renderInvoice(invoice, booking, booking.ChargedPayment()), nil
+}
+
+// GetTaxReport totals the tax of the invoices issued from one date through
+// another, both inclusive, per tax rate
+func (s *invoiceService) GetTaxReport(requestorRole model.UserRole, from, to time.Time) ([]model.TaxTotal, error) {
+	if requestorRole != model.RoleAdmin && requestorRole != model.RoleSuperAdmin {
+		return nil, ErrInsufficientPermission
+	}
+	if to.Before(from) {
+		return nil, ErrTaxReportInvalidRange
+	}
+
+	return s.invoiceRepo.SumTax(from, to.AddDate(0, 0, 1))
+}
+
+// issueInvoice issues the invoice of a paid booking in the caller's transaction,
+// or returns the one already issued
+func issueInvoice(repos repository.Repositories, booking *model.Booking) (*model.Invoice, error) {
+	if invoice, err := repos.Invoices.GetByBookingID(booking.ID); err == nil {
+		return invoice, nil
+	}
+
+	payment := booking.ChargedPayment()
+	if payment == nil || !isSettledPayment(payment) {
+		return nil, ErrInvoiceNotPaid
+	}
+
+	invoice := &model.Invoice{
+		BookingID:        booking.ID,
+		PaymentID:        payment.ID,
+		IssuedAt:         time.Now(),
+		StartDate:        booking.StartDate,
+		EndDate:          booking.EndDate,
+		RentalDays:       booking.RentalDays,
+		DailyPrice:       booking.DailyPrice,
+		TotalRentalPrice: booking.TotalRentalPrice,
+		SecurityDeposit:  booking.SecurityDeposit,
+		DiscountAmount:   booking.DiscountAmount,
+		TaxRate:          booking.TaxRate,
+		TaxInclusive:     booking.TaxInclusive,
+		TaxableAmount:    booking.TaxableAmount,
+		TaxAmount:        booking.TaxAmount,
+		TotalAmount:      booking.TotalAmount,
+	}
+	if err := repos.Invoices.Issue(invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// issueChangeInvoice invoices the difference a priced date change makes to a
+// paid booking, paid with paymentID, in the caller's transaction. It must run
+// before the change is applied to the booking. A change that makes the booking
+// cheaper gets a credit note. The booking's own invoice is issued first when it
+// has none yet, so it keeps the amounts from before the change.
+func issueChangeInvoice(repos repository.Repositories, booking *model.Booking, change *model.BookingDateChange, paymentID uint) (*model.Invoice, error) {
+	if _, err := issueInvoice(repos, booking); err != nil {
+		return nil, err
+	}
+
+	newNet := change.TotalRentalPrice.Sub(change.DiscountAmount)
+	invoice := &model.Invoice{
+		BookingID:        booking.ID,
+		DateChangeID:     &change.ID,
+		PaymentID:        paymentID,
+		IssuedAt:         time.Now(),
+		StartDate:        change.NewStartDate,
+		EndDate:          change.NewEndDate,
+		RentalDays:       change.RentalDays - booking.RentalDays,
+		DailyPrice:       booking.DailyPrice,
+		TotalRentalPrice: change.TotalRentalPrice.Sub(booking.TotalRentalPrice),
+		DiscountAmount:   change.DiscountAmount.Sub(booking.DiscountAmount),
+		TaxRate:          booking.TaxRate,
+		TaxInclusive:     booking.TaxInclusive,
+		TaxableAmount:    newNet.Sub(booking.TotalRentalPrice.Sub(booking.DiscountAmount)),
+		TaxAmount:        change.TaxAmount.Sub(booking.TaxAmount),
+		TotalAmount:      change.AmountDue,
+	}
+	if err := repos.Invoices.Issue(invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// invoiceAttachments attaches the invoice PDF to an email; there is nothing to
+// attach without an invoice
+func invoiceAttachments(invoice *model.Invoice, booking *model.Booking, payment *model.Payment) []email.Attachment {
+	if invoice == nil {
+		return nil
+	}
+	return []email.Attachment{{
+		Filename:    invoiceFilename(invoice),
+		ContentType: "application/pdf",
+		Content:     renderInvoice(invoice, booking, payment),
+	}}
+}
+
+// isSettledPayment reports whether the payment was paid, including when it was
+// refunded afterwards
+func isSettledPayment(payment *model.Payment) bool {
+	switch payment.Status {
+	case model.PaymentPaid, model.PaymentPartiallyRefunded, model.PaymentRefunded:
+		return true
+	}
+	return false
+}
+
+// invoiceFilename is the attachment and download name of an invoice PDF
+func invoiceFilename(invoice *model.Invoice) string {
+	return invoice.Number + ".pdf"
+}
+
+// Column positions of the invoice layout, in points from the left edge
+const (
+	invoiceLeft     = 50.0
+	invoiceQtyX     = 330.0
+	invoiceUnitX    = 450.0 // right edge
+	invoiceAmountX  = 545.0 // right edge
+	invoiceLineStep = 18.0
+)
+
+// renderInvoice lays out the invoice PDF from what was invoiced: line items for
+// the rental days, the deposit, any promo discount and the tax, the return fees
+// once the booking is settled, and the payment. The booking only supplies the
+// customer, the game and the settlement.
+func renderInvoice(invoice *model.Invoice, booking *model.Booking, payment *model.Payment) []byte {
+	pdf := utils.NewPDF()
+	y := 70.0
+
+	title := "INVOICE"
+	if invoice.IsCreditNote() {
+		title = "CREDIT NOTE"
+	}
+	pdf.Text(invoiceLeft, y, utils.FontBold, 20, title)
+	pdf.TextRight(invoiceAmountX, y, utils.FontBold, 12, invoice.Number)
+	y += 20
+	pdf.Text(invoiceLeft, y, utils.FontRegular, 10, "Game Rental")
+	pdf.TextRight(invoiceAmountX, y, utils.FontRegular, 10, "Issued "+invoice.IssuedAt.Format("2006-01-02"))
+	y += 35
+
+	pdf.Text(invoiceLeft, y, utils.FontBold, 10, "Billed to")
+	y += 15
+	pdf.Text(invoiceLeft, y, utils.FontRegular, 10, booking.User.FullName)
+	y += 14
+	pdf.Text(invoiceLeft, y, utils.FontRegular, 10, booking.User.Email)
+	y += 14
+	if booking.User.Address != nil && *booking.User.Address != "" {
+		pdf.Text(invoiceLeft, y, utils.FontRegular, 10, *booking.User.Address)
+		y += 14
+	}
+	if invoice.DateChangeID != nil {
+		pdf.Text(invoiceLeft, y, utils.FontRegular, 10, fmt.Sprintf("Date change of booking #%d", booking.ID))
+	} else {
+		pdf.Text(invoiceLeft, y, utils.FontRegular, 10, fmt.Sprintf("Booking #%d", booking.ID))
+	}
+	y += 35
+
+	pdf.Text(invoiceLeft, y, utils.FontBold, 10, "Description")
+	pdf.Text(invoiceQtyX, y, utils.FontBold, 10, "Qty")
+	pdf.TextRight(invoiceUnitX, y, utils.FontBold, 10, "Unit price")
+	pdf.TextRight(invoiceAmountX, y, utils.FontBold, 10, "Amount")
+	y += 6
+	pdf.Line(invoiceLeft, y, invoiceAmountX, y)
+	y += invoiceLineStep
+
+	item := func(description, qty string, unit *model.Money, amount model.Money) {
+		pdf.Text(invoiceLeft, y, utils.FontRegular, 10, description)
+		pdf.Text(invoiceQtyX, y, utils.FontRegular, 10, qty)
+		if unit != nil {
+			pdf.TextRight(invoiceUnitX, y, utils.FontRegular, 10, unit.Display())
+		}
+		pdf.TextRight(invoiceAmountX, y, utils.FontRegular, 10, amount.Display())
+		y += invoiceLineStep
+	}
+
+	rental := booking.Game.Name
+	if booking.Game.Platform != nil {
+		rental += " (" + *booking.Game.Platform + ")"
+	}
+	period := fmt.Sprintf("%s to %s", invoice.StartDate.Format("2006-01-02"), invoice.EndDate.Format("2006-01-02"))
+	if invoice.DateChangeID != nil {
+		item(fmt.Sprintf("Date change: %s, now %s", rental, period),
+			fmt.Sprintf("%+d days", invoice.RentalDays), &invoice.DailyPrice, invoice.TotalRentalPrice)
+	} else {
+		item(fmt.Sprintf("Rental: %s, %s", rental, period),
+			fmt.Sprintf("%d days", invoice.RentalDays), &invoice.DailyPrice, invoice.TotalRentalPrice)
+	}
+	if !invoice.SecurityDeposit.IsZero() {
+		item("Security deposit (refundable)", "1", &invoice.SecurityDeposit, invoice.SecurityDeposit)
+	}
+	if !invoice.DiscountAmount.IsZero() {
+		item("Promo discount", "", nil, invoice.DiscountAmount.Neg())
+	}
+	if !invoice.TaxInclusive && !invoice.TaxAmount.IsZero() {
+		item(fmt.Sprintf("%s on %s", taxLabel(invoice.TaxRule()), invoice.TaxableAmount.Display()), "", nil, invoice.TaxAmount)
+	}
+	// A refund is never more than was paid for the rental
+	lines := invoice.TotalRentalPrice.Add(invoice.SecurityDeposit).Sub(invoice.DiscountAmount)
+	if !invoice.TaxInclusive {
+		lines = lines.Add(invoice.TaxAmount)
+	}
+	if adjustment := invoice.TotalAmount.Sub(lines); !adjustment.IsZero() {
+		item("Limited to the rental paid", "", nil, adjustment)
+	}
+
+	y -= 6
+	pdf.Line(invoiceLeft, y, invoiceAmountX, y)
+	y += invoiceLineStep
+	pdf.Text(invoiceUnitX-100, y, utils.FontBold, 11, "Total")
+	pdf.TextRight(invoiceAmountX, y, utils.FontBold, 11, invoice.TotalAmount.Display())
+	y += 35
+	if invoice.TaxInclusive && !invoice.TaxAmount.IsZero() {
+		y -= 17
+		pdf.Text(invoiceLeft, y, utils.FontRegular, 10, fmt.Sprintf("Includes VAT %d%% on %s", invoice.TaxRate, invoice.TaxableAmount.Display()))
+		pdf.TextRight(invoiceAmountX, y, utils.FontRegular, 10, invoice.TaxAmount.Display())
+		y += 17
+	}
+
+	if invoice.IsCreditNote() {
+		pdf.Text(invoiceLeft, y, utils.FontRegular, 10, fmt.Sprintf("The difference is refunded against payment #%d.", invoice.PaymentID))
+		return pdf.Bytes()
+	}
+
+	// Fees are taken from the deposit when the copy is returned
+	if settlement := booking.Settlement; settlement != nil && invoice.DateChangeID == nil {
+		pdf.Text(invoiceLeft, y, utils.FontBold, 10, "Return settlement, "+settlement.ReturnedAt.Format("2006-01-02"))
+		y += 6
+		pdf.Line(invoiceLeft, y, invoiceAmountX, y)
+		y += invoiceLineStep
+		if !settlement.LateFee.IsZero() {
+			item("Late fee", fmt.Sprintf("%d days", settlement.LateDays), nil, settlement.LateFee)
+		}
+		if !settlement.DamageCharge.IsZero() {
+			item("Damage charge", "", nil, settlement.DamageCharge)
+		}
+		item("Deposit refunded", "", nil, settlement.DepositRefund)
+		if settlement.OutstandingAmount.IsPositive() {
+			item("Outstanding amount", "", nil, settlement.OutstandingAmount)
+		}
+		y += 17
+	}
+
+	if payment != nil {
+		paidAt := invoice.IssuedAt
+		if payment.PaidAt != nil {
+			paidAt = *payment.PaidAt
+		}
+		method := string(payment.Provider)
+		if payment.PaymentMethod != nil && *payment.PaymentMethod != "" {
+			method = *payment.PaymentMethod + " via " + method
+		}
+
+		pdf.Text(invoiceLeft, y, utils.FontBold, 10, "Payment")
+		y += 6
+		pdf.Line(invoiceLeft, y, invoiceAmountX, y)
+		y += invoiceLineStep
+		pdf.Text(invoiceLeft, y, utils.FontRegular, 10, "Method: "+method)
+		y += 14
+		pdf.Text(invoiceLeft, y, utils.FontRegular, 10, "Paid on: "+paidAt.Format("2006-01-02 15:04"))
+		y += 14
+		if payment.Purpose == model.PaymentPurposeOrder && booking.OrderID != nil {
+			pdf.Text(invoiceLeft, y, utils.FontRegular, 10, fmt.Sprintf("Paid together with order #%d, %s in total", *booking.OrderID, payment.Amount.Display()))
+		} else {
+			pdf.Text(invoiceLeft, y, utils.FontRegular, 10, "Amount paid: "+payment.Amount.Display())
+		}
+		y += 14
+		if payment.WalletAmount.IsPositive() && payment.Provider != model.ProviderWallet {
+			pdf.Text(invoiceLeft, y, utils.FontRegular, 10, "Paid from wallet: "+payment.WalletAmount.Display())
+			y += 14
+		}
+		if !payment.RefundedAmount.IsZero() {
+			pdf.Text(invoiceLeft, y, utils.FontRegular, 10, "Refunded: "+payment.RefundedAmount.Display())
+		}
+	}
+
+	return pdf.Bytes()
+}
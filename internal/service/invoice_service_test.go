@@ -0,0 +1,116 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
+)
+
+func paidBooking(id, userID uint) *model.Booking {
+	paidAt := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	method := "bank_transfer"
+	return &model.Booking{
+		ID: id, UserID: userID, Status: model.BookingConfirmed,
+		StartDate: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC),
+		RentalDays: 3, DailyPrice: model.NewMoney(20000), TotalRentalPrice: model.NewMoney(60000),
+		SecurityDeposit: model.NewMoney(100000), TotalAmount: model.NewMoney(160000),
+		User: model.User{FullName: "Dewi", Email: "dewi@example.com"},
+		Game: model.Game{Name: "Elden Ring"},
+		Payment: &model.Payment{ID: 10 + id, Provider: model.ProviderMidtrans, Status: model.PaymentPaid,
+			Amount: model.NewMoney(160000), PaymentMethod: &method, PaidAt: &paidAt},
+	}
+}
+
+func newInvoiceFixture(bookings ...*model.Booking) (InvoiceService, *fakeInvoiceRepo) {
+	store := &fakeBookingStore{bookings: bookings}
+	invoices := &fakeInvoiceRepo{}
+	txManager := &fakeTxManager{repos: repository.Repositories{Bookings: store, Invoices: invoices}}
+	return NewInvoiceService(txManager, store, invoices), invoices
+}
+
+func TestGetBookingInvoice_IssuesSequentialNumbersOnce(t *testing.T) {
+	svc, invoices := newInvoiceFixture(paidBooking(1, 7), paidBooking(2, 7))
+
+	first, pdf, err := svc.GetBookingInvoice(7, model.RoleCustomer, 1)
+	require.NoError(t, err)
+	second, _, err := svc.GetBookingInvoice(7, model.RoleCustomer, 2)
+	require.NoError(t, err)
+	again, _, err := svc.GetBookingInvoice(7, model.RoleCustomer, 1)
+	require.NoError(t, err)
+
+	year := time.Now().Year()
+	assert.Equal(t, fmt.Sprintf("INV-%d-000001", year), first.Number)
+	assert.Equal(t, fmt.Sprintf("INV-%d-000002", year), second.Number)
+	assert.Equal(t, first.Number, again.Number, "an issued invoice keeps its number")
+	assert.Len(t, invoices.invoices, 2)
+
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-")))
+	for _, text := range []string{first.Number, "3 days", "Rp 20000", "Rp 60000", "Security deposit", "Rp 160000",
+		"bank_transfer via midtrans", "Paid on: 2026-03-02 10:00"} {
+		assert.Contains(t, string(pdf), text)
+	}
+}
+
+func TestGetBookingInvoice_KeepsInvoicedAmountsAfterDateChange(t *testing.T) {
+	booking := paidBooking(1, 7)
+	svc, _ := newInvoiceFixture(booking)
+	issued, _, err := svc.GetBookingInvoice(7, model.RoleCustomer, 1)
+	require.NoError(t, err)
+
+	applyDateChange(booking, &model.BookingDateChange{
+		NewStartDate: booking.StartDate, NewEndDate: booking.EndDate.AddDate(0, 0, 2), RentalDays: 5,
+		TotalRentalPrice: model.NewMoney(100000), AmountDue: model.NewMoney(40000),
+	})
+	again, pdf, err := svc.GetBookingInvoice(7, model.RoleCustomer, 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, issued.Number, again.Number)
+	assert.Equal(t, model.NewMoney(160000), again.TotalAmount)
+	assert.Contains(t, string(pdf), "3 days")
+	assert.Contains(t, string(pdf), "2026-03-07")
+	assert.NotContains(t, string(pdf), "Rp 200000", "the booking's new total is invoiced by the date change")
+}
+
+func TestGetBookingInvoice_RequiresPaidBooking(t *testing.T) {
+	booking := paidBooking(1, 7)
+	booking.Payment.Status = model.PaymentPending
+	svc, invoices := newInvoiceFixture(booking)
+
+	_, _, err := svc.GetBookingInvoice(7, model.RoleCustomer, 1)
+
+	assert.ErrorIs(t, err, ErrInvoiceNotPaid)
+	assert.Empty(t, invoices.invoices)
+}
+
+func TestGetBookingInvoice_OwnerOrAdmin(t *testing.T) {
+	svc, _ := newInvoiceFixture(paidBooking(1, 7))
+
+	_, _, err := svc.GetBookingInvoice(8, model.RoleCustomer, 1)
+	assert.ErrorIs(t, err, ErrBookingNotOwned)
+
+	_, _, err = svc.GetBookingInvoice(8, model.RoleAdmin, 1)
+	assert.NoError(t, err)
+}
+
+func TestConfirmPayment_IssuesInvoice(t *testing.T) {
+	booking := paidBooking(1, 7)
+	booking.Status = model.BookingPending
+	bookings := &fakeBookingStore{bookings: []*model.Booking{booking}}
+	invoices := &fakeInvoiceRepo{}
+	repos := repository.Repositories{Bookings: bookings, BookingHistory: &fakeHistoryRepo{}, Invoices: invoices}
+	svc := NewBookingService(&fakeTxManager{repos: repos}, bookings, nil, nil, nil, nil, transaction.NewRegistry(), &email.MockEmailRepository{}, 24*time.Hour, model.TaxRule{})
+
+	_, err := svc.ConfirmPayment(repos, 1)
+
+	require.NoError(t, err)
+	require.Len(t, invoices.invoices, 1)
+	assert.Equal(t, uint(11), invoices.invoices[0].PaymentID)
+}
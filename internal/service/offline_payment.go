@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
+)
+
+// MaxPaymentProofSize is the largest transfer receipt accepted
+const MaxPaymentProofSize = 5 * 1024 * 1024 // 5MB
+
+// paymentProofLinkTTL is how long a link to a transfer receipt stays valid
+const paymentProofLinkTTL = 10 * time.Minute
+
+// paymentProofExtensions are the receipt formats accepted, by sniffed content type
+var paymentProofExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"application/pdf": ".pdf",
+}
+
+// offlineInstructions tells the customer where to send an offline payment,
+// quoting the charge's order ID as reference
+func (s *paymentService) offlineInstructions(req transaction.ChargeRequest) *model.PaymentInstructions {
+	transfer := s.offlineAccount
+	transfer.Reference = req.OrderID
+	return &model.PaymentInstructions{BankTransfer: &transfer, ExpiresAt: req.ExpiresAt}
+}
+
+// UploadPaymentProof stores the transfer receipt of an offline payment in the
+// private receipt bucket and puts the payment in the verification queue, where the booking or order no longer
+// expires. A receipt sent again while waiting for verification replaces the
+// previous one.
+func (s *paymentService) UploadPaymentProof(userID uint, paymentID uint, fileName string, data []byte) (*model.Payment, error) {
+	payment, err := s.paymentRepo.GetByIDWithRelations(paymentID)
+	if err != nil {
+		return nil, ErrPaymentNotFound
+	}
+	if user := paymentCustomer(payment); user == nil || user.ID != userID {
+		return nil, ErrPaymentNotOwned
+	}
+	if payment.Provider != model.ProviderOffline {
+		return nil, ErrPaymentNotOffline
+	}
+	if payment.Status != model.PaymentPending && payment.Status != model.PaymentReview {
+		return nil, ErrPaymentProofClosed
+	}
+
+	// Trust the bytes, not the name or header the client sent
+	contentType := http.DetectContentType(data)
+	ext, ok := paymentProofExtensions[contentType]
+	if !ok || len(data) > MaxPaymentProofSize {
+		return nil, ErrPaymentProofInvalid
+	}
+
+	path := fmt.Sprintf("payment-proofs/%d/%d%s", payment.ID, time.Now().UnixNano(), ext)
+	if _, err := s.storageRepo.UploadFile(context.Background(), path, fileName, contentType, data); err != nil {
+		return nil, fmt.Errorf("failed to store transfer receipt: %w", err)
+	}
+
+	attach := func(repos repository.Repositories) error {
+		attached, err := repos.Payments.AttachProof(payment.ID, path)
+		if err == nil && !attached {
+			err = ErrPaymentProofClosed
+		}
+		return err
+	}
+	if payment.Status == model.PaymentReview {
+		err = s.txManager.WithTransaction(attach)
+	} else {
+		err = s.applyPaymentStatus(payment, model.PaymentReview, func(repos repository.Repositories, applied bool) error {
+			if !applied {
+				return ErrPaymentProofClosed
+			}
+			return attach(repos)
+		})
+	}
+	if err != nil {
+		if delErr := s.storageRepo.DeleteFile(context.Background(), path); delErr != nil {
+			logrus.WithError(delErr).WithField("path", path).Warn("Failed to delete unused transfer receipt")
+		}
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"payment_id": payment.ID,
+		"user_id":    userID,
+	}).Info("Transfer receipt uploaded, waiting for verification")
+
+	return s.paymentRepo.GetByID(paymentID)
+}
+
+// GetPaymentProofURL returns a link to the transfer receipt of an offline
+// payment that expires after paymentProofLinkTTL; receipts are never public.
+func (s *paymentService) GetPaymentProofURL(requestorRole model.UserRole, paymentID uint) (string, time.Time, error) {
+	if !s.canManagePayments(requestorRole) {
+		return "", time.Time{}, ErrPaymentInsufficientPermission
+	}
+
+	payment, err := s.paymentRepo.GetByID(paymentID)
+	if err != nil {
+		return "", time.Time{}, ErrPaymentNotFound
+	}
+	if payment.ProofPath == nil {
+		return "", time.Time{}, ErrPaymentProofNotFound
+	}
+
+	expiresAt := time.Now().Add(paymentProofLinkTTL)
+	url, err := s.storageRepo.CreateSignedURL(context.Background(), *payment.ProofPath, paymentProofLinkTTL)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to link transfer receipt: %w", err)
+	}
+	return url, expiresAt, nil
+}
+
+// VerifyOfflinePayment approves or rejects an offline payment after checking
+// the receipt, or the cash received at the counter, and settles or fails what
+// it pays for the way a gateway notification would. A rejected customer can pay
+// again while the payment window is open.
+func (s *paymentService) VerifyOfflinePayment(adminID uint, requestorRole model.UserRole, paymentID uint, approve bool, reason string) (*model.Payment, error) {
+	if !s.canManagePayments(requestorRole) {
+		return nil, ErrPaymentInsufficientPermission
+	}
+
+	payment, err := s.paymentRepo.GetByIDWithRelations(paymentID)
+	if err != nil {
+		return nil, ErrPaymentNotFound
+	}
+	if payment.Provider != model.ProviderOffline {
+		return nil, ErrPaymentNotOffline
+	}
+	if payment.Status != model.PaymentPending && payment.Status != model.PaymentReview {
+		return nil, ErrPaymentNotAwaitingVerify
+	}
+
+	newStatus := model.PaymentPaid
+	if !approve {
+		newStatus = model.PaymentFailed
+		if reason == "" {
+			reason = "transfer could not be verified"
+		}
+	}
+
+	err = s.applyPaymentStatus(payment, newStatus, func(repos repository.Repositories, applied bool) error {
+		if !applied {
+			return ErrPaymentNotAwaitingVerify
+		}
+		if !approve {
+			if err := repos.Payments.MarkAsFailed(payment.ID, reason); err != nil {
+				return err
+			}
+		}
+		return repos.Payments.MarkReviewed(payment.ID, adminID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"payment_id": payment.ID,
+		"admin_id":   adminID,
+		"approved":   approve,
+	}).Info("Offline payment verified")
+
+	// SEND EMAIL: Offline payment rejected; confirmed bookings get their own email
+	if user := paymentCustomer(payment); !approve && user != nil {
+		go func() {
+			subject := "Payment Not Verified - Game Rental"
+			htmlContent := fmt.Sprintf(`
+				<h1>Payment Not Verified</h1>
+				<p>Hi %s,</p>
+				<p>We could not verify your payment of <strong>%s</strong>.</p>
+				<ul>
+					<li><strong>Reason:</strong> %s</li>
+				</ul>
+				<p>You can pay again before the payment deadline of your booking.</p>
+			`, user.FullName, payment.GatewayAmount().Display(), reason)
+
+			plainText := fmt.Sprintf("We could not verify your payment of %s. Reason: %s", payment.GatewayAmount().Display(), reason)
+
+			if err := s.emailRepo.SendEmail(context.Background(), user.Email, subject, plainText, htmlContent); err != nil {
+				logrus.WithError(err).Error("Failed to send payment rejection email")
+			}
+		}()
+	}
+
+	return s.paymentRepo.GetByID(paymentID)
+}
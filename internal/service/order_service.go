@@ -0,0 +1,509 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
+	"github.com/yoockh/go-game-rental-api/internal/utils"
+)
+
+var (
+	ErrOrderNotFound        = errors.New("order not found")
+	ErrOrderNotOwned        = errors.New("you don't own this order")
+	ErrOrderEmpty           = errors.New("order must contain at least one item")
+	ErrOrderTooManyItems    = errors.New("order has too many items")
+	ErrOrderNotPending      = errors.New("order has no pending items")
+	ErrOrderCannotCancel    = errors.New("cannot cancel order in current status")
+	ErrOrderGameNotFound    = errors.New("game in order not found")
+	ErrOrderItemUnavailable = errors.New("a game in the order is not available for the selected dates")
+)
+
+// maxOrderItems caps the number of line items in a single order
+const maxOrderItems = 10
+
+type OrderService interface {
+	// Customer
+	Create(userID uint, order *model.Order) error
+	GetUserOrders(userID uint, limit, offset int) ([]*model.Order, int64, error)
+	GetByID(userID uint, orderID uint) (*model.Order, error)
+	Cancel(userID uint, orderID uint) error
+
+	// System (for payment)
+	ConfirmPayment(repos repository.Repositories, orderID uint) (AfterCommit, error)
+	FailPayment(repos repository.Repositories, orderID uint) (AfterCommit, error)
+	ChargeBack(repos repository.Repositories, orderID uint) (AfterCommit, error)
+	CancelRefunded(actorID uint, orderID uint) error
+	ExpireUnpaidOrders() (int, error)
+}
+
+type orderService struct {
+	txManager       repository.TxManager
+	orderRepo       repository.OrderRepository
+	gameRepo        repository.GameRepository
+	waitlistService WaitlistService
+	gateways        *transaction.Registry
+	emailRepo       email.EmailRepository
+	paymentWindow   time.Duration
+	taxRule         model.TaxRule
+}
+
+// NewOrderService creates the order service. paymentWindow and taxRule are the
+// ones single bookings get.
+func NewOrderService(
+	txManager repository.TxManager,
+	orderRepo repository.OrderRepository,
+	gameRepo repository.GameRepository,
+	waitlistService WaitlistService,
+	gateways *transaction.Registry,
+	emailRepo email.EmailRepository,
+	paymentWindow time.Duration,
+	taxRule model.TaxRule,
+) OrderService {
+	return &orderService{
+		txManager:       txManager,
+		orderRepo:       orderRepo,
+		gameRepo:        gameRepo,
+		waitlistService: waitlistService,
+		gateways:        gateways,
+		emailRepo:       emailRepo,
+		paymentWindow:   paymentWindow,
+		taxRule:         taxRule,
+	}
+}
+
+// Create books every line item of the order in one transaction, so either all
+// games are held for their dates or none are
+func (s *orderService) Create(userID uint, order *model.Order) error {
+	if len(order.Items) == 0 {
+		return ErrOrderEmpty
+	}
+	if len(order.Items) > maxOrderItems {
+		return ErrOrderTooManyItems
+	}
+
+	paymentDueAt := time.Now().Add(s.paymentWindow)
+	games := make(map[uint]*model.Game)
+	order.UserID = userID
+	order.PaymentDueAt = &paymentDueAt
+	order.TotalAmount = model.Money{}
+	for i := range order.Items {
+		item := &order.Items[i]
+		game, ok := games[item.GameID]
+		if !ok {
+			var err error
+			game, err = s.gameRepo.GetByID(item.GameID)
+			if err != nil {
+				return ErrOrderGameNotFound
+			}
+			games[item.GameID] = game
+		}
+
+		if err := prepareBooking(item, game, userID, paymentDueAt); err != nil {
+			return err
+		}
+		applyTax(item, s.taxRule)
+		order.TotalAmount = order.TotalAmount.Add(item.TotalAmount)
+	}
+
+	// Lock games in id order so two orders for the same games cannot deadlock
+	gameIDs := make([]uint, 0, len(games))
+	for id := range games {
+		gameIDs = append(gameIDs, id)
+	}
+	sort.Slice(gameIDs, func(i, j int) bool { return gameIDs[i] < gameIDs[j] })
+
+	err := s.txManager.WithTransaction(func(repos repository.Repositories) error {
+		for _, id := range gameIDs {
+			if _, err := repos.Games.LockForUpdate(id); err != nil {
+				return err
+			}
+		}
+
+		if err := repos.Orders.Create(order); err != nil {
+			return err
+		}
+
+		// Items are inserted one by one so later items see the dates held by earlier ones
+		for i := range order.Items {
+			item := &order.Items[i]
+			available, err := repos.Games.CheckAvailability(item.GameID, item.StartDate, item.EndDate)
+			if err != nil {
+				return err
+			}
+			if !available {
+				return fmt.Errorf("%w: %s", ErrOrderItemUnavailable, games[item.GameID].Name)
+			}
+
+			item.OrderID = &order.ID
+			if err := repos.Bookings.Create(item); err != nil {
+				return err
+			}
+
+			if err := repos.BookingHistory.Create(&model.BookingStatusHistory{
+				BookingID: item.ID,
+				ToStatus:  model.BookingPending,
+				ChangedBy: &userID,
+				Reason:    utils.PtrOrNil(fmt.Sprintf("created with order #%d", order.ID)),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range order.Items {
+		order.Items[i].Game = *games[order.Items[i].GameID]
+	}
+	return nil
+}
+
+func (s *orderService) GetUserOrders(userID uint, limit, offset int) ([]*model.Order, int64, error) {
+	orders, err := s.orderRepo.GetUserOrders(userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := s.orderRepo.CountUserOrders(userID)
+	return orders, count, err
+}
+
+func (s *orderService) GetByID(userID uint, orderID uint) (*model.Order, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+
+	if order.UserID != userID {
+		return nil, ErrOrderNotOwned
+	}
+
+	return order, nil
+}
+
+// Cancel cancels every line item that has not been handed over yet, together
+// with the payments still open for them: the order's pending payment attempt
+// and the supplemental payments of pending date changes, whose charges are
+// cancelled at the gateway once the cancellation has committed. A payment that
+// arrives for the cancelled order anyway is refunded in full.
+func (s *orderService) Cancel(userID uint, orderID uint) error {
+	order, err := s.GetByID(userID, orderID)
+	if err != nil {
+		return err
+	}
+
+	cancellable := 0
+	for _, item := range order.Items {
+		switch item.Status {
+		case model.BookingPending, model.BookingConfirmed:
+			cancellable++
+		case model.BookingCancelled:
+		default:
+			return ErrOrderCannotCancel
+		}
+	}
+	if cancellable == 0 {
+		return ErrOrderCannotCancel
+	}
+
+	// Money was taken for a held payment; it is approved or denied first
+	if order.Payment != nil && order.Payment.Status == model.PaymentReview {
+		return ErrPaymentUnderReview
+	}
+
+	const reason = "order cancelled by customer"
+	var openPayments []*model.Payment
+	err = s.transitionItems(order, []model.BookingStatus{model.BookingPending, model.BookingConfirmed}, model.BookingCancelled, &userID, reason,
+		func(repos repository.Repositories) error {
+			openPayments = nil
+			if order.Payment != nil && order.Payment.Status == model.PaymentPending {
+				openPayments = append(openPayments, order.Payment)
+				if err := failPendingPayment(repos, order.Payment, reason); err != nil {
+					return err
+				}
+			}
+			for _, item := range order.Items {
+				changePayment, err := failPendingChange(repos, item.ID, reason)
+				if err != nil {
+					return err
+				}
+				if changePayment != nil {
+					openPayments = append(openPayments, changePayment)
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+
+	for _, payment := range openPayments {
+		cancelGatewayCharge(s.gateways, payment)
+	}
+	return nil
+}
+
+// ConfirmPayment confirms all pending line items together once the order
+// payment was marked paid in the caller's transaction
+func (s *orderService) ConfirmPayment(repos repository.Repositories, orderID uint) (AfterCommit, error) {
+	order, err := repos.Orders.GetByID(orderID)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+
+	if countItems(order, model.BookingPending) == 0 {
+		return nil, ErrOrderNotPending
+	}
+
+	released, err := s.transitionItemsIn(repos, order, []model.BookingStatus{model.BookingPending}, model.BookingConfirmed, nil, "order payment confirmed")
+	if err != nil {
+		return nil, err
+	}
+
+	// Each line item is invoiced on its own, like a single booking
+	var attachments []email.Attachment
+	for i := range order.Items {
+		item := &order.Items[i]
+		if item.Status != model.BookingConfirmed {
+			continue
+		}
+		item.Order = order
+		item.User = order.User
+		invoice, err := issueInvoice(repos, item)
+		if err != nil {
+			return nil, fmt.Errorf("booking %d: %w", item.ID, err)
+		}
+		attachments = append(attachments, invoiceAttachments(invoice, item, order.Payment)...)
+	}
+
+	return func() {
+		released.run()
+
+		// SEND EMAIL: Order payment confirmed
+		go func() {
+			subject := "Payment Confirmed - Game Rental"
+			htmlContent := fmt.Sprintf(`
+				<h1>Payment Successful!</h1>
+				<p>Hi %s,</p>
+				<p>Your payment for order #%d has been confirmed!</p>
+				<h3>Items:</h3>
+				<ul>%s</ul>
+				<p><strong>Total:</strong> %s</p>
+			`, order.User.FullName, order.ID, orderItemsHTML(order), order.TotalAmount.Display())
+
+			plainText := fmt.Sprintf("Payment confirmed for order #%d. Total: %s", order.ID, order.TotalAmount.Display())
+
+			if err := s.emailRepo.SendEmailWithAttachments(context.Background(), order.User.Email, subject, plainText, htmlContent, attachments); err != nil {
+				logrus.WithError(err).Error("Failed to send order payment confirmation email")
+			}
+		}()
+	}, nil
+}
+
+// FailPayment handles an order payment attempt that was just marked failed in
+// the caller's transaction. While the payment window is open the line items stay
+// pending for another attempt; otherwise they are cancelled.
+func (s *orderService) FailPayment(repos repository.Repositories, orderID uint) (AfterCommit, error) {
+	order, err := repos.Orders.GetByID(orderID)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+
+	if countItems(order, model.BookingPending) > 0 && paymentWindowOpen(order.PaymentDueAt) {
+		// SEND EMAIL: Order payment failed, try again
+		return func() {
+			go func() {
+				subject := "Payment Failed - Game Rental"
+				htmlContent := fmt.Sprintf(`
+					<h1>Payment Failed</h1>
+					<p>Hi %s,</p>
+					<p>Your payment for order #%d did not go through.</p>
+					<h3>Items:</h3>
+					<ul>%s</ul>
+					<p>We are holding your order until %s. Please try again, with another payment method if needed.</p>
+				`, order.User.FullName, order.ID, orderItemsHTML(order), order.PaymentDueAt.Format("2006-01-02 15:04"))
+
+				plainText := fmt.Sprintf("Your payment for order #%d failed. Please try again before %s.", order.ID, order.PaymentDueAt.Format("2006-01-02 15:04"))
+
+				if err := s.emailRepo.SendEmail(context.Background(), order.User.Email, subject, plainText, htmlContent); err != nil {
+					logrus.WithError(err).Error("Failed to send order payment failed email")
+				}
+			}()
+		}, nil
+	}
+
+	return s.transitionItemsIn(repos, order, []model.BookingStatus{model.BookingPending}, model.BookingCancelled, nil, "order payment failed")
+}
+
+// ChargeBack cancels the items of an order whose payment was taken back
+// through a lost dispute, in the caller's transaction. Items that were already
+// handed over keep their status.
+func (s *orderService) ChargeBack(repos repository.Repositories, orderID uint) (AfterCommit, error) {
+	order, err := repos.Orders.GetByID(orderID)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+
+	return s.transitionItemsIn(repos, order, []model.BookingStatus{model.BookingPending, model.BookingConfirmed}, model.BookingCancelled, nil, "payment charged back")
+}
+
+// CancelRefunded cancels the items of an order whose payment was refunded in
+// full. Items that were already handed over keep their status.
+func (s *orderService) CancelRefunded(actorID uint, orderID uint) error {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return ErrOrderNotFound
+	}
+
+	from := []model.BookingStatus{model.BookingPending, model.BookingConfirmed}
+	if countItems(order, model.BookingPending)+countItems(order, model.BookingConfirmed) == 0 {
+		return nil
+	}
+
+	return s.transitionItems(order, from, model.BookingCancelled, &actorID, "payment refunded", nil)
+}
+
+// ExpireUnpaidOrders cancels the pending items of orders whose payment window
+// has passed, fails their pending payment, cancels its charge at the gateway
+// and notifies the customer
+func (s *orderService) ExpireUnpaidOrders() (int, error) {
+	orders, err := s.orderRepo.GetExpiredPending(time.Now(), expiryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, order := range orders {
+		var failedPayment *model.Payment
+		err := s.transitionItems(order, []model.BookingStatus{model.BookingPending}, model.BookingCancelled, nil, "payment window expired",
+			func(repos repository.Repositories) error {
+				if order.Payment != nil && order.Payment.Status == model.PaymentPending {
+					failedPayment = order.Payment
+					return failPendingPayment(repos, order.Payment, "payment window expired")
+				}
+				return nil
+			})
+		if err != nil {
+			logrus.WithError(err).WithField("order_id", order.ID).Warn("Failed to expire order")
+			continue
+		}
+		cancelGatewayCharge(s.gateways, failedPayment)
+		expired++
+
+		// SEND EMAIL: Order expired
+		go func(order *model.Order) {
+			subject := "Order Expired - Game Rental"
+			htmlContent := fmt.Sprintf(`
+				<h1>Order Expired</h1>
+				<p>Hi %s,</p>
+				<p>We did not receive payment for order #%d in time, so it has been cancelled:</p>
+				<ul>%s</ul>
+				<p>You are welcome to place a new order if the games are still available.</p>
+			`, order.User.FullName, order.ID, orderItemsHTML(order))
+
+			plainText := fmt.Sprintf("Your order #%d expired because payment was not received in time.", order.ID)
+
+			if err := s.emailRepo.SendEmail(context.Background(), order.User.Email, subject, plainText, htmlContent); err != nil {
+				logrus.WithError(err).Error("Failed to send order expired email")
+			}
+		}(order)
+	}
+
+	if expired > 0 {
+		logrus.WithField("count", expired).Info("Expired unpaid orders")
+	}
+	return expired, nil
+}
+
+// transitionItems moves every line item currently in one of the from statuses
+// to the target status in a single transaction. extra runs in the same
+// transaction after the items have moved.
+func (s *orderService) transitionItems(order *model.Order, from []model.BookingStatus, to model.BookingStatus, actorID *uint, reason string, extra func(repos repository.Repositories) error) error {
+	var after AfterCommit
+	err := s.txManager.WithTransaction(func(repos repository.Repositories) error {
+		var err error
+		after, err = s.transitionItemsIn(repos, order, from, to, actorID, reason)
+		if err != nil {
+			return err
+		}
+		if extra != nil {
+			return extra(repos)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	after.run()
+	return nil
+}
+
+// transitionItemsIn is transitionItems in the caller's transaction. Cancelled
+// items free their dates; the returned AfterCommit tells the waitlist.
+func (s *orderService) transitionItemsIn(repos repository.Repositories, order *model.Order, from []model.BookingStatus, to model.BookingStatus, actorID *uint, reason string) (AfterCommit, error) {
+	changedGames := make(map[uint]bool)
+	for i := range order.Items {
+		item := &order.Items[i]
+		if !containsStatus(from, item.Status) {
+			continue
+		}
+		item.Order = order // the order payment guards confirmation of its items
+		if err := transitionBooking(repos, item, to, actorID, reason); err != nil {
+			return nil, fmt.Errorf("booking %d: %w", item.ID, err)
+		}
+		item.Status = to
+		changedGames[item.GameID] = true
+	}
+
+	if to != model.BookingCancelled {
+		return nil, nil
+	}
+	return func() {
+		for gameID := range changedGames {
+			s.waitlistService.NotifyCapacityReleased(gameID)
+		}
+	}, nil
+}
+
+func containsStatus(statuses []model.BookingStatus, status model.BookingStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func countItems(order *model.Order, status model.BookingStatus) int {
+	count := 0
+	for _, item := range order.Items {
+		if item.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+func orderItemsHTML(order *model.Order) string {
+	var b strings.Builder
+	for _, item := range order.Items {
+		amount := item.TotalAmount.Display()
+		if item.TaxAmount.IsPositive() {
+			amount += fmt.Sprintf(", %s: %s", taxLabel(item.TaxRule()), item.TaxAmount.Display())
+		}
+		fmt.Fprintf(&b, "<li>%s: %s to %s (%s)</li>", item.Game.Name, item.StartDate.Format("2006-01-02"), item.EndDate.Format("2006-01-02"), amount)
+	}
+	return b.String()
+}
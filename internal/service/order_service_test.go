@@ -0,0 +1,232 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
+)
+
+type orderFixture struct {
+	svc      *orderService
+	orders   *fakeOrderRepo
+	bookings *fakeBookingStore
+	payments *fakePaymentRepo
+	wallets  *fakeWalletRepo
+	invoices *fakeInvoiceRepo
+	gateway  *transaction.MockTransactionRepository
+}
+
+// newOrderFixture is the order service over an in-memory store holding one copy
+// of game 1, with Midtrans as the only gateway
+func newOrderFixture() *orderFixture {
+	store := &fakeBookingStore{}
+	payments := &fakePaymentRepo{}
+	orders := &fakeOrderRepo{bookings: store, payments: payments}
+	wallets := newFakeWalletRepo()
+	invoices := &fakeInvoiceRepo{}
+	game := &model.Game{ID: 1, Name: "Zelda", Stock: 1, RentalPricePerDay: model.NewMoney(10000), SecurityDeposit: model.NewMoney(50000), IsActive: true}
+	games := &fakeGameRepo{game: game, bookings: store}
+	gateway := &transaction.MockTransactionRepository{}
+	gateways := transaction.NewRegistry()
+	gateways.Register(string(model.ProviderMidtrans), gateway)
+	waitlist := &MockWaitlistService{}
+	waitlist.On("NotifyCapacityReleased", uint(1)).Return()
+	txManager := &fakeTxManager{repos: repository.Repositories{
+		Bookings:       store,
+		BookingHistory: &fakeHistoryRepo{},
+		Orders:         orders,
+		Games:          games,
+		Payments:       payments,
+		Wallets:        wallets,
+		Invoices:       invoices,
+		DateChanges:    &fakeDateChangeRepo{},
+	}}
+	svc := NewOrderService(txManager, orders, games, waitlist, gateways, &email.MockEmailRepository{}, 24*time.Hour, model.TaxRule{}).(*orderService)
+	return &orderFixture{svc: svc, orders: orders, bookings: store, payments: payments, wallets: wallets, invoices: invoices, gateway: gateway}
+}
+
+// placeOrder orders game 1 for two separate stays of customer 5
+func (f *orderFixture) placeOrder(t *testing.T) *model.Order {
+	start := time.Now().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	order := &model.Order{Items: []model.Booking{
+		{GameID: 1, StartDate: start, EndDate: start.AddDate(0, 0, 1)},
+		{GameID: 1, StartDate: start.AddDate(0, 0, 5), EndDate: start.AddDate(0, 0, 6)},
+	}}
+	require.NoError(t, f.svc.Create(5, order))
+	return order
+}
+
+// ============= TEST CREATE =============
+func TestCreate_UnavailableItemRollsBackOrder(t *testing.T) {
+	f := newOrderFixture()
+	start := time.Now().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	order := &model.Order{Items: []model.Booking{
+		{GameID: 1, StartDate: start, EndDate: start.AddDate(0, 0, 2)},
+		{GameID: 1, StartDate: start.AddDate(0, 0, 1), EndDate: start.AddDate(0, 0, 3)}, // the one copy is taken
+	}}
+
+	err := f.svc.Create(5, order)
+	assert.ErrorIs(t, err, ErrOrderItemUnavailable)
+	assert.Empty(t, f.bookings.bookings, "the first item is not held either")
+	assert.Empty(t, f.orders.orders)
+}
+
+func TestCreate_TotalsItems(t *testing.T) {
+	f := newOrderFixture()
+	order := f.placeOrder(t)
+
+	require.Len(t, f.bookings.bookings, 2)
+	for _, item := range f.bookings.bookings {
+		assert.Equal(t, model.BookingPending, item.Status)
+		assert.Equal(t, order.ID, *item.OrderID)
+	}
+	// Two days plus the deposit, twice
+	assert.Equal(t, model.NewMoney(140000), order.TotalAmount)
+	require.NotNil(t, order.PaymentDueAt)
+}
+
+// ============= TEST PAYMENT =============
+func TestCreateOrderPayment_ChargesWholeOrderOnce(t *testing.T) {
+	f := newOrderFixture()
+	order := f.placeOrder(t)
+	payments := NewPaymentService(f.svc.txManager, f.payments, nil, nil, nil, f.orders, &fakeUserRepo{}, nil, nil, nil, nil, f.svc, nil,
+		f.svc.gateways, &email.MockEmailRepository{}, nil, model.BankTransfer{}, 30*time.Minute)
+
+	payment, err := payments.CreateOrderPayment(5, order.ID, model.ProviderMidtrans, "", false)
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentPurposeOrder, payment.Purpose)
+	require.Len(t, f.gateway.Charges, 1)
+	assert.Equal(t, model.NewMoney(140000), f.gateway.Charges[0].Amount)
+	assert.Equal(t, fmt.Sprintf("order-%d-%d", order.ID, payment.ID), f.gateway.Charges[0].OrderID)
+	assert.Len(t, f.gateway.Charges[0].Items, 4, "rental and deposit of each item")
+
+	_, err = payments.CreateOrderPayment(5, order.ID, model.ProviderMidtrans, "", false)
+	assert.ErrorIs(t, err, ErrPaymentAttemptPending)
+}
+
+func TestConfirmPayment_ConfirmsEveryItem(t *testing.T) {
+	f := newOrderFixture()
+	order := f.placeOrder(t)
+	f.payments.payment = &model.Payment{ID: 9, OrderID: &order.ID, Purpose: model.PaymentPurposeOrder, Status: model.PaymentPaid, Amount: order.TotalAmount}
+
+	err := f.svc.txManager.WithTransaction(func(repos repository.Repositories) error {
+		_, err := f.svc.ConfirmPayment(repos, order.ID)
+		return err
+	})
+	require.NoError(t, err)
+	for _, item := range f.bookings.bookings {
+		assert.Equal(t, model.BookingConfirmed, item.Status)
+	}
+
+	err = f.svc.txManager.WithTransaction(func(repos repository.Repositories) error {
+		_, err := f.svc.ConfirmPayment(repos, order.ID)
+		return err
+	})
+	assert.ErrorIs(t, err, ErrOrderNotPending)
+}
+
+func TestConfirmPayment_InvoicesEveryItem(t *testing.T) {
+	f := newOrderFixture()
+	order := f.placeOrder(t)
+	f.payments.payment = &model.Payment{ID: 9, OrderID: &order.ID, Purpose: model.PaymentPurposeOrder, Status: model.PaymentPaid, Amount: order.TotalAmount}
+
+	err := f.svc.txManager.WithTransaction(func(repos repository.Repositories) error {
+		_, err := f.svc.ConfirmPayment(repos, order.ID)
+		return err
+	})
+	require.NoError(t, err)
+
+	require.Len(t, f.invoices.invoices, 2)
+	total := model.NewMoney(0)
+	for i, invoice := range f.invoices.invoices {
+		assert.Equal(t, f.bookings.bookings[i].ID, invoice.BookingID)
+		assert.Equal(t, uint(9), invoice.PaymentID, "paid by the order payment")
+		assert.Equal(t, 2, invoice.RentalDays)
+		total = total.Add(invoice.TotalAmount)
+	}
+	assert.Equal(t, order.TotalAmount, total)
+}
+
+func TestFailPayment_KeepsOrderHeldForRetry(t *testing.T) {
+	f := newOrderFixture()
+	order := f.placeOrder(t)
+	f.payments.payment = &model.Payment{ID: 9, OrderID: &order.ID, Purpose: model.PaymentPurposeOrder, Status: model.PaymentFailed}
+
+	fail := func() error {
+		return f.svc.txManager.WithTransaction(func(repos repository.Repositories) error {
+			_, err := f.svc.FailPayment(repos, order.ID)
+			return err
+		})
+	}
+	require.NoError(t, fail())
+	for _, item := range f.bookings.bookings {
+		assert.Equal(t, model.BookingPending, item.Status, "held while the payment window is open")
+	}
+
+	closed := time.Now().Add(-time.Minute)
+	f.orders.orders[0].PaymentDueAt = &closed
+	require.NoError(t, fail())
+	for _, item := range f.bookings.bookings {
+		assert.Equal(t, model.BookingCancelled, item.Status)
+	}
+}
+
+// ============= TEST EXPIRY =============
+func TestExpireUnpaidOrders_CancelsItemsAndPayment(t *testing.T) {
+	f := newOrderFixture()
+	order := f.placeOrder(t)
+	txID := "tx-9"
+	f.payments.payment = &model.Payment{
+		ID: 9, OrderID: &order.ID, Purpose: model.PaymentPurposeOrder, Provider: model.ProviderMidtrans, Status: model.PaymentPending, ProviderPaymentID: &txID,
+	}
+
+	expired, err := f.svc.ExpireUnpaidOrders()
+	require.NoError(t, err)
+	assert.Zero(t, expired, "still inside its payment window")
+
+	closed := time.Now().Add(-time.Minute)
+	f.orders.orders[0].PaymentDueAt = &closed
+	expired, err = f.svc.ExpireUnpaidOrders()
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	for _, item := range f.bookings.bookings {
+		assert.Equal(t, model.BookingCancelled, item.Status)
+	}
+	assert.Equal(t, model.PaymentFailed, f.payments.payment.Status)
+	assert.Equal(t, []string{"tx-9"}, f.gateway.Cancelled)
+}
+
+// ============= TEST CANCEL =============
+func TestCancel_FailsPendingOrderPaymentAndCancelsCharge(t *testing.T) {
+	f := newOrderFixture()
+	order := f.placeOrder(t)
+	txID := "tx-9"
+	f.payments.payment = &model.Payment{
+		ID: 9, OrderID: &order.ID, Purpose: model.PaymentPurposeOrder, Provider: model.ProviderMidtrans, Status: model.PaymentPending,
+		Amount: order.TotalAmount, WalletAmount: model.NewMoney(20000), ProviderPaymentID: &txID,
+	}
+
+	require.NoError(t, f.svc.Cancel(5, order.ID))
+	for _, item := range f.bookings.bookings {
+		assert.Equal(t, model.BookingCancelled, item.Status)
+	}
+	assert.Equal(t, model.PaymentFailed, f.payments.payment.Status, "a payment arriving later is refunded")
+	assert.Equal(t, []string{"tx-9"}, f.gateway.Cancelled)
+	assert.Equal(t, model.NewMoney(20000), f.wallets.balances[5], "the wallet part is given back")
+}
+
+func TestCancel_RefusedWhileOrderPaymentUnderReview(t *testing.T) {
+	f := newOrderFixture()
+	order := f.placeOrder(t)
+	f.payments.payment = &model.Payment{ID: 9, OrderID: &order.ID, Purpose: model.PaymentPurposeOrder, Provider: model.ProviderMidtrans, Status: model.PaymentReview}
+
+	assert.ErrorIs(t, f.svc.Cancel(5, order.ID), ErrPaymentUnderReview)
+	assert.Equal(t, model.BookingPending, f.bookings.bookings[0].Status)
+}
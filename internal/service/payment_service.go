@@ -2,70 +2,152 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/yoockh/go-game-rental-api/internal/model"
 	"github.com/yoockh/go-game-rental-api/internal/repository"
 	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+	"github.com/yoockh/go-game-rental-api/internal/repository/storage"
 	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
+	"github.com/yoockh/go-game-rental-api/internal/utils"
 )
 
 var (
 	ErrPaymentNotFound               = errors.New("payment not found")
-	ErrPaymentAlreadyExists          = errors.New("payment already exists for this booking")
+	ErrPaymentAttemptPending         = errors.New("a payment attempt is still pending, complete it or wait for it to fail")
+	ErrPaymentAlreadyPaid            = errors.New("already paid")
+	ErrPaymentWindowClosed           = errors.New("payment window has passed")
 	ErrPaymentBookingNotFound        = errors.New("booking not found")
 	ErrPaymentInvalidStatus          = errors.New("invalid payment status transition")
 	ErrPaymentInsufficientPermission = errors.New("insufficient permission")
+	ErrPaymentBookingInOrder         = errors.New("booking is part of an order, pay for the order instead")
+	ErrPaymentOrderNotFound          = errors.New("order not found")
+	ErrPaymentUnderReview            = errors.New("payment is being reviewed, wait for the outcome")
+	ErrPaymentNotUnderReview         = errors.New("payment is not awaiting review")
+	ErrPaymentInvalidReviewQueue     = errors.New("review queue must be fraud or offline")
+	ErrPaymentReviewUnsupported      = errors.New("payment provider does not support fraud review")
+	ErrPaymentNotOwned               = errors.New("payment not owned by user")
+	ErrPaymentNotOffline             = errors.New("only offline payments take a transfer receipt or manual verification")
+	ErrPaymentProofClosed            = errors.New("payment is no longer waiting for a transfer receipt")
+	ErrPaymentProofInvalid           = errors.New("transfer receipt must be a JPEG, PNG or PDF file of at most 5MB")
+	ErrPaymentProofNotFound          = errors.New("transfer receipt not found")
+	ErrPaymentNotAwaitingVerify      = errors.New("payment is not awaiting verification")
+
+	ErrWebhookInvalidSignature = errors.New("invalid webhook signature")
+	ErrWebhookAmountMismatch   = errors.New("webhook gross_amount does not match the payment amount")
+
+	ErrPaymentEventNotFound      = errors.New("payment event not found")
+	ErrPaymentEventNotReplayable = errors.New("only failed or stale received payment events can be replayed")
 )
 
 type PaymentService interface {
 	// Customer methods
-	CreatePayment(userID uint, bookingID uint, provider model.PaymentProvider, paymentType string) (*model.Payment, error)
+	CreatePayment(userID uint, bookingID uint, provider model.PaymentProvider, paymentType string, useWallet bool) (*model.Payment, error)
+	CreateOrderPayment(userID uint, orderID uint, provider model.PaymentProvider, paymentType string, useWallet bool) (*model.Payment, error)
 	GetPaymentByBooking(userID uint, bookingID uint) (*model.Payment, error)
+	UploadPaymentProof(userID uint, paymentID uint, fileName string, data []byte) (*model.Payment, error)
 
 	// Admin methods
 	GetAllPayments(requestorRole model.UserRole, limit, offset int) ([]*model.Payment, int64, error)
 	GetPaymentsByStatus(requestorRole model.UserRole, status model.PaymentStatus, limit, offset int) ([]*model.Payment, int64, error)
 	GetPaymentDetail(requestorRole model.UserRole, paymentID uint) (*model.Payment, error)
+	GetPaymentProofURL(requestorRole model.UserRole, paymentID uint) (string, time.Time, error)
+	GetEvents(requestorRole model.UserRole, status model.PaymentEventStatus, limit, offset int) ([]*model.PaymentEvent, int64, error)
+	ReplayEvent(requestorRole model.UserRole, eventID uint) (*model.PaymentEvent, error)
+	GetReconciliations(requestorRole model.UserRole, resolution model.ReconciliationResolution, limit, offset int) ([]*model.PaymentReconciliation, int64, error)
+	GetReviewQueue(requestorRole model.UserRole, queue model.ReviewQueue, limit, offset int) ([]*model.Payment, int64, error)
+	ReviewPayment(adminID uint, requestorRole model.UserRole, paymentID uint, approve bool) (*model.Payment, error)
+	VerifyOfflinePayment(adminID uint, requestorRole model.UserRole, paymentID uint, approve bool, reason string) (*model.Payment, error)
 
 	// Webhook/System methods
-	ProcessWebhook(data interface{}) error
+	ProcessWebhook(provider model.PaymentProvider, header http.Header, body []byte) error
+	ReconcilePending() (int, error)
 }
 
+// reconcileBatchSize caps how many payments a single reconciliation run checks
+const reconcileBatchSize = 100
+
+// staleEventAfter is how long an event may stay received before the delivery
+// that stored it is taken to have crashed, so the event can be applied again
+const staleEventAfter = 5 * time.Minute
+
 type paymentService struct {
-	paymentRepo     repository.PaymentRepository
-	bookingRepo     repository.BookingRepository
-	userRepo        repository.UserRepository
-	gameRepo        repository.GameRepository
-	bookingService  BookingService
-	transactionRepo transaction.TransactionRepository
-	emailRepo       email.EmailRepository
+	txManager            repository.TxManager
+	paymentRepo          repository.PaymentRepository
+	eventRepo            repository.PaymentEventRepository
+	reconciliationRepo   repository.PaymentReconciliationRepository
+	bookingRepo          repository.BookingRepository
+	orderRepo            repository.OrderRepository
+	userRepo             repository.UserRepository
+	gameRepo             repository.GameRepository
+	walletRepo           repository.WalletRepository
+	bookingService       BookingService
+	bookingChangeService BookingChangeService
+	orderService         OrderService
+	disputeService       DisputeService
+	gateways             *transaction.Registry
+	emailRepo            email.EmailRepository
+	storageRepo          storage.StorageRepository
+	offlineAccount       model.BankTransfer
+	reconcileAfter       time.Duration
 }
 
+// NewPaymentService creates the payment service. offlineAccount is the bank
+// account offline payments are transferred to, empty for cash only.
+// reconcileAfter is how long a payment may stay pending before its status is
+// checked with the gateway.
 func NewPaymentService(
+	txManager repository.TxManager,
 	paymentRepo repository.PaymentRepository,
+	eventRepo repository.PaymentEventRepository,
+	reconciliationRepo repository.PaymentReconciliationRepository,
 	bookingRepo repository.BookingRepository,
+	orderRepo repository.OrderRepository,
 	userRepo repository.UserRepository,
 	gameRepo repository.GameRepository,
+	walletRepo repository.WalletRepository,
 	bookingService BookingService,
-	transactionRepo transaction.TransactionRepository,
+	bookingChangeService BookingChangeService,
+	orderService OrderService,
+	disputeService DisputeService,
+	gateways *transaction.Registry,
 	emailRepo email.EmailRepository,
+	storageRepo storage.StorageRepository,
+	offlineAccount model.BankTransfer,
+	reconcileAfter time.Duration,
 ) PaymentService {
 	return &paymentService{
-		paymentRepo:     paymentRepo,
-		bookingRepo:     bookingRepo,
-		userRepo:        userRepo,
-		gameRepo:        gameRepo,
-		bookingService:  bookingService,
-		transactionRepo: transactionRepo,
-		emailRepo:       emailRepo,
+		txManager:            txManager,
+		paymentRepo:          paymentRepo,
+		eventRepo:            eventRepo,
+		reconciliationRepo:   reconciliationRepo,
+		bookingRepo:          bookingRepo,
+		orderRepo:            orderRepo,
+		userRepo:             userRepo,
+		gameRepo:             gameRepo,
+		walletRepo:           walletRepo,
+		bookingService:       bookingService,
+		bookingChangeService: bookingChangeService,
+		orderService:         orderService,
+		disputeService:       disputeService,
+		gateways:             gateways,
+		emailRepo:            emailRepo,
+		storageRepo:          storageRepo,
+		offlineAccount:       offlineAccount,
+		reconcileAfter:       reconcileAfter,
 	}
 }
 
-func (s *paymentService) CreatePayment(userID uint, bookingID uint, provider model.PaymentProvider, paymentType string) (*model.Payment, error) {
+// CreatePayment pays for a booking with a provider, the wallet, or the wallet
+// topped up by a provider when useWallet is set
+func (s *paymentService) CreatePayment(userID uint, bookingID uint, provider model.PaymentProvider, paymentType string, useWallet bool) (*model.Payment, error) {
 	// Get booking and validate ownership
 	booking, err := s.bookingRepo.GetByID(bookingID)
 	if err != nil {
@@ -80,55 +162,37 @@ func (s *paymentService) CreatePayment(userID uint, bookingID uint, provider mod
 		return nil, errors.New("booking must be in pending status")
 	}
 
-	// Check if payment already exists
-	existingPayment, _ := s.paymentRepo.GetByBookingID(bookingID)
-	if existingPayment != nil {
-		return nil, ErrPaymentAlreadyExists
+	if booking.OrderID != nil {
+		return nil, ErrPaymentBookingInOrder
+	}
+
+	if err := checkNewAttempt(booking.Payment, booking.PaymentDueAt); err != nil {
+		return nil, err
 	}
 
 	// Create payment record
 	payment := &model.Payment{
-		BookingID: bookingID,
+		BookingID: &bookingID,
 		Provider:  provider,
+		Purpose:   model.PaymentPurposeBooking,
 		Amount:    booking.TotalAmount,
 		Status:    model.PaymentPending,
 	}
 
-	err = s.paymentRepo.Create(payment)
+	err = s.charge(payment, userID, useWallet, transaction.ChargeRequest{
+		OrderID:     fmt.Sprintf("booking-%d", bookingID),
+		PaymentType: paymentType,
+		Items:       bookingChargeItems(booking),
+		Customer:    chargeCustomer(&booking.User),
+		ExpiresAt:   booking.PaymentDueAt,
+	})
 	if err != nil {
-		return nil, err
+		return payment, err
 	}
 
-	// Create charge with payment gateway based on provider
-	orderID := fmt.Sprintf("booking-%d", bookingID)
-
-	switch provider {
-	case model.ProviderMidtrans:
-		// Set default payment type if not provided
-		if paymentType == "" {
-			paymentType = "bank_transfer"
-		}
-
-		txID, _, err := s.transactionRepo.CreateCharge(
-			context.Background(),
-			orderID,
-			int64(payment.Amount),
-			paymentType,
-			nil,
-		)
-		if err != nil {
-			return payment, fmt.Errorf("midtrans payment gateway error: %w", err)
-		}
-
-		// Update payment with provider transaction ID
-		payment.ProviderPaymentID = &txID
-		s.paymentRepo.Update(payment)
-
-	case model.ProviderStripe:
-		return payment, errors.New("stripe payment provider not implemented yet")
-
-	default:
-		return payment, errors.New("unsupported payment provider")
+	// Paid from the wallet, the confirmation email is on its way
+	if payment.Status == model.PaymentPaid {
+		return payment, nil
 	}
 
 	// SEND EMAIL: Payment instruction
@@ -141,6 +205,10 @@ func (s *paymentService) CreatePayment(userID uint, bookingID uint, provider mod
 			if payment.ProviderPaymentID != nil {
 				orderIDStr = *payment.ProviderPaymentID
 			}
+			paymentDeadline := "the booking expires"
+			if booking.PaymentDueAt != nil {
+				paymentDeadline = booking.PaymentDueAt.Format("2006-01-02 15:04")
+			}
 			htmlContent := fmt.Sprintf(`
 				<h1>Complete Your Payment</h1>
 				<p>Hi %s,</p>
@@ -148,13 +216,14 @@ func (s *paymentService) CreatePayment(userID uint, bookingID uint, provider mod
 				<h3>Payment Details:</h3>
 				<ul>
 					<li><strong>Order ID:</strong> %s</li>
-					<li><strong>Amount:</strong> Rp %.0f</li>
+					<li><strong>Amount:</strong> %s</li>
 					<li><strong>Game:</strong> %s</li>
 				</ul>
-				<p>Complete within 24 hours.</p>
-			`, user.FullName, orderIDStr, payment.Amount, game.Name)
+				%s
+				<p>Complete before %s.</p>
+			`, user.FullName, orderIDStr, payment.Amount.Display(), game.Name, instructionsHTML(payment.Instructions), paymentDeadline)
 
-			plainText := fmt.Sprintf("Payment instruction. Order ID: %s, Amount: Rp %.0f", orderIDStr, payment.Amount)
+			plainText := fmt.Sprintf("Payment instruction. Order ID: %s, Amount: %s%s", orderIDStr, payment.Amount.Display(), instructionsText(payment.Instructions))
 
 			if err := s.emailRepo.SendEmail(context.Background(), user.Email, subject, plainText, htmlContent); err != nil {
 				logrus.WithError(err).Error("Failed to send payment instruction email")
@@ -165,6 +234,294 @@ func (s *paymentService) CreatePayment(userID uint, bookingID uint, provider mod
 	return payment, nil
 }
 
+// CreateOrderPayment checks out a whole order with a single charge, paid the
+// same ways as a booking
+func (s *paymentService) CreateOrderPayment(userID uint, orderID uint, provider model.PaymentProvider, paymentType string, useWallet bool) (*model.Payment, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, ErrPaymentOrderNotFound
+	}
+
+	if order.UserID != userID {
+		return nil, errors.New("unauthorized")
+	}
+
+	for _, item := range order.Items {
+		if item.Status != model.BookingPending {
+			return nil, errors.New("all order items must be in pending status")
+		}
+	}
+
+	if err := checkNewAttempt(order.Payment, order.PaymentDueAt); err != nil {
+		return nil, err
+	}
+
+	payment := &model.Payment{
+		OrderID:  &orderID,
+		Provider: provider,
+		Purpose:  model.PaymentPurposeOrder,
+		Amount:   order.TotalAmount,
+		Status:   model.PaymentPending,
+	}
+
+	var items []transaction.ChargeItem
+	for i := range order.Items {
+		items = append(items, bookingChargeItems(&order.Items[i])...)
+	}
+	err = s.charge(payment, userID, useWallet, transaction.ChargeRequest{
+		OrderID:     fmt.Sprintf("order-%d", orderID),
+		PaymentType: paymentType,
+		Items:       items,
+		Customer:    chargeCustomer(&order.User),
+		ExpiresAt:   order.PaymentDueAt,
+	})
+	if err != nil {
+		return payment, err
+	}
+
+	// Paid from the wallet, the confirmation email is on its way
+	if payment.Status == model.PaymentPaid {
+		return payment, nil
+	}
+
+	// SEND EMAIL: Payment instruction
+	go func() {
+		subject := "Payment Instruction - Game Rental"
+		orderIDStr := "N/A"
+		if payment.ProviderPaymentID != nil {
+			orderIDStr = *payment.ProviderPaymentID
+		}
+		paymentDeadline := "the order expires"
+		if order.PaymentDueAt != nil {
+			paymentDeadline = order.PaymentDueAt.Format("2006-01-02 15:04")
+		}
+		htmlContent := fmt.Sprintf(`
+			<h1>Complete Your Payment</h1>
+			<p>Hi %s,</p>
+			<p>Please complete payment to confirm order #%d.</p>
+			<h3>Payment Details:</h3>
+			<ul>
+				<li><strong>Order ID:</strong> %s</li>
+				<li><strong>Amount:</strong> %s</li>
+			</ul>
+			<h3>Items:</h3>
+			<ul>%s</ul>
+			%s
+			<p>Complete before %s.</p>
+		`, order.User.FullName, order.ID, orderIDStr, payment.Amount.Display(), orderItemsHTML(order), instructionsHTML(payment.Instructions), paymentDeadline)
+
+		plainText := fmt.Sprintf("Payment instruction. Order ID: %s, Amount: %s%s", orderIDStr, payment.Amount.Display(), instructionsText(payment.Instructions))
+
+		if err := s.emailRepo.SendEmail(context.Background(), order.User.Email, subject, plainText, htmlContent); err != nil {
+			logrus.WithError(err).Error("Failed to send payment instruction email")
+		}
+	}()
+
+	return payment, nil
+}
+
+// checkNewAttempt tells whether a new payment attempt may be made given the
+// current one. Failed attempts can be retried while the payment window is open.
+func checkNewAttempt(current *model.Payment, paymentDueAt *time.Time) error {
+	if current != nil {
+		switch current.Status {
+		case model.PaymentPending:
+			return ErrPaymentAttemptPending
+		case model.PaymentReview:
+			return ErrPaymentUnderReview
+		case model.PaymentFailed:
+		default:
+			return ErrPaymentAlreadyPaid
+		}
+	}
+	if paymentDueAt != nil && time.Now().After(*paymentDueAt) {
+		return ErrPaymentWindowClosed
+	}
+	return nil
+}
+
+// paymentWindowOpen tells whether a failed payment attempt may still be followed
+// by another one. Bookings and orders without a deadline are not held.
+func paymentWindowOpen(paymentDueAt *time.Time) bool {
+	return paymentDueAt != nil && time.Now().Before(*paymentDueAt)
+}
+
+// charge stores the payment and pays for it. With useWallet, or the wallet as
+// provider, as much as the customer's wallet holds is taken from it in the same
+// transaction; the rest is charged with the provider. A payment the wallet
+// covers in full is paid at once. Offline payments only get their transfer
+// instructions and wait for a receipt. When the provider charge cannot be
+// created the attempt fails and the wallet part is given back. req.OrderID
+// names what is paid for; the payment ID is appended to it, since gateways
+// refuse an order ID they have seen before, even on a failed attempt.
+func (s *paymentService) charge(payment *model.Payment, userID uint, useWallet bool, req transaction.ChargeRequest) error {
+	if useWallet || payment.Provider == model.ProviderWallet {
+		wallet, err := s.walletRepo.GetByUserID(userID)
+		if err != nil {
+			return err
+		}
+		payment.WalletAmount = wallet.Balance.Min(payment.Amount)
+		if payment.Provider != model.ProviderWallet {
+			// Gateways only charge whole units, so the wallet takes the fraction
+			payment.WalletAmount = payment.WalletAmount.Truncate()
+			if payment.WalletAmount.Equal(payment.Amount) {
+				payment.Provider = model.ProviderWallet
+			}
+		}
+		if payment.Provider == model.ProviderWallet && !payment.WalletAmount.Equal(payment.Amount) {
+			return ErrWalletInsufficientBalance
+		}
+	}
+
+	var gateway transaction.TransactionRepository
+	switch payment.Provider {
+	case model.ProviderWallet, model.ProviderOffline:
+	default:
+		var err error
+		if gateway, err = s.gateways.Get(string(payment.Provider)); err != nil {
+			return err
+		}
+	}
+
+	err := s.txManager.WithTransaction(func(repos repository.Repositories) error {
+		if err := repos.Payments.Create(payment); err != nil {
+			return err
+		}
+		req.OrderID = fmt.Sprintf("%s-%d", req.OrderID, payment.ID)
+		if payment.Provider == model.ProviderOffline {
+			payment.Instructions = s.offlineInstructions(req)
+			if err := repos.Payments.Update(payment); err != nil {
+				return err
+			}
+		}
+		if payment.WalletAmount.IsPositive() {
+			return debitWallet(repos, userID, payment)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if payment.Provider == model.ProviderWallet {
+		err := s.applyPaymentStatus(payment, model.PaymentPaid, func(repos repository.Repositories, applied bool) error {
+			return nil
+		})
+		if err != nil {
+			s.abandonCharge(payment, err)
+		}
+		return err
+	}
+	if payment.Provider == model.ProviderOffline {
+		return nil
+	}
+
+	// Set default payment type if not provided
+	if req.PaymentType == "" && payment.Provider == model.ProviderMidtrans {
+		req.PaymentType = "bank_transfer"
+	}
+
+	req.Amount = payment.GatewayAmount()
+	if payment.WalletAmount.IsPositive() && len(req.Items) > 0 {
+		req.Items = append(req.Items, transaction.ChargeItem{
+			ID:       "wallet",
+			Name:     "Paid from wallet",
+			Price:    payment.WalletAmount.Neg(),
+			Quantity: 1,
+		})
+	}
+
+	charge, err := gateway.CreateCharge(context.Background(), req)
+	if err != nil {
+		s.abandonCharge(payment, err)
+		return fmt.Errorf("%s payment gateway error: %w", payment.Provider, err)
+	}
+
+	// Update payment with provider transaction ID and how to pay it
+	payment.ProviderPaymentID = &charge.TransactionID
+	payment.Instructions = charge.Instructions
+	if err := s.paymentRepo.Update(payment); err != nil {
+		logrus.WithError(err).WithField("payment_id", payment.ID).Error("Failed to save payment charge")
+	}
+	return nil
+}
+
+// abandonCharge fails a payment attempt whose provider charge could not be
+// created, so the customer can try again, and gives its wallet part back. The
+// booking or order stays as it is; the caller reports the error. A charge the
+// provider did create is cancelled.
+func (s *paymentService) abandonCharge(payment *model.Payment, cause error) {
+	err := s.txManager.WithTransaction(func(repos repository.Repositories) error {
+		return failPendingPayment(repos, payment, cause.Error())
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("payment_id", payment.ID).Error("Failed to fail abandoned payment attempt")
+		return
+	}
+	payment.Status = model.PaymentFailed
+	payment.FailureReason = utils.PtrOrNil(cause.Error())
+	cancelGatewayCharge(s.gateways, payment)
+}
+
+// bookingChargeItems itemizes what a booking is paid for: the rental days, the
+// deposit, any promo discount and the tax
+func bookingChargeItems(booking *model.Booking) []transaction.ChargeItem {
+	rental := transaction.ChargeItem{
+		ID:       fmt.Sprintf("booking-%d-rental", booking.ID),
+		Name:     booking.Game.Name + " rental",
+		Price:    booking.DailyPrice,
+		Quantity: booking.RentalDays,
+	}
+	if !booking.DailyPrice.Mul(int64(booking.RentalDays)).Equal(booking.TotalRentalPrice) {
+		rental.Price, rental.Quantity = booking.TotalRentalPrice, 1
+	}
+
+	items := []transaction.ChargeItem{rental}
+	if !booking.SecurityDeposit.IsZero() {
+		items = append(items, transaction.ChargeItem{
+			ID:       fmt.Sprintf("booking-%d-deposit", booking.ID),
+			Name:     "Security deposit (refundable)",
+			Price:    booking.SecurityDeposit,
+			Quantity: 1,
+		})
+	}
+	if booking.DiscountAmount.IsPositive() {
+		items = append(items, transaction.ChargeItem{
+			ID:       fmt.Sprintf("booking-%d-discount", booking.ID),
+			Name:     "Promo discount",
+			Price:    booking.DiscountAmount.Neg(),
+			Quantity: 1,
+		})
+	}
+	// Inclusive tax is already part of the prices above
+	if !booking.TaxInclusive && booking.TaxAmount.IsPositive() {
+		items = append(items, transaction.ChargeItem{
+			ID:       fmt.Sprintf("booking-%d-tax", booking.ID),
+			Name:     taxLabel(booking.TaxRule()),
+			Price:    booking.TaxAmount,
+			Quantity: 1,
+		})
+	}
+	return items
+}
+
+// chargeCustomer is the customer details sent with a charge, nil when the user
+// was not loaded
+func chargeCustomer(user *model.User) *transaction.ChargeCustomer {
+	if user == nil || user.ID == 0 {
+		return nil
+	}
+
+	customer := &transaction.ChargeCustomer{Name: user.FullName, Email: user.Email}
+	if user.Phone != nil {
+		customer.Phone = *user.Phone
+	}
+	if user.Address != nil {
+		customer.Address = *user.Address
+	}
+	return customer
+}
+
 func (s *paymentService) GetPaymentByBooking(userID uint, bookingID uint) (*model.Payment, error) {
 	// Validate booking ownership
 	booking, err := s.bookingRepo.GetByID(bookingID)
@@ -215,56 +572,379 @@ func (s *paymentService) GetPaymentDetail(requestorRole model.UserRole, paymentI
 	return s.paymentRepo.GetByIDWithRelations(paymentID)
 }
 
-func (s *paymentService) ProcessWebhook(data interface{}) error {
-	webhookData, ok := data.(map[string]interface{})
-	if !ok {
-		return errors.New("invalid webhook data")
+// ProcessWebhook verifies a provider notification, stores it as a payment event
+// and applies it. Redelivered notifications are acknowledged without being
+// applied again, unless the earlier delivery failed or never finished.
+func (s *paymentService) ProcessWebhook(provider model.PaymentProvider, header http.Header, body []byte) error {
+	gateway, err := s.gateways.Get(string(provider))
+	if err != nil {
+		return err
 	}
 
-	providerPaymentID, ok := webhookData["order_id"].(string)
-	if !ok {
-		return errors.New("missing order_id in webhook")
+	notification, err := gateway.ParseNotification(header, body)
+	if notification == nil {
+		return err
 	}
 
-	transactionStatus, ok := webhookData["transaction_status"].(string)
-	if !ok {
-		return errors.New("missing transaction_status in webhook")
+	// Keep the body as sent; bodies that are not JSON are stored as a JSON string
+	payload := string(body)
+	if !json.Valid(body) {
+		quoted, _ := json.Marshal(payload)
+		payload = string(quoted)
 	}
 
-	payment, err := s.paymentRepo.GetByProviderPaymentID(providerPaymentID)
-	if err != nil {
-		return ErrPaymentNotFound
+	event := &model.PaymentEvent{
+		Provider:          provider,
+		TransactionID:     notification.TransactionID,
+		GatewayOrderID:    notification.OrderID,
+		TransactionStatus: notification.TransactionStatus,
+		GrossAmount:       notification.GrossAmount,
+		Payload:           payload,
+		Status:            model.PaymentEventReceived,
 	}
 
-	var newStatus model.PaymentStatus
-	switch transactionStatus {
-	case "capture", "settlement":
-		newStatus = model.PaymentPaid
-	case "pending":
-		newStatus = model.PaymentPending
-	case "deny", "expire", "cancel":
-		newStatus = model.PaymentFailed
-	default:
-		return errors.New("unknown transaction status")
+	if errors.Is(err, transaction.ErrInvalidSignature) {
+		securityLog(event).Warn("Payment webhook signature verification failed")
+		event.Status = model.PaymentEventRejected
+		event.Error = utils.PtrOrNil(ErrWebhookInvalidSignature.Error())
+		if err := s.eventRepo.Create(event); err != nil {
+			logrus.WithError(err).Error("Failed to store rejected payment event")
+		}
+		return ErrWebhookInvalidSignature
+	}
+	if err != nil {
+		return err
 	}
 
-	now := time.Now()
-	switch newStatus {
-	case model.PaymentPaid:
-		payment.PaidAt = &now
-		if err := s.bookingService.ConfirmPayment(payment.BookingID); err != nil {
+	created, err := s.eventRepo.CreateIfNew(event)
+	if err != nil {
+		return err
+	}
+	if !created {
+		existing, err := s.eventRepo.GetByKey(event.Provider, event.TransactionID, event.TransactionStatus)
+		if err != nil {
 			return err
 		}
-	case model.PaymentFailed:
-		if err := s.bookingService.FailPayment(payment.BookingID); err != nil {
-			return err
+		if !eventReplayable(existing) {
+			logrus.WithField("payment_event_id", existing.ID).Info("Duplicate payment notification ignored")
+			return nil
 		}
+		event = existing
 	}
 
-	payment.Status = newStatus
-	return s.paymentRepo.Update(payment)
+	return s.applyEvent(event)
+}
+
+// GetEvents lists stored payment notifications, optionally by status
+func (s *paymentService) GetEvents(requestorRole model.UserRole, status model.PaymentEventStatus, limit, offset int) ([]*model.PaymentEvent, int64, error) {
+	if !s.canManagePayments(requestorRole) {
+		return nil, 0, ErrPaymentInsufficientPermission
+	}
+
+	events, err := s.eventRepo.GetAll(status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := s.eventRepo.Count(status)
+	return events, count, err
+}
+
+// ReplayEvent applies a failed payment event again, or one left received by a
+// delivery that never finished. Its signature was verified when it was
+// received.
+func (s *paymentService) ReplayEvent(requestorRole model.UserRole, eventID uint) (*model.PaymentEvent, error) {
+	if !s.canManagePayments(requestorRole) {
+		return nil, ErrPaymentInsufficientPermission
+	}
+
+	event, err := s.eventRepo.GetByID(eventID)
+	if err != nil {
+		return nil, ErrPaymentEventNotFound
+	}
+
+	if !eventReplayable(event) {
+		return nil, ErrPaymentEventNotReplayable
+	}
+
+	if err := s.applyEvent(event); err != nil {
+		return event, err
+	}
+	return event, nil
+}
+
+// eventReplayable tells whether an event may be applied again: it failed, or it
+// has been received for longer than staleEventAfter without an outcome
+func eventReplayable(event *model.PaymentEvent) bool {
+	switch event.Status {
+	case model.PaymentEventFailed:
+		return true
+	case model.PaymentEventReceived:
+		return time.Since(event.UpdatedAt) > staleEventAfter
+	}
+	return false
+}
+
+// GetReconciliations lists the mismatches found by the reconciliation job
+func (s *paymentService) GetReconciliations(requestorRole model.UserRole, resolution model.ReconciliationResolution, limit, offset int) ([]*model.PaymentReconciliation, int64, error) {
+	if !s.canManagePayments(requestorRole) {
+		return nil, 0, ErrPaymentInsufficientPermission
+	}
+
+	reports, err := s.reconciliationRepo.GetAll(resolution, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := s.reconciliationRepo.Count(resolution)
+	return reports, count, err
+}
+
+// ReconcilePending asks the gateway about payments that have been pending for
+// longer than reconcileAfter, in case their notification was lost. A status
+// that differs from ours is applied the same way a webhook would apply it and
+// reported; a payment that stays mismatched keeps updating one open report
+// instead of adding a report every run. It returns the number of mismatches
+// found.
+func (s *paymentService) ReconcilePending() (int, error) {
+	payments, err := s.paymentRepo.GetStalePending(time.Now().Add(-s.reconcileAfter), reconcileBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	mismatches := 0
+	for _, payment := range payments {
+		logger := logrus.WithField("payment_id", payment.ID)
+
+		gateway, err := s.gateways.Get(string(payment.Provider))
+		if err != nil {
+			logger.WithError(err).Warn("Cannot reconcile payment")
+			continue
+		}
+
+		gatewayStatus, err := gateway.GetStatus(context.Background(), *payment.ProviderPaymentID)
+		if err != nil {
+			logger.WithError(err).Warn("Failed to check payment status with the gateway")
+			continue
+		}
+
+		newStatus := model.PaymentStatus(gateway.MapStatus(gatewayStatus))
+		if newStatus == payment.Status {
+			continue
+		}
+		mismatches++
+
+		// A mismatch found on an earlier run updates its open report
+		report, err := s.reconciliationRepo.GetOpenByPaymentID(payment.ID)
+		if err != nil {
+			report = &model.PaymentReconciliation{PaymentID: payment.ID}
+		}
+		reportID := report.ID
+		report.LocalStatus = payment.Status
+		report.GatewayStatus = gatewayStatus
+		report.Error = nil
+		report.Attempts++
+
+		if newStatus != model.PaymentPaid && newStatus != model.PaymentFailed && newStatus != model.PaymentReview {
+			report.Resolution = model.ReconciliationUnresolved
+			if err := saveReconciliation(s.reconciliationRepo, report); err != nil {
+				logger.WithError(err).Error("Failed to save reconciliation report")
+			}
+			continue
+		}
+
+		err = s.applyPaymentStatus(payment, newStatus, func(repos repository.Repositories, applied bool) error {
+			report.Resolution = model.ReconciliationSkipped
+			if applied {
+				report.Resolution = model.ReconciliationApplied
+			}
+			return saveReconciliation(repos.Reconciliation, report)
+		})
+		if err != nil {
+			logger.WithError(err).Warn("Failed to apply reconciled payment status")
+			report.ID = reportID
+			report.Resolution = model.ReconciliationFailed
+			report.Error = utils.PtrOrNil(err.Error())
+			if err := saveReconciliation(s.reconciliationRepo, report); err != nil {
+				logger.WithError(err).Error("Failed to save reconciliation report")
+			}
+		}
+	}
+
+	if mismatches > 0 {
+		logrus.WithField("count", mismatches).Info("Reconciled pending payments")
+	}
+	return mismatches, nil
+}
+
+// saveReconciliation stores a new report or updates an open one
+func saveReconciliation(repo repository.PaymentReconciliationRepository, report *model.PaymentReconciliation) error {
+	if report.ID == 0 {
+		return repo.Create(report)
+	}
+	return repo.Update(report)
+}
+
+// GetReviewQueue returns the payments waiting for an admin in one queue: those
+// fraud detection is holding, approved or denied with the gateway, or the
+// offline payments whose transfer receipt waits for verification. The fraud
+// queue is the default.
+func (s *paymentService) GetReviewQueue(requestorRole model.UserRole, queue model.ReviewQueue, limit, offset int) ([]*model.Payment, int64, error) {
+	if !s.canManagePayments(requestorRole) {
+		return nil, 0, ErrPaymentInsufficientPermission
+	}
+
+	switch queue {
+	case "":
+		queue = model.ReviewQueueFraud
+	case model.ReviewQueueFraud, model.ReviewQueueOffline:
+	default:
+		return nil, 0, ErrPaymentInvalidReviewQueue
+	}
+
+	payments, err := s.paymentRepo.GetReviewQueue(queue, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := s.paymentRepo.CountReviewQueue(queue)
+	return payments, count, err
+}
+
+// ReviewPayment approves or denies a payment held by fraud detection. The
+// decision is sent to the gateway first, then the payment is settled or failed
+// the way a notification would; the notification the gateway sends afterwards
+// finds it already decided.
+func (s *paymentService) ReviewPayment(adminID uint, requestorRole model.UserRole, paymentID uint, approve bool) (*model.Payment, error) {
+	if !s.canManagePayments(requestorRole) {
+		return nil, ErrPaymentInsufficientPermission
+	}
+
+	payment, err := s.paymentRepo.GetByID(paymentID)
+	if err != nil {
+		return nil, ErrPaymentNotFound
+	}
+	if payment.Provider == model.ProviderOffline {
+		return nil, ErrPaymentReviewUnsupported
+	}
+	if payment.Status != model.PaymentReview || payment.ProviderPaymentID == nil {
+		return nil, ErrPaymentNotUnderReview
+	}
+
+	gateway, err := s.gateways.Get(string(payment.Provider))
+	if err != nil {
+		return nil, err
+	}
+	reviewer, ok := gateway.(transaction.FraudReviewer)
+	if !ok {
+		return nil, ErrPaymentReviewUnsupported
+	}
+
+	newStatus, decide := model.PaymentFailed, reviewer.DenyTransaction
+	if approve {
+		newStatus, decide = model.PaymentPaid, reviewer.ApproveTransaction
+	}
+	if err := decide(context.Background(), *payment.ProviderPaymentID); err != nil {
+		return nil, fmt.Errorf("%s payment gateway error: %w", payment.Provider, err)
+	}
+
+	err = s.applyPaymentStatus(payment, newStatus, func(repos repository.Repositories, applied bool) error {
+		if !applied {
+			return nil
+		}
+		return repos.Payments.MarkReviewed(payment.ID, adminID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"payment_id": payment.ID,
+		"admin_id":   adminID,
+		"approved":   approve,
+	}).Info("Payment fraud review decided")
+
+	return s.paymentRepo.GetByID(paymentID)
 }
 
 func (s *paymentService) canManagePayments(role model.UserRole) bool {
 	return role == model.RoleAdmin || role == model.RoleSuperAdmin
 }
+
+// instructionsHTML renders how to pay a pending payment for the instruction
+// emails, empty when the provider returned nothing to act on
+func instructionsHTML(instructions *model.PaymentInstructions) string {
+	lines := instructionLines(instructions)
+	if len(lines) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("<h3>How to Pay:</h3><ul>")
+	for _, line := range lines {
+		if line.url != "" {
+			fmt.Fprintf(&b, `<li><a href="%s">%s</a></li>`, html.EscapeString(line.url), html.EscapeString(line.label))
+		} else {
+			fmt.Fprintf(&b, "<li><strong>%s:</strong> %s</li>", html.EscapeString(line.label), html.EscapeString(line.value))
+		}
+	}
+	b.WriteString("</ul>")
+	return b.String()
+}
+
+// instructionsText is the plain text counterpart of instructionsHTML
+func instructionsText(instructions *model.PaymentInstructions) string {
+	var parts []string
+	for _, line := range instructionLines(instructions) {
+		if line.url != "" {
+			parts = append(parts, line.label+": "+line.url)
+		} else {
+			parts = append(parts, line.label+": "+line.value)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return ". " + strings.Join(parts, ", ")
+}
+
+type instructionLine struct {
+	label, value, url string
+}
+
+func instructionLines(instructions *model.PaymentInstructions) []instructionLine {
+	if instructions == nil {
+		return nil
+	}
+
+	var lines []instructionLine
+	for _, va := range instructions.VANumbers {
+		lines = append(lines, instructionLine{label: strings.ToUpper(va.Bank) + " virtual account", value: va.Number})
+	}
+	if instructions.BillKey != "" {
+		lines = append(lines, instructionLine{label: "Mandiri bill payment", value: fmt.Sprintf("biller code %s, bill key %s", instructions.BillerCode, instructions.BillKey)})
+	}
+	if instructions.PaymentCode != "" {
+		lines = append(lines, instructionLine{label: "Payment code", value: instructions.PaymentCode})
+	}
+	for _, action := range instructions.Actions {
+		if action.Method == http.MethodGet {
+			lines = append(lines, instructionLine{label: strings.ReplaceAll(action.Name, "-", " "), url: action.URL})
+		}
+	}
+	if instructions.RedirectURL != "" {
+		lines = append(lines, instructionLine{label: "Continue to payment", url: instructions.RedirectURL})
+	}
+	if transfer := instructions.BankTransfer; transfer != nil {
+		if transfer.AccountNumber != "" {
+			lines = append(lines, instructionLine{label: "Bank transfer", value: fmt.Sprintf("%s %s (%s)", transfer.Bank, transfer.AccountNumber, transfer.AccountName)})
+		} else {
+			lines = append(lines, instructionLine{label: "Cash", value: "pay at the counter"})
+		}
+		lines = append(lines, instructionLine{label: "Reference", value: transfer.Reference})
+	}
+	if instructions.ExpiresAt != nil {
+		lines = append(lines, instructionLine{label: "Pay before", value: instructions.ExpiresAt.Format("2006-01-02 15:04 MST")})
+	}
+	return lines
+}
@@ -0,0 +1,584 @@
+package service
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+	"github.com/yoockh/go-game-rental-api/internal/repository/storage"
+	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
+)
+
+func sign(orderID, statusCode, grossAmount, serverKey string) string {
+	sum := sha512.Sum512([]byte(orderID + statusCode + grossAmount + serverKey))
+	return hex.EncodeToString(sum[:])
+}
+
+type webhookFixture struct {
+	svc      *paymentService
+	payments *fakePaymentRepo
+	events   *fakeEventRepo
+	reports  *fakeReconciliationRepo
+	bookings *MockBookingService
+	disputes *fakeDisputeRepo
+	gateway  *statusGateway
+}
+
+func newWebhookFixture(t *testing.T) *webhookFixture {
+	t.Setenv("MIDTRANS_SERVER_KEY", "server-key")
+	midtrans, err := transaction.NewMidtransRepository()
+	require.NoError(t, err)
+	gateway := &statusGateway{MidtransRepository: midtrans, status: "pending"}
+	gateways := transaction.NewRegistry()
+	gateways.Register(string(model.ProviderMidtrans), gateway)
+
+	bookingID := uint(3)
+	txID := "tx-3"
+	payments := &fakePaymentRepo{payment: &model.Payment{
+		ID:                1,
+		BookingID:         &bookingID,
+		Purpose:           model.PaymentPurposeBooking,
+		Amount:            model.NewMoney(150000),
+		Status:            model.PaymentPending,
+		Provider:          model.ProviderMidtrans,
+		ProviderPaymentID: &txID,
+	}}
+	events := &fakeEventRepo{}
+	reports := &fakeReconciliationRepo{}
+	bookings := &MockBookingService{}
+	bookings.On("ConfirmPayment", mock.Anything, bookingID).Return(nil)
+	bookings.On("FailPayment", mock.Anything, bookingID).Return(nil)
+	bookings.On("ChargeBack", mock.Anything, bookingID).Return(nil)
+	disputes := &fakeDisputeRepo{}
+	txManager := &fakeTxManager{repos: repository.Repositories{Payments: payments, PaymentEvents: events, Reconciliation: reports, Disputes: disputes}}
+
+	svc := NewPaymentService(txManager, payments, events, reports, nil, nil, nil, nil, nil, bookings, nil, nil,
+		newDisputeService(txManager, disputes, bookings, gateways), gateways, &email.MockEmailRepository{}, nil, model.BankTransfer{}, 30*time.Minute).(*paymentService)
+	return &webhookFixture{svc: svc, payments: payments, events: events, reports: reports, bookings: bookings, disputes: disputes, gateway: gateway}
+}
+
+func notification(status, grossAmount, signature string) []byte {
+	body, _ := json.Marshal(map[string]interface{}{
+		"order_id":           "booking-3",
+		"transaction_id":     "tx-3",
+		"transaction_status": status,
+		"status_code":        "200",
+		"gross_amount":       grossAmount,
+		"signature_key":      signature,
+	})
+	return body
+}
+
+func signed(status, grossAmount string) []byte {
+	return notification(status, grossAmount, sign("booking-3", "200", grossAmount, "server-key"))
+}
+
+// signedCapture is a signed card capture with the fraud detection outcome
+func signedCapture(fraudStatus, grossAmount string) []byte {
+	body, _ := json.Marshal(map[string]interface{}{
+		"order_id":           "booking-3",
+		"transaction_id":     "tx-3",
+		"transaction_status": "capture",
+		"fraud_status":       fraudStatus,
+		"status_code":        "200",
+		"gross_amount":       grossAmount,
+		"signature_key":      sign("booking-3", "200", grossAmount, "server-key"),
+	})
+	return body
+}
+
+// ============= TEST WEBHOOK VERIFICATION =============
+func TestProcessWebhook_RejectsForgedSignature(t *testing.T) {
+	f := newWebhookFixture(t)
+
+	err := f.svc.ProcessWebhook(model.ProviderMidtrans, nil, notification("settlement", "150000.00", ""))
+	assert.ErrorIs(t, err, ErrWebhookInvalidSignature)
+
+	err = f.svc.ProcessWebhook(model.ProviderMidtrans, nil, notification("settlement", "150000.00", sign("booking-3", "200", "150000.00", "guessed-key")))
+	assert.ErrorIs(t, err, ErrWebhookInvalidSignature)
+
+	assert.Zero(t, f.payments.statusChanges)
+	require.Len(t, f.events.events, 2)
+	assert.Equal(t, model.PaymentEventRejected, f.events.events[0].Status)
+}
+
+func TestProcessWebhook_RejectsAmountMismatch(t *testing.T) {
+	f := newWebhookFixture(t)
+
+	err := f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signed("settlement", "1000.00"))
+	assert.ErrorIs(t, err, ErrWebhookAmountMismatch)
+	assert.Zero(t, f.payments.statusChanges)
+	assert.Equal(t, model.PaymentEventRejected, f.events.events[0].Status)
+}
+
+// ============= TEST EVENT STORE =============
+func TestProcessWebhook_DuplicateNotificationAppliedOnce(t *testing.T) {
+	f := newWebhookFixture(t)
+
+	require.NoError(t, f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signed("settlement", "150000.00")))
+	require.NoError(t, f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signed("settlement", "150000.00")))
+
+	f.bookings.AssertNumberOfCalls(t, "ConfirmPayment", 1)
+	assert.Equal(t, 1, f.payments.statusChanges)
+	require.Len(t, f.events.events, 1)
+	assert.Equal(t, model.PaymentEventProcessed, f.events.events[0].Status)
+
+	// capture followed by settlement is a new event that finds the payment already paid
+	require.NoError(t, f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signed("capture", "150000.00")))
+	f.bookings.AssertNumberOfCalls(t, "ConfirmPayment", 1)
+	assert.Equal(t, model.PaymentEventIgnored, f.events.events[1].Status)
+}
+
+func TestReplayEvent_RetriesFailedEvent(t *testing.T) {
+	f := newWebhookFixture(t)
+	f.bookings.On("ConfirmPayment", mock.Anything, uint(3)).Unset()
+	confirm := f.bookings.On("ConfirmPayment", mock.Anything, uint(3)).Return(errors.New("booking is not in pending status"))
+
+	err := f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signed("settlement", "150000.00"))
+	assert.Error(t, err)
+	event := f.events.events[0]
+	assert.Equal(t, model.PaymentEventFailed, event.Status)
+	assert.NotNil(t, event.Error)
+
+	_, err = f.svc.ReplayEvent(model.RoleCustomer, event.ID)
+	assert.ErrorIs(t, err, ErrPaymentInsufficientPermission)
+
+	// the fake transaction cannot roll back, so put the payment back the way a rollback would
+	f.payments.payment.Status = model.PaymentPending
+	confirm.Return(nil)
+
+	replayed, err := f.svc.ReplayEvent(model.RoleAdmin, event.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentEventProcessed, replayed.Status)
+	assert.Equal(t, 2, replayed.Attempts)
+	f.bookings.AssertNumberOfCalls(t, "ConfirmPayment", 2) // the failed attempt, then the replay
+
+	_, err = f.svc.ReplayEvent(model.RoleAdmin, event.ID)
+	assert.ErrorIs(t, err, ErrPaymentEventNotReplayable)
+}
+
+func TestProcessWebhook_StaleReceivedEventAppliedAgain(t *testing.T) {
+	f := newWebhookFixture(t)
+	// a delivery stored the event and crashed before applying it
+	stuck := &model.PaymentEvent{Provider: model.ProviderMidtrans, TransactionID: "tx-3", GatewayOrderID: "booking-3",
+		TransactionStatus: "settlement", GrossAmount: "150000.00", Status: model.PaymentEventReceived, UpdatedAt: time.Now()}
+	require.NoError(t, f.events.Create(stuck))
+
+	_, err := f.svc.ReplayEvent(model.RoleAdmin, stuck.ID)
+	assert.ErrorIs(t, err, ErrPaymentEventNotReplayable, "it may still be being applied")
+	require.NoError(t, f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signed("settlement", "150000.00")))
+	f.bookings.AssertNotCalled(t, "ConfirmPayment", mock.Anything, uint(3))
+
+	stuck.UpdatedAt = time.Now().Add(-staleEventAfter - time.Minute)
+	require.NoError(t, f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signed("settlement", "150000.00")))
+	f.bookings.AssertNumberOfCalls(t, "ConfirmPayment", 1)
+	assert.Equal(t, model.PaymentEventProcessed, stuck.Status)
+	require.Len(t, f.events.events, 1)
+}
+
+func TestReplayEvent_StaleReceivedEvent(t *testing.T) {
+	f := newWebhookFixture(t)
+	stuck := &model.PaymentEvent{Provider: model.ProviderMidtrans, TransactionID: "tx-3", GatewayOrderID: "booking-3",
+		TransactionStatus: "settlement", GrossAmount: "150000.00", Status: model.PaymentEventReceived,
+		UpdatedAt: time.Now().Add(-time.Hour)}
+	require.NoError(t, f.events.Create(stuck))
+
+	replayed, err := f.svc.ReplayEvent(model.RoleAdmin, stuck.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentEventProcessed, replayed.Status)
+	assert.Equal(t, model.PaymentPaid, f.payments.payment.Status)
+}
+
+// ============= TEST LATE PAYMENTS =============
+func TestProcessWebhook_RefundsPaymentAfterItWasFailed(t *testing.T) {
+	f := newWebhookFixture(t)
+	f.payments.payment.Status = model.PaymentFailed // the booking expired
+
+	require.NoError(t, f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signed("settlement", "150000.00")))
+	f.bookings.AssertNotCalled(t, "ConfirmPayment", mock.Anything, uint(3))
+	assert.Equal(t, []string{"late-payment-1"}, f.gateway.refunds)
+	assert.Equal(t, model.PaymentEventProcessed, f.events.events[0].Status)
+	require.Len(t, f.reports.reports, 1)
+	assert.Equal(t, model.ReconciliationRefunded, f.reports.reports[0].Resolution)
+	assert.Equal(t, model.PaymentFailed, f.reports.reports[0].LocalStatus)
+
+	require.NoError(t, f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signed("settlement", "150000.00")))
+	assert.Len(t, f.gateway.refunds, 1, "a redelivery is not refunded twice")
+}
+
+func TestProcessWebhook_LatePaymentRefundRetriedOnReplay(t *testing.T) {
+	f := newWebhookFixture(t)
+	f.payments.payment.Status = model.PaymentFailed
+	f.gateway.refundErr = errors.New("gateway unavailable")
+
+	err := f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signed("settlement", "150000.00"))
+	assert.Error(t, err)
+	event := f.events.events[0]
+	assert.Equal(t, model.PaymentEventFailed, event.Status)
+	require.Len(t, f.reports.reports, 1)
+	assert.Equal(t, model.ReconciliationFailed, f.reports.reports[0].Resolution)
+
+	f.gateway.refundErr = nil
+	_, err = f.svc.ReplayEvent(model.RoleAdmin, event.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"late-payment-1"}, f.gateway.refunds)
+	require.Len(t, f.reports.reports, 1, "the open report is updated")
+	assert.Equal(t, model.ReconciliationRefunded, f.reports.reports[0].Resolution)
+	assert.Equal(t, 2, f.reports.reports[0].Attempts)
+}
+
+// ============= TEST RECONCILIATION =============
+func TestReconcilePending_AppliesLostSettlement(t *testing.T) {
+	f := newWebhookFixture(t)
+
+	mismatches, err := f.svc.ReconcilePending()
+	require.NoError(t, err)
+	assert.Zero(t, mismatches)
+	assert.Empty(t, f.reports.reports)
+
+	f.gateway.status = "settlement"
+	mismatches, err = f.svc.ReconcilePending()
+	require.NoError(t, err)
+	assert.Equal(t, 1, mismatches)
+	f.bookings.AssertNumberOfCalls(t, "ConfirmPayment", 1)
+	assert.Equal(t, model.PaymentPaid, f.payments.payment.Status)
+	require.Len(t, f.reports.reports, 1)
+	assert.Equal(t, model.ReconciliationApplied, f.reports.reports[0].Resolution)
+	assert.Equal(t, model.PaymentPending, f.reports.reports[0].LocalStatus)
+
+	// A late webhook for the same settlement finds the payment already paid
+	require.NoError(t, f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signed("settlement", "150000.00")))
+	f.bookings.AssertNumberOfCalls(t, "ConfirmPayment", 1)
+}
+
+func TestReconcilePending_ReportsUnknownGatewayStatus(t *testing.T) {
+	f := newWebhookFixture(t)
+	f.gateway.status = "authorize"
+
+	mismatches, err := f.svc.ReconcilePending()
+	require.NoError(t, err)
+	assert.Equal(t, 1, mismatches)
+	assert.Zero(t, f.payments.statusChanges)
+	require.Len(t, f.reports.reports, 1)
+	assert.Equal(t, model.ReconciliationUnresolved, f.reports.reports[0].Resolution)
+
+	// the next run updates the open report instead of adding another
+	_, err = f.svc.ReconcilePending()
+	require.NoError(t, err)
+	require.Len(t, f.reports.reports, 1)
+	assert.Equal(t, 2, f.reports.reports[0].Attempts)
+
+	f.gateway.status = "settlement"
+	_, err = f.svc.ReconcilePending()
+	require.NoError(t, err)
+	require.Len(t, f.reports.reports, 1)
+	assert.Equal(t, model.ReconciliationApplied, f.reports.reports[0].Resolution)
+}
+
+// ============= TEST FRAUD REVIEW =============
+func TestProcessWebhook_ChallengedCaptureHeldForReview(t *testing.T) {
+	f := newWebhookFixture(t)
+
+	require.NoError(t, f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signedCapture("challenge", "150000.00")))
+	assert.Equal(t, model.PaymentReview, f.payments.payment.Status)
+	// a challenged capture does not confirm the booking
+	f.bookings.AssertNotCalled(t, "ConfirmPayment", mock.Anything, mock.Anything)
+
+	// Approving in the Midtrans dashboard sends the capture again, accepted
+	require.NoError(t, f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signedCapture("accept", "150000.00")))
+	assert.Equal(t, model.PaymentPaid, f.payments.payment.Status)
+	f.bookings.AssertNumberOfCalls(t, "ConfirmPayment", 1)
+	require.Len(t, f.events.events, 2)
+}
+
+func TestReconcilePending_HoldsChallengedCapture(t *testing.T) {
+	f := newWebhookFixture(t)
+	f.gateway.status = "challenge"
+
+	mismatches, err := f.svc.ReconcilePending()
+	require.NoError(t, err)
+	assert.Equal(t, 1, mismatches)
+	assert.Equal(t, model.PaymentReview, f.payments.payment.Status)
+	f.bookings.AssertNotCalled(t, "ConfirmPayment", mock.Anything, mock.Anything)
+}
+
+func TestReviewPayment(t *testing.T) {
+	f := newWebhookFixture(t)
+
+	_, err := f.svc.ReviewPayment(9, model.RoleAdmin, 1, true)
+	assert.ErrorIs(t, err, ErrPaymentNotUnderReview)
+
+	require.NoError(t, f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signedCapture("challenge", "150000.00")))
+
+	_, err = f.svc.ReviewPayment(9, model.RoleCustomer, 1, true)
+	assert.ErrorIs(t, err, ErrPaymentInsufficientPermission)
+
+	payment, err := f.svc.ReviewPayment(9, model.RoleAdmin, 1, true)
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentPaid, payment.Status)
+	require.NotNil(t, payment.ReviewedBy)
+	assert.Equal(t, uint(9), *payment.ReviewedBy)
+	assert.Equal(t, []bool{true}, f.gateway.decisions)
+	f.bookings.AssertNumberOfCalls(t, "ConfirmPayment", 1)
+
+	// The accepted capture Midtrans sends afterwards finds it already paid
+	require.NoError(t, f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signedCapture("accept", "150000.00")))
+	f.bookings.AssertNumberOfCalls(t, "ConfirmPayment", 1)
+}
+
+func TestReviewPayment_DenyFailsPayment(t *testing.T) {
+	f := newWebhookFixture(t)
+	require.NoError(t, f.svc.ProcessWebhook(model.ProviderMidtrans, nil, signedCapture("challenge", "150000.00")))
+
+	payment, err := f.svc.ReviewPayment(9, model.RoleAdmin, 1, false)
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentFailed, payment.Status)
+	assert.Equal(t, []bool{false}, f.gateway.decisions)
+	f.bookings.AssertNotCalled(t, "ConfirmPayment", mock.Anything, mock.Anything)
+	f.bookings.AssertNumberOfCalls(t, "FailPayment", 1)
+}
+
+// ============= TEST OFFLINE PAYMENTS =============
+
+// newOfflineFixture is the webhook fixture with the booking paid offline
+func newOfflineFixture(t *testing.T) (*webhookFixture, *storage.MockStorageRepository) {
+	f := newWebhookFixture(t)
+	f.payments.payment.Provider = model.ProviderOffline
+	f.payments.payment.ProviderPaymentID = nil
+	files := &storage.MockStorageRepository{}
+	f.svc.storageRepo = files
+	f.svc.emailRepo = &email.MockEmailRepository{}
+	return f, files
+}
+
+var pngReceipt = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestUploadPaymentProof_HoldsPaymentForVerification(t *testing.T) {
+	f, files := newOfflineFixture(t)
+
+	_, err := f.svc.UploadPaymentProof(6, 1, "receipt.png", pngReceipt)
+	assert.ErrorIs(t, err, ErrPaymentNotOwned)
+
+	_, err = f.svc.UploadPaymentProof(5, 1, "receipt.png", []byte("not a receipt"))
+	assert.ErrorIs(t, err, ErrPaymentProofInvalid)
+	assert.Empty(t, files.UploadedFiles)
+
+	payment, err := f.svc.UploadPaymentProof(5, 1, "receipt.png", pngReceipt)
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentReview, payment.Status)
+	require.NotNil(t, payment.ProofPath)
+	require.Len(t, files.UploadedFiles, 1)
+	assert.Equal(t, "image/png", files.UploadedFiles[0].ContentType)
+	f.bookings.AssertNotCalled(t, "ConfirmPayment", mock.Anything, mock.Anything)
+
+	// A second receipt replaces the first while it waits for verification
+	payment, err = f.svc.UploadPaymentProof(5, 1, "receipt-2.png", pngReceipt)
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentReview, payment.Status)
+	assert.Len(t, files.UploadedFiles, 2)
+	assert.Equal(t, files.UploadedFiles[1].Path, *payment.ProofPath)
+}
+
+func TestGetPaymentProofURL_SignsReceiptForAdmins(t *testing.T) {
+	f, files := newOfflineFixture(t)
+
+	_, _, err := f.svc.GetPaymentProofURL(model.RoleAdmin, 1)
+	assert.ErrorIs(t, err, ErrPaymentProofNotFound)
+
+	_, err = f.svc.UploadPaymentProof(5, 1, "receipt.png", pngReceipt)
+	require.NoError(t, err)
+
+	_, _, err = f.svc.GetPaymentProofURL(model.RoleCustomer, 1)
+	assert.ErrorIs(t, err, ErrPaymentInsufficientPermission)
+
+	url, expiresAt, err := f.svc.GetPaymentProofURL(model.RoleAdmin, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "https://mock-storage.com/"+files.UploadedFiles[0].Path+"?token=mock", url)
+	assert.WithinDuration(t, time.Now().Add(paymentProofLinkTTL), expiresAt, time.Minute)
+}
+
+func TestGetReviewQueue_KeepsOfflineVerificationApart(t *testing.T) {
+	f, _ := newOfflineFixture(t)
+	_, err := f.svc.UploadPaymentProof(5, 1, "receipt.png", pngReceipt)
+	require.NoError(t, err)
+
+	payments, total, err := f.svc.GetReviewQueue(model.RoleAdmin, "", 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, payments, "the fraud queue is the default")
+	assert.Zero(t, total)
+
+	payments, total, err = f.svc.GetReviewQueue(model.RoleAdmin, model.ReviewQueueOffline, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Equal(t, model.ProviderOffline, payments[0].Provider)
+	assert.Equal(t, int64(1), total)
+
+	_, _, err = f.svc.GetReviewQueue(model.RoleAdmin, "everything", 10, 0)
+	assert.ErrorIs(t, err, ErrPaymentInvalidReviewQueue)
+}
+
+func TestVerifyOfflinePayment_ApproveConfirmsBooking(t *testing.T) {
+	f, _ := newOfflineFixture(t)
+	_, err := f.svc.UploadPaymentProof(5, 1, "receipt.png", pngReceipt)
+	require.NoError(t, err)
+
+	_, err = f.svc.VerifyOfflinePayment(9, model.RoleCustomer, 1, true, "")
+	assert.ErrorIs(t, err, ErrPaymentInsufficientPermission)
+
+	payment, err := f.svc.VerifyOfflinePayment(9, model.RoleAdmin, 1, true, "")
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentPaid, payment.Status)
+	require.NotNil(t, payment.ReviewedBy)
+	assert.Equal(t, uint(9), *payment.ReviewedBy)
+	f.bookings.AssertNumberOfCalls(t, "ConfirmPayment", 1)
+
+	_, err = f.svc.VerifyOfflinePayment(9, model.RoleAdmin, 1, false, "")
+	assert.ErrorIs(t, err, ErrPaymentNotAwaitingVerify)
+	f.bookings.AssertNotCalled(t, "FailPayment", mock.Anything, mock.Anything)
+
+	_, err = f.svc.UploadPaymentProof(5, 1, "receipt.png", pngReceipt)
+	assert.ErrorIs(t, err, ErrPaymentProofClosed)
+}
+
+func TestVerifyOfflinePayment_RejectFailsPayment(t *testing.T) {
+	f, _ := newOfflineFixture(t)
+
+	// Cash at the counter is verified without a receipt
+	payment, err := f.svc.VerifyOfflinePayment(9, model.RoleAdmin, 1, false, "amount does not match")
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentFailed, payment.Status)
+	require.NotNil(t, payment.FailureReason)
+	assert.Equal(t, "amount does not match", *payment.FailureReason)
+	f.bookings.AssertNumberOfCalls(t, "FailPayment", 1)
+	f.bookings.AssertNotCalled(t, "ConfirmPayment", mock.Anything, mock.Anything)
+}
+
+func TestVerifyOfflinePayment_RequiresOfflinePayment(t *testing.T) {
+	f := newWebhookFixture(t)
+
+	_, err := f.svc.VerifyOfflinePayment(9, model.RoleAdmin, 1, true, "")
+	assert.ErrorIs(t, err, ErrPaymentNotOffline)
+}
+
+func TestBookingChargeItems(t *testing.T) {
+	booking := &model.Booking{
+		ID:               3,
+		Game:             model.Game{Name: "Zelda"},
+		RentalDays:       3,
+		DailyPrice:       model.NewMoney(50000),
+		TotalRentalPrice: model.NewMoney(150000),
+		SecurityDeposit:  model.NewMoney(100000),
+		DiscountAmount:   model.NewMoney(15000),
+		TotalAmount:      model.NewMoney(235000),
+	}
+
+	items := bookingChargeItems(booking)
+	require.Len(t, items, 3)
+	total := model.Money{}
+	for _, item := range items {
+		total = total.Add(item.Price.Mul(int64(item.Quantity)))
+	}
+	assert.True(t, total.Equal(booking.TotalAmount), "items add up to the amount charged")
+	assert.Equal(t, 3, items[0].Quantity)
+	assert.True(t, items[2].Price.IsNegative())
+
+	assert.Nil(t, chargeCustomer(&model.User{}))
+	phone := "0812"
+	customer := chargeCustomer(&model.User{ID: 1, FullName: "Budi", Email: "budi@example.com", Phone: &phone})
+	assert.Equal(t, "0812", customer.Phone)
+}
+
+func TestBookingChargeItems_ExclusiveTax(t *testing.T) {
+	booking := &model.Booking{
+		ID:               3,
+		Game:             model.Game{Name: "Zelda"},
+		RentalDays:       3,
+		DailyPrice:       model.NewMoney(50000),
+		TotalRentalPrice: model.NewMoney(150000),
+		TotalAmount:      model.NewMoney(150000),
+	}
+	applyTax(booking, model.TaxRule{Rate: 11})
+
+	items := bookingChargeItems(booking)
+	require.Len(t, items, 2)
+	assert.Equal(t, "VAT 11%", items[1].Name)
+	assert.True(t, items[0].Price.Mul(3).Add(items[1].Price).Equal(booking.TotalAmount), "items add up to the amount charged")
+
+	// Inclusive tax is already in the rental price
+	booking.TaxInclusive = true
+	assert.Len(t, bookingChargeItems(booking), 1)
+}
+
+// ============= TEST PAYMENT ATTEMPTS =============
+func TestCheckNewAttempt(t *testing.T) {
+	open := time.Now().Add(time.Hour)
+	closed := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		current *model.Payment
+		dueAt   *time.Time
+		want    error
+	}{
+		{"first attempt", nil, &open, nil},
+		{"retry after failure", &model.Payment{Status: model.PaymentFailed}, &open, nil},
+		{"attempt still pending", &model.Payment{Status: model.PaymentPending}, &open, ErrPaymentAttemptPending},
+		{"attempt under review", &model.Payment{Status: model.PaymentReview}, &closed, ErrPaymentUnderReview},
+		{"already paid", &model.Payment{Status: model.PaymentPaid}, &open, ErrPaymentAlreadyPaid},
+		{"refunded", &model.Payment{Status: model.PaymentRefunded}, &open, ErrPaymentAlreadyPaid},
+		{"window passed", &model.Payment{Status: model.PaymentFailed}, &closed, ErrPaymentWindowClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, checkNewAttempt(tt.current, tt.dueAt))
+		})
+	}
+}
+
+func TestCreatePayment_RetryGetsItsOwnGatewayOrderID(t *testing.T) {
+	dueAt := time.Now().Add(time.Hour)
+	bookings := &fakeBookingStore{bookings: []*model.Booking{{
+		ID: 1, UserID: 5, GameID: 1, Status: model.BookingPending, PaymentDueAt: &dueAt,
+		TotalAmount: model.NewMoney(150000), TotalRentalPrice: model.NewMoney(150000), RentalDays: 1, DailyPrice: model.NewMoney(150000),
+	}}}
+	payments := &fakePaymentRepo{}
+	gateway := &transaction.MockTransactionRepository{}
+	gateways := transaction.NewRegistry()
+	gateways.Register(string(model.ProviderMidtrans), gateway)
+	txManager := &fakeTxManager{repos: repository.Repositories{Payments: payments}}
+	svc := NewPaymentService(txManager, payments, nil, nil, bookings, nil, &fakeUserRepo{}, &fakeGameRepo{}, nil, nil, nil, nil, nil,
+		gateways, &email.MockEmailRepository{}, nil, model.BankTransfer{}, 30*time.Minute).(*paymentService)
+
+	first, err := svc.CreatePayment(5, 1, model.ProviderMidtrans, "", false)
+	require.NoError(t, err)
+	require.Len(t, gateway.Charges, 1)
+	assert.Equal(t, "booking-1-1", gateway.Charges[0].OrderID)
+
+	// The attempt expires at the gateway; the customer tries again
+	first.Status = model.PaymentFailed
+	bookings.bookings[0].Payment = first
+
+	second, err := svc.CreatePayment(5, 1, model.ProviderMidtrans, "", false)
+	require.NoError(t, err)
+	require.Len(t, gateway.Charges, 2)
+	assert.NotEqual(t, gateway.Charges[0].OrderID, gateway.Charges[1].OrderID)
+	assert.Equal(t, "booking-1-2", gateway.Charges[1].OrderID)
+
+	// So does the reference of a transfer made after that
+	second.Status = model.PaymentFailed
+	bookings.bookings[0].Payment = second
+
+	offline, err := svc.CreatePayment(5, 1, model.ProviderOffline, "", false)
+	require.NoError(t, err)
+	require.NotNil(t, offline.Instructions)
+	assert.Equal(t, "booking-1-3", offline.Instructions.BankTransfer.Reference)
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
+	"github.com/yoockh/go-game-rental-api/internal/utils"
+)
+
+// applyEvent applies a stored notification to its payment and records the
+// outcome on the event. The payment, what it pays for and the event change in
+// one transaction, so a failure leaves all of them as they were and the event
+// can be replayed.
+func (s *paymentService) applyEvent(event *model.PaymentEvent) error {
+	event.Attempts++
+
+	payment, err := s.paymentRepo.GetByProviderPaymentID(event.TransactionID)
+	if err != nil {
+		// Payments store the provider transaction ID; older rows may hold the order ID
+		payment, err = s.paymentRepo.GetByProviderPaymentID(event.GatewayOrderID)
+		if err != nil {
+			return s.finishEvent(event, model.PaymentEventFailed, ErrPaymentNotFound)
+		}
+	}
+	event.PaymentID = &payment.ID
+
+	gateway, err := s.gateways.Get(string(event.Provider))
+	if err != nil {
+		return s.finishEvent(event, model.PaymentEventFailed, err)
+	}
+	mapped := gateway.MapStatus(event.TransactionStatus)
+	disputeStatus, isDispute := strings.CutPrefix(mapped, transaction.DisputeStatusPrefix)
+
+	// The provider only charged what the wallet did not cover, and a dispute
+	// can take back at most that
+	gross, err := model.ParseMoney(event.GrossAmount)
+	amountValid := err == nil && gross.Equal(payment.GatewayAmount())
+	if isDispute {
+		amountValid = err == nil && gross.IsPositive() && !gross.GreaterThan(payment.GatewayAmount())
+	}
+	if !amountValid {
+		securityLog(event).WithFields(logrus.Fields{
+			"payment_id":     payment.ID,
+			"payment_amount": payment.Amount.String(),
+		}).Warn("Payment webhook amount does not match the payment")
+		return s.finishEvent(event, model.PaymentEventRejected, ErrWebhookAmountMismatch)
+	}
+
+	if isDispute {
+		return s.applyDispute(event, payment, gateway, model.DisputeStatus(disputeStatus), gross)
+	}
+
+	newStatus := model.PaymentStatus(mapped)
+	switch newStatus {
+	case model.PaymentPaid, model.PaymentPending, model.PaymentFailed, model.PaymentReview:
+	case model.PaymentRefunded:
+		// Refunds are recorded by RefundService when they are requested
+		return s.finishEvent(event, model.PaymentEventIgnored, nil)
+	default:
+		return s.finishEvent(event, model.PaymentEventFailed, errors.New("unknown transaction status"))
+	}
+
+	err = s.applyPaymentStatus(payment, newStatus, func(repos repository.Repositories, applied bool) error {
+		event.Status = model.PaymentEventIgnored
+		if applied {
+			event.Status = model.PaymentEventProcessed
+		}
+		now := time.Now()
+		event.ProcessedAt = &now
+		event.Error = nil
+		return repos.PaymentEvents.Update(event)
+	})
+	if err != nil {
+		return s.finishEvent(event, model.PaymentEventFailed, err)
+	}
+
+	if newStatus == model.PaymentPaid && event.Status == model.PaymentEventIgnored {
+		return s.refundLatePayment(event, payment, gateway)
+	}
+	return nil
+}
+
+// refundLatePayment refunds a payment the provider took after we had failed
+// the attempt, e.g. when the booking expired or was cancelled and its charge
+// could not be cancelled in time. Nothing is held for such a payment any more,
+// so the customer gets it back. The outcome is recorded on the payment's
+// reconciliation report; when the provider rejects the refund the event fails,
+// and replaying it tries again with the same refund key.
+func (s *paymentService) refundLatePayment(event *model.PaymentEvent, payment *model.Payment, gateway transaction.TransactionRepository) error {
+	current, err := s.paymentRepo.GetByIDWithRelations(payment.ID)
+	if err != nil {
+		return s.finishEvent(event, model.PaymentEventFailed, err)
+	}
+	if current.Status != model.PaymentFailed {
+		// Paid already, the notification was a repeat
+		return nil
+	}
+
+	report, err := s.reconciliationRepo.GetOpenByPaymentID(current.ID)
+	if err != nil {
+		report = &model.PaymentReconciliation{PaymentID: current.ID}
+	}
+	report.LocalStatus = current.Status
+	report.GatewayStatus = event.TransactionStatus
+	report.Error = nil
+	report.Attempts++
+
+	amount := current.GatewayAmount()
+	refundKey := fmt.Sprintf("late-payment-%d", current.ID)
+	_, refundErr := gateway.Refund(context.Background(), *current.ProviderPaymentID, refundKey, amount, "paid after the payment attempt was closed")
+
+	report.Resolution = model.ReconciliationRefunded
+	if refundErr != nil {
+		report.Resolution = model.ReconciliationFailed
+		report.Error = utils.PtrOrNil(refundErr.Error())
+	}
+	if err := saveReconciliation(s.reconciliationRepo, report); err != nil {
+		logrus.WithError(err).WithField("payment_id", current.ID).Error("Failed to save reconciliation report")
+	}
+
+	logger := logrus.WithFields(logrus.Fields{
+		"payment_id": current.ID,
+		"amount":     amount.String(),
+	})
+	if refundErr != nil {
+		logger.WithError(refundErr).Error("Failed to refund payment received after it was failed")
+		return s.finishEvent(event, model.PaymentEventFailed, refundErr)
+	}
+	logger.Warn("Refunded payment received after it was failed")
+
+	// SEND EMAIL: Late payment refunded
+	if user := paymentCustomer(current); user != nil {
+		go func() {
+			subject := "Payment Refunded - Game Rental"
+			htmlContent := fmt.Sprintf(`
+				<h1>Payment Refunded</h1>
+				<p>Hi %s,</p>
+				<p>Your payment of <strong>%s</strong> arrived after the payment window had closed, so nothing was reserved for it any more.</p>
+				<p>We have refunded it in full to your original payment method. It may take a few business days to show up on your statement.</p>
+			`, user.FullName, amount.Display())
+
+			plainText := fmt.Sprintf("Your payment of %s arrived after the payment window had closed and has been refunded.", amount.Display())
+
+			if err := s.emailRepo.SendEmail(context.Background(), user.Email, subject, plainText, htmlContent); err != nil {
+				logrus.WithError(err).Error("Failed to send late payment refund email")
+			}
+		}()
+	}
+
+	return s.finishEvent(event, model.PaymentEventProcessed, nil)
+}
+
+// applyDispute records a chargeback notification through DisputeService and
+// the event outcome in one transaction. Gateways that cannot describe the
+// dispute report it by transaction, with their status as the reason.
+func (s *paymentService) applyDispute(event *model.PaymentEvent, payment *model.Payment, gateway transaction.TransactionRepository, status model.DisputeStatus, amount model.Money) error {
+	report := &model.PaymentDispute{
+		Provider:          event.Provider,
+		ProviderDisputeID: event.TransactionID,
+		Status:            status,
+		Reason:            utils.PtrOrNil(event.TransactionStatus),
+		Amount:            amount,
+	}
+	if parser, ok := gateway.(transaction.DisputeParser); ok {
+		dispute, err := parser.ParseDispute([]byte(event.Payload))
+		if err != nil {
+			return s.finishEvent(event, model.PaymentEventFailed, err)
+		}
+		report.ProviderDisputeID = dispute.ID
+		report.Reason = utils.PtrOrNil(dispute.Reason)
+		report.EvidenceDueBy = dispute.EvidenceDueBy
+	}
+
+	var after AfterCommit
+	err := s.txManager.WithTransaction(func(repos repository.Repositories) error {
+		applied, recorded, err := s.disputeService.RecordDispute(repos, payment, report)
+		if err != nil {
+			return err
+		}
+		after = recorded
+
+		event.Status = model.PaymentEventIgnored
+		if applied {
+			event.Status = model.PaymentEventProcessed
+		}
+		now := time.Now()
+		event.ProcessedAt = &now
+		event.Error = nil
+		return repos.PaymentEvents.Update(event)
+	})
+	if err != nil {
+		return s.finishEvent(event, model.PaymentEventFailed, err)
+	}
+
+	after.run()
+	return nil
+}
+
+// finishEvent stores an event outcome reached outside the apply transaction and
+// returns cause
+func (s *paymentService) finishEvent(event *model.PaymentEvent, status model.PaymentEventStatus, cause error) error {
+	event.Status = status
+	event.Error = nil
+	if cause != nil {
+		event.Error = utils.PtrOrNil(cause.Error())
+	}
+	if status != model.PaymentEventFailed {
+		now := time.Now()
+		event.ProcessedAt = &now
+	}
+	if err := s.eventRepo.Update(event); err != nil {
+		logrus.WithError(err).WithField("payment_event_id", event.ID).Error("Failed to save payment event outcome")
+	}
+	return cause
+}
+
+// applyPaymentStatus moves a pending or held payment to newStatus and settles
+// what it pays for, in one transaction together with record. applied tells
+// record whether anything changed; a payment that has already been decided is
+// not touched again. Side effects run once the transaction has committed.
+func (s *paymentService) applyPaymentStatus(payment *model.Payment, newStatus model.PaymentStatus, record func(repos repository.Repositories, applied bool) error) error {
+	var after AfterCommit
+	applied := false
+	err := s.txManager.WithTransaction(func(repos repository.Repositories) error {
+		if paymentTransitionAllowed(payment.Status, newStatus) {
+			updated, err := repos.Payments.UpdateStatusFrom(payment.ID, payment.Status, newStatus)
+			if err != nil {
+				return err
+			}
+			if updated {
+				applied = true
+				if after, err = s.settlePayment(repos, payment, newStatus); err != nil {
+					return err
+				}
+			}
+		}
+		return record(repos, applied)
+	})
+	if err != nil {
+		return err
+	}
+
+	if applied {
+		payment.Status = newStatus
+		if newStatus == model.PaymentReview && payment.Provider != model.ProviderOffline {
+			logrus.WithFields(logrus.Fields{
+				"payment_id": payment.ID,
+				"provider":   payment.Provider,
+			}).Warn("Payment held by fraud detection, waiting in the admin review queue")
+		}
+	}
+	after.run()
+	return nil
+}
+
+// paymentTransitionAllowed tells whether a gateway outcome moves a payment on.
+// Pending payments are paid, failed or held for review; held payments can only
+// be paid or failed. A failed payment is never paid again: a late payment is
+// refunded by refundLatePayment instead.
+func paymentTransitionAllowed(from, to model.PaymentStatus) bool {
+	switch from {
+	case model.PaymentPending:
+		return to == model.PaymentPaid || to == model.PaymentFailed || to == model.PaymentReview
+	case model.PaymentReview:
+		return to == model.PaymentPaid || to == model.PaymentFailed
+	}
+	return false
+}
+
+// settlePayment applies a payment outcome to what the payment pays for. A
+// payment held for review settles nothing until it is decided; a failed one
+// gives its wallet part back.
+func (s *paymentService) settlePayment(repos repository.Repositories, payment *model.Payment, newStatus model.PaymentStatus) (AfterCommit, error) {
+	if newStatus == model.PaymentFailed {
+		if err := releaseWalletPayment(repos, payment); err != nil {
+			return nil, err
+		}
+	}
+
+	// Supplemental payments settle their date change, never the booking itself
+	if payment.Purpose == model.PaymentPurposeDateChange {
+		switch newStatus {
+		case model.PaymentPaid:
+			return s.bookingChangeService.ApplyPaidChange(repos, payment.ID)
+		case model.PaymentFailed:
+			return s.bookingChangeService.FailChange(repos, payment.ID)
+		}
+		return nil, nil
+	}
+
+	// Order payments settle every line item of the order together
+	if payment.Purpose == model.PaymentPurposeOrder {
+		switch newStatus {
+		case model.PaymentPaid:
+			return s.orderService.ConfirmPayment(repos, *payment.OrderID)
+		case model.PaymentFailed:
+			return s.orderService.FailPayment(repos, *payment.OrderID)
+		}
+		return nil, nil
+	}
+
+	switch newStatus {
+	case model.PaymentPaid:
+		return s.bookingService.ConfirmPayment(repos, *payment.BookingID)
+	case model.PaymentFailed:
+		return s.bookingService.FailPayment(repos, *payment.BookingID)
+	}
+	return nil, nil
+}
+
+// cancelGatewayCharge cancels the provider charge of a payment attempt we
+// failed, so the customer can no longer pay it. It is called once the failure
+// has committed; a charge that cannot be cancelled is only logged, since a
+// payment that arrives anyway is refunded.
+func cancelGatewayCharge(gateways *transaction.Registry, payment *model.Payment) {
+	if payment == nil || payment.ProviderPaymentID == nil {
+		return
+	}
+	if payment.Provider == model.ProviderWallet || payment.Provider == model.ProviderOffline {
+		return
+	}
+
+	logger := logrus.WithField("payment_id", payment.ID)
+	gateway, err := gateways.Get(string(payment.Provider))
+	if err != nil {
+		logger.WithError(err).Warn("Cannot cancel the charge of a failed payment")
+		return
+	}
+	if err := gateway.CancelCharge(context.Background(), *payment.ProviderPaymentID); err != nil {
+		logger.WithError(err).Warn("Failed to cancel the charge of a failed payment")
+	}
+}
+
+// securityLog is the logger for notifications rejected by verification
+func securityLog(event *model.PaymentEvent) *logrus.Entry {
+	return logrus.WithFields(logrus.Fields{
+		"security_event":     "payment_webhook_rejected",
+		"order_id":           event.GatewayOrderID,
+		"transaction_id":     event.TransactionID,
+		"transaction_status": event.TransactionStatus,
+		"gross_amount":       event.GrossAmount,
+	})
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+)
+
+var (
+	ErrPromoCodeNotFound      = errors.New("promo code not found")
+	ErrPromoCodeExists        = errors.New("promo code already exists")
+	ErrPromoCodeInvalid       = errors.New("promo code is not valid at this time")
+	ErrPromoCodeUsedUp        = errors.New("promo code usage limit reached")
+	ErrPromoCodeUserLimit     = errors.New("promo code already used the maximum number of times on this account")
+	ErrPromoCodeNotApplicable = errors.New("promo code does not apply to this game")
+	ErrPromoCodeRedeemed      = errors.New("cannot delete a promo code that has been used, deactivate it instead")
+	ErrPromoInvalidDiscount   = errors.New("percentage codes need percent_off between 1 and 100, fixed codes a positive amount_off")
+	ErrPromoInvalidWindow     = errors.New("valid_until must be after valid_from")
+	ErrPromoInvalidLimit      = errors.New("usage limits must be at least 1")
+)
+
+type PromoCodeService interface {
+	// Admin
+	Create(adminID uint, requestorRole model.UserRole, promo *model.PromoCode) error
+	Update(requestorRole model.UserRole, promoID uint, updateData *model.PromoCode) (*model.PromoCode, error)
+	Delete(requestorRole model.UserRole, promoID uint) error
+	GetAll(requestorRole model.UserRole, limit, offset int) ([]*model.PromoCode, int64, error)
+	GetByID(requestorRole model.UserRole, promoID uint) (*model.PromoCode, error)
+}
+
+type promoCodeService struct {
+	promoRepo repository.PromoCodeRepository
+}
+
+func NewPromoCodeService(promoRepo repository.PromoCodeRepository) PromoCodeService {
+	return &promoCodeService{promoRepo: promoRepo}
+}
+
+func (s *promoCodeService) Create(adminID uint, requestorRole model.UserRole, promo *model.PromoCode) error {
+	if !s.canManagePromoCodes(requestorRole) {
+		return ErrInsufficientPermission
+	}
+
+	promo.Code = normalizePromoCode(promo.Code)
+	if err := validatePromoCode(promo); err != nil {
+		return err
+	}
+	if _, err := s.promoRepo.GetByCode(promo.Code); err == nil {
+		return ErrPromoCodeExists
+	}
+
+	promo.CreatedBy = adminID
+	return s.promoRepo.Create(promo)
+}
+
+// Update replaces the code's settings. Bookings that already used it keep
+// the discount they got.
+func (s *promoCodeService) Update(requestorRole model.UserRole, promoID uint, updateData *model.PromoCode) (*model.PromoCode, error) {
+	if !s.canManagePromoCodes(requestorRole) {
+		return nil, ErrInsufficientPermission
+	}
+
+	promo, err := s.promoRepo.GetByID(promoID)
+	if err != nil {
+		return nil, ErrPromoCodeNotFound
+	}
+
+	code := normalizePromoCode(updateData.Code)
+	if code != promo.Code {
+		if _, err := s.promoRepo.GetByCode(code); err == nil {
+			return nil, ErrPromoCodeExists
+		}
+	}
+
+	promo.Code = code
+	promo.Description = updateData.Description
+	promo.DiscountType = updateData.DiscountType
+	promo.PercentOff = updateData.PercentOff
+	promo.AmountOff = updateData.AmountOff
+	promo.MaxDiscount = updateData.MaxDiscount
+	promo.ValidFrom = updateData.ValidFrom
+	promo.ValidUntil = updateData.ValidUntil
+	promo.MaxUses = updateData.MaxUses
+	promo.MaxUsesPerUser = updateData.MaxUsesPerUser
+	promo.GameIDs = updateData.GameIDs
+	promo.CategoryIDs = updateData.CategoryIDs
+	promo.IsActive = updateData.IsActive
+	if err := validatePromoCode(promo); err != nil {
+		return nil, err
+	}
+
+	if err := s.promoRepo.Update(promo); err != nil {
+		return nil, err
+	}
+	return promo, nil
+}
+
+// Delete removes a code no booking has used; used codes are deactivated instead
+// so the bookings keep their reference
+func (s *promoCodeService) Delete(requestorRole model.UserRole, promoID uint) error {
+	if !s.canManagePromoCodes(requestorRole) {
+		return ErrInsufficientPermission
+	}
+
+	if _, err := s.promoRepo.GetByID(promoID); err != nil {
+		return ErrPromoCodeNotFound
+	}
+
+	// Cancelled bookings still reference the code, so they count here
+	used, err := s.promoRepo.IsReferenced(promoID)
+	if err != nil {
+		return err
+	}
+	if used {
+		return ErrPromoCodeRedeemed
+	}
+
+	return s.promoRepo.Delete(promoID)
+}
+
+func (s *promoCodeService) GetAll(requestorRole model.UserRole, limit, offset int) ([]*model.PromoCode, int64, error) {
+	if !s.canManagePromoCodes(requestorRole) {
+		return nil, 0, ErrInsufficientPermission
+	}
+
+	promos, err := s.promoRepo.GetAll(limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := s.promoRepo.Count()
+	return promos, count, err
+}
+
+func (s *promoCodeService) GetByID(requestorRole model.UserRole, promoID uint) (*model.PromoCode, error) {
+	if !s.canManagePromoCodes(requestorRole) {
+		return nil, ErrInsufficientPermission
+	}
+
+	promo, err := s.promoRepo.GetByID(promoID)
+	if err != nil {
+		return nil, ErrPromoCodeNotFound
+	}
+
+	if promo.UsedCount, err = s.promoRepo.CountUses(promoID); err != nil {
+		return nil, err
+	}
+	return promo, nil
+}
+
+func (s *promoCodeService) canManagePromoCodes(role model.UserRole) bool {
+	return role == model.RoleAdmin || role == model.RoleSuperAdmin
+}
+
+// normalizePromoCode makes codes case-insensitive
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func validatePromoCode(promo *model.PromoCode) error {
+	switch promo.DiscountType {
+	case model.DiscountPercentage:
+		if promo.PercentOff < 1 || promo.PercentOff > 100 || (promo.MaxDiscount != nil && !promo.MaxDiscount.IsPositive()) {
+			return ErrPromoInvalidDiscount
+		}
+		promo.AmountOff = model.Money{}
+	case model.DiscountFixed:
+		if !promo.AmountOff.IsPositive() {
+			return ErrPromoInvalidDiscount
+		}
+		promo.PercentOff = 0
+		promo.MaxDiscount = nil
+	default:
+		return ErrPromoInvalidDiscount
+	}
+
+	if promo.ValidFrom != nil && promo.ValidUntil != nil && !promo.ValidUntil.After(*promo.ValidFrom) {
+		return ErrPromoInvalidWindow
+	}
+	if (promo.MaxUses != nil && *promo.MaxUses < 1) || (promo.MaxUsesPerUser != nil && *promo.MaxUsesPerUser < 1) {
+		return ErrPromoInvalidLimit
+	}
+	return nil
+}
+
+// applyPromoCode checks a code against a new booking in the caller's
+// transaction and takes its discount off the booking total. The code stays
+// locked until commit, so concurrent bookings cannot both take its last use.
+func applyPromoCode(repos repository.Repositories, code string, booking *model.Booking, game *model.Game, now time.Time) error {
+	promo, err := repos.PromoCodes.LockByCode(normalizePromoCode(code))
+	if err != nil {
+		return ErrPromoCodeNotFound
+	}
+
+	if !promo.ValidAt(now) {
+		return ErrPromoCodeInvalid
+	}
+	if !promo.AppliesTo(game) {
+		return ErrPromoCodeNotApplicable
+	}
+
+	if promo.MaxUses != nil {
+		used, err := repos.PromoCodes.CountUses(promo.ID)
+		if err != nil {
+			return err
+		}
+		if used >= int64(*promo.MaxUses) {
+			return ErrPromoCodeUsedUp
+		}
+	}
+	if promo.MaxUsesPerUser != nil {
+		used, err := repos.PromoCodes.CountUserUses(promo.ID, booking.UserID)
+		if err != nil {
+			return err
+		}
+		if used >= int64(*promo.MaxUsesPerUser) {
+			return ErrPromoCodeUserLimit
+		}
+	}
+
+	discount := promo.Discount(booking.TotalRentalPrice)
+	booking.PromoCodeID = &promo.ID
+	booking.DiscountAmount = discount
+	booking.TotalAmount = booking.TotalAmount.Sub(discount)
+	return nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+)
+
+func newPromoFixture(promo *model.PromoCode) (BookingService, *fakeBookingStore) {
+	game := &model.Game{ID: 1, CategoryID: 4, Stock: 10, RentalPricePerDay: model.NewMoney(33333), SecurityDeposit: model.NewMoney(50000), IsActive: true}
+	svc, bookings, txManager := newBookingFixture(game)
+	txManager.repos.PromoCodes = &fakePromoRepo{promo: promo, bookings: bookings}
+	return svc, bookings
+}
+
+func newBookingRequest() *model.Booking {
+	start := time.Now().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	return &model.Booking{GameID: 1, StartDate: start, EndDate: start.AddDate(0, 0, 2)}
+}
+
+func TestCreate_AppliesPercentagePromoToRentalOnly(t *testing.T) {
+	svc, _ := newPromoFixture(&model.PromoCode{ID: 5, Code: "SUMMER15", DiscountType: model.DiscountPercentage, PercentOff: 15, IsActive: true})
+
+	booking := newBookingRequest()
+	require.NoError(t, svc.Create(1, booking, " summer15 "))
+
+	// 15% of 3 × 33333 = 14999.85, rounded down to whole rupiah
+	assert.Equal(t, model.NewMoney(14999), booking.DiscountAmount)
+	assert.Equal(t, model.NewMoney(99999+50000-14999), booking.TotalAmount)
+	require.NotNil(t, booking.PromoCodeID)
+	assert.Equal(t, uint(5), *booking.PromoCodeID)
+}
+
+func TestCreate_PromoDiscountCappedByMaxDiscount(t *testing.T) {
+	maxDiscount := model.NewMoney(10000)
+	svc, _ := newPromoFixture(&model.PromoCode{ID: 5, Code: "HALF", DiscountType: model.DiscountPercentage, PercentOff: 50, MaxDiscount: &maxDiscount, IsActive: true})
+
+	booking := newBookingRequest()
+	require.NoError(t, svc.Create(1, booking, "HALF"))
+
+	assert.Equal(t, maxDiscount, booking.DiscountAmount)
+}
+
+func TestCreate_PromoLimits(t *testing.T) {
+	one, two := 1, 2
+
+	t.Run("total uses", func(t *testing.T) {
+		svc, _ := newPromoFixture(&model.PromoCode{ID: 5, Code: "ONCE", DiscountType: model.DiscountFixed, AmountOff: model.NewMoney(5000), MaxUses: &one, IsActive: true})
+		require.NoError(t, svc.Create(1, newBookingRequest(), "ONCE"))
+		assert.ErrorIs(t, svc.Create(2, newBookingRequest(), "ONCE"), ErrPromoCodeUsedUp)
+	})
+
+	t.Run("per user", func(t *testing.T) {
+		svc, _ := newPromoFixture(&model.PromoCode{ID: 5, Code: "TWICE", DiscountType: model.DiscountFixed, AmountOff: model.NewMoney(5000), MaxUsesPerUser: &two, IsActive: true})
+		require.NoError(t, svc.Create(1, newBookingRequest(), "TWICE"))
+		require.NoError(t, svc.Create(1, newBookingRequest(), "TWICE"))
+		assert.ErrorIs(t, svc.Create(1, newBookingRequest(), "TWICE"), ErrPromoCodeUserLimit)
+		assert.NoError(t, svc.Create(2, newBookingRequest(), "TWICE"))
+	})
+
+	t.Run("cancelled bookings give their use back", func(t *testing.T) {
+		svc, bookings := newPromoFixture(&model.PromoCode{ID: 5, Code: "ONCE", DiscountType: model.DiscountFixed, AmountOff: model.NewMoney(5000), MaxUses: &one, IsActive: true})
+		require.NoError(t, svc.Create(1, newBookingRequest(), "ONCE"))
+		bookings.bookings[0].Status = model.BookingCancelled
+		assert.NoError(t, svc.Create(2, newBookingRequest(), "ONCE"))
+	})
+}
+
+func TestCreate_RejectsUnusablePromo(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name  string
+		promo model.PromoCode
+		want  error
+	}{
+		{"expired", model.PromoCode{ValidUntil: &past, IsActive: true}, ErrPromoCodeInvalid},
+		{"inactive", model.PromoCode{IsActive: false}, ErrPromoCodeInvalid},
+		{"other game", model.PromoCode{GameIDs: []uint{2}, IsActive: true}, ErrPromoCodeNotApplicable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			promo := tt.promo
+			promo.ID, promo.Code, promo.DiscountType, promo.AmountOff = 5, "CODE", model.DiscountFixed, model.NewMoney(5000)
+			svc, bookings := newPromoFixture(&promo)
+
+			assert.ErrorIs(t, svc.Create(1, newBookingRequest(), "CODE"), tt.want)
+			assert.Empty(t, bookings.bookings)
+		})
+	}
+
+	svc, _ := newPromoFixture(&model.PromoCode{ID: 5, Code: "CODE", CategoryIDs: []uint{4}, DiscountType: model.DiscountFixed, AmountOff: model.NewMoney(5000), IsActive: true})
+	assert.NoError(t, svc.Create(1, newBookingRequest(), "CODE"), "the game's category is allowed")
+	assert.ErrorIs(t, svc.Create(1, newBookingRequest(), "NOPE"), ErrPromoCodeNotFound)
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
+	"github.com/yoockh/go-game-rental-api/internal/utils"
+)
+
+var (
+	ErrRefundInvalidAmount   = errors.New("refund amount must be greater than zero")
+	ErrRefundNotRefundable   = errors.New("payment is not paid or already fully refunded")
+	ErrRefundExceedsPayment  = errors.New("refund amount exceeds the refundable amount of the payment")
+	ErrRefundNoProviderRoute = errors.New("payment has no provider transaction to refund")
+	ErrRefundProviderFailed  = errors.New("payment provider rejected the refund")
+	ErrRefundOfflinePayment  = errors.New("offline payments are refunded by hand or to the wallet")
+)
+
+type RefundService interface {
+	// Admin
+	RefundPayment(adminID uint, adminRole model.UserRole, paymentID uint, amount model.Money, reason string, toWallet bool) (*model.PaymentRefund, error)
+
+	// System
+	Refund(payment *model.Payment, amount model.Money, reason string, actorID *uint, toWallet bool) (*model.PaymentRefund, error)
+}
+
+type refundService struct {
+	txManager      repository.TxManager
+	paymentRepo    repository.PaymentRepository
+	bookingService BookingService
+	orderService   OrderService
+	gateways       *transaction.Registry
+	emailRepo      email.EmailRepository
+}
+
+func NewRefundService(
+	txManager repository.TxManager,
+	paymentRepo repository.PaymentRepository,
+	bookingService BookingService,
+	orderService OrderService,
+	gateways *transaction.Registry,
+	emailRepo email.EmailRepository,
+) RefundService {
+	return &refundService{
+		txManager:      txManager,
+		paymentRepo:    paymentRepo,
+		bookingService: bookingService,
+		orderService:   orderService,
+		gateways:       gateways,
+		emailRepo:      emailRepo,
+	}
+}
+
+// RefundPayment refunds amount of a payment, or everything still refundable when
+// amount is zero. With toWallet the refund is credited to the customer's wallet
+// instead of the original payment method. Once a booking or order payment is
+// refunded in full, the bookings it paid for that have not been handed over yet
+// are cancelled.
+func (s *refundService) RefundPayment(adminID uint, adminRole model.UserRole, paymentID uint, amount model.Money, reason string, toWallet bool) (*model.PaymentRefund, error) {
+	if adminRole != model.RoleAdmin && adminRole != model.RoleSuperAdmin {
+		return nil, ErrInsufficientPermission
+	}
+
+	if amount.IsNegative() {
+		return nil, ErrRefundInvalidAmount
+	}
+
+	payment, err := s.paymentRepo.GetByIDWithRelations(paymentID)
+	if err != nil {
+		return nil, ErrPaymentNotFound
+	}
+
+	if amount.IsZero() {
+		amount = payment.RefundableAmount()
+	}
+
+	refund, err := s.Refund(payment, amount, reason, &adminID, toWallet)
+	if err != nil {
+		return refund, err
+	}
+
+	if payment.Status == model.PaymentRefunded {
+		s.cancelRefunded(adminID, payment)
+	}
+
+	// SEND EMAIL: Refund issued
+	if user := paymentCustomer(payment); user != nil {
+		destination := refundDestination(refund)
+		go func() {
+			subject := "Refund Issued - Game Rental"
+			htmlContent := fmt.Sprintf(`
+				<h1>Refund Issued</h1>
+				<p>Hi %s,</p>
+				<p>We have refunded <strong>%s</strong> %s.</p>
+				<ul>
+					<li><strong>Reason:</strong> %s</li>
+					<li><strong>Total refunded:</strong> %s of %s</li>
+				</ul>
+				<p>Refunds to your payment method may take a few business days to show up on your statement. Wallet credit can be used right away.</p>
+			`, user.FullName, refund.Amount.Display(), destination, refund.Reason, payment.RefundedAmount.Display(), payment.Amount.Display())
+
+			plainText := fmt.Sprintf("We have refunded %s %s. Reason: %s", refund.Amount.Display(), destination, refund.Reason)
+
+			if err := s.emailRepo.SendEmail(context.Background(), user.Email, subject, plainText, htmlContent); err != nil {
+				logrus.WithError(err).Error("Failed to send refund email")
+			}
+		}()
+	}
+
+	return refund, nil
+}
+
+// Refund sends a refund of amount through the provider of the payment and
+// records it. The amount is reserved on the payment before the provider is
+// called, so concurrent refunds can never exceed what was paid, and released
+// again when the provider rejects the refund. actorID is nil for system refunds.
+//
+// The part of the refund the provider cannot take back, because it was paid
+// from the wallet, is credited to the wallet; with toWallet all of it is.
+func (s *refundService) Refund(payment *model.Payment, amount model.Money, reason string, actorID *uint, toWallet bool) (*model.PaymentRefund, error) {
+	if !payment.RefundableAmount().IsPositive() {
+		return nil, ErrRefundNotRefundable
+	}
+	if !amount.IsPositive() {
+		return nil, ErrRefundInvalidAmount
+	}
+
+	walletAmount := amount
+	if !toWallet {
+		walletAmount = amount.Sub(payment.GatewayRefundableAmount())
+		if walletAmount.IsNegative() {
+			walletAmount = model.Money{}
+		}
+	}
+	gatewayAmount := amount.Sub(walletAmount)
+
+	var gateway transaction.TransactionRepository
+	if gatewayAmount.IsPositive() {
+		if payment.Provider == model.ProviderOffline {
+			return nil, ErrRefundOfflinePayment
+		}
+		if payment.ProviderPaymentID == nil {
+			return nil, ErrRefundNoProviderRoute
+		}
+		var err error
+		if gateway, err = s.gateways.Get(string(payment.Provider)); err != nil {
+			return nil, err
+		}
+	}
+
+	refund := &model.PaymentRefund{
+		PaymentID:    payment.ID,
+		Amount:       amount,
+		WalletAmount: walletAmount,
+		Reason:       reason,
+		Status:       model.RefundPending,
+		RefundedBy:   actorID,
+	}
+
+	err := s.txManager.WithTransaction(func(repos repository.Repositories) error {
+		reserved, err := repos.Payments.AdjustRefundedAmount(payment.ID, amount)
+		if err != nil {
+			return err
+		}
+		if !reserved {
+			return ErrRefundExceedsPayment
+		}
+		return repos.Refunds.Create(refund)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var providerRefundID string
+	var refundErr error
+	if gateway != nil {
+		refundKey := fmt.Sprintf("refund-%d", refund.ID)
+		providerRefundID, refundErr = gateway.Refund(context.Background(), *payment.ProviderPaymentID, refundKey, gatewayAmount, reason)
+	}
+
+	logger := logrus.WithFields(logrus.Fields{
+		"payment_id": payment.ID,
+		"refund_id":  refund.ID,
+		"amount":     amount.String(),
+		"wallet":     walletAmount.String(),
+	})
+
+	err = s.txManager.WithTransaction(func(repos repository.Repositories) error {
+		if refundErr != nil {
+			refund.Status = model.RefundFailed
+			refund.FailureReason = utils.PtrOrNil(refundErr.Error())
+			if _, err := repos.Payments.AdjustRefundedAmount(payment.ID, amount.Neg()); err != nil {
+				return err
+			}
+		} else {
+			if walletAmount.IsPositive() {
+				if err := creditWalletRefund(repos, payment, refund); err != nil {
+					return err
+				}
+			}
+			refund.Status = model.RefundSucceeded
+			refund.ProviderRefundID = utils.PtrOrNil(providerRefundID)
+		}
+		return repos.Refunds.Update(refund)
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to save refund result")
+		if gateway == nil {
+			// Nothing left the wallet ledger, so nothing was refunded
+			s.releaseReservation(payment, refund, err)
+			return refund, err
+		}
+	}
+
+	if refundErr != nil {
+		logger.WithError(refundErr).Warn("Refund rejected by payment provider")
+		return refund, fmt.Errorf("%w: %v", ErrRefundProviderFailed, refundErr)
+	}
+
+	payment.RefundedAmount = payment.RefundedAmount.Add(amount)
+	payment.WalletRefundedAmount = payment.WalletRefundedAmount.Add(walletAmount)
+	if !payment.RefundedAmount.Add(payment.ChargedBackAmount).LessThan(payment.Amount) {
+		payment.Status = model.PaymentRefunded
+	} else {
+		payment.Status = model.PaymentPartiallyRefunded
+	}
+	payment.Refunds = append(payment.Refunds, *refund)
+	return refund, nil
+}
+
+// releaseReservation marks a refund that could not be credited to the wallet as
+// failed and gives its reserved amount back to the payment
+func (s *refundService) releaseReservation(payment *model.Payment, refund *model.PaymentRefund, cause error) {
+	err := s.txManager.WithTransaction(func(repos repository.Repositories) error {
+		refund.Status = model.RefundFailed
+		refund.FailureReason = utils.PtrOrNil(cause.Error())
+		if _, err := repos.Payments.AdjustRefundedAmount(payment.ID, refund.Amount.Neg()); err != nil {
+			return err
+		}
+		return repos.Refunds.Update(refund)
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("refund_id", refund.ID).Error("Failed to release refund reservation")
+	}
+}
+
+// refundDestination tells the customer where a refund went
+func refundDestination(refund *model.PaymentRefund) string {
+	switch {
+	case refund.WalletAmount.IsZero():
+		return "to your original payment method"
+	case refund.WalletAmount.Equal(refund.Amount):
+		return "to your wallet"
+	}
+	return fmt.Sprintf("to your original payment method, with %s of it credited to your wallet", refund.WalletAmount.Display())
+}
+
+// cancelRefunded cancels what a fully refunded payment paid for. Supplemental
+// date change payments leave the booking as it is.
+func (s *refundService) cancelRefunded(adminID uint, payment *model.Payment) {
+	var err error
+	switch payment.Purpose {
+	case model.PaymentPurposeBooking:
+		err = s.bookingService.CancelRefunded(adminID, *payment.BookingID)
+	case model.PaymentPurposeOrder:
+		err = s.orderService.CancelRefunded(adminID, *payment.OrderID)
+	}
+	if err != nil {
+		logrus.WithError(err).WithField("payment_id", payment.ID).Warn("Failed to cancel bookings of refunded payment")
+	}
+}
+
+// paymentCustomer returns the customer who made the payment, if it was loaded
+func paymentCustomer(payment *model.Payment) *model.User {
+	if payment.Booking != nil && payment.Booking.User.ID != 0 {
+		return &payment.Booking.User
+	}
+	if payment.Order != nil && payment.Order.User.ID != 0 {
+		return &payment.Order.User
+	}
+	return nil
+}
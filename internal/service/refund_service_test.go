@@ -0,0 +1,159 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+	"github.com/yoockh/go-game-rental-api/internal/repository/transaction"
+)
+
+type refundFixture struct {
+	svc      *refundService
+	payments *fakePaymentRepo
+	refunds  *fakeRefundRepo
+	bookings *MockBookingService
+	wallets  *fakeWalletRepo
+}
+
+// newRefundFixture is a paid booking of customer 3 with walletAmount of it
+// paid from the wallet
+func newRefundFixture(gateway transaction.TransactionRepository, walletAmount model.Money) *refundFixture {
+	bookingID := uint(7)
+	providerID := "tx-7"
+	payments := &fakePaymentRepo{payment: &model.Payment{
+		ID:                1,
+		BookingID:         &bookingID,
+		Provider:          model.ProviderMidtrans,
+		Purpose:           model.PaymentPurposeBooking,
+		Amount:            model.NewMoney(100000),
+		WalletAmount:      walletAmount,
+		Status:            model.PaymentPaid,
+		ProviderPaymentID: &providerID,
+	}}
+	refunds := &fakeRefundRepo{}
+	bookings := &MockBookingService{}
+	bookings.On("CancelRefunded", mock.Anything, bookingID).Return(nil)
+	wallets := newFakeWalletRepo()
+	bookingStore := &fakeBookingStore{bookings: []*model.Booking{{ID: bookingID, UserID: 3}}}
+	txManager := &fakeTxManager{repos: repository.Repositories{Payments: payments, Refunds: refunds, Bookings: bookingStore, Wallets: wallets}}
+
+	gateways := transaction.NewRegistry()
+	gateways.Register(string(model.ProviderMidtrans), gateway)
+
+	svc := NewRefundService(txManager, payments, bookings, nil, gateways, &email.MockEmailRepository{}).(*refundService)
+	return &refundFixture{svc: svc, payments: payments, refunds: refunds, bookings: bookings, wallets: wallets}
+}
+
+// ============= TEST REFUNDS =============
+func TestRefundPayment_PartialThenFull(t *testing.T) {
+	gateway := &transaction.MockTransactionRepository{}
+	f := newRefundFixture(gateway, model.Money{})
+
+	refund, err := f.svc.RefundPayment(1, model.RoleAdmin, 1, model.NewMoney(40000), "late delivery", false)
+	require.NoError(t, err)
+	assert.Equal(t, model.RefundSucceeded, refund.Status)
+	assert.Equal(t, model.PaymentPartiallyRefunded, f.payments.payment.Status)
+	f.bookings.AssertNotCalled(t, "CancelRefunded", mock.Anything, mock.Anything)
+
+	_, err = f.svc.RefundPayment(1, model.RoleAdmin, 1, model.NewMoney(70000), "too much", false)
+	assert.ErrorIs(t, err, ErrRefundExceedsPayment)
+
+	// Zero refunds whatever is left and cancels the booking
+	refund, err = f.svc.RefundPayment(1, model.RoleAdmin, 1, model.Money{}, "customer cancelled", false)
+	require.NoError(t, err)
+	assert.Equal(t, model.NewMoney(60000), refund.Amount)
+	assert.Equal(t, model.PaymentRefunded, f.payments.payment.Status)
+	f.bookings.AssertCalled(t, "CancelRefunded", uint(1), uint(7))
+
+	assert.Len(t, f.refunds.refunds, 2)
+	assert.Len(t, gateway.Refunds, 2)
+
+	_, err = f.svc.RefundPayment(1, model.RoleAdmin, 1, model.Money{}, "again", false)
+	assert.ErrorIs(t, err, ErrRefundNotRefundable)
+}
+
+func TestRefundPayment_RestAfterChargebackFullyRefunds(t *testing.T) {
+	gateway := &transaction.MockTransactionRepository{}
+	f := newRefundFixture(gateway, model.Money{})
+	f.payments.payment.ChargedBackAmount = model.NewMoney(30000)
+
+	_, err := f.svc.RefundPayment(1, model.RoleAdmin, 1, model.NewMoney(80000), "too much", false)
+	assert.ErrorIs(t, err, ErrRefundExceedsPayment)
+
+	refund, err := f.svc.RefundPayment(1, model.RoleAdmin, 1, model.Money{}, "customer cancelled", false)
+	require.NoError(t, err)
+	assert.Equal(t, model.NewMoney(70000), refund.Amount)
+	assert.Equal(t, model.PaymentRefunded, f.payments.payment.Status, "nothing is left of the payment")
+	f.bookings.AssertCalled(t, "CancelRefunded", uint(1), uint(7))
+}
+
+func TestRefundPayment_ProviderRejectionReleasesAmount(t *testing.T) {
+	f := newRefundFixture(&rejectingGateway{}, model.Money{})
+
+	refund, err := f.svc.RefundPayment(1, model.RoleAdmin, 1, model.Money{}, "customer cancelled", false)
+	assert.ErrorIs(t, err, ErrRefundProviderFailed)
+	require.NotNil(t, refund)
+	assert.Equal(t, model.RefundFailed, refund.Status)
+	assert.Len(t, f.refunds.refunds, 1)
+
+	assert.True(t, f.payments.payment.RefundedAmount.IsZero())
+	assert.Equal(t, model.PaymentPaid, f.payments.payment.Status)
+	f.bookings.AssertNotCalled(t, "CancelRefunded", mock.Anything, mock.Anything)
+}
+
+func TestRefundPayment_RequiresAdmin(t *testing.T) {
+	f := newRefundFixture(&transaction.MockTransactionRepository{}, model.Money{})
+
+	_, err := f.svc.RefundPayment(1, model.RoleCustomer, 1, model.Money{}, "customer cancelled", false)
+	assert.ErrorIs(t, err, ErrInsufficientPermission)
+}
+
+func TestRefundPayment_ToWallet(t *testing.T) {
+	gateway := &transaction.MockTransactionRepository{}
+	f := newRefundFixture(gateway, model.Money{})
+
+	refund, err := f.svc.RefundPayment(1, model.RoleAdmin, 1, model.NewMoney(40000), "store credit", true)
+	require.NoError(t, err)
+	assert.Equal(t, model.RefundSucceeded, refund.Status)
+	assert.Equal(t, model.NewMoney(40000), refund.WalletAmount)
+	assert.Nil(t, refund.ProviderRefundID)
+	assert.Empty(t, gateway.Refunds)
+
+	assert.Equal(t, model.NewMoney(40000), f.wallets.balances[3])
+	require.Len(t, f.wallets.entries, 1)
+	assert.Equal(t, model.WalletRefund, f.wallets.entries[0].Type)
+	assert.Equal(t, refund.ID, *f.wallets.entries[0].RefundID)
+	assert.Equal(t, model.NewMoney(40000), f.payments.payment.WalletRefundedAmount)
+	assert.Equal(t, model.PaymentPartiallyRefunded, f.payments.payment.Status)
+}
+
+func TestRefundPayment_WalletPartGoesBackToWallet(t *testing.T) {
+	gateway := &transaction.MockTransactionRepository{}
+	f := newRefundFixture(gateway, model.NewMoney(30000))
+
+	// The provider only charged 70000, the rest came from the wallet
+	refund, err := f.svc.RefundPayment(1, model.RoleAdmin, 1, model.Money{}, "customer cancelled", false)
+	require.NoError(t, err)
+	assert.Equal(t, model.NewMoney(100000), refund.Amount)
+	assert.Equal(t, model.NewMoney(30000), refund.WalletAmount)
+	require.Len(t, gateway.Refunds, 1)
+	assert.Equal(t, model.NewMoney(70000), gateway.Refunds[0].Amount)
+
+	assert.Equal(t, model.NewMoney(30000), f.wallets.balances[3])
+	assert.Equal(t, model.PaymentRefunded, f.payments.payment.Status)
+	f.bookings.AssertCalled(t, "CancelRefunded", uint(1), uint(7))
+}
+
+func TestRefundPayment_ProviderRejectionCreditsNothing(t *testing.T) {
+	f := newRefundFixture(&rejectingGateway{}, model.NewMoney(30000))
+
+	_, err := f.svc.RefundPayment(1, model.RoleAdmin, 1, model.Money{}, "customer cancelled", false)
+	assert.ErrorIs(t, err, ErrRefundProviderFailed)
+	assert.Empty(t, f.wallets.entries)
+	assert.True(t, f.payments.payment.RefundedAmount.IsZero())
+}
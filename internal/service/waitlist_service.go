@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+	"github.com/yoockh/go-game-rental-api/internal/repository/email"
+)
+
+var (
+	ErrWaitlistNotFound      = errors.New("waitlist entry not found")
+	ErrWaitlistNotOwned      = errors.New("you don't own this waitlist entry")
+	ErrWaitlistAlreadyJoined = errors.New("already on the waitlist for this game")
+	ErrWaitlistGameAvailable = errors.New("game is available for these dates, book it directly")
+	ErrWaitlistNotActive     = errors.New("waitlist entry is no longer active")
+)
+
+type WaitlistService interface {
+	// Customer
+	Join(userID uint, gameID uint, startDate, endDate time.Time) (*model.WaitlistEntry, error)
+	GetUserEntries(userID uint) ([]*model.WaitlistEntry, error)
+	Leave(userID uint, entryID uint) error
+
+	// System
+	NotifyCapacityReleased(gameID uint)
+	ProcessHolds() (int, error)
+}
+
+type waitlistService struct {
+	txManager    repository.TxManager
+	waitlistRepo repository.WaitlistRepository
+	gameRepo     repository.GameRepository
+	emailRepo    email.EmailRepository
+	holdWindow   time.Duration
+}
+
+// NewWaitlistService creates the waitlist service. holdWindow is how long an
+// offered copy is kept for a customer before it passes to the next in line.
+func NewWaitlistService(
+	txManager repository.TxManager,
+	waitlistRepo repository.WaitlistRepository,
+	gameRepo repository.GameRepository,
+	emailRepo email.EmailRepository,
+	holdWindow time.Duration,
+) WaitlistService {
+	return &waitlistService{
+		txManager:    txManager,
+		waitlistRepo: waitlistRepo,
+		gameRepo:     gameRepo,
+		emailRepo:    emailRepo,
+		holdWindow:   holdWindow,
+	}
+}
+
+// Join queues the customer for a game that has no copy free for the requested dates
+func (s *waitlistService) Join(userID uint, gameID uint, startDate, endDate time.Time) (*model.WaitlistEntry, error) {
+	game, err := s.gameRepo.GetByID(gameID)
+	if err != nil {
+		return nil, ErrGameNotFound
+	}
+
+	if !game.IsActive {
+		return nil, errors.New("game is not available for booking")
+	}
+
+	if startDate.After(endDate) || startDate.Before(time.Now().Truncate(24*time.Hour)) {
+		return nil, ErrBookingInvalidDate
+	}
+
+	if existing, _ := s.waitlistRepo.GetActiveByUserAndGame(userID, gameID); existing != nil {
+		return nil, ErrWaitlistAlreadyJoined
+	}
+
+	available, err := s.gameRepo.CheckAvailability(gameID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	if available {
+		return nil, ErrWaitlistGameAvailable
+	}
+
+	entry := &model.WaitlistEntry{
+		GameID:    gameID,
+		UserID:    userID,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Status:    model.WaitlistWaiting,
+	}
+	if err := s.waitlistRepo.Create(entry); err != nil {
+		return nil, err
+	}
+	entry.Game = *game
+
+	return entry, nil
+}
+
+func (s *waitlistService) GetUserEntries(userID uint) ([]*model.WaitlistEntry, error) {
+	return s.waitlistRepo.GetUserEntries(userID)
+}
+
+// Leave takes the customer off the queue. Giving up an offered hold passes the
+// copy on to the next customer right away.
+func (s *waitlistService) Leave(userID uint, entryID uint) error {
+	entry, err := s.waitlistRepo.GetByID(entryID)
+	if err != nil {
+		return ErrWaitlistNotFound
+	}
+
+	if entry.UserID != userID {
+		return ErrWaitlistNotOwned
+	}
+
+	if entry.Status != model.WaitlistWaiting && entry.Status != model.WaitlistOffered {
+		return ErrWaitlistNotActive
+	}
+
+	updated, err := s.waitlistRepo.UpdateStatusFrom(entry.ID, entry.Status, model.WaitlistCancelled)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrWaitlistNotActive
+	}
+
+	if entry.Status == model.WaitlistOffered {
+		s.NotifyCapacityReleased(entry.GameID)
+	}
+	return nil
+}
+
+// NotifyCapacityReleased is called whenever a copy of the game may have become
+// free (a return, a cancellation, an expired hold). It offers holds to the
+// customers in line whose dates now fit. Errors are only logged, the caller's
+// own change has already been committed.
+func (s *waitlistService) NotifyCapacityReleased(gameID uint) {
+	if _, err := s.offerHolds(gameID); err != nil {
+		logrus.WithError(err).WithField("game_id", gameID).Error("Failed to offer waitlist holds")
+	}
+}
+
+// ProcessHolds expires holds that were not turned into a booking in time and
+// passes their copies to the next customers in line. It also offers holds for
+// any capacity freed by changes that did not notify the waitlist directly.
+func (s *waitlistService) ProcessHolds() (int, error) {
+	entries, err := s.waitlistRepo.GetExpiredHolds(time.Now(), expiryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, entry := range entries {
+		updated, err := s.waitlistRepo.UpdateStatusFrom(entry.ID, model.WaitlistOffered, model.WaitlistExpired)
+		if err != nil {
+			logrus.WithError(err).WithField("waitlist_entry_id", entry.ID).Warn("Failed to expire waitlist hold")
+			continue
+		}
+		if !updated {
+			continue // converted or cancelled in the meantime
+		}
+		expired++
+
+		// SEND EMAIL: Hold expired
+		go func(entry *model.WaitlistEntry) {
+			subject := "Waitlist Hold Expired - Game Rental"
+			htmlContent := fmt.Sprintf(`
+				<h1>Your Hold Has Expired</h1>
+				<p>Hi %s,</p>
+				<p>The copy of <strong>%s</strong> we held for you was not booked in time and has been offered to the next customer in line.</p>
+				<p>You are welcome to join the waitlist again.</p>
+			`, entry.User.FullName, entry.Game.Name)
+
+			plainText := fmt.Sprintf("Your hold on %s expired and was passed to the next customer.", entry.Game.Name)
+
+			if err := s.emailRepo.SendEmail(context.Background(), entry.User.Email, subject, plainText, htmlContent); err != nil {
+				logrus.WithError(err).Error("Failed to send waitlist hold expired email")
+			}
+		}(entry)
+	}
+
+	gameIDs, err := s.waitlistRepo.GetGamesWithWaiting()
+	if err != nil {
+		return expired, err
+	}
+	for _, gameID := range gameIDs {
+		s.NotifyCapacityReleased(gameID)
+	}
+
+	if expired > 0 {
+		logrus.WithField("count", expired).Info("Expired waitlist holds")
+	}
+	return expired, nil
+}
+
+// offerHolds walks the queue of a game in order and gives a hold to every
+// customer whose dates fit the free copies. The game row is locked so the holds
+// cannot race with new bookings.
+func (s *waitlistService) offerHolds(gameID uint) (int, error) {
+	var offered []*model.WaitlistEntry
+	today := time.Now().Truncate(24 * time.Hour)
+	holdExpiresAt := time.Now().Add(s.holdWindow)
+
+	err := s.txManager.WithTransaction(func(repos repository.Repositories) error {
+		if _, err := repos.Games.LockForUpdate(gameID); err != nil {
+			return err
+		}
+
+		queue, err := repos.Waitlist.GetWaitingByGame(gameID)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range queue {
+			// A hold starting in the past could never become a booking
+			if entry.StartDate.Before(today) {
+				if _, err := repos.Waitlist.UpdateStatusFrom(entry.ID, model.WaitlistWaiting, model.WaitlistExpired); err != nil {
+					return err
+				}
+				continue
+			}
+
+			available, err := repos.Games.CheckAvailability(gameID, entry.StartDate, entry.EndDate)
+			if err != nil {
+				return err
+			}
+			if !available {
+				continue
+			}
+
+			ok, err := repos.Waitlist.Offer(entry.ID, holdExpiresAt)
+			if err != nil {
+				return err
+			}
+			if ok {
+				entry.Status = model.WaitlistOffered
+				entry.HoldExpiresAt = &holdExpiresAt
+				offered = append(offered, entry)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, entry := range offered {
+		// SEND EMAIL: Copy held for customer
+		go func(entry *model.WaitlistEntry) {
+			subject := "A Copy Is Waiting for You - Game Rental"
+			htmlContent := fmt.Sprintf(`
+				<h1>Good News!</h1>
+				<p>Hi %s,</p>
+				<p>A copy of <strong>%s</strong> is now free for %s to %s and we are holding it for you.</p>
+				<p>Book it before <strong>%s</strong>, after that the hold passes to the next customer in line.</p>
+			`, entry.User.FullName, entry.Game.Name, entry.StartDate.Format("2006-01-02"), entry.EndDate.Format("2006-01-02"), holdExpiresAt.Format("2006-01-02 15:04"))
+
+			plainText := fmt.Sprintf("A copy of %s is held for you until %s.", entry.Game.Name, holdExpiresAt.Format("2006-01-02 15:04"))
+
+			if err := s.emailRepo.SendEmail(context.Background(), entry.User.Email, subject, plainText, htmlContent); err != nil {
+				logrus.WithError(err).Error("Failed to send waitlist offer email")
+			}
+		}(entry)
+	}
+
+	if len(offered) > 0 {
+		logrus.WithFields(logrus.Fields{"game_id": gameID, "count": len(offered)}).Info("Offered waitlist holds")
+	}
+	return len(offered), nil
+}
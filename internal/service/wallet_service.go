@@ -0,0 +1,207 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yoockh/go-game-rental-api/internal/model"
+	"github.com/yoockh/go-game-rental-api/internal/repository"
+)
+
+var (
+	ErrWalletInsufficientBalance = errors.New("insufficient wallet balance")
+	ErrWalletInvalidAdjustment   = errors.New("adjustment amount cannot be zero")
+)
+
+type WalletService interface {
+	// Customer
+	GetWallet(userID uint, limit, offset int) (*model.Wallet, int64, error)
+
+	// Admin
+	GetUserWallet(requestorRole model.UserRole, userID uint, limit, offset int) (*model.Wallet, int64, error)
+	Adjust(adminID uint, requestorRole model.UserRole, userID uint, amount model.Money, description string) (*model.WalletEntry, error)
+}
+
+type walletService struct {
+	walletRepo repository.WalletRepository
+	userRepo   repository.UserRepository
+}
+
+func NewWalletService(walletRepo repository.WalletRepository, userRepo repository.UserRepository) WalletService {
+	return &walletService{
+		walletRepo: walletRepo,
+		userRepo:   userRepo,
+	}
+}
+
+// GetWallet returns the balance with one page of the ledger, newest first, and
+// the number of entries
+func (s *walletService) GetWallet(userID uint, limit, offset int) (*model.Wallet, int64, error) {
+	wallet, err := s.walletRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries, err := s.walletRepo.GetEntries(userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, entry := range entries {
+		wallet.Entries = append(wallet.Entries, *entry)
+	}
+
+	count, err := s.walletRepo.CountEntries(userID)
+	return wallet, count, err
+}
+
+func (s *walletService) GetUserWallet(requestorRole model.UserRole, userID uint, limit, offset int) (*model.Wallet, int64, error) {
+	if !s.canManageWallets(requestorRole) {
+		return nil, 0, ErrInsufficientPermission
+	}
+
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, 0, ErrUserNotFound
+	}
+
+	return s.GetWallet(userID, limit, offset)
+}
+
+// Adjust credits (positive amount) or debits a customer's wallet by hand, e.g.
+// goodwill credit or correcting a mistake. A debit cannot overdraw the wallet.
+func (s *walletService) Adjust(adminID uint, requestorRole model.UserRole, userID uint, amount model.Money, description string) (*model.WalletEntry, error) {
+	if !s.canManageWallets(requestorRole) {
+		return nil, ErrInsufficientPermission
+	}
+
+	if amount.IsZero() {
+		return nil, ErrWalletInvalidAdjustment
+	}
+
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	entry := &model.WalletEntry{
+		UserID:      userID,
+		Type:        model.WalletAdjustment,
+		Amount:      amount,
+		Description: description,
+		CreatedBy:   &adminID,
+	}
+	posted, err := s.walletRepo.Post(entry)
+	if err != nil {
+		return nil, err
+	}
+	if !posted {
+		return nil, ErrWalletInsufficientBalance
+	}
+	return entry, nil
+}
+
+func (s *walletService) canManageWallets(role model.UserRole) bool {
+	return role == model.RoleAdmin || role == model.RoleSuperAdmin
+}
+
+// debitWallet takes the wallet part of a new payment from the customer's wallet
+// in the caller's transaction
+func debitWallet(repos repository.Repositories, userID uint, payment *model.Payment) error {
+	posted, err := repos.Wallets.Post(&model.WalletEntry{
+		UserID:      userID,
+		Type:        model.WalletPayment,
+		Amount:      payment.WalletAmount.Neg(),
+		PaymentID:   &payment.ID,
+		Description: paymentDescription(payment),
+	})
+	if err != nil {
+		return err
+	}
+	if !posted {
+		return ErrWalletInsufficientBalance
+	}
+	return nil
+}
+
+// releaseWalletPayment gives the wallet part of a payment that just failed back
+// to the customer, in the caller's transaction
+func releaseWalletPayment(repos repository.Repositories, payment *model.Payment) error {
+	if !payment.WalletAmount.IsPositive() {
+		return nil
+	}
+
+	userID, err := paymentOwnerID(repos, payment)
+	if err != nil {
+		return err
+	}
+
+	_, err = repos.Wallets.Post(&model.WalletEntry{
+		UserID:      userID,
+		Type:        model.WalletPaymentRelease,
+		Amount:      payment.WalletAmount,
+		PaymentID:   &payment.ID,
+		Description: paymentDescription(payment) + " failed",
+	})
+	return err
+}
+
+// failPendingPayment fails a payment that is still pending and gives its wallet
+// part back, in the caller's transaction. A payment the provider settled in the
+// meantime is left alone.
+func failPendingPayment(repos repository.Repositories, payment *model.Payment, reason string) error {
+	failed, err := repos.Payments.UpdateStatusFrom(payment.ID, model.PaymentPending, model.PaymentFailed)
+	if err != nil || !failed {
+		return err
+	}
+	if err := repos.Payments.MarkAsFailed(payment.ID, reason); err != nil {
+		return err
+	}
+	return releaseWalletPayment(repos, payment)
+}
+
+// creditWalletRefund credits the wallet part of a refund in the caller's
+// transaction
+func creditWalletRefund(repos repository.Repositories, payment *model.Payment, refund *model.PaymentRefund) error {
+	userID, err := paymentOwnerID(repos, payment)
+	if err != nil {
+		return err
+	}
+
+	_, err = repos.Wallets.Post(&model.WalletEntry{
+		UserID:      userID,
+		Type:        model.WalletRefund,
+		Amount:      refund.WalletAmount,
+		PaymentID:   &payment.ID,
+		RefundID:    &refund.ID,
+		Description: "Refund: " + refund.Reason,
+	})
+	if err != nil {
+		return err
+	}
+	return repos.Payments.AddWalletRefundedAmount(payment.ID, refund.WalletAmount)
+}
+
+// paymentOwnerID returns the customer who made the payment
+func paymentOwnerID(repos repository.Repositories, payment *model.Payment) (uint, error) {
+	switch {
+	case payment.BookingID != nil:
+		booking, err := repos.Bookings.GetByID(*payment.BookingID)
+		if err != nil {
+			return 0, ErrBookingNotFound
+		}
+		return booking.UserID, nil
+	case payment.OrderID != nil:
+		order, err := repos.Orders.GetByID(*payment.OrderID)
+		if err != nil {
+			return 0, ErrOrderNotFound
+		}
+		return order.UserID, nil
+	}
+	return 0, ErrPaymentNotFound
+}
+
+// paymentDescription names what a payment is for in the wallet ledger
+func paymentDescription(payment *model.Payment) string {
+	if payment.OrderID != nil {
+		return fmt.Sprintf("Payment for order #%d", *payment.OrderID)
+	}
+	return fmt.Sprintf("Payment for booking #%d", *payment.BookingID)
+}
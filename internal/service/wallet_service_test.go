@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yoockh/go-game-rental-api/internal/model"
+)
+
+// ============= TEST WALLET ADJUSTMENTS =============
+func TestWalletAdjust_CreditThenDebit(t *testing.T) {
+	wallets := newFakeWalletRepo()
+	svc := NewWalletService(wallets, &fakeUserRepo{users: []*model.User{{ID: 3}}})
+
+	entry, err := svc.Adjust(1, model.RoleAdmin, 3, model.NewMoney(50000), "goodwill credit")
+	require.NoError(t, err)
+	assert.Equal(t, model.WalletAdjustment, entry.Type)
+	assert.Equal(t, model.NewMoney(50000), entry.BalanceAfter)
+	require.NotNil(t, entry.CreatedBy)
+	assert.Equal(t, uint(1), *entry.CreatedBy)
+
+	entry, err = svc.Adjust(1, model.RoleAdmin, 3, model.NewMoney(20000).Neg(), "correction")
+	require.NoError(t, err)
+	assert.Equal(t, model.NewMoney(30000), entry.BalanceAfter)
+}
+
+func TestWalletAdjust_CannotOverdraw(t *testing.T) {
+	wallets := newFakeWalletRepo()
+	svc := NewWalletService(wallets, &fakeUserRepo{users: []*model.User{{ID: 3}}})
+
+	_, err := svc.Adjust(1, model.RoleAdmin, 3, model.NewMoney(10000).Neg(), "correction")
+	assert.ErrorIs(t, err, ErrWalletInsufficientBalance)
+	assert.Empty(t, wallets.entries)
+	assert.True(t, wallets.balances[3].IsZero())
+}
+
+func TestWalletAdjust_Rejected(t *testing.T) {
+	svc := NewWalletService(newFakeWalletRepo(), &fakeUserRepo{users: []*model.User{{ID: 3}}})
+
+	_, err := svc.Adjust(1, model.RoleAdmin, 3, model.Money{}, "nothing")
+	assert.ErrorIs(t, err, ErrWalletInvalidAdjustment)
+
+	_, err = svc.Adjust(3, model.RoleCustomer, 3, model.NewMoney(10000), "free money")
+	assert.ErrorIs(t, err, ErrInsufficientPermission)
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"net/http"
 	"strings"
 
 	"github.com/labstack/echo/v4"
@@ -23,6 +24,9 @@ func MapServiceError(c echo.Context, err error) error {
 		strings.Contains(errMsg, "cannot confirm"):
 		return myResponse.BadRequest(c, errMsg)
 
+	case strings.Contains(errMsg, "changed by another request"):
+		return myResponse.Error(c, http.StatusConflict, errMsg)
+
 	default:
 		return myResponse.BadRequest(c, errMsg)
 	}
@@ -1,9 +1,11 @@
 package utils
 
 import (
+	"reflect"
 	"sync"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/yoockh/go-game-rental-api/internal/model"
 )
 
 var (
@@ -15,6 +17,10 @@ var (
 func GetValidator() *validator.Validate {
 	once.Do(func() {
 		validatorInstance = validator.New()
+		// Money is validated by its amount in cents, so min=0 and gt=0 work on it
+		validatorInstance.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+			return field.Interface().(model.Money).Cents()
+		}, model.Money{})
 	})
 	return validatorInstance
 }
\ No newline at end of file
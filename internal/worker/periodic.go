@@ -0,0 +1,32 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RunPeriodic calls job every interval until ctx is cancelled. Errors are logged
+// and the job simply runs again on the next tick.
+func RunPeriodic(ctx context.Context, name string, interval time.Duration, job func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	logrus.WithFields(logrus.Fields{
+		"job":      name,
+		"interval": interval.String(),
+	}).Info("Background job started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			logrus.WithField("job", name).Info("Background job stopped")
+			return
+		case <-ticker.C:
+			if err := job(); err != nil {
+				logrus.WithError(err).WithField("job", name).Error("Background job failed")
+			}
+		}
+	}
+}
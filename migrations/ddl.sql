@@ -1,8 +1,8 @@
 -- ENUM types (simplified)
 CREATE TYPE user_role AS ENUM ('customer', 'admin', 'super_admin');
 CREATE TYPE booking_status AS ENUM ('pending', 'confirmed', 'active', 'completed', 'cancelled');
-CREATE TYPE payment_status AS ENUM ('pending', 'paid', 'failed', 'refunded');
-CREATE TYPE payment_provider AS ENUM ('midtrans');
+CREATE TYPE payment_status AS ENUM ('pending', 'paid', 'failed', 'refunded', 'partially_refunded', 'review');
+CREATE TYPE payment_provider AS ENUM ('stripe', 'midtrans', 'wallet', 'offline');
 
 -- Users table
 CREATE TABLE users (
@@ -45,10 +45,43 @@ CREATE TABLE games (
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
--- Bookings table 
+-- Orders table (several bookings checked out with one payment)
+CREATE TABLE orders (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    total_amount DECIMAL(12,2) NOT NULL,
+    payment_due_at TIMESTAMP,
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Promo codes table (discounts customers apply when booking)
+CREATE TABLE promo_codes (
+    id BIGSERIAL PRIMARY KEY,
+    code VARCHAR(50) UNIQUE NOT NULL,
+    description TEXT,
+    discount_type VARCHAR(20) NOT NULL,
+    percent_off INTEGER NOT NULL DEFAULT 0,
+    amount_off DECIMAL(12,2) NOT NULL DEFAULT 0,
+    max_discount DECIMAL(12,2),
+    valid_from TIMESTAMP,
+    valid_until TIMESTAMP,
+    max_uses INTEGER,
+    max_uses_per_user INTEGER,
+    game_ids JSONB,
+    category_ids JSONB,
+    is_active BOOLEAN DEFAULT true,
+    created_by BIGINT NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Bookings table (order_id is set when the booking is a line item of an order)
 CREATE TABLE bookings (
     id BIGSERIAL PRIMARY KEY,
     user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    order_id BIGINT REFERENCES orders(id) ON DELETE CASCADE,
     game_id BIGINT NOT NULL REFERENCES games(id) ON DELETE CASCADE,
     start_date DATE NOT NULL,
     end_date DATE NOT NULL,
@@ -56,23 +89,230 @@ CREATE TABLE bookings (
     daily_price DECIMAL(10,2) NOT NULL,
     total_rental_price DECIMAL(10,2) NOT NULL,
     security_deposit DECIMAL(10,2) DEFAULT 0.00,
+    promo_code_id BIGINT REFERENCES promo_codes(id),
+    discount_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    tax_rate BIGINT NOT NULL DEFAULT 0,
+    tax_inclusive BOOLEAN NOT NULL DEFAULT FALSE,
+    taxable_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    tax_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
     total_amount DECIMAL(10,2) NOT NULL,
     status booking_status DEFAULT 'pending',
     notes TEXT,
+    payment_due_at TIMESTAMP,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Booking status history table
+CREATE TABLE booking_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    booking_id BIGINT NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    from_status booking_status,
+    to_status booking_status NOT NULL,
+    changed_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
 -- Payments table 
 CREATE TABLE payments (
     id BIGSERIAL PRIMARY KEY,
-    booking_id BIGINT NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    booking_id BIGINT REFERENCES bookings(id) ON DELETE CASCADE,
+    order_id BIGINT REFERENCES orders(id) ON DELETE CASCADE,
     provider payment_provider NOT NULL,
+    purpose VARCHAR(20) NOT NULL DEFAULT 'booking',
     provider_payment_id VARCHAR(255),
     amount DECIMAL(12,2) NOT NULL,
+    wallet_amount DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (wallet_amount >= 0 AND wallet_amount <= amount), -- paid from the wallet, the rest through the provider
+    refunded_amount DECIMAL(12,2) NOT NULL DEFAULT 0,
+    wallet_refunded_amount DECIMAL(12,2) NOT NULL DEFAULT 0, -- part of refunded_amount credited to the wallet
+    charged_back_amount DECIMAL(12,2) NOT NULL DEFAULT 0, -- taken back through lost disputes
     status payment_status DEFAULT 'pending',
     payment_method VARCHAR(100),
+    instructions JSONB, -- VA numbers, QR string, deeplinks and expiry returned with the charge
     paid_at TIMESTAMP,
+    failed_at TIMESTAMP,
+    failure_reason TEXT,
+    proof_path TEXT, -- transfer receipt of an offline payment, in the private receipt bucket
+    proof_uploaded_at TIMESTAMP,
+    reviewed_by BIGINT REFERENCES users(id), -- admin who decided a fraud review or verified an offline payment
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((booking_id IS NULL) <> (order_id IS NULL))
+);
+
+-- Payment refunds table (full and partial refunds sent through the provider or credited to the wallet)
+CREATE TABLE payment_refunds (
+    id BIGSERIAL PRIMARY KEY,
+    payment_id BIGINT NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    amount DECIMAL(12,2) NOT NULL CHECK (amount > 0),
+    wallet_amount DECIMAL(12,2) NOT NULL DEFAULT 0, -- part credited to the wallet
+    reason TEXT NOT NULL,
+    status VARCHAR(20) DEFAULT 'pending',
+    provider_refund_id VARCHAR(255),
+    failure_reason TEXT,
+    refunded_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Payment events table (every provider notification, deduplicated per transaction status)
+CREATE TABLE payment_events (
+    id BIGSERIAL PRIMARY KEY,
+    provider payment_provider NOT NULL,
+    transaction_id VARCHAR(255) NOT NULL,
+    gateway_order_id VARCHAR(255) NOT NULL,
+    transaction_status VARCHAR(50) NOT NULL,
+    gross_amount VARCHAR(50),
+    payment_id BIGINT REFERENCES payments(id) ON DELETE SET NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) DEFAULT 'received',
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    processed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Payment disputes table (chargebacks reported by the provider, one row per provider dispute)
+CREATE TABLE payment_disputes (
+    id BIGSERIAL PRIMARY KEY,
+    payment_id BIGINT NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    provider payment_provider NOT NULL,
+    provider_dispute_id VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    reason TEXT,
+    amount DECIMAL(12,2) NOT NULL CHECK (amount > 0),
+    evidence_due_by TIMESTAMP,
+    evidence TEXT,
+    evidence_submitted_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    evidence_submitted_at TIMESTAMP,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Payment reconciliations table (pending payments whose gateway status disagreed)
+CREATE TABLE payment_reconciliations (
+    id BIGSERIAL PRIMARY KEY,
+    payment_id BIGINT NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    local_status payment_status NOT NULL,
+    gateway_status VARCHAR(50) NOT NULL,
+    resolution VARCHAR(20) NOT NULL,
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Booking date changes table (extensions and reschedules)
+CREATE TABLE booking_date_changes (
+    id BIGSERIAL PRIMARY KEY,
+    booking_id BIGINT NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL,
+    status VARCHAR(20) DEFAULT 'pending',
+    old_start_date DATE NOT NULL,
+    old_end_date DATE NOT NULL,
+    new_start_date DATE NOT NULL,
+    new_end_date DATE NOT NULL,
+    rental_days INTEGER NOT NULL,
+    total_rental_price DECIMAL(10,2) NOT NULL,
+    discount_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    tax_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    amount_due DECIMAL(10,2) NOT NULL,
+    payment_id BIGINT REFERENCES payments(id) ON DELETE SET NULL,
+    refund_status VARCHAR(20),
+    refund_error TEXT,
+    provider_refund_id VARCHAR(255),
+    requested_by BIGINT NOT NULL REFERENCES users(id),
+    applied_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Booking settlements table (deposit breakdown recorded on return)
+CREATE TABLE booking_settlements (
+    id BIGSERIAL PRIMARY KEY,
+    booking_id BIGINT UNIQUE NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    returned_at DATE NOT NULL,
+    late_days INTEGER NOT NULL DEFAULT 0,
+    late_fee DECIMAL(10,2) NOT NULL DEFAULT 0,
+    damage_charge DECIMAL(10,2) NOT NULL DEFAULT 0,
+    damage_notes TEXT,
+    deposit_amount DECIMAL(10,2) NOT NULL,
+    deposit_refund DECIMAL(10,2) NOT NULL,
+    outstanding_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    refund_status VARCHAR(20) DEFAULT 'none',
+    refund_to_wallet BOOLEAN NOT NULL DEFAULT false,
+    provider_refund_id VARCHAR(255),
+    refund_error TEXT,
+    settled_by BIGINT NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Invoices table (one numbered invoice per paid booking)
+CREATE TABLE invoices (
+    id BIGSERIAL PRIMARY KEY,
+    number VARCHAR(30) UNIQUE NOT NULL,
+    booking_id BIGINT NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    date_change_id BIGINT UNIQUE REFERENCES booking_date_changes(id) ON DELETE CASCADE,
+    payment_id BIGINT NOT NULL REFERENCES payments(id),
+    issued_at TIMESTAMP NOT NULL,
+    -- What was invoiced; for a date change, the difference it made
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    rental_days INTEGER NOT NULL,
+    daily_price DECIMAL(10,2) NOT NULL,
+    total_rental_price DECIMAL(10,2) NOT NULL,
+    security_deposit DECIMAL(10,2) NOT NULL DEFAULT 0,
+    discount_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    tax_rate BIGINT NOT NULL DEFAULT 0,
+    tax_inclusive BOOLEAN NOT NULL DEFAULT FALSE,
+    taxable_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    tax_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    total_amount DECIMAL(10,2) NOT NULL, -- negative on a credit note
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Invoice counters table (last invoice number issued per year)
+CREATE TABLE invoice_counters (
+    year INTEGER PRIMARY KEY,
+    last_number BIGINT NOT NULL
+);
+
+-- Waitlist entries table (customers queued for a game with no free copy)
+CREATE TABLE waitlist_entries (
+    id BIGSERIAL PRIMARY KEY,
+    game_id BIGINT NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    status VARCHAR(20) DEFAULT 'waiting',
+    offered_at TIMESTAMP,
+    hold_expires_at TIMESTAMP,
+    booking_id BIGINT REFERENCES bookings(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Wallets table (store credit balance, always the sum of the user's ledger entries)
+CREATE TABLE wallets (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    balance DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Wallet entries table (append-only ledger: credits positive, debits negative)
+CREATE TABLE wallet_entries (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES wallets(user_id),
+    type VARCHAR(20) NOT NULL,
+    amount DECIMAL(12,2) NOT NULL CHECK (amount <> 0),
+    balance_after DECIMAL(12,2) NOT NULL CHECK (balance_after >= 0),
+    payment_id BIGINT REFERENCES payments(id),
+    refund_id BIGINT REFERENCES payment_refunds(id),
+    description TEXT NOT NULL,
+    created_by BIGINT REFERENCES users(id),
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -97,8 +337,37 @@ CREATE INDEX idx_games_is_active ON games(is_active);
 CREATE INDEX idx_bookings_user_id ON bookings(user_id);
 CREATE INDEX idx_bookings_game_id ON bookings(game_id);
 CREATE INDEX idx_bookings_status ON bookings(status);
+CREATE INDEX idx_bookings_game_dates ON bookings(game_id, start_date, end_date);
+CREATE INDEX idx_bookings_pending_due ON bookings(payment_due_at) WHERE status = 'pending';
+CREATE INDEX idx_booking_status_history_booking_id ON booking_status_history(booking_id);
 CREATE INDEX idx_payments_booking_id ON payments(booking_id);
+CREATE INDEX idx_payments_order_id ON payments(order_id);
+-- one open payment attempt per booking or order at a time
+CREATE UNIQUE INDEX idx_payments_booking_pending_attempt ON payments(booking_id) WHERE purpose = 'booking' AND status IN ('pending', 'review');
+CREATE UNIQUE INDEX idx_payments_order_pending_attempt ON payments(order_id) WHERE purpose = 'order' AND status IN ('pending', 'review');
+CREATE INDEX idx_payment_refunds_payment_id ON payment_refunds(payment_id);
+CREATE UNIQUE INDEX idx_payment_events_dedupe ON payment_events(provider, transaction_id, transaction_status) WHERE status <> 'rejected';
+CREATE INDEX idx_payment_events_status ON payment_events(status, created_at);
+CREATE UNIQUE INDEX idx_payment_disputes_provider_dispute ON payment_disputes(provider, provider_dispute_id);
+CREATE INDEX idx_payment_disputes_payment_id ON payment_disputes(payment_id);
+CREATE INDEX idx_payment_disputes_status ON payment_disputes(status, created_at);
+CREATE INDEX idx_payment_reconciliations_created_at ON payment_reconciliations(created_at);
+CREATE INDEX idx_payment_reconciliations_open ON payment_reconciliations(payment_id) WHERE resolution IN ('unresolved', 'failed');
+CREATE INDEX idx_payments_status_created_at ON payments(status, created_at);
+CREATE INDEX idx_orders_user_id ON orders(user_id);
+CREATE INDEX idx_bookings_order_id ON bookings(order_id);
+CREATE INDEX idx_bookings_promo_code_id ON bookings(promo_code_id) WHERE promo_code_id IS NOT NULL;
+CREATE INDEX idx_booking_date_changes_booking_id ON booking_date_changes(booking_id);
+CREATE INDEX idx_booking_date_changes_payment_id ON booking_date_changes(payment_id);
+CREATE INDEX idx_booking_date_changes_pending ON booking_date_changes(created_at) WHERE status = 'pending';
+CREATE INDEX idx_invoices_issued_at ON invoices(issued_at);
+-- One invoice per booking, besides those of its date changes
+CREATE UNIQUE INDEX idx_invoices_booking_id ON invoices(booking_id) WHERE date_change_id IS NULL;
 CREATE INDEX idx_reviews_game_id ON reviews(game_id);
+CREATE INDEX idx_waitlist_entries_game_status ON waitlist_entries(game_id, status, created_at);
+CREATE INDEX idx_waitlist_entries_user_id ON waitlist_entries(user_id);
+CREATE INDEX idx_wallet_entries_user_id ON wallet_entries(user_id, id);
+CREATE INDEX idx_wallet_entries_payment_id ON wallet_entries(payment_id) WHERE payment_id IS NOT NULL;
 
 -- Triggers for updated_at
 CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -111,7 +380,22 @@ $$ language 'plpgsql';
 
 CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_games_updated_at BEFORE UPDATE ON games FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_promo_codes_updated_at BEFORE UPDATE ON promo_codes FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_orders_updated_at BEFORE UPDATE ON orders FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_bookings_updated_at BEFORE UPDATE ON bookings FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_booking_settlements_updated_at BEFORE UPDATE ON booking_settlements FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_payment_events_updated_at BEFORE UPDATE ON payment_events FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_payment_disputes_updated_at BEFORE UPDATE ON payment_disputes FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_payment_refunds_updated_at BEFORE UPDATE ON payment_refunds FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 CREATE TRIGGER update_reviews_updated_at BEFORE UPDATE ON reviews FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_waitlist_entries_updated_at BEFORE UPDATE ON waitlist_entries FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+-- Wallet entries are never changed or removed, mistakes are corrected with another entry
+CREATE OR REPLACE FUNCTION prevent_wallet_entry_changes()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'wallet_entries is append-only';
+END;
+$$ language 'plpgsql';
 
+CREATE TRIGGER wallet_entries_append_only BEFORE UPDATE OR DELETE ON wallet_entries FOR EACH ROW EXECUTE FUNCTION prevent_wallet_entry_changes();
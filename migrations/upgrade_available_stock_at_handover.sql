@@ -0,0 +1,26 @@
+-- Upgrade for databases created before available_stock moved to handover.
+--
+-- Bookings used to take a copy off games.available_stock when they were
+-- created and give it back when they were cancelled or completed. Now a copy
+-- only leaves the shelf at handover (active) and comes back on return
+-- (completed); pending and confirmed bookings hold their dates through
+-- date-range availability instead. Pending and confirmed bookings created
+-- before the upgrade still carry the copy they took at creation, which their
+-- cancellation or expiry would never give back and their handover would take
+-- a second time, so the shelf count is recomputed from the copies handed out.
+--
+-- Run once right after deploying the upgrade. Running it again is harmless.
+
+BEGIN;
+
+-- Keep handovers and returns out while the counts are taken
+LOCK TABLE bookings IN SHARE MODE;
+
+UPDATE games g
+SET available_stock = GREATEST(g.stock - (
+    SELECT COUNT(*)
+    FROM bookings b
+    WHERE b.game_id = g.id AND b.status = 'active'
+), 0);
+
+COMMIT;
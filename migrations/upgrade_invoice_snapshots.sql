@@ -0,0 +1,57 @@
+-- Upgrade for databases created before invoices kept what they invoiced.
+--
+-- Invoices used to be rendered from the booking, so a date change applied
+-- after payment rewrote the dates and amounts of an invoice already sent to
+-- the customer. Invoices now keep their own copy, and a paid date change gets
+-- an invoice (or a credit note) of its own, so a booking can have more than
+-- one invoice. Existing invoices are filled in from their bookings as they
+-- stand, which is the best record left of what was invoiced.
+--
+-- Run once right after deploying the upgrade, before any date change is paid.
+
+BEGIN;
+
+ALTER TABLE invoices
+    ADD COLUMN date_change_id BIGINT UNIQUE REFERENCES booking_date_changes(id) ON DELETE CASCADE,
+    ADD COLUMN start_date DATE,
+    ADD COLUMN end_date DATE,
+    ADD COLUMN rental_days INTEGER,
+    ADD COLUMN daily_price DECIMAL(10,2),
+    ADD COLUMN total_rental_price DECIMAL(10,2),
+    ADD COLUMN security_deposit DECIMAL(10,2) NOT NULL DEFAULT 0,
+    ADD COLUMN discount_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    ADD COLUMN tax_rate BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN tax_inclusive BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN taxable_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    ADD COLUMN tax_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+    ADD COLUMN total_amount DECIMAL(10,2);
+
+UPDATE invoices i
+SET start_date = b.start_date,
+    end_date = b.end_date,
+    rental_days = b.rental_days,
+    daily_price = b.daily_price,
+    total_rental_price = b.total_rental_price,
+    security_deposit = b.security_deposit,
+    discount_amount = b.discount_amount,
+    tax_rate = b.tax_rate,
+    tax_inclusive = b.tax_inclusive,
+    taxable_amount = b.taxable_amount,
+    tax_amount = b.tax_amount,
+    total_amount = b.total_amount
+FROM bookings b
+WHERE b.id = i.booking_id;
+
+ALTER TABLE invoices
+    ALTER COLUMN start_date SET NOT NULL,
+    ALTER COLUMN end_date SET NOT NULL,
+    ALTER COLUMN rental_days SET NOT NULL,
+    ALTER COLUMN daily_price SET NOT NULL,
+    ALTER COLUMN total_rental_price SET NOT NULL,
+    ALTER COLUMN total_amount SET NOT NULL;
+
+-- One invoice per booking, besides those of its date changes
+ALTER TABLE invoices DROP CONSTRAINT invoices_booking_id_key;
+CREATE UNIQUE INDEX idx_invoices_booking_id ON invoices(booking_id) WHERE date_change_id IS NULL;
+
+COMMIT;